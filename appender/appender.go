@@ -1,21 +1,228 @@
 package appender
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+
 	"github.com/shanexu/logn/appender/encoder"
 	"github.com/shanexu/logn/appender/writer"
+	"github.com/shanexu/logn/appender/writer/async"
+	"github.com/shanexu/logn/appender/writer/breaker"
+	"github.com/shanexu/logn/appender/writer/encrypt"
+	"github.com/shanexu/logn/appender/writer/retry"
+	"github.com/shanexu/logn/appender/writer/shard"
+	"github.com/shanexu/logn/appender/writer/spool"
 	"github.com/shanexu/logn/common"
+	cfg "github.com/shanexu/logn/config"
+	"github.com/shanexu/logn/filter"
+	"github.com/shanexu/logn/hook"
+	"github.com/shanexu/logn/limit"
+	"github.com/shanexu/logn/marker"
+	"github.com/shanexu/logn/redact"
+	"github.com/shanexu/logn/sign"
+	"github.com/shanexu/logn/status"
 )
 
 type Appender struct {
+	// the fields accessed atomically come first, for them to be 64-bit
+	// aligned on 32-bit platforms
+	errors   uint64
+	counters counters
+	health   health
+
+	Name    string
 	Writer  writer.Writer
 	Encoder encoder.Encoder
+	Hooks   []hook.Hook
+	// Markers, if not empty, restricts the appender to entries carrying one
+	// of these markers.
+	Markers []string
+	// DenyMarkers excludes the entries carrying one of these markers.
+	DenyMarkers []string
+	// Filter, if not nil, selects the entries the appender writes.
+	Filter *filter.Filter
+	// Messages, if not nil, selects the entries the appender writes by
+	// message.
+	Messages *filter.Messages
+	// Fields, if not nil, selects the entries the appender writes by field.
+	Fields *filter.Fields
+	// Redactor, if not nil, scrubs personal data from the entries before
+	// they are encoded.
+	Redactor *redact.Redactor
+	// Signer, if not nil, signs the entries written by the appender.
+	Signer *sign.Signer
+	// Profile tells whether to label the encoding and writing of entries
+	// for pprof and execution traces.
+	Profile bool
+	// Levels, if not nil, restricts the appender to entries of a range of
+	// levels.
+	Levels *LevelRange
+	// Limiter, if not nil, caps the number of fields of the entries, and how
+	// deep their values nest.
+	Limiter *limit.Limiter
+
+	// base is Writer without the decorators wrapped around it
+	base writer.Writer
+	// breaker is the circuit breaker among the decorators, if any
+	breaker writer.Writer
+
+	// mu guards the writes against Close, which sets closed, and Replace,
+	// which sets next too
+	mu     sync.RWMutex
+	closed bool
+	next   *Appender
+}
+
+// Config holds the settings shared by all appender types.
+type Config struct {
+	Name          string            `logn-config:"name"`
+	Hooks         []string          `logn-config:"hooks"`
+	Markers       []string          `logn-config:"markers"`
+	DenyMarkers   []string          `logn-config:"deny_markers"`
+	Filter        string            `logn-config:"filter"`
+	AllowMessages []string          `logn-config:"allow_messages"`
+	DenyMessages  []string          `logn-config:"deny_messages"`
+	FieldFilters  []cfg.FieldFilter `logn-config:"field_filters"`
+	Redact        *redact.Config    `logn-config:"redact"`
+	Retry         *retry.Config     `logn-config:"retry"`
+	Breaker       *breaker.Config   `logn-config:"circuit_breaker"`
+	Spool         *spool.Config     `logn-config:"spool"`
+	Encryption    *encrypt.Config   `logn-config:"encryption"`
+	Signing       *sign.Config      `logn-config:"signing"`
+	Async         *async.Config     `logn-config:"async"`
+	Sharded       *shard.Config     `logn-config:"sharded"`
+	Profile       bool              `logn-config:"profile"`
+	MinLevel      string            `logn-config:"min_level"`
+	MaxLevel      string            `logn-config:"max_level"`
+	FieldLimits   *limit.Config     `logn-config:"field_limits"`
 }
 
 func CreateAppender(writerType string, config *common.Config) (*Appender, error) {
-	w, err := writer.NewWriter(writerType, config)
+	return createAppender(writerType, config, nil)
+}
+
+// CreateDryRunAppender creates the appender config describes, except that it
+// writes its encoded entries to w: the writer of writerType is not created,
+// nor are its profiling, retry, circuit breaker, spool, encryption, async or
+// sharded decorators, so that nothing is opened, sent or spilled.
+func CreateDryRunAppender(writerType string, config *common.Config, w writer.Writer) (*Appender, error) {
+	if !writer.Registered(writerType) {
+		return nil, fmt.Errorf("writer type %v undefined", writerType)
+	}
+	return createAppender(writerType, config, w)
+}
+
+// createAppender creates the appender config describes, writing to dry
+// instead of a writer of writerType if dry is not nil.
+func createAppender(writerType string, config *common.Config, dry writer.Writer) (*Appender, error) {
+	ac := Config{}
+	if err := config.Unpack(&ac); err != nil {
+		return nil, err
+	}
+	if ac.Async != nil && ac.Sharded != nil {
+		return nil, errors.New("async and sharded are exclusive")
+	}
+	hooks, err := hook.Lookup(ac.Hooks)
+	if err != nil {
+		return nil, err
+	}
+	var f *filter.Filter
+	if ac.Filter != "" {
+		if f, err = filter.Compile(ac.Filter); err != nil {
+			return nil, err
+		}
+	}
+	messages, err := filter.NewMessages(ac.AllowMessages, ac.DenyMessages)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := filter.NewFields(ac.FieldFilters)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := newLevelRange(ac.MinLevel, ac.MaxLevel)
 	if err != nil {
 		return nil, err
 	}
+	var redactor *redact.Redactor
+	if ac.Redact != nil {
+		if redactor, err = redact.New(*ac.Redact); err != nil {
+			return nil, err
+		}
+	}
+	var limiter *limit.Limiter
+	if ac.FieldLimits != nil {
+		limiter = limit.New(*ac.FieldLimits)
+	}
+	var signer *sign.Signer
+	if ac.Signing != nil {
+		key, err := encrypt.ResolveKey(ac.Signing.Key)
+		if err != nil {
+			return nil, err
+		}
+		signer = sign.NewSigner(key, ac.Signing.Field)
+	}
+	w, base := dry, dry
+	var brk writer.Writer
+	if dry == nil {
+		if base, err = writer.NewWriter(writerType, config); err != nil {
+			return nil, err
+		}
+		if _, ok := base.(writer.EntryWriter); ok {
+			w = base
+		} else if w, brk, err = decorate(base, ac); err != nil {
+			return nil, err
+		}
+	}
+	var e encoder.Encoder
+	switch _, entries := base.(writer.EntryWriter); {
+	case entries:
+		// entries are written as they are
+	case dry != nil && !config.HasField("encoder"):
+		// the writer may take entries, which dry runs encode as JSON
+		if e, err = newEncoder(dryRunEncoder); err != nil {
+			return nil, err
+		}
+	default:
+		if e, err = newEncoder(config); err != nil {
+			return nil, err
+		}
+		e = encoder.ForTerminal(e, colorTerminal(base))
+	}
+	status.Debugf("created %s appender %q", writerType, ac.Name)
+	return &Appender{
+		Name:        ac.Name,
+		Writer:      w,
+		Encoder:     e,
+		Hooks:       hooks,
+		Markers:     ac.Markers,
+		DenyMarkers: ac.DenyMarkers,
+		Filter:      f,
+		Messages:    messages,
+		Fields:      fields,
+		Redactor:    redactor,
+		Signer:      signer,
+		Profile:     ac.Profile,
+		Levels:      levels,
+		Limiter:     limiter,
+		base:        base,
+		breaker:     brk,
+	}, nil
+}
+
+// dryRunEncoder configures the encoder of the dry runs of appenders without
+// one.
+var dryRunEncoder = common.MustNewConfigFrom(map[string]interface{}{
+	"encoder": map[string]interface{}{"json": map[string]interface{}{}},
+})
+
+// newEncoder creates the encoder of the appender config describes.
+func newEncoder(config *common.Config) (encoder.Encoder, error) {
 	encoderConfig, err := config.Child("encoder", -1)
 	if err != nil {
 		return nil, err
@@ -24,9 +231,178 @@ func CreateAppender(writerType string, config *common.Config) (*Appender, error)
 	if err := encoderConfig.Unpack(&ec); err != nil {
 		return nil, err
 	}
-	e, err := encoder.CreateEncoder(ec)
-	if err != nil {
-		return nil, err
+	return encoder.CreateEncoder(ec)
+}
+
+// decorate wraps w in the decorators ac asks for, returning the circuit
+// breaker among them too, if any.
+func decorate(w writer.Writer, ac Config) (writer.Writer, writer.Writer, error) {
+	var brk writer.Writer
+	var err error
+	if ac.Profile {
+		w = newProfileWriter(w, ac.Name)
+	}
+	if ac.Retry != nil {
+		if w, err = retry.New(w, *ac.Retry); err != nil {
+			return nil, nil, err
+		}
+	}
+	if ac.Breaker != nil {
+		if w, err = breaker.New(w, ac.Name, *ac.Breaker); err != nil {
+			return nil, nil, err
+		}
+		brk = w
+	}
+	if ac.Spool != nil {
+		if w, err = spool.New(w, ac.Name, *ac.Spool); err != nil {
+			return nil, nil, err
+		}
 	}
-	return &Appender{w, e}, nil
+	if ac.Encryption != nil {
+		if w, err = encrypt.New(w, *ac.Encryption); err != nil {
+			return nil, nil, err
+		}
+	}
+	if ac.Async != nil {
+		if w, err = async.New(w, ac.Name, *ac.Async); err != nil {
+			return nil, nil, err
+		}
+	}
+	if ac.Sharded != nil {
+		if w, err = shard.New(w, ac.Name, *ac.Sharded); err != nil {
+			return nil, nil, err
+		}
+	}
+	return w, brk, nil
+}
+
+// NewCore builds the zapcore.Core writing entries enabled by level to this
+// appender.
+func (a *Appender) NewCore(level zapcore.LevelEnabler) zapcore.Core {
+	return a.newCore(level, false)
+}
+
+// NewStrictCore is like NewCore, except that write errors, besides being
+// counted and handled, are returned to the caller.
+func (a *Appender) NewStrictCore(level zapcore.LevelEnabler) zapcore.Core {
+	return a.newCore(level, true)
+}
+
+func (a *Appender) newCore(level zapcore.LevelEnabler, strict bool) zapcore.Core {
+	if a.Levels != nil {
+		level = bothLevels{level, a.Levels}
+	}
+	var ioc zapcore.Core
+	if a.takesEntries() {
+		ioc = &entryCore{LevelEnabler: level, appender: a}
+	} else {
+		ioc = &ioCore{LevelEnabler: level, enc: a.Encoder, appender: a}
+	}
+	if r := encoder.Rewriter(a.Encoder); r != nil {
+		ioc = &rewriteCore{Core: ioc, rewriter: r}
+	}
+	if a.Profile {
+		ioc = newProfileCore(ioc, a.Name)
+	}
+	var zc zapcore.Core = &errorCore{
+		Core:     ioc,
+		appender: a,
+		strict:   strict,
+	}
+	zc = limit.NewCore(zc, a.Limiter)
+	zc = redact.NewCore(zc, a.Redactor)
+	zc = hook.NewCore(zc, a.Hooks...)
+	zc = marker.NewCore(zc, a.Markers, a.DenyMarkers)
+	zc = filter.NewFieldsCore(zc, a.Fields)
+	zc = filter.NewCore(zc, a.Filter)
+	zc = filter.NewMessagesCore(zc, a.Messages)
+	return newShedCore(zc, a.Writer)
+}
+
+// rewriteCore rewrites the fields given to With as its encoder does the
+// fields of entries.
+type rewriteCore struct {
+	zapcore.Core
+	rewriter encoder.FieldRewriter
+}
+
+func (c *rewriteCore) With(fields []zapcore.Field) zapcore.Core {
+	return &rewriteCore{Core: c.Core.With(c.rewriter.RewriteFields(fields)), rewriter: c.rewriter}
+}
+
+// File returns the file the appender writes to, if it writes to one as it
+// is, unbuffered.
+func (a *Appender) File() (*os.File, bool) {
+	if fw, ok := a.base.(writer.FileWriter); ok {
+		return fw.OSFile(), true
+	}
+	return nil, false
+}
+
+// Reopen closes and opens again the file the appender writes to, if any,
+// e.g. once logrotate moved it away. The entries queued or buffered before
+// are written to the former file.
+func (a *Appender) Reopen() error {
+	r, ok := a.base.(writer.Reopener)
+	if !ok {
+		return nil
+	}
+	a.Writer.Sync()
+	return r.Reopen()
+}
+
+// Close closes the writer of the appender once the entries being written
+// are, releasing its files, connections and goroutines. The entries written
+// afterwards fail with writer.ErrClosed.
+func (a *Appender) Close() error {
+	return a.Replace(nil)
+}
+
+// Replace closes the appender as Close does, except that the entries written
+// to it afterwards, e.g. by the loggers derived with With before a
+// Core.Update, are written by next instead, unless next is nil or takes
+// entries where the appender takes their encoding, or conversely.
+func (a *Appender) Replace(next *Appender) error {
+	if next != nil && (next == a || a.takesEntries() != next.takesEntries()) {
+		next = nil
+	}
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed, a.next = true, next
+	a.mu.Unlock()
+	return writer.Close(a.Writer)
+}
+
+// acquire returns the appender entries written to a go to, a itself until it
+// is closed, with its read lock held until release is called on it. It
+// returns nil once a is closed without replacement.
+func (a *Appender) acquire() *Appender {
+	a.mu.RLock()
+	if !a.closed {
+		return a
+	}
+	next := a.next
+	a.mu.RUnlock()
+	if next == nil {
+		return nil
+	}
+	return next.acquire()
+}
+
+func (a *Appender) release() {
+	a.mu.RUnlock()
+}
+
+func (a *Appender) takesEntries() bool {
+	_, ok := a.Writer.(writer.EntryWriter)
+	return ok
+}
+
+// Errors returns the number of entries this appender failed to encode or
+// write.
+func (a *Appender) Errors() uint64 {
+	return atomic.LoadUint64(&a.errors)
 }
@@ -0,0 +1,115 @@
+package appender
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	_ "github.com/shanexu/logn/appender/encoder/json"
+	"github.com/shanexu/logn/appender/writer"
+	"github.com/shanexu/logn/common"
+)
+
+func TestRichErrorsWith(t *testing.T) {
+	config, err := common.NewConfigFrom(`
+name: CONSOLE
+encoder:
+  json:
+    error_encoder: rich
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bufferWriter{}
+	a, err := CreateDryRunAppender("console", config, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cause := errors.New("disk full")
+	zap.New(a.NewCore(zapcore.InfoLevel)).With(zap.Error(errors.Wrap(cause, "save"))).Info("failed")
+	var m struct {
+		Error struct {
+			Message string `json:"message"`
+			Causes  []struct {
+				Message string `json:"message"`
+			} `json:"causes"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "save: disk full", m.Error.Message)
+	if assert.Len(t, m.Error.Causes, 1) {
+		assert.Equal(t, "disk full", m.Error.Causes[0].Message)
+	}
+}
+
+func TestStats(t *testing.T) {
+	config, err := common.NewConfigFrom(`
+name: CONSOLE
+async:
+  queue_size: 16
+encoder:
+  json:
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := CreateAppender("console", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := a.Stats()
+	assert.Equal(t, 16, st.QueueCapacity)
+	assert.Equal(t, 0, st.QueueDepth)
+
+	out := &bufferWriter{}
+	a, err = CreateDryRunAppender("console", config, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zap.New(a.NewCore(zapcore.InfoLevel)).Info("counted")
+	st = a.Stats()
+	assert.Equal(t, uint64(1), st.Entries)
+	assert.Equal(t, uint64(out.Len()), st.Bytes)
+	assert.Equal(t, 0, st.QueueCapacity)
+}
+
+func TestReplace(t *testing.T) {
+	config, err := common.NewConfigFrom(`
+name: CONSOLE
+encoder:
+  json:
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newAppender := func() (*Appender, *bufferWriter) {
+		out := &bufferWriter{}
+		a, err := CreateDryRunAppender("console", config, out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a, out
+	}
+	a, aOut := newAppender()
+	b, bOut := newAppender()
+	core := a.NewStrictCore(zapcore.InfoLevel).With([]zapcore.Field{zap.String("k", "v")})
+	logger := zap.New(core)
+	logger.Info("before")
+	assert.Nil(t, a.Replace(b))
+	logger.Info("after")
+	assert.Contains(t, aOut.String(), `"msg":"before"`)
+	assert.NotContains(t, aOut.String(), "after")
+	assert.Contains(t, bOut.String(), `"msg":"after","k":"v"`)
+	assert.Equal(t, uint64(1), b.Stats().Entries)
+
+	assert.Nil(t, b.Close())
+	err = core.Write(zapcore.Entry{Level: zapcore.InfoLevel, Message: "closed"}, nil)
+	assert.Equal(t, writer.ErrClosed, err)
+	assert.NotContains(t, bOut.String(), "closed")
+}
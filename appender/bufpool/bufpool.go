@@ -0,0 +1,62 @@
+// Package bufpool pools the byte slices appenders and writers need per entry,
+// e.g. to copy or frame encoded entries, so that logging to several sinks at
+// high throughput does not allocate a slice per entry and sink. Encoded
+// entries themselves live in the buffers of zap's pool, shared by every
+// encoder.
+//
+// Slices are pooled by size class, so that a few large entries do not leave
+// every pooled slice oversized. Slices larger than the largest class are not
+// pooled.
+package bufpool
+
+import "sync"
+
+var classes = [...]int{512, 2 << 10, 8 << 10, 32 << 10, 128 << 10}
+
+var pools [len(classes)]sync.Pool
+
+func init() {
+	for i := range pools {
+		size := classes[i]
+		pools[i].New = func() interface{} {
+			b := make([]byte, 0, size)
+			return &b
+		}
+	}
+}
+
+func class(n int) int {
+	for i, size := range classes {
+		if n <= size {
+			return i
+		}
+	}
+	return -1
+}
+
+// Get returns an empty slice with a capacity of at least n bytes. It should
+// be given back with Put once no longer used.
+func Get(n int) *[]byte {
+	if i := class(n); i >= 0 {
+		b := pools[i].Get().(*[]byte)
+		*b = (*b)[:0]
+		return b
+	}
+	b := make([]byte, 0, n)
+	return &b
+}
+
+// Put gives b back to the pool of its size class.
+func Put(b *[]byte) {
+	c := cap(*b)
+	for i := len(classes) - 1; i >= 0; i-- {
+		if c >= classes[i] {
+			// slices grown past the largest class are left to the garbage
+			// collector
+			if i < len(classes)-1 || c == classes[i] {
+				pools[i].Put(b)
+			}
+			return
+		}
+	}
+}
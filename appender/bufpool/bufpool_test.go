@@ -0,0 +1,20 @@
+package bufpool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPut(t *testing.T) {
+	for _, n := range []int{0, 100, 512, 513, 100 << 10, 1 << 20} {
+		b := Get(n)
+		assert.Len(t, *b, 0)
+		assert.True(t, cap(*b) >= n, n)
+		*b = append(*b, make([]byte, n)...)
+		Put(b)
+	}
+
+	b := Get(600)
+	assert.Equal(t, 2<<10, cap(*b))
+}
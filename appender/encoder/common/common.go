@@ -15,6 +15,17 @@ type JsonEncoderConfig struct {
 	StacktraceKey string `logn-config:"stacktrace_key"`
 	LineEnding    string `logn-config:"line_ending"`
 	TimeEncoder   string `logn-config:"time_encoder" logn-validate:"logn.oneof=epoch epoch_millis epoch_nanos ISO8601"`
+	// UptimeKey, if set, is the key of the time elapsed between the start of
+	// the process and entries, told by the monotonic clock.
+	UptimeKey string `logn-config:"uptime_key"`
+	// ErrorEncoder is flat, encoding errors as their message, or rich,
+	// encoding them as objects with their type, stack trace and causes.
+	ErrorEncoder string `logn-config:"error_encoder" logn-validate:"logn.oneof=flat rich"`
+	// ReflectDepth, if set, is how deep the structs, maps and slices of
+	// reflected fields are walked, encoding the values implementing
+	// zapcore.ObjectMarshaler, zapcore.ArrayMarshaler or fmt.Stringer
+	// through them, rather than encoding the fields as encoding/json does.
+	ReflectDepth int `logn-config:"reflect_depth" logn-validate:"min=0"`
 }
 
 func GetTimeEncoder(name string) (zapcore.TimeEncoder, error) {
@@ -0,0 +1,143 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+
+	pkgerrors "github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// maxCauses bounds the causes walked, should an error be its own cause.
+const maxCauses = 32
+
+// errorField returns the field encoding err as a rich error, or, if
+// rich is false, as its message along with the errors it joins, or ok false
+// if err is to be encoded as it is.
+func errorField(key string, err error, rich bool) (zapcore.Field, bool) {
+	if rich {
+		return zap.Object(key, richError{err}), true
+	}
+	errs := joinedErrors(err)
+	if errs == nil {
+		return zapcore.Field{}, false
+	}
+	if _, ok := err.(errorGroup); ok {
+		// zap lists them already
+		return zapcore.Field{}, false
+	}
+	return zap.NamedError(key, joinedError{error: err, errs: errs}), true
+}
+
+// errorGroup is implemented by the errors of go.uber.org/multierr, which zap
+// encodes with the errors they join under the key of the field suffixed with
+// Causes.
+type errorGroup interface {
+	Errors() []error
+}
+
+// joinedError is an error joining errs, that zap encodes like an errorGroup.
+type joinedError struct {
+	error
+	errs []error
+}
+
+func (e joinedError) Errors() []error {
+	return e.errs
+}
+
+// joinedErrors returns the errors err, or the first of its causes doing so,
+// joins, as an errorGroup or with an Unwrap method returning them as errors
+// made by errors.Join do.
+func joinedErrors(err error) []error {
+	for _, err := range errorChain(err) {
+		switch err := err.(type) {
+		case errorGroup:
+			return err.Errors()
+		case interface{ Unwrap() []error }:
+			return err.Unwrap()
+		}
+	}
+	return nil
+}
+
+// stackTracer is implemented by the errors of github.com/pkg/errors which
+// record a stack trace.
+type stackTracer interface {
+	StackTrace() pkgerrors.StackTrace
+}
+
+// richError encodes an error with its type, stack trace and causes, and the
+// errors it joins as rich errors of their own.
+type richError struct {
+	err error
+}
+
+func (e richError) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("message", e.err.Error())
+	enc.AddString("type", fmt.Sprintf("%T", e.err))
+	chain := errorChain(e.err)
+	// the deepest stack trace is closest to where the error happened
+	var stack pkgerrors.StackTrace
+	for _, err := range chain {
+		if st, ok := err.(stackTracer); ok {
+			stack = st.StackTrace()
+		}
+	}
+	if len(stack) > 0 {
+		enc.AddString("stack", fmt.Sprintf("%+v", stack)[1:])
+	}
+	// wrappers adding nothing but a stack trace are no causes of their own
+	var causes []error
+	for i := 1; i < len(chain); i++ {
+		if chain[i].Error() != chain[i-1].Error() {
+			causes = append(causes, chain[i])
+		}
+	}
+	if errs := joinedErrors(e.err); len(errs) > 0 {
+		err := enc.AddArray("errors", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+			for _, err := range errs {
+				arr.AppendObject(richError{err})
+			}
+			return nil
+		}))
+		if err != nil {
+			return err
+		}
+	}
+	if len(causes) == 0 {
+		return nil
+	}
+	return enc.AddArray("causes", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		for _, cause := range causes {
+			cause := cause
+			arr.AppendObject(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+				enc.AddString("message", cause.Error())
+				enc.AddString("type", fmt.Sprintf("%T", cause))
+				return nil
+			}))
+		}
+		return nil
+	}))
+}
+
+// errorChain returns err followed by its causes, from the closest, following
+// Unwrap and the Cause method of github.com/pkg/errors.
+func errorChain(err error) []error {
+	chain := []error{err}
+	for len(chain) <= maxCauses {
+		next := errors.Unwrap(err)
+		if next == nil {
+			if c, ok := err.(interface{ Cause() error }); ok {
+				next = c.Cause()
+			}
+		}
+		if next == nil {
+			break
+		}
+		chain = append(chain, next)
+		err = next
+	}
+	return chain
+}
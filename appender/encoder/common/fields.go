@@ -0,0 +1,69 @@
+package common
+
+import (
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// WithFields makes enc encode error fields as config.ErrorEncoder tells: as
+// objects, holding the message, type and stack trace of the error and the
+// messages and types of its causes, if it is rich, or as a message otherwise.
+// Either way the errors an error joins, such as with errors.Join or multierr,
+// are encoded one by one. If config.ReflectDepth is set, the values of
+// reflected fields are walked that deep, see reflectedValue.
+func WithFields(enc zapcore.Encoder, config JsonEncoderConfig) zapcore.Encoder {
+	return fieldsEncoder{
+		Encoder:      enc,
+		richErrors:   config.ErrorEncoder == "rich",
+		reflectDepth: config.ReflectDepth,
+	}
+}
+
+// fieldsEncoder rewrites the error and reflected fields of entries. The fields
+// given to With are rewritten by the cores of appenders, see RewriteFields.
+type fieldsEncoder struct {
+	zapcore.Encoder
+	richErrors   bool
+	reflectDepth int
+}
+
+func (e fieldsEncoder) Clone() zapcore.Encoder {
+	return fieldsEncoder{Encoder: e.Encoder.Clone(), richErrors: e.richErrors, reflectDepth: e.reflectDepth}
+}
+
+func (e fieldsEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	return e.Encoder.EncodeEntry(ent, e.RewriteFields(fields))
+}
+
+// RewriteFields replaces the error fields among fields by rich errors, or by
+// errors listing the errors they join, and the reflected fields by walks of
+// their values.
+func (e fieldsEncoder) RewriteFields(fields []zapcore.Field) []zapcore.Field {
+	var rewritten []zapcore.Field
+	for i, f := range fields {
+		var field zapcore.Field
+		var ok bool
+		switch f.Type {
+		case zapcore.ErrorType:
+			if err, isErr := f.Interface.(error); isErr {
+				field, ok = errorField(f.Key, err, e.richErrors)
+			}
+		case zapcore.ReflectType:
+			if e.reflectDepth > 0 {
+				field, ok = reflectedField(f.Key, f.Interface, e.reflectDepth)
+			}
+		}
+		if !ok {
+			continue
+		}
+		if rewritten == nil {
+			rewritten = make([]zapcore.Field, len(fields))
+			copy(rewritten, fields)
+		}
+		rewritten[i] = field
+	}
+	if rewritten == nil {
+		return fields
+	}
+	return rewritten
+}
@@ -0,0 +1,228 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	objectMarshalerType = reflect.TypeOf((*zapcore.ObjectMarshaler)(nil)).Elem()
+	arrayMarshalerType  = reflect.TypeOf((*zapcore.ArrayMarshaler)(nil)).Elem()
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	errorType           = reflect.TypeOf((*error)(nil)).Elem()
+	stringerType        = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+// reflectedField returns the field encoding v, the value of a reflected
+// field, as reflectedValue tells, or ok false if v is to be encoded as it is.
+func reflectedField(key string, v interface{}, depth int) (zapcore.Field, bool) {
+	switch v := reflectedValue(reflect.ValueOf(v), depth).(type) {
+	case zapcore.ObjectMarshaler:
+		return zap.Object(key, v), true
+	case zapcore.ArrayMarshaler:
+		return zap.Array(key, v), true
+	case string:
+		return zap.String(key, v), true
+	}
+	return zapcore.Field{}, false
+}
+
+// reflectedValue returns how to encode v: as an ObjectMarshaler or an
+// ArrayMarshaler, as a string, or as it is, by reflection.
+//
+// Values implementing zapcore.ObjectMarshaler or zapcore.ArrayMarshaler are
+// encoded through them, those implementing json.Marshaler as they are, errors
+// and fmt.Stringers as strings, so is the case of the fields of structs, the
+// values of maps and the elements of slices. Structs and maps are walked
+// depth levels deep, together with slices, beyond which they are encoded as
+// fmt.Sprint formats them, which keeps cyclic values from being walked
+// forever.
+func reflectedValue(v reflect.Value, depth int) interface{} {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Ptr {
+			if m, ok := marshaler(v); ok {
+				return m
+			}
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	if m, ok := marshaler(v); ok {
+		return m
+	}
+	if v.CanAddr() {
+		if m, ok := marshaler(v.Addr()); ok {
+			return m
+		}
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+	default:
+		return v.Interface()
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+		// base64, as encoding/json encodes them
+		return v.Interface()
+	}
+	if depth <= 0 {
+		return fmt.Sprint(v.Interface())
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		return reflectedStruct{v, depth - 1}
+	case reflect.Map:
+		return reflectedMap{v, depth - 1}
+	default:
+		return reflectedSlice{v, depth - 1}
+	}
+}
+
+// marshaler returns how to encode v, if its type implements one of the
+// interfaces reflectedValue encodes values through.
+func marshaler(v reflect.Value) (interface{}, bool) {
+	if !v.CanInterface() {
+		return nil, false
+	}
+	t := v.Type()
+	switch {
+	case t.Implements(objectMarshalerType), t.Implements(arrayMarshalerType), t.Implements(jsonMarshalerType):
+		return v.Interface(), true
+	case t.Implements(errorType):
+		return v.Interface().(error).Error(), true
+	case t.Implements(stringerType):
+		return v.Interface().(fmt.Stringer).String(), true
+	}
+	return nil, false
+}
+
+func addReflected(enc zapcore.ObjectEncoder, key string, v reflect.Value, depth int) error {
+	switch v := reflectedValue(v, depth).(type) {
+	case zapcore.ObjectMarshaler:
+		return enc.AddObject(key, v)
+	case zapcore.ArrayMarshaler:
+		return enc.AddArray(key, v)
+	case string:
+		enc.AddString(key, v)
+		return nil
+	default:
+		return enc.AddReflected(key, v)
+	}
+}
+
+func appendReflected(enc zapcore.ArrayEncoder, v reflect.Value, depth int) error {
+	switch v := reflectedValue(v, depth).(type) {
+	case zapcore.ObjectMarshaler:
+		return enc.AppendObject(v)
+	case zapcore.ArrayMarshaler:
+		return enc.AppendArray(v)
+	case string:
+		enc.AppendString(v)
+		return nil
+	default:
+		return enc.AppendReflected(v)
+	}
+}
+
+// reflectedStruct encodes the exported fields of a struct, named as their
+// json tags tell, those of exported embedded structs being promoted.
+type reflectedStruct struct {
+	v     reflect.Value
+	depth int
+}
+
+func (s reflectedStruct) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	t := s.v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, omitEmpty := sf.Name, false
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		if tag != "" {
+			parts := strings.Split(tag, ",")
+			if parts[0] != "" {
+				name = parts[0]
+			}
+			for _, opt := range parts[1:] {
+				omitEmpty = omitEmpty || opt == "omitempty"
+			}
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		fv := s.v.Field(i)
+		if sf.Anonymous && tag == "" && fv.Kind() == reflect.Struct {
+			if err := (reflectedStruct{fv, s.depth}).MarshalLogObject(enc); err != nil {
+				return err
+			}
+			continue
+		}
+		if omitEmpty && fv.IsZero() {
+			continue
+		}
+		if err := addReflected(enc, name, fv, s.depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reflectedMap encodes the entries of a map, sorted by key.
+type reflectedMap struct {
+	v     reflect.Value
+	depth int
+}
+
+func (m reflectedMap) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	keys := m.v.MapKeys()
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = fmt.Sprint(k.Interface())
+	}
+	sort.Sort(byName{names, keys})
+	for i, k := range keys {
+		if err := addReflected(enc, names[i], m.v.MapIndex(k), m.depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type byName struct {
+	names []string
+	keys  []reflect.Value
+}
+
+func (b byName) Len() int           { return len(b.names) }
+func (b byName) Less(i, j int) bool { return b.names[i] < b.names[j] }
+func (b byName) Swap(i, j int) {
+	b.names[i], b.names[j] = b.names[j], b.names[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
+
+// reflectedSlice encodes the elements of a slice or an array.
+type reflectedSlice struct {
+	v     reflect.Value
+	depth int
+}
+
+func (s reflectedSlice) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for i := 0; i < s.v.Len(); i++ {
+		if err := appendReflected(enc, s.v.Index(i), s.depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
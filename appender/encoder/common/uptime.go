@@ -0,0 +1,37 @@
+package common
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// start is when the process started, as far as logn can tell.
+var start = time.Now()
+
+// WithUptime makes enc add to entries, under key, the time elapsed between
+// the start of the process and their time. Both times carrying a monotonic
+// clock reading, the uptime is unaffected by changes of the wall clock, which
+// the timestamp of entries follows. enc is returned as it is if key is empty.
+func WithUptime(enc zapcore.Encoder, key string) zapcore.Encoder {
+	if key == "" {
+		return enc
+	}
+	return uptimeEncoder{Encoder: enc, key: key}
+}
+
+type uptimeEncoder struct {
+	zapcore.Encoder
+	key string
+}
+
+func (e uptimeEncoder) Clone() zapcore.Encoder {
+	return uptimeEncoder{Encoder: e.Encoder.Clone(), key: e.key}
+}
+
+func (e uptimeEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	fields = append(fields[:len(fields):len(fields)], zap.Duration(e.key, ent.Time.Sub(start)))
+	return e.Encoder.EncodeEntry(ent, fields)
+}
@@ -1,21 +1,34 @@
 package console
 
 import (
+	"fmt"
+
 	"github.com/shanexu/logn/appender/encoder"
 	ec "github.com/shanexu/logn/appender/encoder/common"
 	"github.com/shanexu/logn/common"
 	"go.uber.org/zap/zapcore"
 )
 
-var defaultConfig = ec.JsonEncoderConfig{
-	TimeKey:       "ts",
-	LevelKey:      "level",
-	NameKey:       "logger",
-	CallerKey:     "caller",
-	MessageKey:    "msg",
-	StacktraceKey: "stacktrace",
-	LineEnding:    "\n",
-	TimeEncoder:   "epoch",
+// Config is the configuration of console encoders.
+type Config struct {
+	ec.JsonEncoderConfig `logn-config:",inline"`
+	// Color tells whether to render levels in color: always, never, or in
+	// auto mode when writing to a terminal.
+	Color string `logn-config:"color" logn-validate:"logn.oneof=auto always never"`
+}
+
+var defaultConfig = Config{
+	JsonEncoderConfig: ec.JsonEncoderConfig{
+		TimeKey:       "ts",
+		LevelKey:      "level",
+		NameKey:       "logger",
+		CallerKey:     "caller",
+		MessageKey:    "msg",
+		StacktraceKey: "stacktrace",
+		LineEnding:    "\n",
+		TimeEncoder:   "epoch",
+	},
+	Color: "auto",
 }
 
 func init() {
@@ -46,6 +59,29 @@ func init() {
 		}
 		encoderConfig.EncodeTime = te
 
-		return zapcore.NewConsoleEncoder(encoderConfig), nil
+		colorConfig := encoderConfig
+		colorConfig.EncodeLevel = zapcore.LowercaseColorLevelEncoder
+		switch config.Color {
+		case "never":
+			return newEncoder(encoderConfig, config), nil
+		case "always":
+			return newEncoder(colorConfig, config), nil
+		case "auto":
+			return encoder.TerminalChoice{
+				Encoder:  newEncoder(encoderConfig, config),
+				Terminal: newEncoder(colorConfig, config),
+			}, nil
+		default:
+			return nil, fmt.Errorf("unknown color mode %q", config.Color)
+		}
 	})
 }
+
+func newEncoder(encoderConfig zapcore.EncoderConfig, config Config) encoder.Encoder {
+	enc := ec.WithUptime(zapcore.NewConsoleEncoder(encoderConfig), config.UptimeKey)
+	enc = ec.WithFields(enc, config.JsonEncoderConfig)
+	if config.CallerKey == "" {
+		return encoder.WithoutCaller(enc)
+	}
+	return enc
+}
@@ -4,4 +4,60 @@ import "go.uber.org/zap/zapcore"
 
 type Encoder interface {
 	zapcore.Encoder
-}
\ No newline at end of file
+}
+// WithoutCaller marks e as not rendering the caller of entries, so that the
+// loggers writing only to such encoders skip capturing it.
+func WithoutCaller(e Encoder) Encoder {
+	return noCaller{e}
+}
+
+type noCaller struct {
+	Encoder
+}
+
+func (e noCaller) Clone() zapcore.Encoder {
+	return noCaller{e.Encoder.Clone()}
+}
+
+// RendersCaller reports whether e renders the caller of entries.
+func RendersCaller(e Encoder) bool {
+	_, ok := e.(noCaller)
+	return !ok
+}
+
+// FieldRewriter is implemented by encoders rewriting the fields of entries
+// before encoding them. The fields given to With, encoded once beforehand,
+// are to be rewritten by the cores using such encoders.
+type FieldRewriter interface {
+	RewriteFields(fields []zapcore.Field) []zapcore.Field
+}
+
+// Rewriter returns the FieldRewriter e is, or nil.
+func Rewriter(e Encoder) FieldRewriter {
+	if nc, ok := e.(noCaller); ok {
+		e = nc.Encoder
+	}
+	r, _ := e.(FieldRewriter)
+	return r
+}
+
+// TerminalChoice encodes like its Encoder, meant for files and pipes, unless
+// it is resolved by ForTerminal for a terminal, for which Terminal is meant,
+// e.g. rendering levels in color.
+type TerminalChoice struct {
+	Encoder
+	Terminal Encoder
+}
+
+// ForTerminal returns the encoder e stands for when writing to a terminal, or
+// not: the one of a TerminalChoice suiting the writer, e itself otherwise.
+func ForTerminal(e Encoder, terminal bool) Encoder {
+	c, ok := e.(TerminalChoice)
+	if !ok {
+		return e
+	}
+	if terminal {
+		return c.Terminal
+	}
+	return c.Encoder
+}
@@ -1,21 +1,35 @@
 package json
 
 import (
+	"bytes"
+	"encoding/json"
+
 	"github.com/shanexu/logn/appender/encoder"
 	ec "github.com/shanexu/logn/appender/encoder/common"
 	"github.com/shanexu/logn/common"
+	"go.uber.org/zap/buffer"
 	"go.uber.org/zap/zapcore"
 )
 
-var defaultConfig = ec.JsonEncoderConfig{
-	TimeKey:       "ts",
-	LevelKey:      "level",
-	NameKey:       "logger",
-	CallerKey:     "caller",
-	MessageKey:    "msg",
-	StacktraceKey: "stacktrace",
-	LineEnding:    "\n",
-	TimeEncoder:   "epoch",
+// Config is the configuration of JSON encoders.
+type Config struct {
+	ec.JsonEncoderConfig `logn-config:",inline"`
+	// Pretty indents the entries over several lines, for reading them
+	// locally. Tools reading logs line by line expect compact entries.
+	Pretty bool `logn-config:"pretty"`
+}
+
+var defaultConfig = Config{
+	JsonEncoderConfig: ec.JsonEncoderConfig{
+		TimeKey:       "ts",
+		LevelKey:      "level",
+		NameKey:       "logger",
+		CallerKey:     "caller",
+		MessageKey:    "msg",
+		StacktraceKey: "stacktrace",
+		LineEnding:    "\n",
+		TimeEncoder:   "epoch",
+	},
 }
 
 func init() {
@@ -47,6 +61,46 @@ func init() {
 		}
 		encoderConfig.EncodeTime = te
 
-		return zapcore.NewJSONEncoder(encoderConfig), nil
+		enc := ec.WithUptime(zapcore.NewJSONEncoder(encoderConfig), config.UptimeKey)
+		if config.Pretty {
+			lineEnding := config.LineEnding
+			if lineEnding == "" {
+				lineEnding = zapcore.DefaultLineEnding
+			}
+			enc = prettyEncoder{Encoder: enc, lineEnding: lineEnding}
+		}
+		enc = ec.WithFields(enc, config.JsonEncoderConfig)
+		if config.CallerKey == "" {
+			return encoder.WithoutCaller(enc), nil
+		}
+		return enc, nil
 	})
 }
+
+var pool = buffer.NewPool()
+
+// prettyEncoder indents the entries its Encoder encodes.
+type prettyEncoder struct {
+	encoder.Encoder
+	lineEnding string
+}
+
+func (e prettyEncoder) Clone() zapcore.Encoder {
+	return prettyEncoder{Encoder: e.Encoder.Clone(), lineEnding: e.lineEnding}
+}
+
+func (e prettyEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	buf, err := e.Encoder.EncodeEntry(ent, fields)
+	if err != nil {
+		return nil, err
+	}
+	defer buf.Free()
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, bytes.TrimSuffix(buf.Bytes(), []byte(e.lineEnding)), "", "  "); err != nil {
+		return nil, err
+	}
+	out := pool.Get()
+	out.Write(indented.Bytes())
+	out.AppendString(e.lineEnding)
+	return out, nil
+}
@@ -0,0 +1,242 @@
+package json
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/appender/encoder"
+	"github.com/shanexu/logn/common"
+)
+
+func newEncoder(t *testing.T, config string) encoder.Encoder {
+	raw, err := common.NewConfigFrom(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec := encoder.Config{}
+	if err := raw.Unpack(&ec); err != nil {
+		t.Fatal(err)
+	}
+	enc, err := encoder.CreateEncoder(ec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return enc
+}
+
+func TestPretty(t *testing.T) {
+	enc := newEncoder(t, `
+json:
+  time_key: ""
+  caller_key: ""
+  pretty: true
+`)
+	assert.False(t, encoder.RendersCaller(enc))
+	enc = enc.Clone()
+	enc.AddString("app", "demo")
+	buf, err := enc.EncodeEntry(zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now(), Message: "hello"},
+		[]zapcore.Field{zap.Any("user", map[string]interface{}{"id": 1})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `{
+  "level": "info",
+  "msg": "hello",
+  "app": "demo",
+  "user": {
+    "id": 1
+  }
+}
+`, buf.String())
+}
+
+func TestUptime(t *testing.T) {
+	enc := newEncoder(t, `
+json:
+  uptime_key: uptime
+`)
+	uptime := func(ts time.Time) float64 {
+		buf, err := enc.EncodeEntry(zapcore.Entry{Level: zapcore.InfoLevel, Time: ts, Message: "hello"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+			t.Fatal(err)
+		}
+		return m["uptime"].(float64)
+	}
+	now := time.Now()
+	assert.True(t, uptime(now) > 0)
+	assert.InDelta(t, 1.5, uptime(now.Add(1500*time.Millisecond))-uptime(now), 1e-6)
+}
+
+func TestRichErrors(t *testing.T) {
+	enc := newEncoder(t, `
+json:
+  error_encoder: rich
+`)
+	root := fmt.Errorf("connection refused")
+	err := fmt.Errorf("load user: %w", errors.Wrap(root, "query"))
+	buf, encErr := enc.EncodeEntry(zapcore.Entry{Level: zapcore.ErrorLevel, Time: time.Now(), Message: "failed"},
+		[]zapcore.Field{zap.Error(err)})
+	if encErr != nil {
+		t.Fatal(encErr)
+	}
+	var m struct {
+		Error struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+			Stack   string `json:"stack"`
+			Causes  []struct {
+				Message string `json:"message"`
+				Type    string `json:"type"`
+			} `json:"causes"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "load user: query: connection refused", m.Error.Message)
+	assert.Equal(t, "*fmt.wrapError", m.Error.Type)
+	assert.Contains(t, m.Error.Stack, "TestRichErrors")
+	if assert.Len(t, m.Error.Causes, 2) {
+		assert.Equal(t, "query: connection refused", m.Error.Causes[0].Message)
+		assert.Equal(t, "*errors.withStack", m.Error.Causes[0].Type)
+		assert.Equal(t, "connection refused", m.Error.Causes[1].Message)
+		assert.Equal(t, "*errors.errorString", m.Error.Causes[1].Type)
+	}
+
+	flat := newEncoder(t, `
+json:
+  error_encoder: flat
+`)
+	buf, encErr = flat.EncodeEntry(zapcore.Entry{Level: zapcore.ErrorLevel, Time: time.Now(), Message: "failed"},
+		[]zapcore.Field{zap.Error(root)})
+	if encErr != nil {
+		t.Fatal(encErr)
+	}
+	assert.Contains(t, buf.String(), `"error":"connection refused"`)
+}
+
+// joined is an error joining errs, as errors.Join makes them.
+type joined struct {
+	errs []error
+}
+
+func (e joined) Error() string {
+	return "several errors"
+}
+
+func (e joined) Unwrap() []error {
+	return e.errs
+}
+
+func TestJoinedErrors(t *testing.T) {
+	err := fmt.Errorf("close: %w", joined{[]error{fmt.Errorf("flush failed"), errors.New("sync failed")}})
+	encode := func(config string) map[string]interface{} {
+		buf, encErr := newEncoder(t, config).EncodeEntry(zapcore.Entry{Level: zapcore.ErrorLevel, Message: "failed"},
+			[]zapcore.Field{zap.Error(err)})
+		if encErr != nil {
+			t.Fatal(encErr)
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	m := encode(`
+json:
+  time_key: ""
+`)
+	assert.Equal(t, "close: several errors", m["error"])
+	causes := m["errorCauses"].([]interface{})
+	if assert.Len(t, causes, 2) {
+		assert.Equal(t, "flush failed", causes[0].(map[string]interface{})["error"])
+		assert.Equal(t, "sync failed", causes[1].(map[string]interface{})["error"])
+	}
+
+	m = encode(`
+json:
+  time_key: ""
+  error_encoder: rich
+`)
+	rich := m["error"].(map[string]interface{})
+	errs := rich["errors"].([]interface{})
+	if assert.Len(t, errs, 2) {
+		assert.Equal(t, "flush failed", errs[0].(map[string]interface{})["message"])
+		assert.Equal(t, "sync failed", errs[1].(map[string]interface{})["message"])
+		assert.Contains(t, errs[1].(map[string]interface{})["stack"], "TestJoinedErrors")
+	}
+}
+
+type userID int
+
+func (id userID) String() string {
+	return fmt.Sprintf("u-%d", int(id))
+}
+
+type account struct {
+	Balance int
+}
+
+func (a *account) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("balance", fmt.Sprintf("%d.%02d", a.Balance/100, a.Balance%100))
+	return nil
+}
+
+type node struct {
+	Name   string            `json:"name"`
+	ID     userID            `json:"id,omitempty"`
+	Secret string            `json:"-"`
+	Owner  error             `json:"owner,omitempty"`
+	Tags   map[string]userID `json:"tags,omitempty"`
+	Acct   account           `json:"account"`
+	Next   *node             `json:"next,omitempty"`
+}
+
+func TestReflectDepth(t *testing.T) {
+	encode := func(config string, v interface{}) string {
+		buf, err := newEncoder(t, config).EncodeEntry(zapcore.Entry{Level: zapcore.InfoLevel, Message: "hello"},
+			[]zapcore.Field{zap.Any("node", v)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	const depth2 = `
+json:
+  time_key: ""
+  reflect_depth: 2
+`
+	n := &node{
+		Name:   "a",
+		ID:     1,
+		Secret: "s",
+		Owner:  fmt.Errorf("nobody"),
+		Tags:   map[string]userID{"b": 3, "a": 2},
+		Acct:   account{Balance: 1050},
+		Next:   &node{Name: "b", Next: &node{Name: "c"}},
+	}
+	assert.Equal(t, `{"level":"info","msg":"hello","node":{"name":"a","id":"u-1","owner":"nobody","tags":{"a":"u-2","b":"u-3"},"account":{"balance":"10.50"},"next":{"name":"b","account":{"balance":"0.00"},"next":"{c u-0  <nil> map[] {0} <nil>}"}}}
+`, encode(depth2, n))
+	assert.Equal(t, `{"level":"info","msg":"hello","node":{"name":"a","id":1,"owner":{},"tags":{"a":2,"b":3},"account":{"Balance":1050},"next":{"name":"b","account":{"Balance":0},"next":{"name":"c","account":{"Balance":0}}}}}
+`, encode(`
+json:
+  time_key: ""
+`, n))
+
+	// cycles are cut short
+	n.Next.Next = n
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(encode(depth2, n)), &m))
+}
@@ -0,0 +1,100 @@
+package appender
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/appender/writer/breaker"
+)
+
+// ErrorHandler receives the errors raised while an appender encodes or writes
+// an entry, together with the appender name and the entry metadata.
+type ErrorHandler func(appenderName string, ent zapcore.Entry, err error)
+
+var errorHandler atomic.Value
+
+func init() {
+	SetErrorHandler(NewRateLimitedErrorHandler(os.Stderr, time.Second))
+}
+
+// SetErrorHandler replaces the process-wide appender error handler. A nil
+// handler discards errors; they are still counted.
+func SetErrorHandler(h ErrorHandler) {
+	if h == nil {
+		h = func(string, zapcore.Entry, error) {}
+	}
+	errorHandler.Store(h)
+}
+
+func handleError(appenderName string, ent zapcore.Entry, err error) {
+	errorHandler.Load().(ErrorHandler)(appenderName, ent, err)
+}
+
+// NewRateLimitedErrorHandler returns an ErrorHandler printing at most one
+// error per interval to w. Errors arriving in between are counted and
+// reported with the next printed one.
+func NewRateLimitedErrorHandler(w io.Writer, interval time.Duration) ErrorHandler {
+	var (
+		mu         sync.Mutex
+		last       time.Time
+		suppressed int
+	)
+	return func(appenderName string, ent zapcore.Entry, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		now := time.Now()
+		if !last.IsZero() && now.Sub(last) < interval {
+			suppressed++
+			return
+		}
+		fmt.Fprintf(w, "%s logn: appender %q failed on %s entry of logger %q: %v",
+			now.Format(time.RFC3339), appenderName, ent.Level, ent.LoggerName, err)
+		if suppressed > 0 {
+			fmt.Fprintf(w, " (%d more errors suppressed)", suppressed)
+		}
+		fmt.Fprintln(w)
+		last = now
+		suppressed = 0
+	}
+}
+
+type errorCore struct {
+	zapcore.Core
+	appender *Appender
+	strict   bool
+}
+
+func (c *errorCore) With(fields []zapcore.Field) zapcore.Core {
+	return &errorCore{Core: c.Core.With(fields), appender: c.appender, strict: c.strict}
+}
+
+func (c *errorCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *errorCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	err := c.Core.Write(ent, fields)
+	if err == nil {
+		c.appender.health.success(ent)
+	} else {
+		atomic.AddUint64(&c.appender.errors, 1)
+		// the breaker reported opening already
+		if err != breaker.ErrOpen {
+			c.appender.health.failure(ent, err)
+			handleError(c.appender.Name, ent, err)
+		}
+		if c.strict {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package appender
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func (failingWriter) Sync() error {
+	return nil
+}
+
+func TestErrorCore(t *testing.T) {
+	var got []string
+	SetErrorHandler(func(name string, ent zapcore.Entry, err error) {
+		got = append(got, name+"|"+ent.LoggerName+"|"+ent.Level.String()+"|"+err.Error())
+	})
+	defer SetErrorHandler(NewRateLimitedErrorHandler(os.Stderr, time.Second))
+
+	a := &Appender{
+		Name:    "BROKEN",
+		Writer:  failingWriter{},
+		Encoder: zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg"}),
+	}
+	logger := zap.New(a.NewCore(zapcore.InfoLevel)).Named("app")
+	logger.Info("one")
+	logger.Warn("two")
+
+	assert.Equal(t, []string{"BROKEN|app|info|disk full", "BROKEN|app|warn|disk full"}, got)
+	assert.Equal(t, uint64(2), a.Errors())
+}
+
+func TestNewRateLimitedErrorHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	h := NewRateLimitedErrorHandler(buf, 50*time.Millisecond)
+	ent := zapcore.Entry{Level: zapcore.ErrorLevel, LoggerName: "app"}
+	err := errors.New("boom")
+
+	h("FILE", ent, err)
+	h("FILE", ent, err)
+	h("FILE", ent, err)
+	time.Sleep(60 * time.Millisecond)
+	h("FILE", ent, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `appender "FILE" failed on error entry of logger "app": boom`)
+	assert.True(t, strings.HasSuffix(lines[1], "(2 more errors suppressed)"))
+}
@@ -0,0 +1,53 @@
+package appender
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/appender/writer/breaker"
+	"github.com/shanexu/logn/core"
+)
+
+// health records the outcome of the writes of an appender.
+type health struct {
+	// lastSuccess is the time of the last entry written, in Unix
+	// nanoseconds
+	lastSuccess int64
+
+	mu          sync.Mutex
+	lastError   error
+	lastErrorAt time.Time
+}
+
+func (h *health) success(ent zapcore.Entry) {
+	atomic.StoreInt64(&h.lastSuccess, ent.Time.UnixNano())
+}
+
+func (h *health) failure(ent zapcore.Entry, err error) {
+	h.mu.Lock()
+	h.lastError, h.lastErrorAt = err, ent.Time
+	h.mu.Unlock()
+}
+
+// Health returns a snapshot of the health of the appender.
+func (a *Appender) Health() core.AppenderHealth {
+	ah := core.AppenderHealth{State: core.AppenderConnected, Errors: a.Errors()}
+	if ns := atomic.LoadInt64(&a.health.lastSuccess); ns != 0 {
+		ah.LastSuccess = time.Unix(0, ns)
+	}
+	a.health.mu.Lock()
+	if a.health.lastError != nil {
+		ah.LastError, ah.LastErrorTime = a.health.lastError.Error(), a.health.lastErrorAt
+	}
+	a.health.mu.Unlock()
+	switch {
+	case a.breaker != nil && breaker.Open(a.breaker):
+		ah.State = core.AppenderOpenFailed
+	case ah.LastError != "" && ah.LastErrorTime.After(ah.LastSuccess):
+		ah.State = core.AppenderDegraded
+	}
+	return ah
+}
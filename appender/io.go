@@ -0,0 +1,170 @@
+package appender
+
+import (
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/appender/writer"
+	"github.com/shanexu/logn/appender/writer/async"
+	"github.com/shanexu/logn/core"
+	"github.com/shanexu/logn/metrics"
+)
+
+// counters count the work of an appender.
+type counters struct {
+	entries      uint64
+	bytes        uint64
+	encodeErrors uint64
+	writeErrors  uint64
+}
+
+// Stats returns a snapshot of the work of the appender.
+func (a *Appender) Stats() core.AppenderStats {
+	st := core.AppenderStats{
+		Entries:      atomic.LoadUint64(&a.counters.entries),
+		Bytes:        atomic.LoadUint64(&a.counters.bytes),
+		EncodeErrors: atomic.LoadUint64(&a.counters.encodeErrors),
+		WriteErrors:  atomic.LoadUint64(&a.counters.writeErrors),
+	}
+	if q, ok := a.Writer.(async.Queue); ok {
+		st.QueueDepth, st.QueueCapacity = q.Queued()
+	}
+	return st
+}
+
+// ioCore is the core of appenders. It encodes entries like the cores built
+// by zapcore.NewCore, and writes them through the appender's signer, which
+// chains their signatures, if any, counting what it encodes and writes. Once
+// the appender is replaced, the entries go to its replacement.
+type ioCore struct {
+	zapcore.LevelEnabler
+	enc      zapcore.Encoder
+	appender *Appender
+}
+
+func (c *ioCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &ioCore{LevelEnabler: c.LevelEnabler, enc: enc, appender: c.appender}
+}
+
+func (c *ioCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *ioCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	a := c.appender.acquire()
+	if a == nil {
+		return writer.ErrClosed
+	}
+	defer a.release()
+	var start time.Time
+	if metrics.Enabled() {
+		start = time.Now()
+	}
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		atomic.AddUint64(&a.counters.encodeErrors, 1)
+		metrics.AppenderEncodeError(a.Name)
+		return err
+	}
+	n := buf.Len()
+	if a.Signer != nil {
+		_, err = a.Signer.Write(a.Writer, buf.Bytes())
+	} else {
+		_, err = a.Writer.Write(buf.Bytes())
+	}
+	buf.Free()
+	if metrics.Enabled() {
+		metrics.AppenderWrite(a.Name, time.Since(start), err)
+	}
+	if err != nil {
+		atomic.AddUint64(&a.counters.writeErrors, 1)
+		return err
+	}
+	atomic.AddUint64(&a.counters.entries, 1)
+	atomic.AddUint64(&a.counters.bytes, uint64(n))
+	if metrics.Enabled() {
+		metrics.AppenderBytes(a.Name, n)
+	}
+	if ent.Level > zapcore.ErrorLevel {
+		a.Writer.Sync()
+	}
+	return nil
+}
+
+func (c *ioCore) Sync() error {
+	return c.appender.sync()
+}
+
+// sync syncs the writer of the appender entries go to, if any.
+func (a *Appender) sync() error {
+	if a = a.acquire(); a == nil {
+		return nil
+	}
+	defer a.release()
+	return a.Writer.Sync()
+}
+
+// entryCore is the core of the appenders whose writer takes entries.
+type entryCore struct {
+	zapcore.LevelEnabler
+	appender *Appender
+	// ctx holds the fields given to With
+	ctx []zapcore.Field
+}
+
+func (c *entryCore) With(fields []zapcore.Field) zapcore.Core {
+	ctx := make([]zapcore.Field, 0, len(c.ctx)+len(fields))
+	ctx = append(ctx, c.ctx...)
+	ctx = append(ctx, fields...)
+	return &entryCore{LevelEnabler: c.LevelEnabler, appender: c.appender, ctx: ctx}
+}
+
+func (c *entryCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *entryCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	a := c.appender.acquire()
+	if a == nil {
+		return writer.ErrClosed
+	}
+	defer a.release()
+	if len(c.ctx) > 0 {
+		all := make([]zapcore.Field, 0, len(c.ctx)+len(fields))
+		all = append(all, c.ctx...)
+		fields = append(all, fields...)
+	}
+	var start time.Time
+	if metrics.Enabled() {
+		start = time.Now()
+	}
+	err := a.Writer.(writer.EntryWriter).WriteEntry(ent, fields)
+	if metrics.Enabled() {
+		metrics.AppenderWrite(a.Name, time.Since(start), err)
+	}
+	if err != nil {
+		atomic.AddUint64(&a.counters.writeErrors, 1)
+		return err
+	}
+	atomic.AddUint64(&a.counters.entries, 1)
+	if ent.Level > zapcore.ErrorLevel {
+		a.Writer.Sync()
+	}
+	return nil
+}
+
+func (c *entryCore) Sync() error {
+	return c.appender.sync()
+}
@@ -0,0 +1,50 @@
+package appender
+
+import (
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// LevelRange enables the levels from Min to Max, both included.
+type LevelRange struct {
+	Min zapcore.Level
+	Max zapcore.Level
+}
+
+// Enabled reports whether l is in the range.
+func (r LevelRange) Enabled(l zapcore.Level) bool {
+	return l >= r.Min && l <= r.Max
+}
+
+// newLevelRange parses the min_level and max_level settings of an appender,
+// which default to debug and fatal. It returns nil if neither is set.
+func newLevelRange(min, max string) (*LevelRange, error) {
+	if min == "" && max == "" {
+		return nil, nil
+	}
+	r := &LevelRange{Min: zapcore.DebugLevel, Max: zapcore.FatalLevel}
+	if min != "" {
+		if err := r.Min.UnmarshalText([]byte(min)); err != nil {
+			return nil, fmt.Errorf("min_level: %v", err)
+		}
+	}
+	if max != "" {
+		if err := r.Max.UnmarshalText([]byte(max)); err != nil {
+			return nil, fmt.Errorf("max_level: %v", err)
+		}
+	}
+	if r.Min > r.Max {
+		return nil, fmt.Errorf("min_level %s is above max_level %s", r.Min, r.Max)
+	}
+	return r, nil
+}
+
+// bothLevels enables the levels both of its enablers enable.
+type bothLevels struct {
+	a, b zapcore.LevelEnabler
+}
+
+func (e bothLevels) Enabled(l zapcore.Level) bool {
+	return e.a.Enabled(l) && e.b.Enabled(l)
+}
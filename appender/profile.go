@@ -0,0 +1,89 @@
+package appender
+
+import (
+	"context"
+	"runtime/pprof"
+	"runtime/trace"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/appender/writer"
+)
+
+// Phases of the work of appenders, as told by the logn_phase pprof label.
+const (
+	phaseEncode = "encode"
+	phaseWrite  = "write"
+)
+
+// profile runs f with the pprof labels logn_appender and logn_phase set to
+// the appender and phase, within the execution trace region region. The
+// labels of the goroutine are cleared afterwards, the context of the logging
+// call being unknown.
+func profile(labels pprof.LabelSet, region string, f func()) {
+	pprof.Do(context.Background(), labels, func(ctx context.Context) {
+		defer trace.StartRegion(ctx, region).End()
+		f()
+	})
+}
+
+// profileCore profiles the encoding of entries, and their writing unless it
+// is deferred to another goroutine.
+type profileCore struct {
+	zapcore.Core
+	labels pprof.LabelSet
+	region string
+}
+
+func newProfileCore(core zapcore.Core, name string) *profileCore {
+	return &profileCore{
+		Core:   core,
+		labels: pprof.Labels("logn_appender", name, "logn_phase", phaseEncode),
+		region: "logn " + phaseEncode + " " + name,
+	}
+}
+
+func (c *profileCore) With(fields []zapcore.Field) zapcore.Core {
+	return &profileCore{Core: c.Core.With(fields), labels: c.labels, region: c.region}
+}
+
+func (c *profileCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *profileCore) Write(ent zapcore.Entry, fields []zapcore.Field) (err error) {
+	profile(c.labels, c.region, func() {
+		err = c.Core.Write(ent, fields)
+	})
+	return err
+}
+
+// profileWriter profiles the writes of an appender, wherever they happen.
+type profileWriter struct {
+	writer.Writer
+	labels pprof.LabelSet
+	region string
+}
+
+func newProfileWriter(w writer.Writer, name string) *profileWriter {
+	return &profileWriter{
+		Writer: w,
+		labels: pprof.Labels("logn_appender", name, "logn_phase", phaseWrite),
+		region: "logn " + phaseWrite + " " + name,
+	}
+}
+
+func (w *profileWriter) Write(p []byte) (n int, err error) {
+	profile(w.labels, w.region, func() {
+		n, err = w.Writer.Write(p)
+	})
+	return n, err
+}
+
+// Close closes the wrapped writer.
+func (w *profileWriter) Close() error {
+	return writer.Close(w.Writer)
+}
@@ -0,0 +1,41 @@
+package appender
+
+import (
+	"bytes"
+	"runtime/trace"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type bufferWriter struct {
+	bytes.Buffer
+}
+
+func (*bufferWriter) Sync() error {
+	return nil
+}
+
+func TestProfile(t *testing.T) {
+	out := &bufferWriter{}
+	a := &Appender{
+		Name:    "PROFILED",
+		Writer:  newProfileWriter(out, "PROFILED"),
+		Encoder: zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg"}),
+		Profile: true,
+	}
+	logger := zap.New(a.NewCore(zapcore.InfoLevel)).With(zap.String("k", "v"))
+
+	var tr bytes.Buffer
+	if err := trace.Start(&tr); err != nil {
+		t.Skip("tracing is in use: ", err)
+	}
+	logger.Info("profiled")
+	trace.Stop()
+
+	assert.Equal(t, `{"msg":"profiled","k":"v"}`+"\n", out.String())
+	assert.Contains(t, tr.String(), "logn encode PROFILED")
+	assert.Contains(t, tr.String(), "logn write PROFILED")
+}
@@ -0,0 +1,34 @@
+package appender
+
+import (
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/appender/writer/async"
+	"github.com/shanexu/logn/metrics"
+)
+
+// shedCore drops the entries the writer of an appender sheds before they are
+// encoded.
+type shedCore struct {
+	zapcore.Core
+	shedder async.Shedder
+}
+
+func newShedCore(core zapcore.Core, w interface{}) zapcore.Core {
+	if s, ok := w.(async.Shedder); ok {
+		return &shedCore{Core: core, shedder: s}
+	}
+	return core
+}
+
+func (c *shedCore) With(fields []zapcore.Field) zapcore.Core {
+	return &shedCore{Core: c.Core.With(fields), shedder: c.shedder}
+}
+
+func (c *shedCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.shedder.Shed(ent.Level) {
+		metrics.Dropped(ent.LoggerName, ent.Level, metrics.DropLoadShedding)
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
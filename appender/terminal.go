@@ -0,0 +1,23 @@
+package appender
+
+import (
+	"os"
+
+	"github.com/shanexu/logn/appender/writer"
+)
+
+// terminal is implemented by the writers which may write to a terminal.
+type terminal interface {
+	IsTerminal() bool
+}
+
+// colorTerminal reports whether w writes to a terminal to be written in
+// color, which it is not with NO_COLOR set (see https://no-color.org) or TERM
+// set to dumb.
+func colorTerminal(w writer.Writer) bool {
+	t, ok := w.(terminal)
+	if !ok || !t.IsTerminal() {
+		return false
+	}
+	return os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+}
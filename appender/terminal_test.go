@@ -0,0 +1,60 @@
+package appender
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	_ "github.com/shanexu/logn/appender/encoder/console"
+	_ "github.com/shanexu/logn/appender/writer/console"
+	"github.com/shanexu/logn/common"
+)
+
+type terminalWriter struct {
+	bufferWriter
+	terminal bool
+}
+
+func (w *terminalWriter) IsTerminal() bool {
+	return w.terminal
+}
+
+func TestConsoleColor(t *testing.T) {
+	for _, key := range []string{"NO_COLOR", "TERM"} {
+		if v, ok := os.LookupEnv(key); ok {
+			os.Unsetenv(key)
+			defer os.Setenv(key, v)
+		}
+	}
+	write := func(color string, terminal bool) string {
+		config, err := common.NewConfigFrom(`
+name: CONSOLE
+encoder:
+  console:
+    time_key: ""
+    color: ` + color)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := &terminalWriter{terminal: terminal}
+		a, err := CreateDryRunAppender("console", config, out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zap.New(a.NewCore(zapcore.InfoLevel)).Warn("careful")
+		return out.String()
+	}
+	colored := "\x1b[33mwarn\x1b[0m\tcareful\n"
+	assert.Equal(t, colored, write("auto", true))
+	assert.Equal(t, "warn\tcareful\n", write("auto", false))
+	assert.Equal(t, colored, write("always", false))
+	assert.Equal(t, "warn\tcareful\n", write("never", true))
+
+	os.Setenv("NO_COLOR", "1")
+	assert.Equal(t, "warn\tcareful\n", write("auto", true))
+	assert.Equal(t, colored, write("always", true))
+	os.Unsetenv("NO_COLOR")
+}
@@ -0,0 +1,463 @@
+// Package async decouples appenders from their writers: writes are queued and
+// performed by a goroutine, so that logging calls do not wait on slow disks or
+// networks. The queue is a lock-free ring buffer, written to by any number of
+// goroutines without locking as long as it is not full.
+//
+// What happens to a write arriving while the queue is full depends on the
+// policy: the caller blocks until there is room (Block), the write is dropped
+// (DropNewest), the oldest queued write is dropped to make room for it
+// (DropOldest), or it is appended to a spill file and written once the queue
+// has drained (Spill). Each of these outcomes is reported with
+// metrics.QueueFull.
+//
+// With a batch size, the goroutine coalesces the queued writes into writes of
+// up to that many bytes, optionally waiting for more writes to arrive. Batches
+// suit stream writers, such as files, but not writers expecting a message per
+// write, such as gelf_udp.
+//
+// Appenders can also shed load: once the queue is filled beyond a high-water
+// mark, debug and info entries are dropped with a probability growing with
+// the queue, see Shedder.
+package async
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/appender/bufpool"
+	"github.com/shanexu/logn/appender/writer"
+	"github.com/shanexu/logn/appender/writer/breaker"
+	"github.com/shanexu/logn/metrics"
+	"github.com/shanexu/logn/status"
+)
+
+// Policies applied when the queue is full.
+const (
+	Block      = "block"
+	DropNewest = "drop_newest"
+	DropOldest = "drop_oldest"
+	Spill      = "spill"
+)
+
+// DefaultQueueSize is the number of writes queued when Config.QueueSize is
+// not set.
+const DefaultQueueSize = 1024
+
+// Config is the async section of an appender.
+type Config struct {
+	// QueueSize is the number of writes which can be queued.
+	QueueSize int `logn-config:"queue_size" logn-validate:"min=0"`
+	// OnFull is the policy applied when the queue is full, Block by default.
+	OnFull string `logn-config:"on_full" logn-validate:"logn.oneof=block drop_newest drop_oldest spill"`
+	// SpillFile is the file writes are spilled to under the Spill policy.
+	SpillFile string `logn-config:"spill_file"`
+	// BatchBytes, if not zero, is the size queued writes are coalesced up to.
+	BatchBytes int `logn-config:"batch_bytes" logn-validate:"min=0"`
+	// BatchLatency is the longest time a batch waits for more writes.
+	// Batches only take the writes already queued when it is not set.
+	BatchLatency string `logn-config:"batch_latency"`
+	// ShedAbove, if not zero, is the fraction of the queue from which debug
+	// and info entries are shed.
+	ShedAbove float64 `logn-config:"shed_above"`
+}
+
+// Shedder is implemented by the writers of async appenders shedding load.
+type Shedder interface {
+	// Shed tells whether to drop an entry at level rather than queue it.
+	Shed(level zapcore.Level) bool
+}
+
+// Queue is implemented by the writers of async appenders.
+type Queue interface {
+	// Queued returns the number of writes queued, and how many can be.
+	Queued() (depth, capacity int)
+}
+
+type asyncWriter struct {
+	out    writer.Writer
+	name   string
+	policy string
+	size   int
+
+	batchBytes   int
+	batchLatency time.Duration
+	shedAbove    float64
+
+	queue *ring
+	// wake wakes the goroutine up while sleeping is set, and Sync wakes
+	// batches up
+	wake     chan struct{}
+	sleeping int32
+	// busy is set while the goroutine holds writes
+	busy int32
+	// syncing counts the Sync calls waiting, which cut batches short
+	syncing int32
+	// blocked counts the writes waiting for room under Block
+	blocked int32
+	// carry is the write taken by the goroutine which did not fit in the
+	// last batch
+	carry *[]byte
+	// done is closed by Close to stop the goroutine, which closes stopped
+	// on its way out
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+
+	// mu guards the conditions and the spill file
+	mu      sync.Mutex
+	notFull *sync.Cond
+	idle    *sync.Cond
+	// spilled is set while the spill file holds writes, read back from
+	// spillOff up to spillEnd
+	spilled  int32
+	spill    *os.File
+	spillOff int64
+	spillEnd int64
+}
+
+// New wraps w, the writer of the appender called name, so that writes to it
+// are queued.
+func New(w writer.Writer, name string, config Config) (writer.Writer, error) {
+	aw := &asyncWriter{
+		out:    w,
+		name:   name,
+		policy: config.OnFull,
+		size:   config.QueueSize,
+
+		batchBytes: config.BatchBytes,
+		shedAbove:  config.ShedAbove,
+
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if aw.shedAbove < 0 || aw.shedAbove >= 1 {
+		return nil, fmt.Errorf("shed_above must be between 0 and 1, got %v", aw.shedAbove)
+	}
+	if config.BatchLatency != "" {
+		var err error
+		if aw.batchLatency, err = time.ParseDuration(config.BatchLatency); err != nil {
+			return nil, err
+		}
+	}
+	if aw.policy == "" {
+		aw.policy = Block
+	}
+	if aw.size == 0 {
+		aw.size = DefaultQueueSize
+	}
+	if aw.policy == Spill {
+		if config.SpillFile == "" {
+			return nil, fmt.Errorf("spill_file is required by policy %q", Spill)
+		}
+		f, err := os.OpenFile(config.SpillFile, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return nil, err
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		// writes spilled by a previous process are written first
+		aw.spill = f
+		aw.spillEnd = fi.Size()
+		if aw.spillEnd > 0 {
+			aw.spilled = 1
+		}
+	}
+	aw.queue = newRing(aw.size)
+	aw.notFull = sync.NewCond(&aw.mu)
+	aw.idle = sync.NewCond(&aw.mu)
+	go aw.run()
+	return aw, nil
+}
+
+func (w *asyncWriter) Write(p []byte) (int, error) {
+	select {
+	case <-w.done:
+		return 0, writer.ErrClosed
+	default:
+	}
+	if atomic.LoadInt32(&w.spilled) == 1 {
+		w.mu.Lock()
+		if w.spillEnd > w.spillOff {
+			// keep spilled writes ahead of the new ones
+			defer w.mu.Unlock()
+			return w.spillWrite(p)
+		}
+		w.mu.Unlock()
+	}
+	// the caller may reuse p once Write returns
+	b := bufpool.Get(len(p))
+	*b = append(*b, p...)
+	for !w.queue.push(b) {
+		switch w.policy {
+		case DropNewest:
+			metrics.QueueFull(w.name, metrics.QueueDroppedNewest)
+			bufpool.Put(b)
+			return len(p), nil
+		case DropOldest:
+			metrics.QueueFull(w.name, metrics.QueueDroppedOldest)
+			if old := w.queue.pop(); old != nil {
+				bufpool.Put(old)
+			}
+		case Spill:
+			metrics.QueueFull(w.name, metrics.QueueSpilled)
+			bufpool.Put(b)
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			return w.spillWrite(p)
+		default:
+			metrics.QueueFull(w.name, metrics.QueueBlocked)
+			w.waitPush(b)
+			return len(p), nil
+		}
+	}
+	w.wakeUp()
+	return len(p), nil
+}
+
+// waitPush appends b to the queue once there is room.
+func (w *asyncWriter) waitPush(b *[]byte) {
+	w.mu.Lock()
+	atomic.AddInt32(&w.blocked, 1)
+	for !w.queue.push(b) {
+		w.notFull.Wait()
+	}
+	atomic.AddInt32(&w.blocked, -1)
+	w.mu.Unlock()
+	w.wakeUp()
+}
+
+// wakeUp wakes the goroutine up if it sleeps.
+func (w *asyncWriter) wakeUp() {
+	if atomic.LoadInt32(&w.sleeping) == 1 {
+		w.signal()
+	}
+}
+
+func (w *asyncWriter) signal() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// spillWrite appends p to the spill file. It is called with w.mu held.
+func (w *asyncWriter) spillWrite(p []byte) (int, error) {
+	rec := bufpool.Get(4 + len(p))
+	defer bufpool.Put(rec)
+	*rec = (*rec)[:4]
+	binary.BigEndian.PutUint32(*rec, uint32(len(p)))
+	*rec = append(*rec, p...)
+	if _, err := w.spill.Write(*rec); err != nil {
+		return 0, err
+	}
+	w.spillEnd += int64(len(*rec))
+	atomic.StoreInt32(&w.spilled, 1)
+	w.wakeUp()
+	return len(p), nil
+}
+
+// take returns the next write, from the queue or, once it is empty, from the
+// spill file, or nil if there is none. It is only called by the goroutine.
+func (w *asyncWriter) take() *[]byte {
+	if b := w.carry; b != nil {
+		w.carry = nil
+		return b
+	}
+	if b := w.queue.pop(); b != nil {
+		if atomic.LoadInt32(&w.blocked) > 0 {
+			w.mu.Lock()
+			w.notFull.Broadcast()
+			w.mu.Unlock()
+		}
+		return b
+	}
+	if atomic.LoadInt32(&w.spilled) == 0 {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	b, err := w.unspill()
+	if err != nil {
+		// the spill file is unreadable, give up on what it holds
+		status.Errorf("async appender %q lost spilled entries: %v", w.name, err)
+		w.spillOff, w.spillEnd = 0, 0
+		atomic.StoreInt32(&w.spilled, 0)
+		w.spill.Truncate(0)
+		return nil
+	}
+	return b
+}
+
+// unspill reads the next write back from the spill file. It is called with
+// w.mu held.
+func (w *asyncWriter) unspill() (*[]byte, error) {
+	var n [4]byte
+	if _, err := w.spill.ReadAt(n[:], w.spillOff); err != nil {
+		return nil, err
+	}
+	size := int(binary.BigEndian.Uint32(n[:]))
+	b := bufpool.Get(size)
+	*b = (*b)[:size]
+	if _, err := w.spill.ReadAt(*b, w.spillOff+4); err != nil {
+		bufpool.Put(b)
+		return nil, err
+	}
+	w.spillOff += int64(4 + size)
+	if w.spillOff == w.spillEnd {
+		w.spillOff, w.spillEnd = 0, 0
+		atomic.StoreInt32(&w.spilled, 0)
+		if err := w.spill.Truncate(0); err != nil {
+			bufpool.Put(b)
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func (w *asyncWriter) Queued() (depth, capacity int) {
+	return w.queue.len(), w.size
+}
+
+// Shed drops debug and info entries with a probability rising from 0 at the
+// high-water mark to 1 when the queue is full.
+func (w *asyncWriter) Shed(level zapcore.Level) bool {
+	if w.shedAbove == 0 || level > zapcore.InfoLevel {
+		return false
+	}
+	fill := float64(w.queue.len()) / float64(w.size)
+	if fill <= w.shedAbove {
+		return false
+	}
+	return rand.Float64() < (fill-w.shedAbove)/(1-w.shedAbove)
+}
+
+func (w *asyncWriter) run() {
+	defer close(w.stopped)
+	for {
+		atomic.StoreInt32(&w.busy, 1)
+		b := w.take()
+		if b == nil {
+			atomic.StoreInt32(&w.busy, 0)
+			select {
+			case <-w.done:
+				return
+			default:
+			}
+			w.sleep()
+			continue
+		}
+		if w.batchBytes > 0 {
+			b = w.batch(b)
+		}
+		if _, err := w.out.Write(*b); err != nil && err != breaker.ErrOpen {
+			status.Warnf("async appender %q failed to write: %v", w.name, err)
+		}
+		bufpool.Put(b)
+		if metrics.Enabled() {
+			metrics.QueueDepth(w.name, w.queue.len())
+			metrics.QueueCapacity(w.name, w.size)
+		}
+	}
+}
+
+// sleep waits for writes to arrive, after telling Sync the writer is idle.
+func (w *asyncWriter) sleep() {
+	if atomic.LoadInt32(&w.syncing) > 0 {
+		w.mu.Lock()
+		w.idle.Broadcast()
+		w.mu.Unlock()
+	}
+	atomic.StoreInt32(&w.sleeping, 1)
+	if w.queue.len() > 0 || atomic.LoadInt32(&w.spilled) == 1 {
+		// a write is being published
+		atomic.StoreInt32(&w.sleeping, 0)
+		runtime.Gosched()
+		return
+	}
+	select {
+	case <-w.wake:
+	case <-w.done:
+	}
+	atomic.StoreInt32(&w.sleeping, 0)
+}
+
+// batch returns b followed by the writes queued within the batch latency,
+// up to the batch size.
+func (w *asyncWriter) batch(b *[]byte) *[]byte {
+	var expired <-chan time.Time
+	if w.batchLatency > 0 {
+		t := time.NewTimer(w.batchLatency)
+		defer t.Stop()
+		expired = t.C
+	}
+	batch := bufpool.Get(w.batchBytes)
+	*batch = append(*batch, *b...)
+	bufpool.Put(b)
+	for len(*batch) < w.batchBytes {
+		next := w.take()
+		if next == nil {
+			if expired == nil || atomic.LoadInt32(&w.syncing) > 0 {
+				break
+			}
+			atomic.StoreInt32(&w.sleeping, 1)
+			if w.queue.len() == 0 {
+				select {
+				case <-w.wake:
+				case <-expired:
+					expired = nil
+				}
+			}
+			atomic.StoreInt32(&w.sleeping, 0)
+			continue
+		}
+		if len(*batch)+len(*next) > w.batchBytes {
+			w.carry = next
+			break
+		}
+		*batch = append(*batch, *next...)
+		bufpool.Put(next)
+	}
+	return batch
+}
+
+// Sync waits for the queued writes to be written, then syncs the writer.
+func (w *asyncWriter) Sync() error {
+	w.mu.Lock()
+	atomic.AddInt32(&w.syncing, 1)
+	w.signal()
+	for w.queue.len() > 0 || w.spillEnd > w.spillOff || atomic.LoadInt32(&w.busy) == 1 {
+		w.idle.Wait()
+	}
+	atomic.AddInt32(&w.syncing, -1)
+	w.mu.Unlock()
+	return w.out.Sync()
+}
+
+// Close writes the queued writes, stops the goroutine and closes the spill
+// file, if any, then the writer. Writes to a closed writer fail with
+// writer.ErrClosed.
+func (w *asyncWriter) Close() error {
+	w.closeOnce.Do(func() {
+		w.Sync()
+		close(w.done)
+		<-w.stopped
+		if w.spill != nil {
+			w.spill.Close()
+		}
+		w.closeErr = writer.Close(w.out)
+	})
+	return w.closeErr
+}
@@ -0,0 +1,259 @@
+package async
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/appender/writer"
+)
+
+// gatedWriter holds writes until released.
+type gatedWriter struct {
+	started chan struct{}
+	release chan struct{}
+
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	writes []string
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{started: make(chan struct{}, 100), release: make(chan struct{})}
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	w.started <- struct{}{}
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes = append(w.writes, string(p))
+	return w.buf.Write(p)
+}
+
+func (w *gatedWriter) Sync() error { return nil }
+
+func (w *gatedWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestPolicies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "async")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tc := range []struct {
+		policy string
+		want   string
+	}{
+		{DropNewest, "ab"},
+		{DropOldest, "ad"},
+		{Spill, "abcd"},
+		{Block, "abcd"},
+	} {
+		t.Run(tc.policy, func(t *testing.T) {
+			out := newGatedWriter()
+			w, err := New(out, "ASYNC", Config{
+				QueueSize: 1,
+				OnFull:    tc.policy,
+				SpillFile: filepath.Join(dir, tc.policy),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// a is being written, b is queued, the queue is full
+			w.Write([]byte("a"))
+			<-out.started
+			w.Write([]byte("b"))
+
+			done := make(chan struct{})
+			go func() {
+				w.Write([]byte("c"))
+				w.Write([]byte("d"))
+				close(done)
+			}()
+			if tc.policy == Block {
+				select {
+				case <-done:
+					t.Fatal("write did not block")
+				default:
+				}
+			} else {
+				<-done
+			}
+			close(out.release)
+			<-done
+			assert.Nil(t, w.Sync())
+			assert.Equal(t, tc.want, out.String())
+		})
+	}
+}
+
+func TestSpillRecovery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "async")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	spill := filepath.Join(dir, "spill")
+
+	// spilled writes left by a previous process are written first
+	out := newGatedWriter()
+	w, err := New(out, "ASYNC", Config{QueueSize: 1, OnFull: Spill, SpillFile: spill})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("a"))
+	<-out.started
+	w.Write([]byte("b"))
+	w.Write([]byte("c"))
+
+	out = newGatedWriter()
+	close(out.release)
+	w, err = New(out, "ASYNC", Config{QueueSize: 1, OnFull: Spill, SpillFile: spill})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("d"))
+	assert.Nil(t, w.Sync())
+	assert.Equal(t, "cd", out.String())
+
+	_, err = New(out, "ASYNC", Config{OnFull: Spill})
+	assert.NotNil(t, err)
+}
+
+func TestBatches(t *testing.T) {
+	out := newGatedWriter()
+	w, err := New(out, "ASYNC", Config{BatchBytes: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("a"))
+	<-out.started
+	for _, s := range []string{"b", "c", "d"} {
+		w.Write([]byte(s))
+	}
+	close(out.release)
+	assert.Nil(t, w.Sync())
+	assert.Equal(t, []string{"a", "bc", "d"}, out.writes)
+
+	// batches wait for more writes, but not past Sync
+	out = newGatedWriter()
+	close(out.release)
+	w, err = New(out, "ASYNC", Config{BatchBytes: 100, BatchLatency: "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("a"))
+	w.Write([]byte("b"))
+	assert.Nil(t, w.Sync())
+	assert.Equal(t, []string{"ab"}, out.writes)
+}
+
+func TestShed(t *testing.T) {
+	out := newGatedWriter()
+	w, err := New(out, "ASYNC", Config{QueueSize: 4, OnFull: DropNewest, ShedAbove: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := w.(Shedder)
+	assert.False(t, s.Shed(zapcore.DebugLevel))
+
+	w.Write([]byte("a"))
+	<-out.started
+	for i := 0; i < 4; i++ {
+		w.Write([]byte("b"))
+	}
+	// the queue is full
+	assert.True(t, s.Shed(zapcore.DebugLevel))
+	assert.True(t, s.Shed(zapcore.InfoLevel))
+	assert.False(t, s.Shed(zapcore.WarnLevel))
+	close(out.release)
+
+	_, err = New(out, "ASYNC", Config{ShedAbove: 1})
+	assert.NotNil(t, err)
+}
+
+type countingWriter struct {
+	mu     sync.Mutex
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes += len(p)
+	return len(p), nil
+}
+
+func (w *countingWriter) Sync() error { return nil }
+
+func TestConcurrentWrites(t *testing.T) {
+	for _, config := range []Config{
+		{QueueSize: 8},
+		{QueueSize: 8, BatchBytes: 64, BatchLatency: "1ms"},
+	} {
+		out := &countingWriter{}
+		w, err := New(out, "ASYNC", config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 1000; j++ {
+					w.Write([]byte("x"))
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Nil(t, w.Sync())
+		assert.Equal(t, 8000, out.writes)
+	}
+}
+
+// closingWriter records whether it was closed.
+type closingWriter struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (w *closingWriter) Sync() error { return nil }
+
+func (w *closingWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestClose(t *testing.T) {
+	out := &closingWriter{}
+	w, err := New(out, "ASYNC", Config{QueueSize: 8, BatchBytes: 64, BatchLatency: "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("a"))
+	w.Write([]byte("b"))
+	assert.Nil(t, writer.Close(w))
+	assert.Equal(t, "ab", out.String())
+	assert.True(t, out.closed)
+	select {
+	case <-w.(*asyncWriter).stopped:
+	default:
+		t.Error("goroutine not stopped")
+	}
+	_, err = w.Write([]byte("c"))
+	assert.Equal(t, writer.ErrClosed, err)
+	assert.Nil(t, writer.Close(w))
+}
@@ -0,0 +1,105 @@
+package async
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// ring is a bounded lock-free queue of writes. Producers reserve a slot by
+// advancing head with a compare-and-swap, then publish their write by
+// setting the sequence number of the slot; takers advance tail likewise and
+// release the slot for the next round. Besides the goroutine of the writer,
+// producers dropping the oldest write take from the ring, hence tail is
+// advanced with a compare-and-swap too.
+type ring struct {
+	// head and tail count the slots reserved and taken, padded so that
+	// producers and takers do not share a cache line
+	head uint64
+	_    [56]byte
+	tail uint64
+	_    [56]byte
+
+	// size is the number of writes the ring holds, its capacity being
+	// the next power of two
+	size uint64
+	mask uint64
+	// seqs[i] is the value of head which reserves slot i when the slot is
+	// free, and that value plus one once its write is published
+	seqs []uint64
+	bufs []*[]byte
+}
+
+func newRing(size int) *ring {
+	capacity := 2
+	for capacity < size {
+		capacity *= 2
+	}
+	r := &ring{
+		size: uint64(size),
+		mask: uint64(capacity - 1),
+		seqs: make([]uint64, capacity),
+		bufs: make([]*[]byte, capacity),
+	}
+	for i := range r.seqs {
+		r.seqs[i] = uint64(i)
+	}
+	return r
+}
+
+// push appends b to the ring, unless it is full.
+func (r *ring) push(b *[]byte) bool {
+	for {
+		// head is loaded first, for pos-tail to count the writes in the
+		// ring at that time, unless takers meanwhile went past pos
+		pos := atomic.LoadUint64(&r.head)
+		tail := atomic.LoadUint64(&r.tail)
+		if tail > pos {
+			continue
+		}
+		if pos-tail >= r.size {
+			return false
+		}
+		i := pos & r.mask
+		seq := atomic.LoadUint64(&r.seqs[i])
+		if seq == pos && atomic.CompareAndSwapUint64(&r.head, pos, pos+1) {
+			r.bufs[i] = b
+			atomic.StoreUint64(&r.seqs[i], pos+1)
+			return true
+		}
+		if seq < pos {
+			// the slot is being released by a taker
+			runtime.Gosched()
+		}
+	}
+}
+
+// pop takes the oldest write from the ring, or returns nil if there is none
+// published yet.
+func (r *ring) pop() *[]byte {
+	for {
+		pos := atomic.LoadUint64(&r.tail)
+		i := pos & r.mask
+		seq := atomic.LoadUint64(&r.seqs[i])
+		if seq < pos+1 {
+			return nil
+		}
+		if seq == pos+1 && atomic.CompareAndSwapUint64(&r.tail, pos, pos+1) {
+			b := r.bufs[i]
+			r.bufs[i] = nil
+			atomic.StoreUint64(&r.seqs[i], pos+r.mask+1)
+			return b
+		}
+	}
+}
+
+// len returns the number of writes in the ring, including those being
+// published.
+func (r *ring) len() int {
+	tail := atomic.LoadUint64(&r.tail)
+	n := atomic.LoadUint64(&r.head) - tail
+	if n > r.size {
+		// writes were taken and pushed between the loads
+		n = r.size
+	}
+	return int(n)
+}
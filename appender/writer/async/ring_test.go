@@ -0,0 +1,91 @@
+package async
+
+import (
+	"encoding/binary"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRing(t *testing.T) {
+	r := newRing(3)
+	for i := 0; i < 3; i++ {
+		b := []byte{byte(i)}
+		assert.True(t, r.push(&b))
+	}
+	// the ring is bounded by its size, not its capacity
+	b := []byte{3}
+	assert.False(t, r.push(&b))
+	assert.Equal(t, 3, r.len())
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, []byte{byte(i)}, *r.pop())
+	}
+	assert.Nil(t, r.pop())
+	assert.Equal(t, 0, r.len())
+}
+
+func TestRingConcurrent(t *testing.T) {
+	const producers, writes = 8, 10000
+	r := newRing(16)
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < writes; i++ {
+				b := make([]byte, 8)
+				binary.BigEndian.PutUint32(b, uint32(p))
+				binary.BigEndian.PutUint32(b[4:], uint32(i))
+				for !r.push(&b) {
+					runtime.Gosched()
+				}
+			}
+		}(p)
+	}
+
+	// every write is taken once, in the order of its producer
+	next := make([]uint32, producers)
+	for n := 0; n < producers*writes; {
+		b := r.pop()
+		if b == nil {
+			runtime.Gosched()
+			continue
+		}
+		p := binary.BigEndian.Uint32(*b)
+		assert.Equal(t, next[p], binary.BigEndian.Uint32((*b)[4:]))
+		next[p]++
+		n++
+	}
+	wg.Wait()
+	assert.Nil(t, r.pop())
+}
+
+func TestRingNeverFalselyFull(t *testing.T) {
+	const goroutines, rounds = 8, 10000
+	r := newRing(goroutines)
+
+	// each goroutine holds at most one write, so the ring is never full
+	var wg sync.WaitGroup
+	var full uint64
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b := []byte{1}
+			for i := 0; i < rounds; i++ {
+				if !r.push(&b) {
+					atomic.AddUint64(&full, 1)
+				}
+				for r.pop() == nil {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Zero(t, full)
+}
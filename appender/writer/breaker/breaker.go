@@ -0,0 +1,120 @@
+// Package breaker disables persistently failing writers for a while, so that a
+// dead sink does not cost every entry a doomed write.
+//
+// After Failures consecutive failed writes, the breaker opens: writes fail
+// right away with ErrOpen for the cool-down period, after which a single write
+// probes the writer. The breaker closes again if it succeeds and stays open for
+// another period otherwise.
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/shanexu/logn/appender/writer"
+	"github.com/shanexu/logn/status"
+)
+
+// Defaults of Config.
+const (
+	DefaultFailures = 5
+	DefaultCoolDown = 30 * time.Second
+)
+
+// ErrOpen is returned by the writes refused by an open breaker.
+var ErrOpen = errors.New("circuit breaker open")
+
+// Config is the circuit_breaker section of an appender.
+type Config struct {
+	// Failures is the number of consecutive failed writes opening the
+	// breaker.
+	Failures int `logn-config:"failures" logn-validate:"min=0"`
+	// CoolDown is the time the breaker stays open before probing the writer.
+	CoolDown string `logn-config:"cool_down"`
+}
+
+type breakerWriter struct {
+	writer.Writer
+	name     string
+	failures int
+	coolDown time.Duration
+	now      func() time.Time
+
+	mu       sync.Mutex
+	failed   int
+	openedAt time.Time
+	probing  bool
+}
+
+// New wraps w, the writer of the appender called name, with a circuit
+// breaker.
+func New(w writer.Writer, name string, config Config) (writer.Writer, error) {
+	bw := &breakerWriter{
+		Writer:   w,
+		name:     name,
+		failures: config.Failures,
+		coolDown: DefaultCoolDown,
+		now:      time.Now,
+	}
+	if bw.failures == 0 {
+		bw.failures = DefaultFailures
+	}
+	if config.CoolDown != "" {
+		var err error
+		if bw.coolDown, err = time.ParseDuration(config.CoolDown); err != nil {
+			return nil, err
+		}
+	}
+	return bw, nil
+}
+
+func (w *breakerWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	if w.failed >= w.failures {
+		if w.probing || w.now().Sub(w.openedAt) < w.coolDown {
+			w.mu.Unlock()
+			return 0, ErrOpen
+		}
+		w.probing = true
+	}
+	w.mu.Unlock()
+
+	n, err := w.Writer.Write(p)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.probing = false
+	if err == nil {
+		if w.failed >= w.failures {
+			status.Infof("appender %q recovered, circuit breaker closed", w.name)
+		}
+		w.failed = 0
+		return n, nil
+	}
+	if w.failed++; w.failed == w.failures {
+		status.Warnf("appender %q failed %d times in a row, disabled for %s: %v",
+			w.name, w.failed, w.coolDown, err)
+	}
+	if w.failed >= w.failures {
+		w.openedAt = w.now()
+	}
+	return n, err
+}
+
+// Close closes the wrapped writer.
+func (w *breakerWriter) Close() error {
+	return writer.Close(w.Writer)
+}
+
+// Open reports whether w is a circuit breaker which is open, refusing writes
+// until the next probe.
+func Open(w writer.Writer) bool {
+	bw, ok := w.(*breakerWriter)
+	if !ok {
+		return false
+	}
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+	return bw.failed >= bw.failures
+}
@@ -0,0 +1,62 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type switchWriter struct {
+	err    error
+	writes int
+}
+
+func (w *switchWriter) Write(p []byte) (int, error) {
+	w.writes++
+	if w.err != nil {
+		return 0, w.err
+	}
+	return len(p), nil
+}
+
+func (w *switchWriter) Sync() error { return nil }
+
+func TestBreaker(t *testing.T) {
+	out := &switchWriter{err: errors.New("connection refused")}
+	w, err := New(out, "REMOTE", Config{Failures: 2, CoolDown: "1m"})
+	assert.Nil(t, err)
+	now := time.Unix(0, 0)
+	w.(*breakerWriter).now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		_, err = w.Write([]byte("entry"))
+		assert.Equal(t, out.err, err)
+	}
+	// open: writes are refused without reaching the writer
+	_, err = w.Write([]byte("entry"))
+	assert.Equal(t, ErrOpen, err)
+	assert.Equal(t, 2, out.writes)
+
+	// a failed probe opens the breaker for another period
+	now = now.Add(time.Minute)
+	_, err = w.Write([]byte("entry"))
+	assert.Equal(t, out.err, err)
+	_, err = w.Write([]byte("entry"))
+	assert.Equal(t, ErrOpen, err)
+	assert.Equal(t, 3, out.writes)
+
+	// a successful probe closes it
+	now = now.Add(time.Minute)
+	out.err = nil
+	for i := 0; i < 2; i++ {
+		n, err := w.Write([]byte("entry"))
+		assert.Nil(t, err)
+		assert.Equal(t, 5, n)
+	}
+	assert.Equal(t, 5, out.writes)
+
+	_, err = New(out, "REMOTE", Config{CoolDown: "a while"})
+	assert.NotNil(t, err)
+}
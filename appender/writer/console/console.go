@@ -11,6 +11,19 @@ type Console struct {
 	*os.File
 }
 
+// IsTerminal reports whether the console is a terminal, rather than, say, a
+// pipe to a container runtime or a file.
+func (c *Console) IsTerminal() bool {
+	return IsTerminal(c.File)
+}
+
+// Close syncs the console, leaving the standard stream open to the rest of
+// the process.
+func (c *Console) Close() error {
+	c.Sync()
+	return nil
+}
+
 type Config struct {
 	Target `logn-config:"target" logn-validate:"required,logn.oneof=stderr stdout"`
 }
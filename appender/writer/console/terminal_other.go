@@ -0,0 +1,13 @@
+//go:build !windows
+// +build !windows
+
+package console
+
+import "os"
+
+// IsTerminal reports whether f is a terminal, which renders ANSI escape
+// sequences such as colors.
+func IsTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
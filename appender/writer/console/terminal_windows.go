@@ -0,0 +1,27 @@
+package console
+
+import (
+	"os"
+	"syscall"
+)
+
+const enableVirtualTerminalProcessing = 0x0004
+
+var setConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// IsTerminal reports whether f is a console which renders ANSI escape
+// sequences such as colors. Virtual terminal processing, which renders them,
+// is enabled on the consoles of Windows 10 and later; older consoles, which
+// would print the sequences as they are, are not deemed terminals.
+func IsTerminal(f *os.File) bool {
+	h := syscall.Handle(f.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(h, &mode); err != nil {
+		return false
+	}
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return true
+	}
+	r, _, _ := setConsoleMode.Call(uintptr(h), uintptr(mode|enableVirtualTerminalProcessing))
+	return r != 0
+}
@@ -0,0 +1,170 @@
+// Package encrypt encrypts the output of writers with AES-GCM, for
+// environments where logs may not be stored in plain text.
+//
+// Every write is sealed into one or more frames, each made of the big-endian
+// uint32 length of the sealed data, a 12 byte nonce, drawn at random for each
+// frame, and the sealed data, at most MaxChunk bytes of plain text long.
+// Frames are self-contained, so encrypted output can be appended to and
+// rotated like plain text. NewReader decrypts it back.
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/shanexu/logn/appender/writer"
+)
+
+// MaxChunk is the maximum length of the plain text sealed in a frame.
+const MaxChunk = 64 << 10
+
+const (
+	lengthSize = 4
+	nonceSize  = 12
+)
+
+// Config is the encryption section of an appender.
+type Config struct {
+	// Key references the key, see ResolveKey.
+	Key string `logn-config:"key" logn-validate:"required"`
+}
+
+type encryptWriter struct {
+	writer.Writer
+	aead cipher.AEAD
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// New wraps w so that everything written to it is encrypted with config's key.
+func New(w writer.Writer, config Config) (writer.Writer, error) {
+	key, err := ResolveKey(config.Key)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter(w, key)
+}
+
+// NewWriter wraps w so that everything written to it is encrypted with key.
+func NewWriter(w writer.Writer, key []byte) (writer.Writer, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptWriter{Writer: w, aead: aead}, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (w *encryptWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = w.buf[:0]
+	for rest := p; len(rest) > 0; {
+		chunk := rest
+		if len(chunk) > MaxChunk {
+			chunk = chunk[:MaxChunk]
+		}
+		rest = rest[len(chunk):]
+		if err := w.seal(chunk); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := w.Writer.Write(w.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the wrapped writer.
+func (w *encryptWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return writer.Close(w.Writer)
+}
+
+// seal appends the frame of chunk to w.buf. Its nonce is random: the frames
+// of the writers sharing a key, in this process or others, then only collide
+// with negligible probability, unlike those of counters.
+func (w *encryptWriter) seal(chunk []byte) error {
+	start := len(w.buf)
+	w.buf = append(w.buf, make([]byte, lengthSize+nonceSize)...)
+	nonce := w.buf[start+lengthSize:]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	w.buf = w.aead.Seal(w.buf, nonce, chunk, nil)
+	binary.BigEndian.PutUint32(w.buf[start:], uint32(len(w.buf)-start-lengthSize-nonceSize))
+	return nil
+}
+
+type reader struct {
+	r     io.Reader
+	aead  cipher.AEAD
+	frame []byte
+	plain []byte
+}
+
+// NewReader returns a reader decrypting the frames read from r with key.
+func NewReader(r io.Reader, key []byte) (io.Reader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &reader{r: r, aead: aead}, nil
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+// next decrypts the next frame into r.plain.
+func (r *reader) next() error {
+	var header [lengthSize + nonceSize]byte
+	if _, err := io.ReadFull(r.r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return errors.New("truncated frame header")
+		}
+		return err
+	}
+	n := binary.BigEndian.Uint32(header[:lengthSize])
+	if n > MaxChunk+uint32(r.aead.Overhead()) {
+		return fmt.Errorf("invalid frame length %d", n)
+	}
+	if cap(r.frame) < int(n) {
+		r.frame = make([]byte, n)
+	}
+	r.frame = r.frame[:n]
+	if _, err := io.ReadFull(r.r, r.frame); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return errors.New("truncated frame")
+		}
+		return err
+	}
+	plain, err := r.aead.Open(r.frame[:0], header[lengthSize:], r.frame, nil)
+	if err != nil {
+		return err
+	}
+	r.plain = plain
+	return nil
+}
@@ -0,0 +1,90 @@
+package encrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+var testKey = bytes.Repeat([]byte{7}, 32)
+
+func TestRoundTrip(t *testing.T) {
+	var out bytes.Buffer
+	w, err := NewWriter(zapcore.AddSync(&out), testKey)
+	assert.Nil(t, err)
+
+	big := strings.Repeat("x", MaxChunk+10) + "\n"
+	for _, s := range []string{"first entry\n", big, "last entry\n"} {
+		n, err := w.Write([]byte(s))
+		assert.Nil(t, err)
+		assert.Equal(t, len(s), n)
+	}
+	assert.NotContains(t, out.String(), "entry")
+
+	r, err := NewReader(bytes.NewReader(out.Bytes()), testKey)
+	assert.Nil(t, err)
+	plain, err := ioutil.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "first entry\n"+big+"last entry\n", string(plain))
+
+	// tampering is detected
+	bs := out.Bytes()
+	bs[len(bs)-1] ^= 1
+	r, _ = NewReader(bytes.NewReader(bs), testKey)
+	_, err = ioutil.ReadAll(r)
+	assert.NotNil(t, err)
+
+	// so is truncation
+	r, _ = NewReader(bytes.NewReader(out.Bytes()[:len(bs)-3]), testKey)
+	_, err = ioutil.ReadAll(r)
+	assert.EqualError(t, err, "truncated frame")
+}
+
+func TestResolveKey(t *testing.T) {
+	os.Setenv("LOGN_TEST_KEY", base64.StdEncoding.EncodeToString(testKey))
+	defer os.Unsetenv("LOGN_TEST_KEY")
+
+	k, err := ResolveKey("env:LOGN_TEST_KEY")
+	assert.Nil(t, err)
+	assert.Equal(t, testKey, k)
+
+	RegisterKeySource("test", func(ref string) (string, error) {
+		return base64.StdEncoding.EncodeToString([]byte(ref)), nil
+	})
+	k, err = ResolveKey("test:0123456789abcdef")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("0123456789abcdef"), k)
+
+	for _, key := range []string{"LOGN_TEST_KEY", "env:LOGN_MISSING_KEY", "kms:alias", "test:short"} {
+		_, err = ResolveKey(key)
+		assert.NotNil(t, err, key)
+	}
+}
+
+func TestNonces(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		var out bytes.Buffer
+		w, err := NewWriter(zapcore.AddSync(&out), testKey)
+		assert.Nil(t, err)
+		for j := 0; j < 100; j++ {
+			w.Write([]byte("entry\n"))
+		}
+		frames := out.Bytes()
+		for len(frames) > 0 {
+			n := int(binary.BigEndian.Uint32(frames))
+			nonce := string(frames[lengthSize : lengthSize+nonceSize])
+			assert.False(t, seen[nonce], "nonce reused")
+			seen[nonce] = true
+			frames = frames[lengthSize+nonceSize+n:]
+		}
+	}
+	assert.Len(t, seen, 200)
+}
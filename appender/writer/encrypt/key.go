@@ -0,0 +1,79 @@
+package encrypt
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+// KeySource resolves a key reference, e.g. the name of an environment
+// variable, into the base64 encoding of a 16, 24 or 32 byte AES key.
+type KeySource func(ref string) (string, error)
+
+var (
+	keySourcesMu sync.RWMutex
+	keySources   = map[string]KeySource{
+		"env":  envKey,
+		"file": fileKey,
+	}
+)
+
+// RegisterKeySource makes keys of the form "scheme:ref" resolvable by s, e.g.
+// to fetch them from a key management service.
+func RegisterKeySource(scheme string, s KeySource) {
+	keySourcesMu.Lock()
+	defer keySourcesMu.Unlock()
+	if _, exists := keySources[scheme]; exists {
+		panic(fmt.Sprintf("key source %q already registered", scheme))
+	}
+	keySources[scheme] = s
+}
+
+// ResolveKey returns the AES key referenced by key, which has the form
+// "scheme:ref": "env:NAME" reads the environment variable NAME and
+// "file:PATH" the file at PATH, other schemes being registered with
+// RegisterKeySource.
+func ResolveKey(key string) ([]byte, error) {
+	i := strings.IndexByte(key, ':')
+	if i < 0 {
+		return nil, fmt.Errorf("encryption key %q is not of the form scheme:ref", key)
+	}
+	keySourcesMu.RLock()
+	s := keySources[key[:i]]
+	keySourcesMu.RUnlock()
+	if s == nil {
+		return nil, fmt.Errorf("unknown encryption key source %q", key[:i])
+	}
+	encoded, err := s(key[i+1:])
+	if err != nil {
+		return nil, err
+	}
+	k, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("encryption key %q: %v", key, err)
+	}
+	switch len(k) {
+	case 16, 24, 32:
+		return k, nil
+	}
+	return nil, fmt.Errorf("encryption key %q: invalid length %d, want 16, 24 or 32 bytes", key, len(k))
+}
+
+func envKey(name string) (string, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return v, nil
+}
+
+func fileKey(path string) (string, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(bs), nil
+}
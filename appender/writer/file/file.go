@@ -1,17 +1,78 @@
 package file
 
 import (
+	"fmt"
 	"github.com/shanexu/logn/appender/writer"
+	"github.com/shanexu/logn/appender/writer/buffer"
+	"github.com/shanexu/logn/appender/writer/mmap"
+	"github.com/shanexu/logn/appender/writer/rotate"
 	"github.com/shanexu/logn/common"
+	"github.com/shanexu/logn/status"
 	"os"
+	"sync"
+	"time"
 )
 
+// File appends to a file, which Reopen switches to the one found at its
+// name. When its name is a template, File switches to the file the template
+// names whenever it changes.
 type File struct {
 	*os.File
+	opts *options
+	tmpl *template
+	// until is when the name made from tmpl changes
+	until time.Time
+	// hooks run on the files switched from, if any
+	hooks *rotate.Hooks
+	// link is the path of the link to keep pointing at the file, if any
+	link string
+
+	mu sync.RWMutex
 }
 
 type Config struct {
+	// FileName is the name of the file, or a template of it whose
+	// placeholders, such as %Y-%m-%d, are replaced by the local time when
+	// the file is opened, the directories of the files being created as
+	// needed.
 	FileName string `logn-config:"file_name" logn-validate:"required"`
+	// Buffer, if set, buffers the writes to the file.
+	Buffer *buffer.Config `logn-config:"buffer"`
+	// Mmap, if set, writes the file through memory mappings.
+	Mmap *mmap.Config `logn-config:"mmap"`
+	// Preallocate, if set, is the number of bytes reserved on disk past the
+	// end of the file whenever it is opened, sparing the file system
+	// allocations as it grows. It is only supported on Linux.
+	Preallocate int64 `logn-config:"preallocate" logn-validate:"min=0"`
+	// CreateDirs, if set, creates the missing parent directories of the
+	// file, which must exist otherwise.
+	CreateDirs bool `logn-config:"create_dirs"`
+	// FileMode is the permissions of the file, in octal, 0644 by default.
+	// When set, they are enforced whenever the file is opened, whatever the
+	// umask.
+	FileMode string `logn-config:"file_mode"`
+	// DirMode is the permissions of the directories CreateDirs creates, in
+	// octal, 0755 by default.
+	DirMode string `logn-config:"dir_mode"`
+	// Owner and Group, if set, are the user and group, by name or id, the
+	// file and the directories created are given on Unix. Changing them
+	// takes privileges, a failure being reported to the status logger.
+	Owner string `logn-config:"owner"`
+	Group string `logn-config:"group"`
+	// Lock, if set, takes an exclusive advisory lock on the file around each
+	// write, flock on Unix and LockFileEx on Windows, so that the entries of
+	// the processes appending to the same file with Lock set do not
+	// interleave. It is not supported with Buffer, which may split entries
+	// across writes, nor with Mmap.
+	Lock bool `logn-config:"lock"`
+	// OnRotate lists the hooks run on the files switched from when FileName
+	// is a template, e.g. to upload them.
+	OnRotate []common.ConfigNamespace `logn-config:"on_rotate"`
+	// LinkName, if set, is a symlink kept pointing at the file being
+	// written to when FileName is a template, for the tools tailing the
+	// logs. Unless absolute, it is relative to the directory of FileName
+	// before its first placeholder.
+	LinkName string `logn-config:"link_name"`
 }
 
 var (
@@ -27,11 +88,182 @@ func NewFile(v *common.Config) (writer.Writer, error) {
 	if err := v.Unpack(&cfg); err != nil {
 		return nil, err
 	}
-	f, err := os.OpenFile(cfg.FileName, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	opts, err := newOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := parseTemplate(cfg.FileName)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Lock && (cfg.Buffer != nil || cfg.Mmap != nil) {
+		return nil, fmt.Errorf("file_name %s: lock is not supported with buffer or mmap", cfg.FileName)
+	}
+	if tmpl == nil && len(cfg.OnRotate) > 0 {
+		return nil, fmt.Errorf("on_rotate needs file_name %s to be a template, or a rolling_file appender", cfg.FileName)
+	}
+	if tmpl == nil && cfg.LinkName != "" {
+		return nil, fmt.Errorf("link_name needs file_name %s to be a template", cfg.FileName)
+	}
+	if tmpl != nil {
+		if cfg.Mmap != nil {
+			return nil, fmt.Errorf("file_name %s: templates are not supported with mmap", cfg.FileName)
+		}
+		opts.createDirs = true
+		hooks, err := rotate.New(cfg.OnRotate)
+		if err != nil {
+			return nil, err
+		}
+		var link string
+		if cfg.LinkName != "" {
+			link = linkPath(cfg.LinkName, tmpl.dir())
+			if err := checkLink(link); err != nil {
+				return nil, err
+			}
+		}
+		t := now()
+		f, err := opts.openAppend(tmpl.format(t))
+		if err != nil {
+			return nil, err
+		}
+		w := &File{File: f, opts: opts, tmpl: tmpl, until: tmpl.next(t), hooks: hooks, link: link}
+		w.relink()
+		return buffered(w, cfg)
+	}
+	if cfg.Mmap != nil {
+		// mappings need the file to be readable
+		f, err := opts.open(cfg.FileName, os.O_RDWR|os.O_CREATE)
+		if err != nil {
+			return nil, err
+		}
+		w, err := mmap.New(f, *cfg.Mmap)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if f, ok := w.(*os.File); ok {
+			w = &File{File: f, opts: opts}
+		}
+		return buffered(w, cfg)
+	}
+	f, err := opts.openAppend(cfg.FileName)
 	if err != nil {
 		return nil, err
 	}
-	return &File{f}, nil
+	return buffered(&File{File: f, opts: opts}, cfg)
+}
+
+func (f *File) Write(p []byte) (int, error) {
+	if f.tmpl != nil {
+		f.roll()
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.opts.lock {
+		if err := lockFile(f.File); err != nil {
+			return 0, fmt.Errorf("cannot lock %s: %v", f.File.Name(), err)
+		}
+		defer unlockFile(f.File)
+	}
+	return f.File.Write(p)
+}
+
+func (f *File) Sync() error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.File.Sync()
+}
+
+// Close closes the file, then waits for the hooks of the files switched
+// from to have run.
+func (f *File) Close() error {
+	f.mu.Lock()
+	err := f.File.Close()
+	f.mu.Unlock()
+	if f.hooks != nil {
+		f.hooks.Wait()
+	}
+	return err
+}
+
+// OSFile returns the file currently written to.
+func (f *File) OSFile() *os.File {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.File
+}
+
+// roll switches to the file the template names once the name changed. The
+// file is kept if the new one cannot be opened, which is tried again at the
+// next write.
+func (f *File) roll() {
+	t := now()
+	f.mu.RLock()
+	due := !t.Before(f.until)
+	f.mu.RUnlock()
+	if !due {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if t.Before(f.until) {
+		return
+	}
+	name := f.tmpl.format(t)
+	if name != f.File.Name() {
+		nf, err := f.opts.openAppend(name)
+		if err != nil {
+			status.Errorf("cannot open %s: %v", name, err)
+			return
+		}
+		old := f.File
+		f.File = nf
+		old.Close()
+		f.relink()
+		if f.hooks != nil {
+			f.hooks.Run(old.Name())
+		}
+	}
+	f.until = f.tmpl.next(t)
+}
+
+// relink points the link at the file, if there is a link. The caller must
+// hold f.mu, unless f is not shared yet.
+func (f *File) relink() {
+	if f.link == "" {
+		return
+	}
+	if err := relink(f.link, f.File.Name()); err != nil {
+		status.Warnf("cannot link %s to %s: %v", f.link, f.File.Name(), err)
+	}
+}
+
+// Reopen closes the file and opens the one now found at its name, creating
+// it if need be.
+func (f *File) Reopen() error {
+	f.mu.RLock()
+	name := f.Name()
+	f.mu.RUnlock()
+	if f.tmpl != nil {
+		name = f.tmpl.format(now())
+	}
+	nf, err := f.opts.openAppend(name)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	old := f.File
+	f.File = nf
+	f.relink()
+	f.mu.Unlock()
+	return old.Close()
+}
+
+func buffered(w writer.Writer, cfg Config) (writer.Writer, error) {
+	if cfg.Buffer != nil {
+		return buffer.New(w, *cfg.Buffer)
+	}
+	return w, nil
 }
 
 func init() {
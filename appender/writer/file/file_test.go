@@ -1,7 +1,16 @@
 package file
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
+	"time"
+
+	"github.com/shanexu/logn/appender/writer"
+	"github.com/shanexu/logn/common"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -10,3 +19,215 @@ func TestDefaultConfig(t *testing.T) {
 	c := DefaultConfig()
 	assert.Empty(t, c.FileName)
 }
+
+func TestBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "app.log")
+
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"file_name": name,
+		"buffer":    map[string]interface{}{"size": 1024, "flush_interval": "1h"},
+	})
+	assert.Nil(t, err)
+	w, err := NewFile(cfg)
+	assert.Nil(t, err)
+
+	w.Write([]byte("buffered\n"))
+	bs, _ := ioutil.ReadFile(name)
+	assert.Empty(t, bs)
+
+	assert.Nil(t, w.Sync())
+	bs, _ = ioutil.ReadFile(name)
+	assert.Equal(t, "buffered\n", string(bs))
+}
+
+func TestReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "app.log")
+
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"file_name": name,
+		"buffer":    map[string]interface{}{"flush_interval": "1h"},
+	})
+	assert.Nil(t, err)
+	w, err := NewFile(cfg)
+	assert.Nil(t, err)
+
+	w.Write([]byte("rotated\n"))
+	assert.Nil(t, os.Rename(name, name+".1"))
+	assert.Nil(t, w.(writer.Reopener).Reopen())
+	w.Write([]byte("reopened\n"))
+	assert.Nil(t, w.Sync())
+
+	bs, _ := ioutil.ReadFile(name + ".1")
+	assert.Equal(t, "rotated\n", string(bs))
+	bs, _ = ioutil.ReadFile(name)
+	assert.Equal(t, "reopened\n", string(bs))
+}
+
+func TestCreateDirs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "a", "b", "app.log")
+
+	cfg, err := common.NewConfigFrom(map[string]interface{}{"file_name": name})
+	assert.Nil(t, err)
+	_, err = NewFile(cfg)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "set create_dirs")
+	}
+
+	cfg, err = common.NewConfigFrom(map[string]interface{}{
+		"file_name":   name,
+		"create_dirs": true,
+		"file_mode":   "0600",
+		"dir_mode":    "0750",
+	})
+	assert.Nil(t, err)
+	w, err := NewFile(cfg)
+	assert.Nil(t, err)
+	w.Write([]byte("created\n"))
+	bs, _ := ioutil.ReadFile(name)
+	assert.Equal(t, "created\n", string(bs))
+
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(name)
+		assert.Nil(t, err)
+		assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+		fi, err = os.Stat(filepath.Join(dir, "a"))
+		assert.Nil(t, err)
+		assert.Equal(t, os.FileMode(0750), fi.Mode().Perm())
+	}
+
+	cfg, err = common.NewConfigFrom(map[string]interface{}{"file_name": name, "file_mode": "rw"})
+	assert.Nil(t, err)
+	_, err = NewFile(cfg)
+	assert.NotNil(t, err)
+}
+
+func TestTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	clock := time.Date(2024, 3, 31, 23, 59, 0, 0, time.Local)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+	host, _ := os.Hostname()
+
+	cfg, err := common.NewConfigFrom(fmt.Sprintf("file_name: %s/%%Y-%%m-%%d/app-${hostname}-%%H.log",
+		filepath.ToSlash(dir)))
+	assert.Nil(t, err)
+	w, err := NewFile(cfg)
+	assert.Nil(t, err)
+	w.Write([]byte("march\n"))
+	clock = clock.Add(30 * time.Second)
+	w.Write([]byte("still march\n"))
+	clock = clock.Add(30 * time.Second)
+	w.Write([]byte("april\n"))
+
+	bs, _ := ioutil.ReadFile(filepath.Join(dir, "2024-03-31", "app-"+host+"-23.log"))
+	assert.Equal(t, "march\nstill march\n", string(bs))
+	bs, _ = ioutil.ReadFile(filepath.Join(dir, "2024-04-01", "app-"+host+"-00.log"))
+	assert.Equal(t, "april\n", string(bs))
+
+	for _, name := range []string{"app-%Q.log", "app-%"} {
+		cfg, err := common.NewConfigFrom(map[string]interface{}{"file_name": filepath.Join(dir, name)})
+		assert.Nil(t, err)
+		_, err = NewFile(cfg)
+		assert.NotNil(t, err, name)
+	}
+}
+
+func TestTemplateOnRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	clock := time.Date(2024, 3, 31, 23, 0, 0, 0, time.Local)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"file_name": filepath.Join(dir, "app-%H.log"),
+		"on_rotate": []interface{}{map[string]interface{}{"gzip": nil}},
+	})
+	assert.Nil(t, err)
+	w, err := NewFile(cfg)
+	assert.Nil(t, err)
+	w.Write([]byte("23\n"))
+	clock = clock.Add(time.Hour)
+	w.Write([]byte("00\n"))
+	w.(*File).hooks.Wait()
+
+	_, err = os.Stat(filepath.Join(dir, "app-23.log.gz"))
+	assert.Nil(t, err)
+	bs, _ := ioutil.ReadFile(filepath.Join(dir, "app-00.log"))
+	assert.Equal(t, "00\n", string(bs))
+
+	cfg, err = common.NewConfigFrom(map[string]interface{}{
+		"file_name": filepath.Join(dir, "app.log"),
+		"on_rotate": []interface{}{map[string]interface{}{"gzip": nil}},
+	})
+	assert.Nil(t, err)
+	_, err = NewFile(cfg)
+	assert.NotNil(t, err)
+}
+
+func TestTemplateLinkName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	clock := time.Date(2024, 3, 31, 23, 0, 0, 0, time.Local)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"file_name": filepath.Join(dir, "%Y-%m-%d", "app-%H.log"),
+		"link_name": "current.log",
+	})
+	assert.Nil(t, err)
+	w, err := NewFile(cfg)
+	assert.Nil(t, err)
+	link := filepath.Join(dir, "current.log")
+	w.Write([]byte("23\n"))
+	bs, _ := ioutil.ReadFile(link)
+	assert.Equal(t, "23\n", string(bs))
+
+	// once the name changed, the link shows the new file
+	clock = clock.Add(time.Hour)
+	w.Write([]byte("00\n"))
+	bs, _ = ioutil.ReadFile(link)
+	assert.Equal(t, "00\n", string(bs))
+	bs, _ = ioutil.ReadFile(filepath.Join(dir, "2024-03-31", "app-23.log"))
+	assert.Equal(t, "23\n", string(bs))
+	assert.Nil(t, w.(*File).Close())
+
+	for name, config := range map[string]map[string]interface{}{
+		"not a template": {"file_name": filepath.Join(dir, "app.log"), "link_name": "current.log"},
+		"not a link":     {"file_name": filepath.Join(dir, "app-%H.log"), "link_name": "2024-03-31"},
+	} {
+		if name == "not a link" && runtime.GOOS == "windows" {
+			continue
+		}
+		cfg, err := common.NewConfigFrom(config)
+		assert.Nil(t, err)
+		_, err = NewFile(cfg)
+		assert.NotNil(t, err, name)
+	}
+}
@@ -0,0 +1,84 @@
+package file
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// linkPath returns the path of the link called name, relative to dir unless
+// absolute.
+func linkPath(name, dir string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(dir, name)
+}
+
+// checkLink fails if link exists and is not a link, not to overwrite a file
+// by mistake. Links being files on Windows, they are not checked there.
+func checkLink(link string) error {
+	if fi, err := os.Lstat(link); err == nil && fi.Mode()&os.ModeSymlink == 0 && runtime.GOOS != "windows" {
+		return fmt.Errorf("link_name %s exists and is not a symlink", link)
+	}
+	return nil
+}
+
+// relink points the link at target, replacing the link in place so that
+// readers never miss it. Where symlinks cannot be made, as on Windows without
+// the privilege, the link is a hard link to target or, on file systems
+// without them, a copy of it.
+func relink(link, target string) error {
+	// the link points at target relatively when it can, so that both can
+	// be moved together
+	dest := target
+	if rel, err := relPath(filepath.Dir(link), target); err == nil {
+		dest = rel
+	}
+	tmp := link + ".tmp"
+	os.Remove(tmp)
+	err := os.Symlink(dest, tmp)
+	if err != nil && runtime.GOOS == "windows" {
+		if err = os.Link(target, tmp); err != nil {
+			err = copyFile(target, tmp)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func relPath(base, target string) (string, error) {
+	base, err := filepath.Abs(base)
+	if err != nil {
+		return "", err
+	}
+	if target, err = filepath.Abs(target); err != nil {
+		return "", err
+	}
+	return filepath.Rel(base, target)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
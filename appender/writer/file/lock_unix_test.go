@@ -0,0 +1,73 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanexu/logn/common"
+)
+
+func TestLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "app.log")
+
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"file_name": name,
+		"lock":      true,
+	})
+	assert.Nil(t, err)
+	w, err := NewFile(cfg)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	// another process holding the lock
+	other, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	assert.Nil(t, syscall.Flock(int(other.Fd()), syscall.LOCK_EX))
+	other.Write([]byte("other\n"))
+
+	done := make(chan struct{})
+	go func() {
+		w.Write([]byte("locked\n"))
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("wrote while the file was locked")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Nil(t, syscall.Flock(int(other.Fd()), syscall.LOCK_UN))
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("still waiting for the lock")
+	}
+	bs, _ := ioutil.ReadFile(name)
+	assert.Equal(t, "other\nlocked\n", string(bs))
+
+	cfg, err = common.NewConfigFrom(map[string]interface{}{
+		"file_name": name,
+		"lock":      true,
+		"buffer":    map[string]interface{}{},
+	})
+	assert.Nil(t, err)
+	_, err = NewFile(cfg)
+	assert.NotNil(t, err)
+}
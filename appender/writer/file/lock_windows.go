@@ -0,0 +1,39 @@
+package file
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	lockfileExclusiveLock = 0x2
+	// the lock covers all the bytes the file may have
+	allBytes = 0xffffffff
+)
+
+var (
+	kernel32       = syscall.NewLazyDLL("kernel32.dll")
+	procLockFile   = kernel32.NewProc("LockFileEx")
+	procUnlockFile = kernel32.NewProc("UnlockFileEx")
+)
+
+// lockFile takes an exclusive lock on the whole of f, waiting for the other
+// processes holding one to release it.
+func lockFile(f *os.File) error {
+	var ol syscall.Overlapped
+	r, _, err := procLockFile.Call(f.Fd(), lockfileExclusiveLock, 0, allBytes, allBytes, uintptr(unsafe.Pointer(&ol)))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	var ol syscall.Overlapped
+	r, _, err := procUnlockFile.Call(f.Fd(), 0, allBytes, allBytes, uintptr(unsafe.Pointer(&ol)))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/shanexu/logn/status"
+)
+
+const (
+	defaultFileMode os.FileMode = 0644
+	defaultDirMode  os.FileMode = 0755
+)
+
+// options tell how files are opened.
+type options struct {
+	preallocate int64
+	createDirs  bool
+	// chmod is whether fileMode is enforced on the files opened
+	chmod    bool
+	fileMode os.FileMode
+	dirMode  os.FileMode
+	// chown is whether the files and the directories created are given to
+	// uid and gid
+	chown    bool
+	uid, gid int
+	// lock is whether writes take an exclusive lock on the files
+	lock bool
+}
+
+func newOptions(cfg Config) (*options, error) {
+	o := &options{preallocate: cfg.Preallocate, createDirs: cfg.CreateDirs, chmod: cfg.FileMode != "", lock: cfg.Lock}
+	var err error
+	if o.fileMode, err = parseMode(cfg.FileMode, defaultFileMode); err != nil {
+		return nil, fmt.Errorf("invalid file_mode: %v", err)
+	}
+	if o.dirMode, err = parseMode(cfg.DirMode, defaultDirMode); err != nil {
+		return nil, fmt.Errorf("invalid dir_mode: %v", err)
+	}
+	if cfg.Owner != "" || cfg.Group != "" {
+		o.chown = true
+		if o.uid, o.gid, err = lookupOwner(cfg.Owner, cfg.Group); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// parseMode parses the octal permissions s, def if empty.
+func parseMode(s string, def os.FileMode) (os.FileMode, error) {
+	if s == "" {
+		return def, nil
+	}
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || m&^uint64(os.ModePerm) != 0 {
+		return 0, fmt.Errorf("%q is not an octal mode such as 0644", s)
+	}
+	return os.FileMode(m), nil
+}
+
+// openAppend opens the file called name for appending, creating it if need
+// be.
+func (o *options) openAppend(name string) (*os.File, error) {
+	f, err := o.open(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE)
+	if err != nil {
+		return nil, err
+	}
+	if o.preallocate > 0 {
+		if err := preallocate(f, o.preallocate); err != nil {
+			status.Warnf("cannot preallocate %s: %v", name, err)
+		}
+	}
+	if o.lock {
+		// tells at once when the file system does not support locks
+		if err := lockFile(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("cannot lock %s: %v", name, err)
+		}
+		unlockFile(f)
+	}
+	return f, nil
+}
+
+// open opens the file called name with flag, creating its directory if told
+// to, and sets its permissions and owner.
+func (o *options) open(name string, flag int) (*os.File, error) {
+	dir := filepath.Dir(name)
+	if o.createDirs {
+		if err := o.mkdirs(dir); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.OpenFile(name, flag, o.fileMode)
+	if err != nil {
+		if os.IsNotExist(err) {
+			if _, serr := os.Stat(dir); os.IsNotExist(serr) {
+				return nil, fmt.Errorf("cannot open %s: directory %s does not exist, set create_dirs to create it", name, dir)
+			}
+		}
+		return nil, err
+	}
+	if o.chmod {
+		if err := f.Chmod(o.fileMode); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	if o.chown {
+		if err := f.Chown(o.uid, o.gid); err != nil {
+			status.Warnf("cannot change the owner of %s: %v", name, err)
+		}
+	}
+	return f, nil
+}
+
+// mkdirs creates dir and its missing parents with the permissions and owner
+// of the options.
+func (o *options) mkdirs(dir string) error {
+	if _, err := os.Stat(dir); err == nil || !os.IsNotExist(err) {
+		return err
+	}
+	if parent := filepath.Dir(dir); parent != dir {
+		if err := o.mkdirs(parent); err != nil {
+			return err
+		}
+	}
+	if err := os.Mkdir(dir, o.dirMode); err != nil && !os.IsExist(err) {
+		return err
+	}
+	// the umask may have dropped some of the permissions
+	if err := os.Chmod(dir, o.dirMode); err != nil {
+		return err
+	}
+	if o.chown {
+		if err := os.Chown(dir, o.uid, o.gid); err != nil {
+			status.Warnf("cannot change the owner of %s: %v", dir, err)
+		}
+	}
+	return nil
+}
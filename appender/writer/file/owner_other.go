@@ -0,0 +1,10 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package file
+
+import "errors"
+
+func lookupOwner(owner, group string) (uid, gid int, err error) {
+	return 0, 0, errors.New("owner and group are only supported on unix")
+}
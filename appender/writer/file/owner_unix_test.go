@@ -0,0 +1,53 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanexu/logn/common"
+)
+
+func TestOwner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "logs", "app.log")
+	u, err := user.Current()
+	if err != nil {
+		t.Skip(err)
+	}
+
+	// giving the files to oneself takes no privileges
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"file_name":   name,
+		"create_dirs": true,
+		"owner":       u.Username,
+		"group":       u.Gid,
+	})
+	assert.Nil(t, err)
+	_, err = NewFile(cfg)
+	assert.Nil(t, err)
+	fi, err := os.Stat(name)
+	assert.Nil(t, err)
+	uid, _ := strconv.Atoi(u.Uid)
+	gid, _ := strconv.Atoi(u.Gid)
+	assert.Equal(t, uint32(uid), fi.Sys().(*syscall.Stat_t).Uid)
+	assert.Equal(t, uint32(gid), fi.Sys().(*syscall.Stat_t).Gid)
+
+	cfg, err = common.NewConfigFrom(map[string]interface{}{"file_name": name, "owner": "no-such-user-logn"})
+	assert.Nil(t, err)
+	_, err = NewFile(cfg)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,18 @@
+package file
+
+import (
+	"os"
+	"syscall"
+)
+
+// fallocKeepSize is FALLOC_FL_KEEP_SIZE: the space is reserved without
+// changing the size of the file, which is appended to.
+const fallocKeepSize = 1
+
+func preallocate(f *os.File, size int64) error {
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return syscall.Fallocate(int(f.Fd()), fallocKeepSize, fi.Size(), size)
+}
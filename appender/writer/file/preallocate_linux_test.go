@@ -0,0 +1,39 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanexu/logn/common"
+)
+
+func TestPreallocate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "app.log")
+
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"file_name":   name,
+		"preallocate": 1 << 20,
+	})
+	assert.Nil(t, err)
+	w, err := NewFile(cfg)
+	assert.Nil(t, err)
+	w.Write([]byte("appended\n"))
+
+	// the space is reserved, the file still ends with what was written
+	var st syscall.Stat_t
+	assert.Nil(t, syscall.Stat(name, &st))
+	assert.Equal(t, int64(len("appended\n")), st.Size)
+	if st.Blocks*512 < 1<<20 {
+		t.Skip("file system does not support preallocation")
+	}
+}
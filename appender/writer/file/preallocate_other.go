@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package file
+
+import (
+	"errors"
+	"os"
+)
+
+func preallocate(*os.File, int64) error {
+	return errors.New("preallocation is not supported on this platform")
+}
@@ -0,0 +1,147 @@
+package file
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// now tells the time the names of files are made at.
+var now = time.Now
+
+// unit is the span of time a name made from a template is valid for.
+type unit int
+
+const (
+	never unit = iota
+	year
+	month
+	day
+	hour
+	minute
+	second
+)
+
+// template makes the names of files from the time, in local time:
+//
+//	%Y  year, 2006
+//	%y  year in the century, 06
+//	%m  month, 01-12
+//	%d  day of the month, 01-31
+//	%j  day of the year, 001-366
+//	%H  hour, 00-23
+//	%M  minute, 00-59
+//	%S  second, 00-59
+//	%%  a percent sign
+type template struct {
+	s    string
+	unit unit
+}
+
+// parseTemplate parses s, returning nil if it has no placeholder.
+func parseTemplate(s string) (*template, error) {
+	t := &template{s: s}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			continue
+		}
+		if i++; i == len(s) {
+			return nil, fmt.Errorf("file_name %s ends with %%", s)
+		}
+		u := never
+		switch s[i] {
+		case 'Y', 'y':
+			u = year
+		case 'm':
+			u = month
+		case 'd', 'j':
+			u = day
+		case 'H':
+			u = hour
+		case 'M':
+			u = minute
+		case 'S':
+			u = second
+		case '%':
+		default:
+			return nil, fmt.Errorf("file_name %s has unknown placeholder %%%c", s, s[i])
+		}
+		if u > t.unit {
+			t.unit = u
+		}
+	}
+	if t.unit == never {
+		return nil, nil
+	}
+	return t, nil
+}
+
+// dir returns the last directory of the names made from t whatever the
+// time, that of the first placeholder.
+func (t *template) dir() string {
+	return filepath.Dir(t.s[:strings.IndexByte(t.s, '%')] + "_")
+}
+
+// format returns the name of the file for tm.
+func (t *template) format(tm time.Time) string {
+	var b strings.Builder
+	for i := 0; i < len(t.s); i++ {
+		if t.s[i] != '%' {
+			b.WriteByte(t.s[i])
+			continue
+		}
+		i++
+		switch t.s[i] {
+		case 'Y':
+			b.WriteString(strconv.Itoa(tm.Year()))
+		case 'y':
+			pad(&b, tm.Year()%100, 2)
+		case 'm':
+			pad(&b, int(tm.Month()), 2)
+		case 'd':
+			pad(&b, tm.Day(), 2)
+		case 'j':
+			pad(&b, tm.YearDay(), 3)
+		case 'H':
+			pad(&b, tm.Hour(), 2)
+		case 'M':
+			pad(&b, tm.Minute(), 2)
+		case 'S':
+			pad(&b, tm.Second(), 2)
+		case '%':
+			b.WriteByte('%')
+		}
+	}
+	return b.String()
+}
+
+func pad(b *strings.Builder, n, width int) {
+	s := strconv.Itoa(n)
+	for i := len(s); i < width; i++ {
+		b.WriteByte('0')
+	}
+	b.WriteString(s)
+}
+
+// next returns when the name made for tm stops being valid.
+func (t *template) next(tm time.Time) time.Time {
+	y, mo, d := tm.Date()
+	h, mi, s := tm.Clock()
+	loc := tm.Location()
+	switch t.unit {
+	case year:
+		return time.Date(y+1, 1, 1, 0, 0, 0, 0, loc)
+	case month:
+		return time.Date(y, mo+1, 1, 0, 0, 0, 0, loc)
+	case day:
+		return time.Date(y, mo, d+1, 0, 0, 0, 0, loc)
+	case hour:
+		return time.Date(y, mo, d, h+1, 0, 0, 0, loc)
+	case minute:
+		return time.Date(y, mo, d, h, mi+1, 0, 0, loc)
+	default:
+		return time.Date(y, mo, d, h, mi, s+1, 0, loc)
+	}
+}
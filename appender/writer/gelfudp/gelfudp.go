@@ -7,12 +7,13 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"github.com/shanexu/logn/appender/bufpool"
 	"github.com/shanexu/logn/appender/writer"
 	"github.com/shanexu/logn/common"
-	"go.uber.org/zap/zapcore"
 	"io"
 	"io/ioutil"
 	"net"
+	"time"
 )
 
 type Config struct {
@@ -20,6 +21,9 @@ type Config struct {
 	Port             int    `logn-config:"port"`
 	CompressionType  string `logn-config:"compression_type" logn-validate:"logn.oneof=none gzip zlib"`
 	CompressionLevel int    `logn-config:"compression_level"`
+	// WriteTimeout bounds the time sending a message may take, e.g. "2s".
+	// Messages are sent without deadline when it is not set.
+	WriteTimeout string `logn-config:"write_timeout"`
 }
 
 var defaultConfig = Config{
@@ -48,6 +52,9 @@ type UDPSender struct {
 	raddr *net.UDPAddr
 	conn  *net.UDPConn
 	id    IdGenerator
+	// Timeout, if not zero, bounds the time Send may take. Sends running
+	// late fail with a net.Error whose Timeout method returns true.
+	Timeout time.Duration
 }
 
 func NewUDPSender(address string) (*UDPSender, error) {
@@ -76,6 +83,12 @@ func (s *UDPSender) Send(message []byte) error {
 		return ErrTooLargeMessageSize
 	}
 
+	if s.Timeout > 0 {
+		if err := s.conn.SetWriteDeadline(time.Now().Add(s.Timeout)); err != nil {
+			return err
+		}
+	}
+
 	if len(message) <= MaxDatagramSize {
 		_, err := s.conn.WriteToUDP(message, s.raddr)
 		return err
@@ -87,7 +100,9 @@ func (s *UDPSender) Send(message []byte) error {
 	}
 
 	messageID := s.id.NextId()
-	chunk := make([]byte, MaxDatagramSize)
+	buf := bufpool.Get(MaxDatagramSize)
+	defer bufpool.Put(buf)
+	chunk := (*buf)[:MaxDatagramSize]
 	for i := 0; i < chunks; i++ {
 		copy(chunk[0:2], Magic)
 		binary.BigEndian.PutUint64(chunk[2:10], messageID)
@@ -106,6 +121,11 @@ func (s *UDPSender) Send(message []byte) error {
 	return nil
 }
 
+// Close closes the connection the messages are sent through.
+func (s *UDPSender) Close() error {
+	return s.conn.Close()
+}
+
 func NewCompressor(compressionType string, compressionLevel int) (*Compressor, error) {
 	switch compressionType {
 	case "none":
@@ -177,6 +197,15 @@ func (w *Writer) Write(p []byte) (n int, err error) {
 	return n, nil
 }
 
+func (w *Writer) Sync() error {
+	return nil
+}
+
+// Close closes the sender.
+func (w *Writer) Close() error {
+	return w.sender.Close()
+}
+
 func init() {
 	writer.RegisterType("gelf_udp", func(rawConfig *common.Config) (writer.Writer, error) {
 		config := defaultConfig
@@ -187,10 +216,17 @@ func init() {
 		if err != nil {
 			return nil, err
 		}
+		var timeout time.Duration
+		if config.WriteTimeout != "" {
+			if timeout, err = time.ParseDuration(config.WriteTimeout); err != nil {
+				return nil, err
+			}
+		}
 		s, err := NewUDPSender(fmt.Sprintf("%s:%d", config.Host, config.Port))
 		if err != nil {
 			return nil, err
 		}
-		return zapcore.AddSync(&Writer{s, c}), nil
+		s.Timeout = timeout
+		return &Writer{s, c}, nil
 	})
 }
@@ -0,0 +1,23 @@
+// Package mmap writes files through memory mappings, sparing a write system
+// call per entry, for workloads where they dominate.
+//
+// The file is grown and mapped by segments of SegmentSize bytes. While it is
+// mapped, the file thus ends with the zeroed, unused part of the last
+// segment, which Close and Reopen trim. A process stopping without closing
+// the writer leaves it, and a writer opening the file again resumes after
+// the last non-zero byte. The file must not be truncated by others while
+// mapped.
+//
+// On platforms without memory mappings, New returns the file itself.
+package mmap
+
+// DefaultSegmentSize is the segment size used when Config.SegmentSize is not
+// set.
+const DefaultSegmentSize = 64 << 20
+
+// Config is the mmap section of file appenders.
+type Config struct {
+	// SegmentSize is the number of bytes the file is grown by at once,
+	// rounded up to a multiple of the page size.
+	SegmentSize int `logn-config:"segment_size" logn-validate:"min=0"`
+}
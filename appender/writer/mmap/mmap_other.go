@@ -0,0 +1,19 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package mmap
+
+import (
+	"io"
+	"os"
+
+	"github.com/shanexu/logn/appender/writer"
+)
+
+// New returns f, memory mappings not being supported on this platform.
+func New(f *os.File, config Config) (writer.Writer, error) {
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,102 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package mmap
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "app.log")
+	page := os.Getpagesize()
+
+	open := func() *mmapWriter {
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := New(f, Config{SegmentSize: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return w.(*mmapWriter)
+	}
+
+	// writes cross segments, the file grows by pages
+	w := open()
+	line := strings.Repeat("x", page/3) + "\n"
+	var want bytes.Buffer
+	for i := 0; i < 10; i++ {
+		w.Write([]byte(line))
+		want.WriteString(line)
+	}
+	big := strings.Repeat("y", 2*page) + "\n"
+	w.Write([]byte(big))
+	want.WriteString(big)
+	assert.Nil(t, w.Sync())
+
+	bs, _ := ioutil.ReadFile(name)
+	assert.Equal(t, 0, len(bs)%page)
+	assert.Equal(t, want.String(), string(bytes.TrimRight(bs, "\x00")))
+
+	// a new writer resumes after the data
+	w = open()
+	w.Write([]byte("resumed\n"))
+	want.WriteString("resumed\n")
+	assert.Nil(t, w.Sync())
+	bs, _ = ioutil.ReadFile(name)
+	assert.Equal(t, want.String(), string(bytes.TrimRight(bs, "\x00")))
+
+	// reopening trims the moved file and maps a new one
+	assert.Nil(t, os.Rename(name, name+".1"))
+	assert.Nil(t, w.Reopen())
+	w.Write([]byte("reopened\n"))
+	assert.Nil(t, w.Sync())
+	bs, _ = ioutil.ReadFile(name + ".1")
+	assert.Equal(t, want.String(), string(bs))
+	bs, _ = ioutil.ReadFile(name)
+	assert.Equal(t, "reopened\n", string(bytes.TrimRight(bs, "\x00")))
+}
+
+func TestClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "app.log")
+
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := New(f, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("first\n"))
+	w.Write([]byte("second\n"))
+	fi, err := os.Stat(name)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(DefaultSegmentSize), fi.Size())
+
+	assert.Nil(t, w.(*mmapWriter).Close())
+	fi, err = os.Stat(name)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len("first\nsecond\n")), fi.Size())
+	bs, _ := ioutil.ReadFile(name)
+	assert.Equal(t, "first\nsecond\n", string(bs))
+}
@@ -0,0 +1,176 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package mmap
+
+import (
+	"io"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/shanexu/logn/appender/writer"
+	"github.com/shanexu/logn/status"
+)
+
+type mmapWriter struct {
+	file    *os.File
+	segment int64
+
+	mu sync.Mutex
+	// data maps the file from offset base
+	data []byte
+	base int64
+	// size is the length of what was written to the file
+	size int64
+}
+
+// New returns a writer appending to f through memory mappings. f is written
+// to directly if it cannot be mapped.
+func New(f *os.File, config Config) (writer.Writer, error) {
+	page := int64(os.Getpagesize())
+	segment := int64(config.SegmentSize)
+	if segment == 0 {
+		segment = DefaultSegmentSize
+	}
+	segment = (segment + page - 1) / page * page
+	size, err := dataSize(f, segment)
+	if err != nil {
+		return nil, err
+	}
+	w := &mmapWriter{file: f, segment: segment, size: size}
+	if err := w.remap(size); err != nil {
+		status.Warnf("cannot map %s, writing it directly: %v", f.Name(), err)
+		return appendTo(f, size)
+	}
+	return w, nil
+}
+
+// appendTo returns f set to be written to from size on.
+func appendTo(f *os.File, size int64) (writer.Writer, error) {
+	if err := f.Truncate(size); err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// dataSize returns the length of f up to the zeroed end of a segment a
+// previous writer left.
+func dataSize(f *os.File, segment int64) (int64, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := fi.Size()
+	from := size - segment
+	if from < 0 {
+		from = 0
+	}
+	tail := make([]byte, size-from)
+	if _, err := f.ReadAt(tail, from); err != nil {
+		return 0, err
+	}
+	i := len(tail)
+	for i > 0 && tail[i-1] == 0 {
+		i--
+	}
+	return from + int64(i), nil
+}
+
+// remap maps the segments of the file holding w.size up to need bytes. It is
+// called with w.mu held, unless w is not shared yet.
+func (w *mmapWriter) remap(need int64) error {
+	if w.data != nil {
+		if err := syscall.Munmap(w.data); err != nil {
+			return err
+		}
+		w.data = nil
+	}
+	page := int64(os.Getpagesize())
+	base := w.size / page * page
+	length := w.segment
+	for base+length < need {
+		length += w.segment
+	}
+	if err := w.file.Truncate(base + length); err != nil {
+		return err
+	}
+	data, err := syscall.Mmap(int(w.file.Fd()), base, int(length), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return err
+	}
+	w.data, w.base = data, base
+	return nil
+}
+
+func (w *mmapWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	end := w.size + int64(len(p))
+	if end > w.base+int64(len(w.data)) {
+		if err := w.remap(end); err != nil {
+			return 0, err
+		}
+	}
+	copy(w.data[w.size-w.base:], p)
+	w.size = end
+	return len(p), nil
+}
+
+// Sync flushes the mapped pages to disk, fsync covering them.
+func (w *mmapWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Sync()
+}
+
+// Close unmaps the file, trims it to what was written, syncs and closes it.
+func (w *mmapWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.data != nil {
+		if err := syscall.Munmap(w.data); err != nil {
+			return err
+		}
+		w.data = nil
+	}
+	err := w.file.Truncate(w.size)
+	if serr := w.file.Sync(); err == nil {
+		err = serr
+	}
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Reopen switches to the file now found at the name of the mapped one,
+// creating it if need be. The former file is trimmed to what was written.
+func (w *mmapWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	f, err := os.OpenFile(w.file.Name(), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	size, err := dataSize(f, w.segment)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if err := syscall.Munmap(w.data); err != nil {
+		f.Close()
+		return err
+	}
+	w.data = nil
+	old := w.file
+	if err := old.Truncate(w.size); err != nil {
+		status.Warnf("cannot trim %s: %v", old.Name(), err)
+	}
+	old.Close()
+	w.file, w.size = f, size
+	return w.remap(size)
+}
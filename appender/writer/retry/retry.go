@@ -0,0 +1,106 @@
+// Package retry retries the writes of remote writers failing with transient
+// errors, waiting an exponentially growing, jittered delay between attempts.
+package retry
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/shanexu/logn/appender/writer"
+)
+
+// Defaults of Config.
+const (
+	DefaultMaxAttempts = 3
+	DefaultBackoff     = 100 * time.Millisecond
+	DefaultMaxBackoff  = 10 * time.Second
+)
+
+// Config is the retry section of an appender.
+type Config struct {
+	// MaxAttempts is the number of times a write is attempted.
+	MaxAttempts int `logn-config:"max_attempts" logn-validate:"min=0"`
+	// Backoff is the delay before the second attempt, doubled for each
+	// further one.
+	Backoff string `logn-config:"backoff"`
+	// MaxBackoff caps the delay between attempts.
+	MaxBackoff string `logn-config:"max_backoff"`
+}
+
+// Retryable reports whether err is transient, i.e. whether the write failing
+// with it may succeed if attempted again. Errors tell so by implementing
+// interface{ Retryable() bool }; otherwise timeouts and network errors are
+// transient.
+func Retryable(err error) bool {
+	var r interface{ Retryable() bool }
+	if errors.As(err, &r) {
+		return r.Retryable()
+	}
+	var ne net.Error
+	return errors.As(err, &ne)
+}
+
+type retryWriter struct {
+	writer.Writer
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	sleep       func(time.Duration)
+}
+
+// New wraps w so that writes to it failing with a transient error are
+// attempted again.
+func New(w writer.Writer, config Config) (writer.Writer, error) {
+	rw := &retryWriter{
+		Writer:      w,
+		maxAttempts: config.MaxAttempts,
+		backoff:     DefaultBackoff,
+		maxBackoff:  DefaultMaxBackoff,
+		sleep:       time.Sleep,
+	}
+	if rw.maxAttempts == 0 {
+		rw.maxAttempts = DefaultMaxAttempts
+	}
+	var err error
+	if config.Backoff != "" {
+		if rw.backoff, err = time.ParseDuration(config.Backoff); err != nil {
+			return nil, err
+		}
+	}
+	if config.MaxBackoff != "" {
+		if rw.maxBackoff, err = time.ParseDuration(config.MaxBackoff); err != nil {
+			return nil, err
+		}
+	}
+	return rw, nil
+}
+
+func (w *retryWriter) Write(p []byte) (int, error) {
+	delay := w.backoff
+	for attempt := 1; ; attempt++ {
+		n, err := w.Writer.Write(p)
+		if err == nil || attempt >= w.maxAttempts || !Retryable(err) {
+			return n, err
+		}
+		w.sleep(jitter(delay))
+		if delay *= 2; delay > w.maxBackoff {
+			delay = w.maxBackoff
+		}
+	}
+}
+
+// Close closes the wrapped writer.
+func (w *retryWriter) Close() error {
+	return writer.Close(w.Writer)
+}
+
+// jitter returns a random duration between d/2 and d, so that writers
+// failing together do not retry together.
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
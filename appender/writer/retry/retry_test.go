@@ -0,0 +1,71 @@
+package retry
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingWriter struct {
+	errs   []error
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	if len(w.errs) > 0 {
+		err := w.errs[0]
+		w.errs = w.errs[1:]
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *failingWriter) Sync() error { return nil }
+
+type permanentError struct{}
+
+func (permanentError) Error() string   { return "permanent" }
+func (permanentError) Retryable() bool { return false }
+
+func TestRetry(t *testing.T) {
+	transient := &net.OpError{Op: "write", Net: "udp", Err: errors.New("connection refused")}
+
+	out := &failingWriter{errs: []error{transient, transient}}
+	w, err := New(out, Config{MaxAttempts: 3, Backoff: "10ms", MaxBackoff: "15ms"})
+	assert.Nil(t, err)
+	var delays []time.Duration
+	w.(*retryWriter).sleep = func(d time.Duration) { delays = append(delays, d) }
+
+	n, err := w.Write([]byte("entry"))
+	assert.Nil(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, 3, out.writes)
+	if assert.Len(t, delays, 2) {
+		assert.True(t, delays[0] >= 5*time.Millisecond && delays[0] <= 10*time.Millisecond, delays[0])
+		assert.True(t, delays[1] >= 7*time.Millisecond && delays[1] <= 15*time.Millisecond, delays[1])
+	}
+
+	// attempts are bounded
+	out = &failingWriter{errs: []error{transient, transient, transient}}
+	w, _ = New(out, Config{MaxAttempts: 2})
+	w.(*retryWriter).sleep = func(time.Duration) {}
+	_, err = w.Write([]byte("entry"))
+	assert.Equal(t, transient, err)
+	assert.Equal(t, 2, out.writes)
+
+	// permanent errors are not retried
+	out = &failingWriter{errs: []error{permanentError{}, errors.New("bad message")}}
+	w, _ = New(out, Config{})
+	_, err = w.Write([]byte("entry"))
+	assert.Equal(t, permanentError{}, err)
+	_, err = w.Write([]byte("entry"))
+	assert.EqualError(t, err, "bad message")
+	assert.Equal(t, 2, out.writes)
+
+	_, err = New(out, Config{Backoff: "soon"})
+	assert.NotNil(t, err)
+}
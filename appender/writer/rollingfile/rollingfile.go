@@ -2,13 +2,30 @@ package rollingfile
 
 import (
 	"github.com/shanexu/logn/appender/writer"
+	"github.com/shanexu/logn/appender/writer/buffer"
+	"github.com/shanexu/logn/appender/writer/rotate"
 	"github.com/shanexu/logn/common"
-	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 )
 
+const megabyte = 1024 * 1024
+
+// RollingFile writes to a file, which it rotates once it would grow over
+// its maximum size, lumberjack moving it aside and pruning the old ones.
 type RollingFile struct {
-	zapcore.WriteSyncer
+	mu     sync.Mutex
+	logger *lumberjack.Logger
+	// hooks run on the files moved aside, if any
+	hooks  *rotate.Hooks
+	opened bool
+	size   int64
+	max    int64
 }
 
 type Config struct {
@@ -41,6 +58,13 @@ type Config struct {
 	// Compress determines if the rotated log files should be compressed
 	// using gzip. The default is not to perform compression.
 	Compress bool `logn-config:"compress"`
+
+	// OnRotate lists the hooks run on the files moved aside, e.g. to upload
+	// them. With Compress, the files are compressed before the hooks run.
+	OnRotate []common.ConfigNamespace `logn-config:"on_rotate"`
+
+	// Buffer, if set, buffers the writes to the file.
+	Buffer *buffer.Config `logn-config:"buffer"`
 }
 
 func NewRollingFile(v *common.Config) (writer.Writer, error) {
@@ -53,15 +77,153 @@ func NewRollingFile(v *common.Config) (writer.Writer, error) {
 	if cfg.MaxAge == 0 {
 		cfg.MaxAge = 7
 	}
-	w := &lumberjack.Logger{
-		Filename:   cfg.FileName,
-		MaxSize:    cfg.MaxSize,
-		MaxAge:     cfg.MaxAge,
-		MaxBackups: cfg.MaxBackups,
-		LocalTime:  cfg.LocalTime,
-		Compress:   cfg.Compress,
+	w := &RollingFile{
+		logger: &lumberjack.Logger{
+			Filename:   cfg.FileName,
+			MaxSize:    cfg.MaxSize,
+			MaxAge:     cfg.MaxAge,
+			MaxBackups: cfg.MaxBackups,
+			LocalTime:  cfg.LocalTime,
+			Compress:   cfg.Compress,
+		},
+		max: int64(cfg.MaxSize) * megabyte,
+	}
+	hooks, err := rotate.New(cfg.OnRotate)
+	if err != nil {
+		return nil, err
+	}
+	if hooks != nil && cfg.Compress {
+		// lumberjack compresses in the background, the hooks would race it
+		w.logger.Compress = false
+		hooks.Prepend("gzip", rotate.Gzip)
+	}
+	w.hooks = hooks
+	if cfg.Buffer != nil {
+		return buffer.New(w, *cfg.Buffer)
+	}
+	return w, nil
+}
+
+func (w *RollingFile) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.opened {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	if w.size > 0 && w.size+int64(len(p)) > w.max {
+		if err := w.withHooks(w.logger.Rotate); err != nil {
+			return 0, err
+		}
+		w.size = 0
+	}
+	n, err := w.logger.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Sync commits the file to stable storage. lumberjack not exposing the file,
+// it is opened again to be synced, which syncs what was written through
+// lumberjack too.
+func (w *RollingFile) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.opened {
+		return nil
+	}
+	f, err := os.OpenFile(w.logger.Filename, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	err = f.Sync()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Reopen closes the file, the one found at its name being opened by the next
+// write, e.g. once logrotate moved it away.
+func (w *RollingFile) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.opened = false
+	return w.logger.Close()
+}
+
+// Close closes the file, then waits for the hooks of the files moved aside
+// to have run.
+func (w *RollingFile) Close() error {
+	w.mu.Lock()
+	w.opened = false
+	err := w.logger.Close()
+	w.mu.Unlock()
+	if w.hooks != nil {
+		w.hooks.Wait()
+	}
+	return err
+}
+
+// open opens the file, rotating it first if it is full, as lumberjack does
+// on the first write.
+func (w *RollingFile) open() error {
+	err := w.withHooks(func() error {
+		_, err := w.logger.Write(nil)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	fi, err := os.Stat(w.logger.Filename)
+	if err != nil {
+		return err
+	}
+	w.size = fi.Size()
+	w.opened = true
+	return nil
+}
+
+// withHooks calls rotate, running the hooks on the files it moved aside.
+func (w *RollingFile) withHooks(rotate func() error) error {
+	if w.hooks == nil {
+		return rotate()
+	}
+	before := w.backups()
+	if err := rotate(); err != nil {
+		return err
+	}
+	for _, b := range w.backups() {
+		if !contains(before, b) {
+			w.hooks.Run(b)
+		}
 	}
-	return &RollingFile{zapcore.AddSync(w)}, nil
+	return nil
+}
+
+// backups returns the names of the files lumberjack moved aside, sorted.
+func (w *RollingFile) backups() []string {
+	dir := filepath.Dir(w.logger.Filename)
+	base := filepath.Base(w.logger.Filename)
+	ext := filepath.Ext(base)
+	prefix := base[:len(base)-len(ext)] + "-"
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, fi := range fis {
+		if n := fi.Name(); !fi.IsDir() && strings.HasPrefix(n, prefix) && strings.HasSuffix(n, ext) {
+			names = append(names, filepath.Join(dir, n))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func contains(names []string, name string) bool {
+	i := sort.SearchStrings(names, name)
+	return i < len(names) && names[i] == name
 }
 
 func init() {
@@ -1,9 +1,18 @@
 package rollingfile
 
 import (
+	"bytes"
+	"github.com/shanexu/logn/appender/writer"
+	"github.com/shanexu/logn/appender/writer/rotate"
 	"github.com/shanexu/logn/common"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestNewRollingFile(t *testing.T) {
@@ -30,3 +39,118 @@ encoder:
 		assert.Equal(t, c.hasErr, err != nil, c.name)
 	}
 }
+
+func TestSync(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rollingfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "app.log")
+
+	cfg, err := common.NewConfigFrom(map[string]interface{}{"file_name": name})
+	assert.Nil(t, err)
+	w, err := NewRollingFile(cfg)
+	assert.Nil(t, err)
+	// nothing is synced before the file is opened
+	assert.Nil(t, w.Sync())
+	_, err = w.Write([]byte("synced\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, w.Sync())
+	bs, _ := ioutil.ReadFile(name)
+	assert.Equal(t, "synced\n", string(bs))
+
+	// the file moved away, syncing fails
+	assert.Nil(t, os.Remove(name))
+	assert.NotNil(t, w.Sync())
+	assert.Nil(t, w.(*RollingFile).Close())
+}
+
+func TestReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rollingfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "app.log")
+
+	cfg, err := common.NewConfigFrom(map[string]interface{}{"file_name": name})
+	assert.Nil(t, err)
+	w, err := NewRollingFile(cfg)
+	assert.Nil(t, err)
+
+	w.Write([]byte("rotated\n"))
+	assert.Nil(t, os.Rename(name, name+".1"))
+	assert.Nil(t, w.(writer.Reopener).Reopen())
+	w.Write([]byte("reopened\n"))
+
+	bs, _ := ioutil.ReadFile(name + ".1")
+	assert.Equal(t, "rotated\n", string(bs))
+	bs, _ = ioutil.ReadFile(name)
+	assert.Equal(t, "reopened\n", string(bs))
+}
+
+func TestOnRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rollingfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "app.log")
+
+	cfg, err := common.NewConfigFrom(`
+file_name: ` + filepath.ToSlash(name) + `
+max_size: 1
+compress: true
+on_rotate:
+  - checksum:
+`)
+	assert.Nil(t, err)
+	w, err := NewRollingFile(cfg)
+	assert.Nil(t, err)
+
+	chunk := bytes.Repeat([]byte("a"), 600*1024)
+	w.Write(chunk)
+	w.Write(chunk)
+	w.(*RollingFile).hooks.Wait()
+
+	// compressed before the hooks run
+	matches, _ := filepath.Glob(filepath.Join(dir, "app-*.log*"))
+	if assert.Len(t, matches, 2) {
+		assert.True(t, strings.HasSuffix(matches[0], ".log.gz"))
+		assert.Equal(t, matches[0]+".sha256", matches[1])
+	}
+}
+
+func TestCloseWaitsForHooks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rollingfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "app.log")
+
+	cfg, err := common.NewConfigFrom(`
+file_name: ` + filepath.ToSlash(name) + `
+max_size: 1
+on_rotate:
+  - checksum:
+`)
+	assert.Nil(t, err)
+	w, err := NewRollingFile(cfg)
+	assert.Nil(t, err)
+	var ran int32
+	w.(*RollingFile).hooks.Prepend("slow", rotate.HookFunc(func(file string) (string, error) {
+		time.Sleep(100 * time.Millisecond)
+		atomic.StoreInt32(&ran, 1)
+		return file, nil
+	}))
+
+	chunk := bytes.Repeat([]byte("a"), 600*1024)
+	w.Write(chunk)
+	w.Write(chunk)
+	assert.Nil(t, writer.Close(w))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&ran))
+	matches, _ := filepath.Glob(filepath.Join(dir, "app-*.log.sha256"))
+	assert.Len(t, matches, 1)
+}
@@ -0,0 +1,156 @@
+package rotate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shanexu/logn/common"
+)
+
+// Gzip compresses the files into the files suffixed with .gz, which replace
+// them.
+var Gzip Hook = HookFunc(gzipHook)
+
+func gzipHook(file string) (string, error) {
+	in, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return "", err
+	}
+	dst := file + ".gz"
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return "", err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return "", err
+	}
+	in.Close()
+	return dst, os.Remove(file)
+}
+
+// Checksum returns the hex-encoded SHA-256 digest of the content of file.
+func Checksum(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checksumHook writes the SHA-256 digest of the file to the file suffixed
+// with .sha256, as sha256sum does, so that sha256sum -c checks it.
+func checksumHook(file string) (string, error) {
+	sum, err := Checksum(file)
+	if err != nil {
+		return "", err
+	}
+	line := sum + "  " + filepath.Base(file) + "\n"
+	return file, ioutil.WriteFile(file+".sha256", []byte(line), 0644)
+}
+
+const defaultExecTimeout = time.Minute
+
+type execConfig struct {
+	// Command is the command to run and its arguments, {file} being
+	// replaced by the name of the file, which is the last argument
+	// otherwise.
+	Command []string `logn-config:"command" logn-validate:"required"`
+	// Timeout is how long the command may run before being killed, 1m by
+	// default.
+	Timeout string `logn-config:"timeout"`
+}
+
+// execHook runs a command on the files.
+type execHook struct {
+	command []string
+	timeout time.Duration
+}
+
+func newExecHook(config *common.Config) (Hook, error) {
+	cfg := execConfig{}
+	if err := config.Unpack(&cfg); err != nil {
+		return nil, err
+	}
+	h := &execHook{command: cfg.Command, timeout: defaultExecTimeout}
+	if cfg.Timeout != "" {
+		d, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		h.timeout = d
+	}
+	return h, nil
+}
+
+func (h *execHook) Rotated(file string) (string, error) {
+	args := make([]string, 0, len(h.command)+1)
+	replaced := false
+	for _, a := range h.command {
+		if strings.Contains(a, "{file}") {
+			a = strings.Replace(a, "{file}", file, -1)
+			replaced = true
+		}
+		args = append(args, a)
+	}
+	if !replaced {
+		args = append(args, file)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), "LOGN_ROTATED_FILE="+file)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		if o := strings.TrimSpace(out.String()); o != "" {
+			return "", fmt.Errorf("%v: %s", err, o)
+		}
+		return "", err
+	}
+	return file, nil
+}
+
+func init() {
+	RegisterType("gzip", func(*common.Config) (Hook, error) {
+		return Gzip, nil
+	})
+	RegisterType("checksum", func(*common.Config) (Hook, error) {
+		return HookFunc(checksumHook), nil
+	})
+	RegisterType("exec", newExecHook)
+	RegisterType("manifest", newManifestHook)
+}
@@ -0,0 +1,100 @@
+package rotate
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/shanexu/logn/appender/writer/encrypt"
+	"github.com/shanexu/logn/common"
+	"github.com/shanexu/logn/sign"
+)
+
+// DefaultManifest is the name of the manifest written beside the rotated
+// files unless configured otherwise.
+const DefaultManifest = "manifest.jsonl"
+
+type manifestConfig struct {
+	// File is the manifest the entries are appended to, DefaultManifest in
+	// the directory of the rotated files by default.
+	File string `logn-config:"file"`
+	// Signing, if set, signs the entries as the signing section of the
+	// appenders does, so that logn verify checks the manifest.
+	Signing *sign.Config `logn-config:"signing"`
+}
+
+// ManifestEntry is the line a manifest holds for a rotated file.
+type ManifestEntry struct {
+	// Time is when the file was added to the manifest.
+	Time time.Time `json:"time"`
+	// File is the base name of the file.
+	File string `json:"file"`
+	// Size is the size of the file in bytes.
+	Size int64 `json:"size"`
+	// SHA256 is the hex-encoded SHA-256 digest of the content of the file.
+	SHA256 string `json:"sha256"`
+}
+
+// manifestHook appends an entry per file to a manifest, one JSON object per
+// line, for archival pipelines to check the integrity of the files with.
+type manifestHook struct {
+	file   string
+	signer *sign.Signer
+}
+
+func newManifestHook(config *common.Config) (Hook, error) {
+	cfg := manifestConfig{}
+	if err := config.Unpack(&cfg); err != nil {
+		return nil, err
+	}
+	h := &manifestHook{file: cfg.File}
+	if cfg.Signing != nil {
+		key, err := encrypt.ResolveKey(cfg.Signing.Key)
+		if err != nil {
+			return nil, err
+		}
+		h.signer = sign.NewSigner(key, cfg.Signing.Field)
+	}
+	return h, nil
+}
+
+func (h *manifestHook) Rotated(file string) (string, error) {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return "", err
+	}
+	sum, err := Checksum(file)
+	if err != nil {
+		return "", err
+	}
+	line, err := json.Marshal(ManifestEntry{
+		Time:   time.Now().UTC(),
+		File:   filepath.Base(file),
+		Size:   fi.Size(),
+		SHA256: sum,
+	})
+	if err != nil {
+		return "", err
+	}
+	name := h.file
+	if name == "" {
+		name = filepath.Join(filepath.Dir(file), DefaultManifest)
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return "", err
+	}
+	if h.signer != nil {
+		_, err = h.signer.Write(f, line)
+	} else {
+		_, err = f.Write(append(line, '\n'))
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return file, err
+}
@@ -0,0 +1,142 @@
+// Package rotate runs hooks on the files appenders are done writing to once
+// rotated, e.g. to archive or upload them. Hooks are configured with the
+// on_rotate list of the appenders, each item naming a hook type:
+//
+//	on_rotate:
+//	  - checksum:
+//	  - exec:
+//	      command: [/usr/local/bin/archive, "{file}"]
+//
+// Built-in types are gzip, checksum, manifest and exec, others being registered with
+// RegisterType, such as the s3 and gcs types of modules logns3 and logngcs.
+package rotate
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/shanexu/logn/common"
+	"github.com/shanexu/logn/status"
+)
+
+// Hook acts on a rotated file, returning the file the next hooks act on,
+// e.g. the compressed file replacing it.
+type Hook interface {
+	Rotated(file string) (string, error)
+}
+
+// HookFunc adapts a function to Hook.
+type HookFunc func(file string) (string, error)
+
+func (f HookFunc) Rotated(file string) (string, error) {
+	return f(file)
+}
+
+// Factory creates a hook from its configuration.
+type Factory func(config *common.Config) (Hook, error)
+
+var (
+	hookTypesMu sync.RWMutex
+	hookTypes   = map[string]Factory{}
+)
+
+// RegisterType makes the hooks of type name configurable.
+func RegisterType(name string, f Factory) {
+	hookTypesMu.Lock()
+	defer hookTypesMu.Unlock()
+	if _, exists := hookTypes[name]; exists {
+		panic(fmt.Sprintf("rotation hook type %q already registered", name))
+	}
+	hookTypes[name] = f
+}
+
+type namedHook struct {
+	name string
+	Hook
+}
+
+// Hooks run hooks on rotated files, in the background and one file after the
+// other, the failures being reported to the status logger. The writers
+// running them wait for them on Close.
+type Hooks struct {
+	hooks []namedHook
+	// wg tracks the goroutine running the hooks
+	wg sync.WaitGroup
+
+	mu      sync.Mutex
+	pending []string
+	running bool
+}
+
+// New creates the hooks configs describe, nil if there is none.
+func New(configs []common.ConfigNamespace) (*Hooks, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+	hs := &Hooks{}
+	for _, c := range configs {
+		hookTypesMu.RLock()
+		f := hookTypes[c.Name()]
+		hookTypesMu.RUnlock()
+		if f == nil {
+			return nil, fmt.Errorf("rotation hook type %q undefined", c.Name())
+		}
+		h, err := f(c.Config())
+		if err != nil {
+			return nil, fmt.Errorf("rotation hook %s: %v", c.Name(), err)
+		}
+		hs.hooks = append(hs.hooks, namedHook{name: c.Name(), Hook: h})
+	}
+	return hs, nil
+}
+
+// Prepend makes h run before the other hooks.
+func (hs *Hooks) Prepend(name string, h Hook) {
+	hs.hooks = append([]namedHook{{name: name, Hook: h}}, hs.hooks...)
+}
+
+// Run runs the hooks on file in the background, once those of the files
+// rotated before have run.
+func (hs *Hooks) Run(file string) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.pending = append(hs.pending, file)
+	if !hs.running {
+		hs.running = true
+		hs.wg.Add(1)
+		go hs.loop()
+	}
+}
+
+// Wait waits for the hooks of the files rotated so far to have run, e.g.
+// before the process exits.
+func (hs *Hooks) Wait() {
+	hs.wg.Wait()
+}
+
+// loop runs the hooks on the pending files, until there is none left.
+func (hs *Hooks) loop() {
+	hs.mu.Lock()
+	for len(hs.pending) > 0 {
+		file := hs.pending[0]
+		hs.pending = hs.pending[1:]
+		hs.mu.Unlock()
+		hs.run(file)
+		hs.mu.Lock()
+	}
+	hs.running = false
+	hs.mu.Unlock()
+	hs.wg.Done()
+}
+
+// run runs the hooks on file, stopping at the first failing.
+func (hs *Hooks) run(file string) {
+	for _, h := range hs.hooks {
+		next, err := h.Rotated(file)
+		if err != nil {
+			status.Errorf("rotation hook %s failed on %s: %v", h.name, file, err)
+			return
+		}
+		file = next
+	}
+}
@@ -0,0 +1,196 @@
+package rotate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanexu/logn/common"
+	"github.com/shanexu/logn/sign"
+)
+
+func newHooks(t *testing.T, config string) *Hooks {
+	var cfg struct {
+		OnRotate []common.ConfigNamespace `logn-config:"on_rotate"`
+	}
+	c, err := common.NewConfigFrom(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Unpack(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	hs, err := New(cfg.OnRotate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hs
+}
+
+func TestHooks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "app-1.log")
+	assert.Nil(t, ioutil.WriteFile(name, []byte("rotated\n"), 0644))
+
+	var mu sync.Mutex
+	var seen []string
+	hs := newHooks(t, `
+on_rotate:
+  - gzip:
+  - checksum:
+`)
+	hs.hooks = append(hs.hooks, namedHook{name: "test", Hook: HookFunc(func(file string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, file)
+		return file, nil
+	})})
+	hs.Run(name)
+	hs.Wait()
+
+	assert.Equal(t, []string{name + ".gz"}, seen)
+	_, err = os.Stat(name)
+	assert.True(t, os.IsNotExist(err))
+	f, err := os.Open(name + ".gz")
+	if assert.Nil(t, err) {
+		defer f.Close()
+		zr, err := gzip.NewReader(f)
+		assert.Nil(t, err)
+		bs, _ := ioutil.ReadAll(zr)
+		assert.Equal(t, "rotated\n", string(bs))
+	}
+	sum, err := Checksum(name + ".gz")
+	assert.Nil(t, err)
+	bs, _ := ioutil.ReadFile(name + ".gz.sha256")
+	assert.Equal(t, sum+"  app-1.log.gz\n", string(bs))
+
+	// failures stop the hooks of the file
+	seen = nil
+	hs.Run(filepath.Join(dir, "missing.log"))
+	hs.Wait()
+	assert.Empty(t, seen)
+}
+
+func TestExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "app-1.log")
+	assert.Nil(t, ioutil.WriteFile(name, []byte("rotated\n"), 0644))
+
+	hs := newHooks(t, `
+on_rotate:
+  - exec:
+      command: [sh, -c, 'cp "$0" "$0.copy" && echo "$LOGN_ROTATED_FILE" > "$0.env"']
+  - exec:
+      command: [cp, "{file}", "{file}.second"]
+`)
+	hs.Run(name)
+	hs.Wait()
+	bs, _ := ioutil.ReadFile(name + ".copy")
+	assert.Equal(t, "rotated\n", string(bs))
+	bs, _ = ioutil.ReadFile(name + ".env")
+	assert.Equal(t, name+"\n", string(bs))
+	bs, _ = ioutil.ReadFile(name + ".second")
+	assert.Equal(t, "rotated\n", string(bs))
+
+	h, err := newExecHook(mustConfig(t, `{command: [sh, -c, 'echo broken >&2; exit 3']}`))
+	assert.Nil(t, err)
+	_, err = h.Rotated(name)
+	if assert.NotNil(t, err) {
+		assert.True(t, strings.HasSuffix(err.Error(), ": broken"), err.Error())
+	}
+}
+
+func TestManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	key := bytes.Repeat([]byte{7}, 32)
+	os.Setenv("LOGN_TEST_MANIFEST_KEY", base64.StdEncoding.EncodeToString(key))
+	defer os.Unsetenv("LOGN_TEST_MANIFEST_KEY")
+
+	hs := newHooks(t, `
+on_rotate:
+  - manifest:
+  - manifest:
+      file: `+filepath.Join(dir, "signed.jsonl")+`
+      signing:
+        key: env:LOGN_TEST_MANIFEST_KEY
+`)
+	var names []string
+	for _, n := range []string{"app-1.log", "app-2.log"} {
+		name := filepath.Join(dir, n)
+		assert.Nil(t, ioutil.WriteFile(name, []byte(n+"\n"), 0644))
+		names = append(names, name)
+		hs.Run(name)
+	}
+	hs.Wait()
+
+	bs, err := ioutil.ReadFile(filepath.Join(dir, DefaultManifest))
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(bs)), "\n")
+	if assert.Len(t, lines, 2) {
+		for i, line := range lines {
+			var e ManifestEntry
+			assert.Nil(t, json.Unmarshal([]byte(line), &e))
+			sum, _ := Checksum(names[i])
+			assert.Equal(t, filepath.Base(names[i]), e.File)
+			assert.Equal(t, int64(10), e.Size)
+			assert.Equal(t, sum, e.SHA256)
+			assert.False(t, e.Time.IsZero())
+		}
+	}
+
+	f, err := os.Open(filepath.Join(dir, "signed.jsonl"))
+	if assert.Nil(t, err) {
+		res, err := sign.Verify(f, key, "")
+		f.Close()
+		assert.Nil(t, err)
+		assert.Equal(t, 2, res.Entries)
+	}
+	bs, _ = ioutil.ReadFile(filepath.Join(dir, "signed.jsonl"))
+	bs = bytes.Replace(bs, []byte("app-2.log"), []byte("app-3.log"), 1)
+	_, err = sign.Verify(bytes.NewReader(bs), key, "")
+	assert.NotNil(t, err)
+}
+
+func TestUnknownHook(t *testing.T) {
+	c, err := common.NewConfigFrom(`{on_rotate: [{teleport: {}}]}`)
+	assert.Nil(t, err)
+	var cfg struct {
+		OnRotate []common.ConfigNamespace `logn-config:"on_rotate"`
+	}
+	assert.Nil(t, c.Unpack(&cfg))
+	_, err = New(cfg.OnRotate)
+	assert.NotNil(t, err)
+}
+
+func mustConfig(t *testing.T, s string) *common.Config {
+	c, err := common.NewConfigFrom(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
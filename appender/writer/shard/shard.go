@@ -0,0 +1,210 @@
+// Package shard buffers writes in shards, one per processor by default, so
+// that goroutines logging concurrently do not contend on a single queue. A
+// flusher goroutine takes the shards at once, merges their writes in the
+// order they were made, and writes them in a single write. It targets the
+// throughputs, of the order of a million entries per second, at which the
+// goroutine of an async appender is the bottleneck.
+//
+// The mode is experimental. Writes are ordered within each flush, but a
+// write may be flushed after a later one made at the time the shards are
+// taken.
+package shard
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/shanexu/logn/appender/writer"
+	"github.com/shanexu/logn/appender/writer/breaker"
+	"github.com/shanexu/logn/status"
+)
+
+// Defaults of Config.
+const (
+	DefaultSize          = 64 << 10
+	DefaultFlushInterval = 100 * time.Millisecond
+)
+
+// Config is the sharded section of an appender.
+type Config struct {
+	// Shards is the number of buffers, GOMAXPROCS by default.
+	Shards int `logn-config:"shards" logn-validate:"min=0"`
+	// Size is the number of bytes a shard holds before it is flushed.
+	Size int `logn-config:"size" logn-validate:"min=0"`
+	// FlushInterval is the longest time a write stays buffered.
+	FlushInterval string `logn-config:"flush_interval"`
+}
+
+// record locates a write in the buffer of its shard.
+type record struct {
+	// at is the time of the write, on the monotonic clock of the writer
+	at  time.Duration
+	end int
+}
+
+type shard struct {
+	mu      sync.Mutex
+	buf     []byte
+	records []record
+	// pad the shards apart, so that they do not share cache lines
+	_ [64]byte
+}
+
+type shardWriter struct {
+	out    writer.Writer
+	name   string
+	size   int
+	start  time.Time
+	shards []shard
+	// pool hands goroutines the shard last used on their processor
+	pool sync.Pool
+	next uint32
+	full chan struct{}
+	// done is closed by Close to stop the flushing goroutine, which closes
+	// stopped on its way out
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+
+	// flushMu serializes flushes, which own the spare buffers
+	flushMu      sync.Mutex
+	spareBufs    [][]byte
+	spareRecords [][]record
+	merged       []byte
+}
+
+// New wraps w, the writer of the appender called name, so that writes to it
+// are buffered in shards.
+func New(w writer.Writer, name string, config Config) (writer.Writer, error) {
+	interval := DefaultFlushInterval
+	if config.FlushInterval != "" {
+		var err error
+		if interval, err = time.ParseDuration(config.FlushInterval); err != nil {
+			return nil, err
+		}
+	}
+	sw := newShardWriter(w, name, config)
+	go func() {
+		defer close(sw.stopped)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+			case <-sw.full:
+			case <-sw.done:
+				return
+			}
+			sw.flush()
+		}
+	}()
+	return sw, nil
+}
+
+func newShardWriter(w writer.Writer, name string, config Config) *shardWriter {
+	n := config.Shards
+	if n == 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	sw := &shardWriter{
+		out:          w,
+		name:         name,
+		size:         config.Size,
+		start:        time.Now(),
+		shards:       make([]shard, n),
+		full:         make(chan struct{}, 1),
+		done:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+		spareBufs:    make([][]byte, n),
+		spareRecords: make([][]record, n),
+	}
+	if sw.size == 0 {
+		sw.size = DefaultSize
+	}
+	sw.pool.New = func() interface{} {
+		return &sw.shards[atomic.AddUint32(&sw.next, 1)%uint32(n)]
+	}
+	return sw
+}
+
+func (w *shardWriter) Write(p []byte) (int, error) {
+	s := w.pool.Get().(*shard)
+	s.mu.Lock()
+	s.buf = append(s.buf, p...)
+	s.records = append(s.records, record{at: time.Since(w.start), end: len(s.buf)})
+	full := len(s.buf) >= w.size
+	s.mu.Unlock()
+	w.pool.Put(s)
+	if full {
+		select {
+		case w.full <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// flush takes the writes of all shards and writes them in order.
+func (w *shardWriter) flush() {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+	bufs, records := w.spareBufs, w.spareRecords
+	for i := range w.shards {
+		s := &w.shards[i]
+		s.mu.Lock()
+		s.buf, bufs[i] = bufs[i][:0], s.buf
+		s.records, records[i] = records[i][:0], s.records
+		s.mu.Unlock()
+	}
+	w.merged = merge(w.merged[:0], bufs, records)
+	if len(w.merged) > 0 {
+		if _, err := w.out.Write(w.merged); err != nil && err != breaker.ErrOpen {
+			status.Warnf("sharded appender %q failed to write: %v", w.name, err)
+		}
+	}
+}
+
+// merge appends the writes held by bufs to dst, in the order of their
+// records, each shard being in order already.
+func merge(dst []byte, bufs [][]byte, records [][]record) []byte {
+	// next[i] is the index of the next record of shard i
+	next := make([]int, len(bufs))
+	for {
+		first := -1
+		for i, rs := range records {
+			if next[i] < len(rs) && (first < 0 || rs[next[i]].at < records[first][next[first]].at) {
+				first = i
+			}
+		}
+		if first < 0 {
+			return dst
+		}
+		begin := 0
+		if j := next[first]; j > 0 {
+			begin = records[first][j-1].end
+		}
+		dst = append(dst, bufs[first][begin:records[first][next[first]].end]...)
+		next[first]++
+	}
+}
+
+// Sync writes the buffered writes, then syncs the writer.
+func (w *shardWriter) Sync() error {
+	w.flush()
+	return w.out.Sync()
+}
+
+// Close stops the periodic flushes and writes the buffered writes, then
+// closes the writer.
+func (w *shardWriter) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.done)
+		<-w.stopped
+		w.flush()
+		w.closeErr = writer.Close(w.out)
+	})
+	return w.closeErr
+}
@@ -0,0 +1,69 @@
+package shard
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bufferWriter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *bufferWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *bufferWriter) Sync() error { return nil }
+
+func TestMerge(t *testing.T) {
+	bufs := [][]byte{[]byte("ad"), []byte("bce"), nil}
+	records := [][]record{
+		{{at: 1, end: 1}, {at: 4, end: 2}},
+		{{at: 2, end: 1}, {at: 3, end: 2}, {at: 5, end: 3}},
+		nil,
+	}
+	assert.Equal(t, "abcde", string(merge(nil, bufs, records)))
+}
+
+func TestWriter(t *testing.T) {
+	out := &bufferWriter{}
+	w := newShardWriter(out, "SHARDED", Config{Shards: 4})
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				fmt.Fprintf(w, "%d %d\n", g, i)
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.Nil(t, w.Sync())
+
+	// every write is there, those of a goroutine in order
+	lines := strings.Split(strings.TrimSpace(out.buf.String()), "\n")
+	assert.Len(t, lines, 800)
+	next := make([]int, 8)
+	for _, line := range lines {
+		var g, i int
+		fmt.Sscanf(line, "%d %d", &g, &i)
+		assert.Equal(t, next[g], i)
+		next[g]++
+	}
+
+	// flushes take the shards at once
+	w.Write([]byte("a"))
+	w.Write([]byte("b"))
+	assert.Nil(t, w.Sync())
+	assert.True(t, strings.HasSuffix(out.buf.String(), "ab"))
+}
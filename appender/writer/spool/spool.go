@@ -0,0 +1,294 @@
+// Package spool saves the writes failing while a remote sink is down to a file,
+// and replays them in order once it is back.
+//
+// The spool file is made of records, each holding a 2 byte magic number, the
+// big-endian uint32 length and CRC-32 of the data, and the data. Corrupted
+// records, e.g. the last one written before a crash, are skipped: replay
+// resumes at the next magic number.
+package spool
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/shanexu/logn/appender/bufpool"
+	"github.com/shanexu/logn/appender/writer"
+	"github.com/shanexu/logn/status"
+)
+
+// Defaults of Config.
+const (
+	DefaultMaxSize        = 100 << 20
+	DefaultReplayInterval = 5 * time.Second
+)
+
+const headerSize = 10
+
+var magic = []byte{0xf0, 0x9f}
+
+// Config is the spool section of an appender.
+type Config struct {
+	// File is the spool file.
+	File string `logn-config:"file" logn-validate:"required"`
+	// MaxSize is the size in bytes the spool file may grow to; writes failing
+	// while it is full are lost.
+	MaxSize int64 `logn-config:"max_size" logn-validate:"min=0"`
+	// ReplayInterval is the delay between attempts at replaying the spool.
+	ReplayInterval string `logn-config:"replay_interval"`
+}
+
+type spoolWriter struct {
+	writer.Writer
+	name    string
+	maxSize int64
+
+	mu         sync.Mutex
+	file       *os.File
+	checkpoint *os.File
+	// records are read back from off up to end
+	off, end int64
+	// lost counts the writes lost since spooling started
+	lost int
+
+	// done is closed by Close to stop the replaying goroutine, which closes
+	// stopped on its way out
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// New wraps w, the writer of the appender called name, so that the writes
+// failing are spooled to disk.
+func New(w writer.Writer, name string, config Config) (writer.Writer, error) {
+	interval := DefaultReplayInterval
+	if config.ReplayInterval != "" {
+		var err error
+		if interval, err = time.ParseDuration(config.ReplayInterval); err != nil {
+			return nil, err
+		}
+	}
+	sw, err := newSpoolWriter(w, name, config)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		defer close(sw.stopped)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				sw.replay()
+			case <-sw.done:
+				return
+			}
+		}
+	}()
+	return sw, nil
+}
+
+func newSpoolWriter(w writer.Writer, name string, config Config) (*spoolWriter, error) {
+	f, err := os.OpenFile(config.File, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	cp, err := os.OpenFile(config.File+".checkpoint", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	sw := &spoolWriter{
+		Writer:     w,
+		name:       name,
+		maxSize:    config.MaxSize,
+		file:       f,
+		checkpoint: cp,
+		// records spooled by a previous process are replayed first, from
+		// where it left
+		end:     fi.Size(),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	var b [8]byte
+	if _, err := cp.ReadAt(b[:], 0); err == nil {
+		if off := int64(binary.BigEndian.Uint64(b[:])); off <= sw.end {
+			sw.off = off
+		}
+	}
+	if sw.maxSize == 0 {
+		sw.maxSize = DefaultMaxSize
+	}
+	return sw, nil
+}
+
+func (w *spoolWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.end > w.off {
+		// keep spooled records ahead of the new ones
+		return w.spool(p)
+	}
+	n, err := w.Writer.Write(p)
+	if err == nil {
+		return n, nil
+	}
+	status.Warnf("appender %q failed, spooling to %s: %v", w.name, w.file.Name(), err)
+	return w.spool(p)
+}
+
+// spool appends p to the spool file. It is called with w.mu held.
+func (w *spoolWriter) spool(p []byte) (int, error) {
+	if w.end+headerSize+int64(len(p)) > w.maxSize {
+		if w.lost == 0 {
+			status.Errorf("spool of appender %q is full, losing entries", w.name)
+		}
+		w.lost++
+		return len(p), nil
+	}
+	rec := bufpool.Get(headerSize + len(p))
+	defer bufpool.Put(rec)
+	*rec = (*rec)[:headerSize]
+	copy(*rec, magic)
+	binary.BigEndian.PutUint32((*rec)[2:], uint32(len(p)))
+	binary.BigEndian.PutUint32((*rec)[6:], crc32.ChecksumIEEE(p))
+	*rec = append(*rec, p...)
+	if _, err := w.file.Write(*rec); err != nil {
+		return 0, err
+	}
+	w.end += int64(len(*rec))
+	return len(p), nil
+}
+
+// replay writes the spooled records in order, until one fails.
+func (w *spoolWriter) replay() {
+	replayed := 0
+	for {
+		w.mu.Lock()
+		if w.off >= w.end {
+			w.mu.Unlock()
+			break
+		}
+		p, next := w.read(w.off)
+		w.mu.Unlock()
+
+		if p != nil {
+			if _, err := w.Writer.Write(p); err != nil {
+				break
+			}
+			replayed++
+		}
+
+		w.mu.Lock()
+		w.off = next
+		if w.off < w.end {
+			w.storeCheckpoint()
+		} else {
+			if replayed > 0 || w.lost > 0 {
+				status.Infof("appender %q recovered, replayed %d spooled entries, lost %d",
+					w.name, replayed, w.lost)
+			}
+			// a crash in between replays the spool again rather than
+			// resuming in the middle of the next records
+			w.off, w.end, w.lost = 0, 0, 0
+			w.storeCheckpoint()
+			if err := w.file.Truncate(0); err != nil {
+				status.Errorf("failed to truncate spool of appender %q: %v", w.name, err)
+			}
+		}
+		w.mu.Unlock()
+	}
+}
+
+// storeCheckpoint stores w.off to the checkpoint file. It is called with
+// w.mu held.
+func (w *spoolWriter) storeCheckpoint() {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(w.off))
+	if _, err := w.checkpoint.WriteAt(b[:], 0); err != nil {
+		status.Errorf("failed to store checkpoint of appender %q: %v", w.name, err)
+	}
+}
+
+// read returns the data of the record at off and the offset of the next
+// one. The data is nil if the record is corrupted, in which case the next
+// offset is that of the next magic number. It is called with w.mu held.
+func (w *spoolWriter) read(off int64) ([]byte, int64) {
+	var h [headerSize]byte
+	if _, err := w.file.ReadAt(h[:], off); err == nil && bytes.Equal(h[:2], magic) {
+		n := int64(binary.BigEndian.Uint32(h[2:]))
+		if off+headerSize+n <= w.end {
+			p := make([]byte, n)
+			if _, err := w.file.ReadAt(p, off+headerSize); err == nil &&
+				crc32.ChecksumIEEE(p) == binary.BigEndian.Uint32(h[6:]) {
+				return p, off + headerSize + n
+			}
+		}
+	}
+	next := w.resync(off + 1)
+	status.Warnf("skipped %d corrupted bytes in spool of appender %q", next-off, w.name)
+	return nil, next
+}
+
+// resync returns the offset of the first magic number from off on, or the
+// end of the spool.
+func (w *spoolWriter) resync(off int64) int64 {
+	buf := make([]byte, 64<<10)
+	for off < w.end {
+		n, err := w.file.ReadAt(buf, off)
+		if i := bytes.Index(buf[:n], magic); i >= 0 {
+			return off + int64(i)
+		}
+		if err != nil && err != io.EOF || n < len(magic) {
+			break
+		}
+		// a magic number may straddle two reads
+		off += int64(n - len(magic) + 1)
+	}
+	return w.end
+}
+
+func (w *spoolWriter) Sync() error {
+	w.mu.Lock()
+	err := w.file.Sync()
+	if cerr := w.checkpoint.Sync(); err == nil {
+		err = cerr
+	}
+	w.mu.Unlock()
+	if serr := w.Writer.Sync(); err == nil {
+		err = serr
+	}
+	return err
+}
+
+// Close stops replaying the spool, which the next writer spooling to the same
+// file resumes, and closes the spool files, then the writer.
+func (w *spoolWriter) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.done)
+		<-w.stopped
+		w.mu.Lock()
+		err := w.file.Sync()
+		if cerr := w.checkpoint.Sync(); err == nil {
+			err = cerr
+		}
+		w.file.Close()
+		w.checkpoint.Close()
+		w.mu.Unlock()
+		if cerr := writer.Close(w.Writer); err == nil {
+			err = cerr
+		}
+		w.closeErr = err
+	})
+	return w.closeErr
+}
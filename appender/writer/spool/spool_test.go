@@ -0,0 +1,134 @@
+package spool
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type switchWriter struct {
+	err error
+	buf bytes.Buffer
+}
+
+func (w *switchWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	return w.buf.Write(p)
+}
+
+func (w *switchWriter) Sync() error { return nil }
+
+func TestSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "remote.spool")
+
+	out := &switchWriter{err: errors.New("connection refused")}
+	w, err := newSpoolWriter(out, "REMOTE", Config{File: file, MaxSize: 3 * (headerSize + 2)})
+	assert.Nil(t, err)
+	for _, s := range []string{"a\n", "b\n", "c\n", "d\n"} {
+		n, err := w.Write([]byte(s))
+		assert.Nil(t, err)
+		assert.Equal(t, 2, n)
+	}
+
+	// the sink is still down
+	w.replay()
+	assert.Empty(t, out.buf.String())
+
+	// the sink is back, d was lost as the spool was full
+	out.err = nil
+	w.replay()
+	w.Write([]byte("e\n"))
+	assert.Equal(t, "a\nb\nc\ne\n", out.buf.String())
+	fi, _ := os.Stat(file)
+	assert.Zero(t, fi.Size())
+}
+
+func TestCorruption(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "remote.spool")
+
+	out := &switchWriter{err: errors.New("connection refused")}
+	w, err := newSpoolWriter(out, "REMOTE", Config{File: file})
+	assert.Nil(t, err)
+	for _, s := range []string{"first\n", "second\n", "third\n", "last\n"} {
+		w.Write([]byte(s))
+	}
+
+	// flip a byte of the second record and cut the last one short, as
+	// after a crash
+	bs, _ := ioutil.ReadFile(file)
+	bs[headerSize+6+headerSize] ^= 0xff
+	bs = bs[:len(bs)-2]
+	assert.Nil(t, ioutil.WriteFile(file, bs, 0600))
+
+	out.err = nil
+	w, err = newSpoolWriter(out, "REMOTE", Config{File: file})
+	assert.Nil(t, err)
+	w.replay()
+	assert.Equal(t, "first\nthird\n", out.buf.String())
+}
+
+// flakyWriter accepts n writes, then fails.
+type flakyWriter struct {
+	n   int
+	buf bytes.Buffer
+}
+
+func (w *flakyWriter) Write(p []byte) (int, error) {
+	if w.n == 0 {
+		return 0, errors.New("connection reset")
+	}
+	w.n--
+	return w.buf.Write(p)
+}
+
+func (w *flakyWriter) Sync() error { return nil }
+
+func TestCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "remote.spool")
+
+	out := &flakyWriter{}
+	w, err := newSpoolWriter(out, "REMOTE", Config{File: file})
+	assert.Nil(t, err)
+	for _, s := range []string{"a\n", "b\n", "c\n", "d\n"} {
+		w.Write([]byte(s))
+	}
+	out.n = 2
+	w.replay()
+	assert.Equal(t, "a\nb\n", out.buf.String())
+
+	// a restarted process resumes the replay at c
+	out = &flakyWriter{n: 10}
+	w, err = newSpoolWriter(out, "REMOTE", Config{File: file})
+	assert.Nil(t, err)
+	w.replay()
+	assert.Equal(t, "c\nd\n", out.buf.String())
+
+	// and the next one has nothing to replay
+	out = &flakyWriter{n: 10}
+	w, err = newSpoolWriter(out, "REMOTE", Config{File: file})
+	assert.Nil(t, err)
+	w.Write([]byte("e\n"))
+	assert.Equal(t, "e\n", out.buf.String())
+}
@@ -1,7 +1,51 @@
 package writer
 
-import "go.uber.org/zap/zapcore"
+import (
+	"errors"
+	"os"
+
+	"go.uber.org/zap/zapcore"
+)
 
 type Writer interface {
 	zapcore.WriteSyncer
-}
\ No newline at end of file
+}
+
+// Closer is implemented by the writers holding resources, such as files,
+// connections or goroutines, which Close releases once what was written to
+// them is. Decorators close the writer they wrap.
+type Closer interface {
+	Close() error
+}
+
+// ErrClosed is returned by the writes to closed writers.
+var ErrClosed = errors.New("writer closed")
+
+// Close closes w if it is a Closer, and syncs it otherwise.
+func Close(w Writer) error {
+	if c, ok := w.(Closer); ok {
+		return c.Close()
+	}
+	return w.Sync()
+}
+
+// Reopener is implemented by the writers of files, which Reopen closes and
+// opens again by name, e.g. once logrotate moved them away.
+type Reopener interface {
+	Reopen() error
+}
+
+// FileWriter is implemented by the writers appending to a file as it is,
+// OSFile returning the file currently written to.
+type FileWriter interface {
+	OSFile() *os.File
+}
+
+// EntryWriter is implemented by the writers taking entries rather than their
+// encoding, such as bridges to other logging systems. Their appenders need
+// no encoder, and take no decorators, such as async or retry.
+type EntryWriter interface {
+	Writer
+	// WriteEntry writes ent with fields, those given to With first.
+	WriteEntry(ent zapcore.Entry, fields []zapcore.Field) error
+}
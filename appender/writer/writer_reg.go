@@ -18,6 +18,11 @@ func RegisterType(name string, f Factory) {
 	writers[name] = f
 }
 
+// Registered tells whether a writer type called name is registered.
+func Registered(name string) bool {
+	return writers[name] != nil
+}
+
 func NewWriter(name string, config *common.Config) (Writer, error) {
 	factory := writers[name]
 	if factory == nil {
@@ -0,0 +1,155 @@
+package benchmarks
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/shanexu/logn"
+	"github.com/shanexu/logn/common"
+	"github.com/shanexu/logn/core"
+	"github.com/shanexu/logn/core/zap"
+	_ "github.com/shanexu/logn/includes"
+)
+
+// newCore returns a Core whose root logger writes at info level to the
+// appenders NULL0 to NULL<n-1>, configured with the given encoder and extra
+// settings.
+func newCore(b *testing.B, n int, encoder, extra string) core.Core {
+	var sb strings.Builder
+	sb.WriteString("appenders:\n  file:\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "    - name: NULL%d\n      file_name: %s\n", i, os.DevNull)
+		if extra != "" {
+			fmt.Fprintf(&sb, "      %s\n", strings.ReplaceAll(strings.TrimSpace(extra), "\n", "\n      "))
+		}
+		fmt.Fprintf(&sb, "      encoder:\n        %s:\n", encoder)
+	}
+	sb.WriteString("loggers:\n  root:\n    level: info\n    appender_refs:\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "      - NULL%d\n", i)
+	}
+	rawConfig, err := common.NewConfigFrom(sb.String())
+	if err != nil {
+		b.Fatal(err)
+	}
+	c, err := zap.New(rawConfig)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { c.Sync() })
+	return c
+}
+
+func BenchmarkEncoders(b *testing.B) {
+	for _, encoder := range []string{"json", "console", "gelf"} {
+		b.Run(encoder, func(b *testing.B) {
+			l := newCore(b, 1, encoder, "").GetFieldLogger("bench")
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				l.Info("request served", logn.String("path", "/"), logn.Int("status", 200))
+			}
+		})
+	}
+}
+
+func BenchmarkAppenders(b *testing.B) {
+	for _, n := range []int{1, 3} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			l := newCore(b, n, "json", "").GetFieldLogger("bench")
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				l.Info("request served", logn.String("path", "/"), logn.Int("status", 200))
+			}
+		})
+	}
+}
+
+func BenchmarkSurfaces(b *testing.B) {
+	c := newCore(b, 1, "json", "")
+	sugared := c.GetLogger("bench")
+	typed := c.GetFieldLogger("bench")
+
+	b.Run("sugared/Info", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sugared.Info("request served")
+		}
+	})
+	b.Run("sugared/Infof", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sugared.Infof("request %s served", "/")
+		}
+	})
+	b.Run("sugared/Infow", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sugared.Infow("request served", "path", "/", "status", 200)
+		}
+	})
+	b.Run("typed/Info", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			typed.Info("request served", logn.String("path", "/"), logn.Int("status", 200))
+		}
+	})
+	b.Run("disabled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			typed.Debug("request served", logn.String("path", "/"), logn.Int("status", 200))
+		}
+	})
+}
+
+func BenchmarkAsync(b *testing.B) {
+	for _, bc := range []struct {
+		name  string
+		extra string
+	}{
+		{"sync", ""},
+		{"async", "async:\n  queue_size: 8192"},
+		{"async/batched", "async:\n  queue_size: 8192\n  batch_bytes: 65536"},
+		{"buffered", "buffer:\n  size: 65536"},
+		{"sharded", "sharded:\n  size: 65536"},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			l := newCore(b, 1, "json", bc.extra).GetFieldLogger("bench")
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					l.Info("request served", logn.String("path", "/"), logn.Int("status", 200))
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkGetLogger(b *testing.B) {
+	c := newCore(b, 1, "json", "")
+	names := []string{"app", "app.db", "app.cache", "app.http"}
+	for _, name := range names {
+		c.GetLogger(name)
+	}
+
+	b.Run("hot", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				c.GetLogger(names[i%len(names)])
+			}
+		})
+	})
+	b.Run("unnormalized", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				c.GetLogger(" app.db.")
+			}
+		})
+	})
+}
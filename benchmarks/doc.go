@@ -0,0 +1,10 @@
+// Package benchmarks holds the benchmarks comparing logn configurations:
+// encoders, number of appenders, sugared and typed loggers, synchronous and
+// asynchronous appenders. Appenders write to the null device, so that the
+// benchmarks measure logn rather than disks. Run them with allocation
+// reports:
+//
+//	go test -run '^$' -bench . -benchmem ./benchmarks
+//
+// and compare runs with benchstat to catch regressions.
+package benchmarks
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+var configCommand = &command{
+	name:  "config",
+	short: "convert and create configuration files",
+	run:   runConfig,
+}
+
+var configCommands = []*command{
+	convertCommand,
+	initCommand,
+}
+
+func runConfig(args []string, stdout io.Writer) error {
+	if len(args) > 0 {
+		for _, c := range configCommands {
+			if c.name == args[0] {
+				return c.run(args[1:], stdout)
+			}
+		}
+	}
+	w := flag.CommandLine.Output()
+	fmt.Fprintf(w, "usage: logn config <command> [flags] [args]\n\ncommands:\n")
+	for _, c := range configCommands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.short)
+	}
+	return flag.ErrHelp
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+var initCommand = &command{
+	name:  "init",
+	short: "write a starter configuration",
+	run:   runInit,
+}
+
+// starter is a template of configuration for a common setup.
+type starter struct {
+	short string
+	level string
+	text  string
+}
+
+// starterParams are the values starter templates are executed with.
+type starterParams struct {
+	App   string
+	Env   string
+	Level string
+	File  string
+	Host  string
+	Port  int
+}
+
+const fileAppender = `  rolling_file:
+    - name: FILE
+      file_name: {{.File}}
+      max_size: 100
+      max_backups: 10
+      compress: true
+      async:
+        queue_size: 4096
+        on_full: drop_oldest
+      encoder:
+        json:
+          time_encoder: ISO8601
+`
+
+const prodLoggers = `fields:
+  app: {{.App}}
+  env: {{.Env}}
+loggers:
+  root:
+    level: {{.Level}}
+    appender_refs:
+      - FILE
+`
+
+var starters = map[string]starter{
+	"console-dev": {
+		short: "console output for development",
+		level: "debug",
+		text: `appenders:
+  console:
+    - name: CONSOLE
+      target: stdout
+      encoder:
+        console:
+          time_encoder: ISO8601
+loggers:
+  root:
+    level: {{.Level}}
+    appender_refs:
+      - CONSOLE
+`,
+	},
+	"json-file-prod": {
+		short: "JSON to a rotated file, written asynchronously",
+		level: "info",
+		text:  "appenders:\n" + fileAppender + prodLoggers,
+	},
+	"file-graylog": {
+		short: "JSON to a rotated file, and GELF to Graylog",
+		level: "info",
+		text: `appenders:
+` + fileAppender + `  gelf_udp:
+    - name: GRAYLOG
+      host: {{.Host}}
+      port: {{.Port}}
+      encoder:
+        gelf:
+          key_value_pairs:
+            - key: app
+              value: {{.App}}
+            - key: env
+              value: {{.Env}}
+` + strings.Replace(prodLoggers, "      - FILE\n", "      - FILE\n      - GRAYLOG\n", 1),
+	},
+	"file-loki": {
+		short: "JSON to a rotated file, for Promtail to ship to Loki",
+		level: "info",
+		text: `# logn has no Loki appender: have Promtail or Grafana Alloy tail
+# {{.File}} and push it to Loki, with app and env as labels.
+appenders:
+` + fileAppender + prodLoggers,
+	},
+	"file-kafka": {
+		short: "JSON to a rotated file, for an agent to ship to Kafka",
+		level: "info",
+		text: `# logn has no Kafka appender: have Vector or Filebeat tail
+# {{.File}} and produce its lines to Kafka.
+appenders:
+` + fileAppender + prodLoggers,
+	},
+}
+
+func runInit(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("config init", flag.ContinueOnError)
+	params := starterParams{}
+	fs.StringVar(&params.App, "app", "app", "name of the application")
+	fs.StringVar(&params.Env, "env", "dev", "environment the application runs in")
+	fs.StringVar(&params.Level, "level", "", "level of the root logger, the template's by default")
+	fs.StringVar(&params.File, "file", "", "file to write to, /var/log/<app>/<app>.log by default")
+	fs.StringVar(&params.Host, "host", "127.0.0.1", "host of the log server")
+	fs.IntVar(&params.Port, "port", 12201, "port of the log server")
+	out := fs.String("o", "", "file to write the configuration to, instead of stdout")
+	fs.Usage = func() {
+		w := fs.Output()
+		fmt.Fprintf(w, "usage: logn config init [flags] template\n\ntemplates:\n")
+		names := make([]string, 0, len(starters))
+		for name := range starters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "  %-15s %s\n", name, starters[name].short)
+		}
+		fmt.Fprintf(w, "\nflags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	s, ok := starters[fs.Arg(0)]
+	if !ok {
+		return fmt.Errorf("unknown template %q", fs.Arg(0))
+	}
+	if params.Level == "" {
+		params.Level = s.level
+	}
+	if params.File == "" {
+		params.File = fmt.Sprintf("/var/log/%s/%s.log", params.App, params.App)
+	}
+	var buf bytes.Buffer
+	if err := template.Must(template.New(fs.Arg(0)).Parse(s.text)).Execute(&buf, params); err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err := stdout.Write(buf.Bytes())
+		return err
+	}
+	if _, err := os.Stat(*out); err == nil {
+		return fmt.Errorf("%s exists already", *out)
+	}
+	return ioutil.WriteFile(*out, buf.Bytes(), 0644)
+}
@@ -0,0 +1,472 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+var convertCommand = &command{
+	name:  "convert",
+	short: "translate configurations between formats",
+	run:   runConvert,
+}
+
+func runConvert(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("config convert", flag.ContinueOnError)
+	from := fs.String("from", "", "format of the input: yaml, json, toml, logback or log4j2, guessed from the file by default")
+	to := fs.String("to", "yaml", "format of the output: yaml, json or toml")
+	fs.Usage = func() {
+		fs.Output().Write([]byte("usage: logn config convert [-from format] [-to format] [file]\n"))
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	var in []byte
+	var err error
+	if fs.NArg() == 0 {
+		in, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		in, err = ioutil.ReadFile(fs.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+	format := *from
+	if format == "" {
+		format = guessFormat(fs.Arg(0), in)
+	}
+	doc, err := decodeConfig(format, in)
+	if err != nil {
+		return err
+	}
+	out, err := encodeConfig(*to, doc)
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(out)
+	return err
+}
+
+// guessFormat returns the format of the configuration in the file called
+// name.
+func guessFormat(name string, in []byte) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return "json"
+	case ".toml":
+		return "toml"
+	case ".xml":
+		// logback's root element is configuration, log4j2's Configuration
+		if bytes.Contains(in, []byte("<Configuration")) {
+			return "log4j2"
+		}
+		return "logback"
+	}
+	return "yaml"
+}
+
+// decodeConfig parses in, a configuration in format, into a YAML document,
+// which holds its comments if the format has any.
+func decodeConfig(format string, in []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	switch format {
+	case "yaml", "json":
+		// JSON is YAML
+		if err := yaml.Unmarshal(in, &doc); err != nil {
+			return nil, err
+		}
+		if doc.Kind == 0 {
+			return document(mapping()), nil
+		}
+		if format == "json" {
+			block(&doc)
+		}
+		return &doc, nil
+	case "toml":
+		var v map[string]interface{}
+		md, err := toml.Decode(string(in), &v)
+		if err != nil {
+			return nil, err
+		}
+		// keys are sorted in the order of the file, tables at their first key
+		order := map[string]int{}
+		for i, k := range md.Keys() {
+			for j := 1; j <= len(k); j++ {
+				if _, ok := order[k[:j].String()]; !ok {
+					order[k[:j].String()] = i
+				}
+			}
+		}
+		return document(tomlNode(v, "", order)), nil
+	case "logback", "log4j2":
+		var root xmlElement
+		if err := xml.Unmarshal(in, &root); err != nil {
+			return nil, err
+		}
+		if format == "logback" {
+			return document(fromLogback(&root)), nil
+		}
+		return document(fromLog4j2(&root)), nil
+	}
+	return nil, fmt.Errorf("unknown input format %q", format)
+}
+
+func encodeConfig(format string, doc *yaml.Node) ([]byte, error) {
+	root := doc
+	if root.Kind == yaml.DocumentNode {
+		root = root.Content[0]
+	}
+	switch format {
+	case "yaml":
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), enc.Close()
+	case "json":
+		var buf bytes.Buffer
+		if err := writeJSON(&buf, root); err != nil {
+			return nil, err
+		}
+		var out bytes.Buffer
+		if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+			return nil, err
+		}
+		out.WriteByte('\n')
+		return out.Bytes(), nil
+	case "toml":
+		if root.Kind != yaml.MappingNode {
+			return nil, errors.New("a TOML document must be a table")
+		}
+		var buf bytes.Buffer
+		writeTOMLTable(&buf, nil, root, false)
+		return bytes.TrimLeft(buf.Bytes(), "\n"), nil
+	}
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
+
+// block drops the styles of n, parsed from JSON, so that it is written as
+// YAML is usually written.
+func block(n *yaml.Node) {
+	n.Style = 0
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!null" {
+		n.Value = ""
+	}
+	for _, c := range n.Content {
+		block(c)
+	}
+}
+
+func document(n *yaml.Node) *yaml.Node {
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{n}}
+}
+
+func mapping() *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+}
+
+func sequence() *yaml.Node {
+	return &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+}
+
+// null returns an empty value, as in the sections set without settings, like
+// "json:".
+func null() *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+}
+
+func scalar(v interface{}) *yaml.Node {
+	n := &yaml.Node{}
+	if err := n.Encode(v); err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// set sets key to value in the mapping m.
+func set(m *yaml.Node, key string, value *yaml.Node) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return value
+		}
+	}
+	m.Content = append(m.Content, scalar(key), value)
+	return value
+}
+
+// get returns the value of key in the mapping m, if set.
+func get(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// tomlNode returns v, decoded from TOML at path, as a YAML node with the keys
+// of tables in order.
+func tomlNode(v interface{}, path string, order map[string]int) *yaml.Node {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			// written for null, which TOML lacks
+			return null()
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		child := func(k string) string {
+			if path == "" {
+				return k
+			}
+			return path + "." + k
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return order[child(keys[i])] < order[child(keys[j])]
+		})
+		m := mapping()
+		for _, k := range keys {
+			set(m, k, tomlNode(v[k], child(k), order))
+		}
+		return m
+	case []map[string]interface{}:
+		s := sequence()
+		for _, e := range v {
+			s.Content = append(s.Content, tomlNode(e, path, order))
+		}
+		return s
+	case []interface{}:
+		s := sequence()
+		for _, e := range v {
+			s.Content = append(s.Content, tomlNode(e, path, order))
+		}
+		return s
+	}
+	return scalar(v)
+}
+
+// value returns the scalar n as a Go value.
+func value(n *yaml.Node) interface{} {
+	var v interface{}
+	if err := n.Decode(&v); err != nil {
+		return n.Value
+	}
+	return v
+}
+
+// writeJSON writes n as compact JSON, the keys of objects in order.
+func writeJSON(buf *bytes.Buffer, n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.AliasNode:
+		return writeJSON(buf, n.Alias)
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(n.Content[i].Value)
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeJSON(buf, n.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, e := range n.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, e); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		b, err := json.Marshal(value(n))
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+	}
+	return nil
+}
+
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func tomlKey(k string) string {
+	if bareKey.MatchString(k) {
+		return k
+	}
+	return tomlString(k)
+}
+
+func tomlString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// writeComment writes the comment c, made of lines starting with #.
+func writeComment(buf *bytes.Buffer, c string) {
+	if c != "" {
+		buf.WriteString(c)
+		buf.WriteByte('\n')
+	}
+}
+
+// isTable tells whether n is written as a table, rather than inline.
+func isTable(n *yaml.Node) bool {
+	return n.Kind == yaml.MappingNode && len(n.Content) > 0
+}
+
+// isTableArray tells whether n is written as an array of tables.
+func isTableArray(n *yaml.Node) bool {
+	if n.Kind != yaml.SequenceNode || len(n.Content) == 0 {
+		return false
+	}
+	for _, e := range n.Content {
+		if e.Kind != yaml.MappingNode {
+			return false
+		}
+	}
+	return true
+}
+
+// writeTOMLTable writes the mapping n as the table at path: its values first,
+// then its tables. The header of the table is written if it has values, or if
+// element is set, the table being an element of an array of tables.
+func writeTOMLTable(buf *bytes.Buffer, path []string, n *yaml.Node, element bool) {
+	keys := make([]string, len(path))
+	for i, k := range path {
+		keys[i] = tomlKey(k)
+	}
+	header := false
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if !isTable(n.Content[i+1]) && !isTableArray(n.Content[i+1]) {
+			header = true
+		}
+	}
+	switch {
+	case element:
+		buf.WriteString("\n[[" + strings.Join(keys, ".") + "]]\n")
+	case header && len(path) > 0:
+		buf.WriteString("\n[" + strings.Join(keys, ".") + "]\n")
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if isTable(v) || isTableArray(v) {
+			continue
+		}
+		writeComment(buf, k.HeadComment)
+		buf.WriteString(tomlKey(k.Value) + " = ")
+		writeTOMLValue(buf, v)
+		if c := v.LineComment; c != "" {
+			buf.WriteString(" " + c)
+		}
+		buf.WriteByte('\n')
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		child := append(path[:len(path):len(path)], k.Value)
+		switch {
+		case isTable(v):
+			if k.HeadComment != "" {
+				buf.WriteByte('\n')
+				writeComment(buf, k.HeadComment)
+			}
+			writeTOMLTable(buf, child, v, false)
+		case isTableArray(v):
+			if k.HeadComment != "" {
+				buf.WriteByte('\n')
+				writeComment(buf, k.HeadComment)
+			}
+			for _, e := range v.Content {
+				writeTOMLTable(buf, child, e, true)
+			}
+		}
+	}
+}
+
+// writeTOMLValue writes n inline. TOML has no null, which is written as an
+// empty table, as in the sections set without settings, like "json:".
+func writeTOMLValue(buf *bytes.Buffer, n *yaml.Node) {
+	switch n.Kind {
+	case yaml.AliasNode:
+		writeTOMLValue(buf, n.Alias)
+	case yaml.MappingNode:
+		buf.WriteString("{")
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			buf.WriteString(" " + tomlKey(n.Content[i].Value) + " = ")
+			writeTOMLValue(buf, n.Content[i+1])
+		}
+		if len(n.Content) > 0 {
+			buf.WriteString(" ")
+		}
+		buf.WriteString("}")
+	case yaml.SequenceNode:
+		buf.WriteString("[")
+		for i, e := range n.Content {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			writeTOMLValue(buf, e)
+		}
+		buf.WriteString("]")
+	default:
+		switch v := value(n).(type) {
+		case nil:
+			buf.WriteString("{}")
+		case string:
+			buf.WriteString(tomlString(v))
+		case float64:
+			f := strconv.FormatFloat(v, 'g', -1, 64)
+			switch {
+			case math.IsInf(v, 1):
+				f = "inf"
+			case math.IsInf(v, -1):
+				f = "-inf"
+			case math.IsNaN(v):
+				f = "nan"
+			case !strings.ContainsAny(f, ".e"):
+				f += ".0"
+			}
+			buf.WriteString(f)
+		default:
+			fmt.Fprint(buf, v)
+		}
+	}
+}
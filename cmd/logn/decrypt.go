@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+
+	"github.com/shanexu/logn/appender/writer/encrypt"
+)
+
+var decryptCommand = &command{
+	name:  "decrypt",
+	short: "decrypt the output of encrypted appenders",
+	run:   runDecrypt,
+}
+
+func runDecrypt(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("decrypt", flag.ContinueOnError)
+	keyRef := fs.String("key", "env:LOGN_ENCRYPTION_KEY", "key reference, env:NAME or file:PATH")
+	fs.Usage = func() {
+		fs.Output().Write([]byte("usage: logn decrypt [-key ref] [file ...]\n"))
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	key, err := encrypt.ResolveKey(*keyRef)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return decrypt(stdout, os.Stdin, key)
+	}
+	for _, name := range fs.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = decrypt(stdout, f, key)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decrypt(w io.Writer, r io.Reader, key []byte) error {
+	dr, err := encrypt.NewReader(r, key)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, dr)
+	return err
+}
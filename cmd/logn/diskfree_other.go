@@ -0,0 +1,8 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+func diskFree(dir string) (uint64, error) {
+	return 0, errDiskFreeUnsupported
+}
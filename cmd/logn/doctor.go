@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/shanexu/logn/common"
+)
+
+var doctorCommand = &command{
+	name:  "doctor",
+	short: "check the environment configurations are deployed to",
+	run:   runDoctor,
+}
+
+// errDiskFreeUnsupported is returned by diskFree where free space cannot be
+// told.
+var errDiskFreeUnsupported = errors.New("free space cannot be told on this system")
+
+// finding is something a doctor check found about an appender.
+type finding struct {
+	err      bool
+	appender string
+	msg      string
+	// fix tells what to do about it
+	fix string
+}
+
+// doctor checks the environment of the appenders of a configuration.
+type doctor struct {
+	minFree  uint64
+	timeout  time.Duration
+	findings []finding
+}
+
+func runDoctor(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	minFree := fs.Uint64("min-free", 1<<30, "least free bytes on the filesystems of files")
+	timeout := fs.Duration("timeout", 5*time.Second, "timeout of network checks")
+	fs.Usage = func() {
+		fs.Output().Write([]byte("usage: logn doctor [-min-free bytes] [-timeout duration] file ...\n"))
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	errs, warnings := 0, 0
+	for _, name := range fs.Args() {
+		raw, _, err := common.LoadFile(name)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		d := &doctor{minFree: *minFree, timeout: *timeout}
+		if err := d.check(raw); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		for _, f := range d.findings {
+			severity := "warning"
+			if f.err {
+				severity = "error"
+				errs++
+			} else {
+				warnings++
+			}
+			fmt.Fprintf(stdout, "%s: %s: appender %s: %s\n", name, severity, f.appender, f.msg)
+			if f.fix != "" {
+				fmt.Fprintf(stdout, "\t%s\n", f.fix)
+			}
+		}
+		if len(d.findings) == 0 {
+			fmt.Fprintf(stdout, "%s: ok\n", name)
+		}
+	}
+	if errs > 0 {
+		return fmt.Errorf("%d errors, %d warnings", errs, warnings)
+	}
+	return nil
+}
+
+func (d *doctor) errorf(appender, fix, format string, args ...interface{}) {
+	d.findings = append(d.findings, finding{err: true, appender: appender, msg: fmt.Sprintf(format, args...), fix: fix})
+}
+
+func (d *doctor) warnf(appender, fix, format string, args ...interface{}) {
+	d.findings = append(d.findings, finding{appender: appender, msg: fmt.Sprintf(format, args...), fix: fix})
+}
+
+// check checks the appenders of raw: the files they write to, the servers
+// they send to and the TLS certificates of these.
+func (d *doctor) check(raw *common.Config) error {
+	var section struct {
+		Appenders map[string][]*common.Config `logn-config:"appenders"`
+	}
+	if err := raw.Unpack(&section); err != nil {
+		return err
+	}
+	types := make([]string, 0, len(section.Appenders))
+	for t := range section.Appenders {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		for i, c := range section.Appenders[t] {
+			name := c.MustName(fmt.Sprintf("%s.%d", t, i))
+			createDirs, _ := c.Bool("create_dirs", -1)
+			for _, key := range pathKeys {
+				if file, err := c.String(key, -1); err == nil && file != "" {
+					d.checkFile(name, file, createsDirs(t, key, file, createDirs))
+				}
+			}
+			if t == "rolling_file" {
+				d.checkRotation(name, c)
+			}
+			if t == "gelf_udp" {
+				host, err := c.String("host", -1)
+				if err != nil {
+					host = "127.0.0.1"
+				}
+				port, err := c.Int("port", -1)
+				if err != nil {
+					port = 12201
+				}
+				d.checkUDP(name, host, int(port))
+			}
+			var settings map[string]interface{}
+			if err := c.Unpack(&settings); err == nil {
+				d.checkURLs(name, settings)
+			}
+		}
+	}
+	return nil
+}
+
+// checkFile checks that file can be written and that its filesystem has room.
+func (d *doctor) checkFile(appender, file string, createDirs bool) {
+	if err := checkWritable(file, createDirs); err != nil {
+		dir := filepath.Dir(file)
+		fix := fmt.Sprintf("give the user running the service write access to %s", dir)
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			fix = fmt.Sprintf("create %s, writable by the user running the service", dir)
+		}
+		d.errorf(appender, fix, "%v", err)
+	}
+	free, err := diskFree(existingDir(file))
+	switch {
+	case err == errDiskFreeUnsupported:
+	case err != nil:
+		d.warnf(appender, "", "cannot tell the free space for %s: %v", file, err)
+	case free < d.minFree:
+		d.warnf(appender, "free up space, or write to a larger filesystem",
+			"only %s free for %s", bytesString(free), file)
+	}
+}
+
+// checkRotation checks that the files a rolling file appender keeps fit on
+// their filesystem.
+func (d *doctor) checkRotation(appender string, c *common.Config) {
+	file, err := c.String("file_name", -1)
+	if err != nil {
+		return
+	}
+	maxSize, err := c.Int("max_size", -1)
+	if err != nil {
+		// the default of rolling file appenders
+		maxSize = 500
+	}
+	backups, err := c.Int("max_backups", -1)
+	if err != nil || backups <= 0 {
+		return
+	}
+	need := uint64(maxSize) << 20 * uint64(backups+1)
+	if free, err := diskFree(existingDir(file)); err == nil && free < need {
+		d.warnf(appender, "lower max_size or max_backups, or free up space",
+			"%s and its %d backups take up to %s, only %s is free", file, backups, bytesString(need), bytesString(free))
+	}
+}
+
+// checkUDP checks that host resolves, and that nothing refuses datagrams sent
+// to host:port, which is as much as UDP tells.
+func (d *doctor) checkUDP(appender, host string, port int) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+	if _, err := net.DefaultResolver.LookupHost(ctx, host); err != nil {
+		d.errorf(appender, "check the host name, and the DNS settings of the machine", "cannot resolve %s: %v", host, err)
+		return
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := net.DialTimeout("udp", addr, d.timeout)
+	if err != nil {
+		d.errorf(appender, "check the host and port, and the routes of the machine", "cannot reach %s: %v", addr, err)
+		return
+	}
+	defer conn.Close()
+	// an empty datagram is ignored by servers, a refusal comes back on
+	// the next read
+	conn.Write(nil)
+	conn.SetReadDeadline(time.Now().Add(d.timeout / 10))
+	if _, err := conn.Read(make([]byte, 1)); errors.Is(err, syscall.ECONNREFUSED) {
+		d.errorf(appender, "check the server is up, and the port", "nothing listens on udp %s", addr)
+	}
+}
+
+// checkURLs checks the TLS certificates of the servers the https or tls URLs
+// among settings point to.
+func (d *doctor) checkURLs(appender string, settings map[string]interface{}) {
+	for _, v := range settings {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			d.checkURLs(appender, v)
+		case string:
+			u, err := url.Parse(v)
+			if err != nil || (u.Scheme != "https" && u.Scheme != "tls") || u.Host == "" {
+				continue
+			}
+			addr := u.Host
+			if u.Port() == "" {
+				addr = net.JoinHostPort(u.Hostname(), "443")
+			}
+			d.checkTLS(appender, addr, nil)
+		}
+	}
+}
+
+// certExpiryWarning is how long before their expiry certificates are warned
+// about.
+const certExpiryWarning = 30 * 24 * time.Hour
+
+// checkTLS checks that the server at addr presents a valid certificate, not
+// about to expire. roots are the trusted authorities, the system's if nil.
+func (d *doctor) checkTLS(appender, addr string, roots *x509.CertPool) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		d.errorf(appender, "", "invalid address %s: %v", addr, err)
+		return
+	}
+	config := &tls.Config{ServerName: host, RootCAs: roots}
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: d.timeout}, "tcp", addr, config)
+	if err != nil {
+		d.errorf(appender, "check the server is up, and that its certificate is valid for "+host+" and signed by a trusted authority",
+			"TLS handshake with %s failed: %v", addr, err)
+		return
+	}
+	defer conn.Close()
+	leaf := conn.ConnectionState().PeerCertificates[0]
+	if left := time.Until(leaf.NotAfter); left < certExpiryWarning {
+		d.warnf(appender, "renew the certificate", "the certificate of %s expires on %s", addr, leaf.NotAfter.Format("2006-01-02"))
+	}
+}
+
+// existingDir returns the closest directory of file which exists.
+func existingDir(file string) string {
+	dir := filepath.Dir(file)
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+func bytesString(n uint64) string {
+	const unit = 1 << 10
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
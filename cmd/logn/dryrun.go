@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/shanexu/logn/common"
+	lognzap "github.com/shanexu/logn/core/zap"
+)
+
+var dryRunCommand = &command{
+	name:  "dryrun",
+	short: "feed test entries through configurations, writing nowhere",
+	run:   runDryRun,
+}
+
+func runDryRun(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("dryrun", flag.ContinueOnError)
+	fs.Usage = func() {
+		fs.Output().Write([]byte("usage: logn dryrun file ...\n"))
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	n := 0
+	for _, name := range fs.Args() {
+		raw, _, err := common.LoadFile(name)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		r, err := lognzap.DryRun(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		for _, logger := range r.Loggers() {
+			routes := r.Routes[logger]
+			appenders := make([]string, 0, len(routes))
+			for a := range routes {
+				appenders = append(appenders, a)
+			}
+			sort.Strings(appenders)
+			for i, a := range appenders {
+				levels := make([]string, len(routes[a]))
+				for j, l := range routes[a] {
+					levels[j] = l.String()
+				}
+				appenders[i] = a + " " + strings.Join(levels, ",")
+			}
+			if len(appenders) == 0 {
+				appenders = append(appenders, "nowhere")
+			}
+			if logger == "" {
+				logger = "root"
+			}
+			fmt.Fprintf(stdout, "%s: logger %s: %s\n", name, logger, strings.Join(appenders, "; "))
+		}
+		appenders := make([]string, 0, len(r.Appenders))
+		for a := range r.Appenders {
+			appenders = append(appenders, a)
+		}
+		sort.Strings(appenders)
+		for _, a := range appenders {
+			ar := r.Appenders[a]
+			fmt.Fprintf(stdout, "%s: appender %s: %d entries, %s\n", name, a, ar.Entries, bytesString(uint64(ar.Bytes)))
+			for _, e := range ar.Errors {
+				fmt.Fprintf(stdout, "%s: appender %s: %s\n", name, a, e)
+			}
+			n += len(ar.Errors)
+		}
+	}
+	if n > 0 {
+		return fmt.Errorf("%d problems found", n)
+	}
+	return nil
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// xmlElement is an element of a logback or log4j2 configuration.
+type xmlElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr   `xml:",any,attr"`
+	Children []xmlElement `xml:",any"`
+	Text     string       `xml:",chardata"`
+}
+
+// attr returns the value of the attribute called name, ignoring case.
+func (e *xmlElement) attr(name string) string {
+	for _, a := range e.Attrs {
+		if strings.EqualFold(a.Name.Local, name) {
+			return strings.TrimSpace(a.Value)
+		}
+	}
+	return ""
+}
+
+// child returns the first child element called name, ignoring case.
+func (e *xmlElement) child(name string) *xmlElement {
+	for i := range e.Children {
+		if strings.EqualFold(e.Children[i].XMLName.Local, name) {
+			return &e.Children[i]
+		}
+	}
+	return nil
+}
+
+// children returns the child elements called name, ignoring case.
+func (e *xmlElement) children(name string) []*xmlElement {
+	var es []*xmlElement
+	for i := range e.Children {
+		if strings.EqualFold(e.Children[i].XMLName.Local, name) {
+			es = append(es, &e.Children[i])
+		}
+	}
+	return es
+}
+
+// text returns the text of the child element called name, if any.
+func (e *xmlElement) text(name string) string {
+	if c := e.child(name); c != nil {
+		return strings.TrimSpace(c.Text)
+	}
+	return ""
+}
+
+// javaConfig is a logback or log4j2 configuration being converted.
+type javaConfig struct {
+	appenders []*javaAppender
+	// asyncs maps the async appenders to the appender they wrap
+	asyncs map[string]string
+	// notes are about what could not be converted
+	notes []string
+	root  javaLogger
+	// loggers are the loggers other than root
+	loggers []javaLogger
+}
+
+type javaAppender struct {
+	name     string
+	kind     string
+	settings *yaml.Node
+}
+
+type javaLogger struct {
+	name       string
+	level      string
+	refs       []string
+	additivity bool
+}
+
+func (c *javaConfig) note(format string, args ...interface{}) {
+	c.notes = append(c.notes, fmt.Sprintf(format, args...))
+}
+
+// appender returns the appender called name.
+func (c *javaConfig) appender(name string) *javaAppender {
+	for _, a := range c.appenders {
+		if a.name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// level returns the logn level of a Java level, if there is one.
+func (c *javaConfig) level(logger, level string) string {
+	switch l := strings.ToLower(level); l {
+	case "", "inherited", "null":
+		return ""
+	case "trace", "all":
+		return "debug"
+	case "debug", "info", "warn", "error", "fatal":
+		return l
+	default:
+		c.note("level %s of logger %q is not supported", level, logger)
+		return ""
+	}
+}
+
+// encoder returns the encoder section for a layout: JSON layouts are
+// converted to the json encoder, others to the console encoder, the pattern
+// of which is noted as a comment.
+func encoder(json bool, pattern string) *yaml.Node {
+	enc := mapping()
+	if json {
+		set(enc, "json", null())
+	} else {
+		set(enc, "console", null())
+	}
+	if pattern != "" {
+		enc.Content[0].HeadComment = "# was pattern " + pattern
+	}
+	return enc
+}
+
+var size = regexp.MustCompile(`(?i)^\s*([0-9]+)\s*([kmg]?)b?\s*$`)
+
+// megabytes returns the size s, such as "100MB" or "10 GB", in megabytes,
+// rounded up.
+func megabytes(s string) (int, bool) {
+	m := size.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, false
+	}
+	switch strings.ToLower(m[2]) {
+	case "":
+		n = (n + 1<<20 - 1) >> 20
+	case "k":
+		n = (n + 1<<10 - 1) >> 10
+	case "g":
+		n <<= 10
+	}
+	return n, true
+}
+
+// fromLogback converts the logback configuration with root element e.
+func fromLogback(e *xmlElement) *yaml.Node {
+	c := &javaConfig{asyncs: map[string]string{}}
+	for _, a := range e.children("appender") {
+		name, class := a.attr("name"), a.attr("class")
+		enc := a.child("encoder")
+		if enc == nil {
+			enc = a.child("layout")
+		}
+		json, pattern := false, ""
+		if enc != nil {
+			json = strings.Contains(strings.ToLower(enc.attr("class")), "json") ||
+				strings.Contains(enc.attr("class"), "Logstash")
+			pattern = enc.text("pattern")
+		}
+		settings := mapping()
+		set(settings, "name", scalar(name))
+		kind := ""
+		switch class[strings.LastIndex(class, ".")+1:] {
+		case "ConsoleAppender":
+			kind = "console"
+			if strings.EqualFold(a.text("target"), "System.err") {
+				set(settings, "target", scalar("stderr"))
+			}
+		case "FileAppender":
+			kind = "file"
+			set(settings, "file_name", scalar(a.text("file")))
+		case "RollingFileAppender":
+			kind = "rolling_file"
+			file := a.text("file")
+			policy := a.child("rollingPolicy")
+			if policy == nil {
+				policy = &xmlElement{}
+			}
+			filePattern := policy.text("fileNamePattern")
+			if file == "" {
+				c.note("appender %q has no file, only the pattern %s", name, filePattern)
+			}
+			set(settings, "file_name", scalar(file))
+			maxSize := policy.text("maxFileSize")
+			if t := a.child("triggeringPolicy"); t != nil && maxSize == "" {
+				maxSize = t.text("maxFileSize")
+			}
+			if mb, ok := megabytes(maxSize); ok {
+				set(settings, "max_size", scalar(mb))
+			}
+			if days, err := strconv.Atoi(policy.text("maxHistory")); err == nil {
+				set(settings, "max_age", scalar(days))
+			}
+			if strings.HasSuffix(filePattern, ".gz") || strings.HasSuffix(filePattern, ".zip") {
+				set(settings, "compress", scalar(true))
+			}
+		case "AsyncAppender":
+			refs := a.children("appender-ref")
+			if len(refs) != 1 {
+				c.note("async appender %q does not wrap a single appender", name)
+				continue
+			}
+			c.asyncs[name] = refs[0].attr("ref")
+			async := mapping()
+			if n, err := strconv.Atoi(a.text("queueSize")); err == nil {
+				set(async, "queue_size", scalar(n))
+			}
+			if a.text("neverBlock") == "true" {
+				set(async, "on_full", scalar("drop_newest"))
+			}
+			c.appenders = append(c.appenders, &javaAppender{name: name, kind: "async", settings: async})
+			continue
+		default:
+			c.note("appender %q of class %s is not supported", name, class)
+			continue
+		}
+		set(settings, "encoder", encoder(json, pattern))
+		c.appenders = append(c.appenders, &javaAppender{name: name, kind: kind, settings: settings})
+	}
+	logger := func(l *xmlElement, name string) javaLogger {
+		jl := javaLogger{name: name, additivity: l.attr("additivity") != "false"}
+		jl.level = c.level(name, l.attr("level"))
+		if lv := l.child("level"); lv != nil && jl.level == "" {
+			jl.level = c.level(name, lv.attr("value"))
+		}
+		for _, r := range l.children("appender-ref") {
+			jl.refs = append(jl.refs, r.attr("ref"))
+		}
+		return jl
+	}
+	if r := e.child("root"); r != nil {
+		c.root = logger(r, "root")
+	}
+	for _, l := range e.children("logger") {
+		c.loggers = append(c.loggers, logger(l, l.attr("name")))
+	}
+	return c.node()
+}
+
+// fromLog4j2 converts the log4j2 configuration with root element e.
+func fromLog4j2(e *xmlElement) *yaml.Node {
+	c := &javaConfig{asyncs: map[string]string{}}
+	appenders := e.child("Appenders")
+	if appenders == nil {
+		appenders = &xmlElement{}
+	}
+	for i := range appenders.Children {
+		a := &appenders.Children[i]
+		name := a.attr("name")
+		json, pattern := false, ""
+		for _, l := range a.Children {
+			switch layout := l.XMLName.Local; {
+			case strings.EqualFold(layout, "PatternLayout"):
+				pattern = l.attr("pattern")
+				if pattern == "" {
+					pattern = strings.TrimSpace(l.text("Pattern"))
+				}
+			case strings.HasSuffix(layout, "Layout") && (strings.Contains(layout, "Json") || strings.Contains(layout, "Ecs")):
+				json = true
+			}
+		}
+		settings := mapping()
+		set(settings, "name", scalar(name))
+		kind := ""
+		switch strings.ToLower(a.XMLName.Local) {
+		case "console":
+			kind = "console"
+			if strings.EqualFold(a.attr("target"), "SYSTEM_ERR") {
+				set(settings, "target", scalar("stderr"))
+			}
+		case "file", "randomaccessfile", "memorymappedfile":
+			kind = "file"
+			set(settings, "file_name", scalar(a.attr("fileName")))
+		case "rollingfile", "rollingrandomaccessfile":
+			kind = "rolling_file"
+			set(settings, "file_name", scalar(a.attr("fileName")))
+			if p := a.child("Policies"); p != nil {
+				if s := p.child("SizeBasedTriggeringPolicy"); s != nil {
+					if mb, ok := megabytes(s.attr("size")); ok {
+						set(settings, "max_size", scalar(mb))
+					}
+				}
+			}
+			if s := a.child("DefaultRolloverStrategy"); s != nil {
+				if n, err := strconv.Atoi(s.attr("max")); err == nil {
+					set(settings, "max_backups", scalar(n))
+				}
+			}
+			if p := a.attr("filePattern"); strings.HasSuffix(p, ".gz") || strings.HasSuffix(p, ".zip") {
+				set(settings, "compress", scalar(true))
+			}
+		case "async":
+			refs := a.children("AppenderRef")
+			if len(refs) != 1 {
+				c.note("async appender %q does not wrap a single appender", name)
+				continue
+			}
+			c.asyncs[name] = refs[0].attr("ref")
+			async := mapping()
+			if n, err := strconv.Atoi(a.attr("bufferSize")); err == nil {
+				set(async, "queue_size", scalar(n))
+			}
+			if a.attr("blocking") == "false" {
+				set(async, "on_full", scalar("drop_newest"))
+			}
+			c.appenders = append(c.appenders, &javaAppender{name: name, kind: "async", settings: async})
+			continue
+		default:
+			c.note("appender %q of type %s is not supported", name, a.XMLName.Local)
+			continue
+		}
+		set(settings, "encoder", encoder(json, pattern))
+		c.appenders = append(c.appenders, &javaAppender{name: name, kind: kind, settings: settings})
+	}
+	loggers := e.child("Loggers")
+	if loggers == nil {
+		loggers = &xmlElement{}
+	}
+	for i := range loggers.Children {
+		l := &loggers.Children[i]
+		name := l.attr("name")
+		isRoot := strings.EqualFold(l.XMLName.Local, "Root") || strings.EqualFold(l.XMLName.Local, "AsyncRoot")
+		if isRoot {
+			name = "root"
+		}
+		jl := javaLogger{name: name, additivity: l.attr("additivity") != "false"}
+		jl.level = c.level(name, l.attr("level"))
+		for _, r := range l.children("AppenderRef") {
+			jl.refs = append(jl.refs, r.attr("ref"))
+		}
+		if isRoot {
+			c.root = jl
+		} else {
+			c.loggers = append(c.loggers, jl)
+		}
+	}
+	return c.node()
+}
+
+// ref returns the name of the appender a reference stands for, async
+// appenders being folded into the appender they wrap.
+func (c *javaConfig) ref(name string) string {
+	if wrapped, ok := c.asyncs[name]; ok {
+		return wrapped
+	}
+	return name
+}
+
+// refs returns the references of l, including those of root when l is
+// additive, since logn loggers do not inherit the appenders of their parents.
+func (c *javaConfig) refs(l javaLogger) *yaml.Node {
+	refs := sequence()
+	seen := map[string]bool{}
+	add := func(names []string) {
+		for _, r := range names {
+			if r = c.ref(r); !seen[r] {
+				seen[r] = true
+				refs.Content = append(refs.Content, scalar(r))
+			}
+		}
+	}
+	add(l.refs)
+	if l.name != "root" && l.additivity && len(l.refs) > 0 {
+		add(c.root.refs)
+	}
+	return refs
+}
+
+// node returns the logn configuration.
+func (c *javaConfig) node() *yaml.Node {
+	root := mapping()
+	appenders := mapping()
+	for _, a := range c.appenders {
+		if a.kind != "async" {
+			continue
+		}
+		wrapped := c.appender(c.asyncs[a.name])
+		if wrapped == nil || wrapped.kind == "async" {
+			c.note("async appender %q does not wrap a supported appender", a.name)
+			continue
+		}
+		set(wrapped.settings, "async", a.settings)
+	}
+	for _, a := range c.appenders {
+		if a.kind == "async" {
+			continue
+		}
+		list := get(appenders, a.kind)
+		if list == nil {
+			list = set(appenders, a.kind, sequence())
+		}
+		list.Content = append(list.Content, a.settings)
+	}
+	set(root, "appenders", appenders)
+
+	loggers := mapping()
+	rootLogger := mapping()
+	if c.root.level != "" {
+		set(rootLogger, "level", scalar(c.root.level))
+	}
+	set(rootLogger, "appender_refs", c.refs(c.root))
+	set(loggers, "root", rootLogger)
+	if len(c.loggers) > 0 {
+		list := set(loggers, "logger", sequence())
+		for _, l := range c.loggers {
+			logger := mapping()
+			set(logger, "name", scalar(l.name))
+			if l.level != "" {
+				set(logger, "level", scalar(l.level))
+			}
+			if len(l.refs) > 0 {
+				set(logger, "appender_refs", c.refs(l))
+			}
+			list.Content = append(list.Content, logger)
+		}
+	}
+	set(root, "loggers", loggers)
+
+	if len(c.notes) > 0 {
+		root.Content[0].HeadComment = "# " + strings.Join(c.notes, "\n# ")
+	}
+	return root
+}
@@ -0,0 +1,75 @@
+// Command logn is a companion tool for logn's configuration and output.
+//
+// Usage:
+//
+//	logn <command> [flags] [args]
+//
+// The commands are:
+//
+//	config     convert and create configuration files
+//	decrypt    decrypt the output of encrypted appenders
+//	doctor     check the environment configurations are deployed to
+//	dryrun     feed test entries through configurations, writing nowhere
+//	replay     replay logs through a configuration's appenders
+//	tail       follow and pretty-print JSON and logfmt logs
+//	validate   check configuration files
+//	verify     verify the signatures of signed appenders' output
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+type command struct {
+	name  string
+	short string
+	run   func(args []string, stdout io.Writer) error
+}
+
+var commands = []*command{
+	configCommand,
+	decryptCommand,
+	doctorCommand,
+	dryRunCommand,
+	replayCommand,
+	tailCommand,
+	validateCommand,
+	verifyCommand,
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: logn <command> [flags] [args]\n\ncommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.short)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		if err := c.run(args[1:], stdout); err != nil {
+			if err == flag.ErrHelp {
+				return 2
+			}
+			fmt.Fprintf(stderr, "logn %s: %v\n", c.name, err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprintf(stderr, "logn: unknown command %q\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
@@ -0,0 +1,511 @@
+package main
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanexu/logn/appender/writer/encrypt"
+)
+
+func TestDecrypt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key := bytes.Repeat([]byte{1}, 16)
+	keyFile := filepath.Join(dir, "key")
+	assert.Nil(t, ioutil.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(key)), 0600))
+
+	logFile := filepath.Join(dir, "app.log")
+	f, err := os.Create(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := encrypt.NewWriter(f, key)
+	assert.Nil(t, err)
+	w.Write([]byte(`{"msg":"hello"}` + "\n"))
+	f.Close()
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"decrypt", "-key", "file:" + keyFile, logFile}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, `{"msg":"hello"}`+"\n", stdout.String())
+
+	stdout.Reset()
+	code = run([]string{"nope"}, &stdout, &stderr)
+	assert.Equal(t, 2, code)
+}
+
+func TestValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	good := filepath.Join(dir, "good.yaml")
+	logFile := filepath.Join(dir, "app.log")
+	assert.Nil(t, ioutil.WriteFile(good, []byte(`
+appenders:
+  file:
+    - name: FILE
+      file_name: `+logFile+`
+      encoder:
+        json:
+    - name: CREATED
+      file_name: `+filepath.Join(dir, "created", "app.log")+`
+      create_dirs: true
+      encoder:
+        json:
+    - name: DAILY
+      file_name: `+filepath.Join(dir, "%Y-%m-%d", "app.log")+`
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+`), 0644))
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"validate", good}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stdout.String()+stderr.String())
+	assert.Equal(t, good+": ok\n", stdout.String())
+	// appenders are tried writing elsewhere
+	_, err = os.Stat(logFile)
+	assert.True(t, os.IsNotExist(err))
+
+	bad := filepath.Join(dir, "bad.yaml")
+	assert.Nil(t, ioutil.WriteFile(bad, []byte(`appenders:
+  file:
+    - name: FILE
+      file_name: `+filepath.Join(dir, "missing", "app.log")+`
+      async:
+        on_full: explode
+      encoder:
+        json:
+    - name: FILE
+      file_name: `+logFile+`
+      encoder:
+        json:
+loggers:
+  root:
+    level: loud
+    appender_refs:
+      - FILE
+      - MISSING
+    appender_levels:
+      FILE: noisy
+      CONSOLE: warn
+`), 0644))
+	stdout.Reset()
+	stderr.Reset()
+	code = run([]string{"validate", bad}, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, []string{
+		bad + ":4: appenders.file.0.file_name: directory " + filepath.Join(dir, "missing") + " does not exist",
+		bad + `:6: appenders.file.0.async.on_full: requires value one of "block drop_newest drop_oldest spill"`,
+		bad + `:9: appenders.file.1.name: duplicated appender name "FILE"`,
+		bad + `:15: loggers.root.level: unrecognized level: "loud"`,
+		bad + `:18: loggers.root.appender_refs.1: not found appender "MISSING"`,
+		bad + `:20: loggers.root.appender_levels.FILE: unrecognized level: "noisy"`,
+		bad + `:21: loggers.root.appender_levels.CONSOLE: level of appender "CONSOLE", which is not referenced`,
+	}, strings.Split(strings.TrimSpace(stdout.String()), "\n"))
+	assert.Equal(t, "logn validate: 7 problems found\n", stderr.String())
+}
+
+func TestTail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	logFile := filepath.Join(dir, "app.log")
+	assert.Nil(t, ioutil.WriteFile(logFile, []byte(`{"level":"debug","msg":"noise"}
+{"level":"info","ts":"2023-11-14T22:13:20.5Z","logger":"app.db","caller":"db/db.go:12","msg":"connected","host":"db1","n":3}
+panic: boom
+level=warn ts=2023-11-14T22:13:21Z logger=app msg="slow query" took=1.5s
+{"level":"error","logger":"other","msg":"failed"}
+`), 0644))
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"tail", "-color", "never", logFile}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, `DEBUG	noise
+2023-11-14T22:13:20.500Z	INFO	app.db	db/db.go:12	connected	{"host": "db1", "n": 3}
+panic: boom
+2023-11-14T22:13:21.000Z	WARN	app	slow query	{"took": "1.5s"}
+ERROR	other	failed
+`, stdout.String())
+
+	stdout.Reset()
+	code = run([]string{"tail", "-n", "3", "-level", "info", "-logger", "app", "-filter", "level >= 'warn'", logFile}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, "panic: boom\n2023-11-14T22:13:21.000Z\tWARN\tapp\tslow query\t{\"took\": \"1.5s\"}\n", stdout.String())
+
+	ts, ok := parseTime(int64(1700000000123))
+	assert.True(t, ok)
+	assert.Equal(t, time.Unix(1700000000, 123e6), ts)
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestTailFollow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = time.Millisecond
+
+	logFile := filepath.Join(dir, "app.log")
+	assert.Nil(t, ioutil.WriteFile(logFile, []byte(`{"msg":"a"}`+"\n"), 0644))
+	out := &syncBuffer{}
+	tl := &tailer{out: out, enc: newTailEncoder(false)}
+	done := make(chan struct{})
+	errs := make(chan error)
+	go func() { errs <- tl.tailFile(logFile, 10, true, done) }()
+	waitFor := func(want string) {
+		t.Helper()
+		for i := 0; i < 1000 && out.String() != want; i++ {
+			time.Sleep(time.Millisecond)
+		}
+		assert.Equal(t, want, out.String())
+	}
+	waitFor("INFO\ta\n")
+
+	f, err := os.OpenFile(logFile, os.O_WRONLY|os.O_APPEND, 0)
+	assert.Nil(t, err)
+	f.Write([]byte(`{"msg":"b"}` + "\n"))
+	f.Close()
+	waitFor("INFO\ta\nINFO\tb\n")
+
+	// rotation
+	assert.Nil(t, os.Rename(logFile, logFile+".1"))
+	assert.Nil(t, ioutil.WriteFile(logFile, []byte(`{"msg":"c"}`+"\n"), 0644))
+	waitFor("INFO\ta\nINFO\tb\nINFO\tc\n")
+
+	close(done)
+	assert.Nil(t, <-errs)
+}
+
+func TestConvert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	logback := filepath.Join(dir, "logback.xml")
+	assert.Nil(t, ioutil.WriteFile(logback, []byte(`<configuration>
+  <appender name="STDOUT" class="ch.qos.logback.core.ConsoleAppender">
+    <encoder><pattern>%d %-5level %logger - %msg%n</pattern></encoder>
+  </appender>
+  <appender name="ROLL" class="ch.qos.logback.core.rolling.RollingFileAppender">
+    <file>/var/log/app.log</file>
+    <rollingPolicy class="ch.qos.logback.core.rolling.SizeAndTimeBasedRollingPolicy">
+      <fileNamePattern>/var/log/app-%d.%i.log.gz</fileNamePattern>
+      <maxFileSize>100MB</maxFileSize>
+      <maxHistory>30</maxHistory>
+    </rollingPolicy>
+    <encoder class="net.logstash.logback.encoder.LogstashEncoder"/>
+  </appender>
+  <appender name="ASYNC" class="ch.qos.logback.classic.AsyncAppender">
+    <queueSize>512</queueSize>
+    <appender-ref ref="ROLL"/>
+  </appender>
+  <appender name="SENTRY" class="io.sentry.logback.SentryAppender"/>
+  <logger name="com.acme.db" level="TRACE"><appender-ref ref="ASYNC"/></logger>
+  <root level="INFO"><appender-ref ref="STDOUT"/></root>
+</configuration>
+`), 0644))
+	want := `# appender "SENTRY" of class io.sentry.logback.SentryAppender is not supported
+appenders:
+  console:
+    - name: STDOUT
+      encoder:
+        # was pattern %d %-5level %logger - %msg%n
+        console:
+  rolling_file:
+    - name: ROLL
+      file_name: /var/log/app.log
+      max_size: 100
+      max_age: 30
+      compress: true
+      encoder:
+        json:
+      async:
+        queue_size: 512
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - STDOUT
+  logger:
+    - name: com.acme.db
+      level: debug
+      appender_refs:
+        - ROLL
+        - STDOUT
+`
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"config", "convert", logback}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, want, stdout.String())
+
+	// back and forth through TOML and JSON, which lose comments
+	converted := filepath.Join(dir, "logn.yaml")
+	assert.Nil(t, ioutil.WriteFile(converted, stdout.Bytes(), 0644))
+	for _, format := range []string{"toml", "json"} {
+		stdout.Reset()
+		code = run([]string{"config", "convert", "-to", format, converted}, &stdout, &stderr)
+		assert.Equal(t, 0, code, stderr.String())
+		file := filepath.Join(dir, "logn."+format)
+		assert.Nil(t, ioutil.WriteFile(file, stdout.Bytes(), 0644))
+		stdout.Reset()
+		code = run([]string{"config", "convert", file}, &stdout, &stderr)
+		assert.Equal(t, 0, code, stderr.String())
+		assert.Equal(t, strings.NewReplacer(
+			want[:strings.Index(want, "\n")+1], "",
+			"        # was pattern %d %-5level %logger - %msg%n\n", "",
+		).Replace(want), stdout.String(), format)
+	}
+	stdout.Reset()
+	code = run([]string{"validate", converted}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stdout.String())
+}
+
+func TestInit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name := range starters {
+		var stdout, stderr bytes.Buffer
+		file := filepath.Join(dir, name+".yaml")
+		code := run([]string{"config", "init", "-app", "shop", "-file", filepath.Join(dir, "shop.log"), "-o", file, name}, &stdout, &stderr)
+		assert.Equal(t, 0, code, stderr.String())
+		code = run([]string{"validate", file}, &stdout, &stderr)
+		assert.Equal(t, 0, code, name+": "+stdout.String())
+
+		// configurations are not overwritten
+		code = run([]string{"config", "init", "-o", file, name}, &stdout, &stderr)
+		assert.Equal(t, 1, code)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"config", "init", "-level", "warn", "json-file-prod"}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "file_name: /var/log/app/app.log\n")
+	assert.Contains(t, stdout.String(), "level: warn\n")
+}
+
+func TestDoctor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := filepath.Join(dir, "logn.yaml")
+	writeConfig := func(file string) {
+		assert.Nil(t, ioutil.WriteFile(config, []byte(`
+appenders:
+  file:
+    - name: FILE
+      file_name: `+file+`
+      encoder:
+        json:
+`), 0644))
+	}
+	writeConfig(filepath.Join(dir, "app.log"))
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"doctor", "-min-free", "0", config}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stdout.String()+stderr.String())
+	assert.Equal(t, config+": ok\n", stdout.String())
+
+	// warnings do not fail
+	stdout.Reset()
+	code = run([]string{"doctor", "-min-free", "18446744073709551615", config}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	if _, err := diskFree(dir); err == nil {
+		assert.Contains(t, stdout.String(), config+": warning: appender FILE: only ")
+	}
+
+	writeConfig(filepath.Join(dir, "missing", "app.log"))
+	stdout.Reset()
+	code = run([]string{"doctor", "-min-free", "0", config}, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, config+": error: appender FILE: directory "+filepath.Join(dir, "missing")+" does not exist\n"+
+		"\tcreate "+filepath.Join(dir, "missing")+", writable by the user running the service\n", stdout.String())
+	assert.Equal(t, "logn doctor: 1 errors, 0 warnings\n", stderr.String())
+}
+
+func TestDoctorNetwork(t *testing.T) {
+	// a port nothing listens on
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	conn.Close()
+	d := &doctor{timeout: time.Second}
+	d.checkUDP("GRAYLOG", "127.0.0.1", port)
+	if assert.Len(t, d.findings, 1) {
+		assert.True(t, d.findings[0].err)
+		assert.Contains(t, d.findings[0].msg, "nothing listens on udp")
+	}
+
+	conn, err = net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	d = &doctor{timeout: time.Second}
+	d.checkUDP("GRAYLOG", "127.0.0.1", conn.LocalAddr().(*net.UDPAddr).Port)
+	assert.Empty(t, d.findings)
+
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+	addr := srv.Listener.Addr().String()
+	d = &doctor{timeout: time.Second}
+	d.checkURLs("LOKI", map[string]interface{}{"push": map[string]interface{}{"url": srv.URL}})
+	if assert.Len(t, d.findings, 1) {
+		assert.Contains(t, d.findings[0].msg, "TLS handshake with "+addr+" failed")
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	d = &doctor{timeout: time.Second}
+	d.checkTLS("LOKI", addr, roots)
+	assert.Empty(t, d.findings)
+}
+
+func TestDryRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := filepath.Join(dir, "logn.yaml")
+	assert.Nil(t, ioutil.WriteFile(config, []byte(`
+appenders:
+  file:
+    - name: FILE
+      file_name: `+filepath.Join(dir, "app.log")+`
+      encoder:
+        json:
+loggers:
+  root:
+    level: warn
+    appender_refs:
+      - FILE
+  logger:
+    - name: quiet
+      level: error
+      allow_messages:
+        - ^nothing$
+`), 0644))
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"dryrun", config}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	out := stdout.String()
+	assert.Contains(t, out, config+": logger root: FILE warn,error\n")
+	assert.Contains(t, out, config+": logger quiet: nowhere\n")
+	assert.Contains(t, out, config+": appender FILE: 2 entries, ")
+	_, err = os.Stat(filepath.Join(dir, "app.log"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "out.log")
+	config := filepath.Join(dir, "logn.yaml")
+	assert.Nil(t, ioutil.WriteFile(config, []byte(`
+appenders:
+  file:
+    - name: FILE
+      file_name: `+out+`
+      encoder:
+        json:
+          time_encoder: ISO8601
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+`), 0644))
+	in := filepath.Join(dir, "in.log")
+	assert.Nil(t, ioutil.WriteFile(in, []byte(
+		`{"level":"info","ts":"2024-05-01T10:00:00Z","logger":"web","msg":"started","port":8080}`+"\n"+
+			"not an entry\n"+
+			`{"level":"debug","ts":"2024-05-01T10:00:00.05Z","msg":"dropped"}`+"\n"+
+			`{"level":"fatal","ts":"2024-05-01T10:00:00.1Z","msg":"failed"}`+"\n"), 0644))
+
+	var stdout, stderr bytes.Buffer
+	start := time.Now()
+	code := run([]string{"replay", "-pace", "-keep-time", config, in}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.True(t, time.Since(start) >= 90*time.Millisecond)
+	assert.Equal(t, "replayed 3 entries, skipped 1 lines\n", stdout.String())
+	b, err := ioutil.ReadFile(out)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.Contains(t, lines[0], `"ts":"2024-05-01T10:00:00.000Z","logger":"web","msg":"started",`)
+		assert.Contains(t, lines[0], `"port":8080`)
+		assert.Contains(t, lines[1], `"level":"fatal","ts":"2024-05-01T10:00:00.100Z","msg":"failed"`)
+	}
+
+	// times are the current ones by default
+	stdout.Reset()
+	code = run([]string{"replay", "-repeat", "2", config, in}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, "replayed 6 entries, skipped 2 lines\n", stdout.String())
+	b, err = ioutil.ReadFile(out)
+	assert.Nil(t, err)
+	lines = strings.Split(strings.TrimSpace(string(b)), "\n")
+	if assert.Len(t, lines, 6) {
+		assert.NotContains(t, lines[2], "2024-05-01")
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/shanexu/logn/common"
+	lognzap "github.com/shanexu/logn/core/zap"
+)
+
+var replayCommand = &command{
+	name:  "replay",
+	short: "replay logs through a configuration's appenders",
+	run:   runReplay,
+}
+
+// replayer writes entries read from logs to the loggers of a Core.
+type replayer struct {
+	c        *lognzap.Core
+	keepTime bool
+	// speed divides the time between entries when pacing them, which they
+	// are not if 0
+	speed float64
+
+	// first is the time of the first paced entry, and start when it was
+	// written
+	first, start time.Time
+	entries      int
+	skipped      int
+}
+
+func runReplay(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	pace := fs.Bool("pace", false, "write entries as far apart as they were logged")
+	speed := fs.Float64("speed", 1, "with -pace, how many times faster than logged to write entries")
+	keepTime := fs.Bool("keep-time", false, "keep the times of entries, instead of stamping them with the current time")
+	repeat := fs.Int("repeat", 1, "number of times to replay the logs")
+	fs.Usage = func() {
+		fs.Output().Write([]byte("usage: logn replay [flags] config file ...\n\nA file named - is the standard input.\n\nflags:\n"))
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 || *speed <= 0 || *repeat < 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	raw, _, err := common.LoadFile(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("%s: %v", fs.Arg(0), err)
+	}
+	c, err := lognzap.New(raw)
+	if err != nil {
+		return fmt.Errorf("%s: %v", fs.Arg(0), err)
+	}
+	r := &replayer{c: c.(*lognzap.Core), keepTime: *keepTime}
+	if *pace {
+		r.speed = *speed
+	}
+	for i := 0; i < *repeat; i++ {
+		for _, name := range fs.Args()[1:] {
+			if err := r.replayFile(name); err != nil {
+				r.c.Sync()
+				return err
+			}
+		}
+		// each repetition is paced on its own
+		r.first = time.Time{}
+	}
+	r.c.Sync()
+	fmt.Fprintf(stdout, "replayed %d entries, skipped %d lines\n", r.entries, r.skipped)
+	return nil
+}
+
+func (r *replayer) replayFile(name string) error {
+	if name == "-" {
+		return r.replay(os.Stdin)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return r.replay(f)
+}
+
+// replay writes the entries read from rd, skipping the lines which are not
+// entries.
+func (r *replayer) replay(rd io.Reader) error {
+	br := bufio.NewReader(rd)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			r.line(bytes.TrimRight(line, "\r\n"))
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (r *replayer) line(line []byte) {
+	ent, fields, ok := parseEntry(line)
+	if !ok {
+		r.skipped++
+		return
+	}
+	if r.speed > 0 && !ent.Time.IsZero() {
+		if r.first.IsZero() {
+			r.first, r.start = ent.Time, time.Now()
+		} else {
+			at := r.start.Add(time.Duration(float64(ent.Time.Sub(r.first)) / r.speed))
+			if d := time.Until(at); d > 0 {
+				time.Sleep(d)
+			}
+		}
+	}
+	if !r.keepTime || ent.Time.IsZero() {
+		ent.Time = time.Now()
+	}
+	// the core of the logger, rather than the logger, is written to, so that
+	// the time, caller and stacktrace of the entry are kept, and Fatal or
+	// Panic entries end nothing
+	l, _ := lognzap.Unwrap(r.c.GetLogger(ent.LoggerName))
+	if ce := l.Core().Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+	r.entries++
+}
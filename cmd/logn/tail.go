@@ -0,0 +1,499 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/appender/writer/console"
+	"github.com/shanexu/logn/filter"
+)
+
+var tailCommand = &command{
+	name:  "tail",
+	short: "follow and pretty-print JSON and logfmt logs",
+	run:   runTail,
+}
+
+// pollInterval is how often followed files are checked for more lines.
+var pollInterval = 250 * time.Millisecond
+
+func runTail(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("tail", flag.ContinueOnError)
+	follow := fs.Bool("f", false, "follow the files as they grow, across rotations")
+	n := fs.Int("n", 10, "number of last lines of the files to print first, all if negative")
+	level := fs.String("level", "", "least level of the entries to print")
+	logger := fs.String("logger", "", "logger of the entries to print, including its descendants")
+	expr := fs.String("filter", "", "filter expression the entries to print must match")
+	color := fs.String("color", "auto", "colorize levels: auto, always or never")
+	fs.Usage = func() {
+		fs.Output().Write([]byte("usage: logn tail [-f] [-n lines] [-level level] [-logger name] [-filter expr] [-color when] [file ...]\n"))
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	t := &tailer{out: stdout, logger: *logger, minLevel: zapcore.DebugLevel}
+	if *level != "" {
+		if err := t.minLevel.UnmarshalText([]byte(*level)); err != nil {
+			return err
+		}
+	}
+	if *expr != "" {
+		var err error
+		if t.filter, err = filter.Compile(*expr); err != nil {
+			return err
+		}
+	}
+	switch *color {
+	case "always":
+		t.enc = newTailEncoder(true)
+	case "never":
+		t.enc = newTailEncoder(false)
+	case "auto":
+		t.enc = newTailEncoder(isTerminal(stdout))
+	default:
+		return fmt.Errorf("invalid -color %q", *color)
+	}
+
+	if fs.NArg() == 0 {
+		return t.copy(os.Stdin)
+	}
+	errs := make(chan error, fs.NArg())
+	for _, name := range fs.Args() {
+		go func(name string) {
+			errs <- t.tailFile(name, *n, *follow, nil)
+		}(name)
+	}
+	var first error
+	for range fs.Args() {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// isTerminal reports whether w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && console.IsTerminal(f)
+}
+
+func newTailEncoder(color bool) zapcore.Encoder {
+	levelEncoder := zapcore.CapitalLevelEncoder
+	if color {
+		levelEncoder = zapcore.CapitalColorLevelEncoder
+	}
+	return zapcore.NewConsoleEncoder(zapcore.EncoderConfig{
+		TimeKey:       "ts",
+		LevelKey:      "level",
+		NameKey:       "logger",
+		CallerKey:     "caller",
+		MessageKey:    "msg",
+		StacktraceKey: "stacktrace",
+		LineEnding:    "\n",
+		EncodeLevel:   levelEncoder,
+		EncodeTime: func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+			if !t.IsZero() {
+				zapcore.ISO8601TimeEncoder(t, enc)
+			}
+		},
+		EncodeDuration: zapcore.StringDurationEncoder,
+		// callers are rendered as they were read
+		EncodeCaller: func(c zapcore.EntryCaller, enc zapcore.PrimitiveArrayEncoder) {
+			enc.AppendString(c.File)
+		},
+	})
+}
+
+// tailer prints the entries read from logs in the console format.
+type tailer struct {
+	out      io.Writer
+	enc      zapcore.Encoder
+	minLevel zapcore.Level
+	logger   string
+	filter   *filter.Filter
+
+	// mu serializes the lines of the files followed at once
+	mu sync.Mutex
+}
+
+// tailFile prints the last n lines of the file called name, then the lines
+// appended to it until done is closed if follow is set.
+func (t *tailer) tailFile(name string, n int, follow bool, done <-chan struct{}) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer func() { f.Close() }()
+	if n >= 0 {
+		offset, err := lastLines(f, n)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	if !follow {
+		return t.copy(f)
+	}
+	fr := &follower{name: name, f: f, done: done}
+	err = t.copy(fr)
+	f = fr.f
+	return err
+}
+
+// lastLines returns the offset of the last n lines of f.
+func lastLines(f *os.File, n int) (int64, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	end := fi.Size()
+	buf := make([]byte, 32<<10)
+	// a newline ending the file does not start a line
+	seen := -1
+	for end > 0 {
+		size := int64(len(buf))
+		if end < size {
+			size = end
+		}
+		chunk := buf[:size]
+		if _, err := f.ReadAt(chunk, end-size); err != nil {
+			return 0, err
+		}
+		for i := len(chunk) - 1; i >= 0; i-- {
+			if chunk[i] != '\n' {
+				continue
+			}
+			if seen++; seen == n {
+				return end - size + int64(i) + 1, nil
+			}
+		}
+		end -= size
+	}
+	return 0, nil
+}
+
+// copy prints the lines read from r.
+func (t *tailer) copy(r io.Reader) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if err := t.line(bytes.TrimRight(line, "\r\n")); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// line prints line, pretty if it is an entry, as it is otherwise.
+func (t *tailer) line(line []byte) error {
+	ent, fields, ok := parseEntry(line)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !ok {
+		_, err := fmt.Fprintf(t.out, "%s\n", line)
+		return err
+	}
+	if !t.match(ent, fields) {
+		return nil
+	}
+	buf, err := t.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	_, err = t.out.Write(buf.Bytes())
+	buf.Free()
+	return err
+}
+
+func (t *tailer) match(ent zapcore.Entry, fields []zapcore.Field) bool {
+	if ent.Level < t.minLevel {
+		return false
+	}
+	if t.logger != "" && ent.LoggerName != t.logger && !strings.HasPrefix(ent.LoggerName, t.logger+".") {
+		return false
+	}
+	return t.filter == nil || t.filter.Match(ent, fields)
+}
+
+// follower reads a file as it grows, reopening it when it is rotated and
+// reading it from the start when it is truncated, until done is closed.
+type follower struct {
+	name string
+	f    *os.File
+	done <-chan struct{}
+}
+
+func (r *follower) Read(p []byte) (int, error) {
+	for {
+		n, err := r.f.Read(p)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+		select {
+		case <-r.done:
+			return 0, io.EOF
+		case <-time.After(pollInterval):
+		}
+		if err := r.check(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// check switches to the file now found at the name of the followed one, once
+// the followed one is read through, and rewinds a truncated file.
+func (r *follower) check() error {
+	fi, err := r.f.Stat()
+	if err != nil {
+		return err
+	}
+	offset, err := r.f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if fi.Size() < offset {
+		_, err := r.f.Seek(0, io.SeekStart)
+		return err
+	}
+	if fi.Size() > offset {
+		return nil
+	}
+	current, err := os.Stat(r.name)
+	if err != nil || os.SameFile(fi, current) {
+		// the file is being rotated, or was not
+		return nil
+	}
+	f, err := os.Open(r.name)
+	if err != nil {
+		return nil
+	}
+	r.f.Close()
+	r.f = f
+	return nil
+}
+
+// Keys of the well-known members of entries.
+var (
+	timeKeys    = []string{"ts", "time", "timestamp", "@timestamp"}
+	levelKeys   = []string{"level", "lvl", "severity"}
+	loggerKeys  = []string{"logger"}
+	messageKeys = []string{"msg", "message", "short_message"}
+	callerKeys  = []string{"caller"}
+	stackKeys   = []string{"stacktrace", "stack"}
+)
+
+// member is a key and value of an entry, in the order they were read.
+type member struct {
+	key   string
+	value interface{}
+}
+
+// parseEntry parses line as a JSON object or logfmt, returning the entry and
+// the fields it holds.
+func parseEntry(line []byte) (zapcore.Entry, []zapcore.Field, bool) {
+	var members []member
+	var err error
+	if t := bytes.TrimSpace(line); len(t) > 0 && t[0] == '{' {
+		members, err = parseJSON(t)
+	} else {
+		members, err = parseLogfmt(string(line))
+	}
+	if err != nil || !hasAny(members, messageKeys, levelKeys) {
+		return zapcore.Entry{}, nil, false
+	}
+
+	ent := zapcore.Entry{Level: zapcore.InfoLevel}
+	fields := make([]zapcore.Field, 0, len(members))
+	for _, m := range members {
+		s, isString := m.value.(string)
+		switch {
+		case in(m.key, timeKeys) && ent.Time.IsZero():
+			if t, ok := parseTime(m.value); ok {
+				ent.Time = t
+				continue
+			}
+		case in(m.key, levelKeys) && isString:
+			if l, ok := parseLevel(s); ok {
+				ent.Level = l
+				continue
+			}
+		case in(m.key, loggerKeys) && isString && ent.LoggerName == "":
+			ent.LoggerName = s
+			continue
+		case in(m.key, messageKeys) && isString && ent.Message == "":
+			ent.Message = s
+			continue
+		case in(m.key, callerKeys) && isString && !ent.Caller.Defined:
+			ent.Caller = zapcore.EntryCaller{Defined: true, File: s}
+			continue
+		case in(m.key, stackKeys) && isString && ent.Stack == "":
+			ent.Stack = s
+			continue
+		}
+		fields = append(fields, zap.Any(m.key, m.value))
+	}
+	return ent, fields, true
+}
+
+func in(key string, keys []string) bool {
+	for _, k := range keys {
+		if key == k {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAny(members []member, keySets ...[]string) bool {
+	for _, m := range members {
+		for _, keys := range keySets {
+			if in(m.key, keys) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func parseLevel(s string) (zapcore.Level, bool) {
+	var l zapcore.Level
+	switch strings.ToLower(s) {
+	case "warning":
+		return zapcore.WarnLevel, true
+	case "critical", "crit", "fatal":
+		return zapcore.FatalLevel, true
+	case "trace":
+		return zapcore.DebugLevel, true
+	}
+	if err := l.UnmarshalText([]byte(strings.ToLower(s))); err != nil {
+		return l, false
+	}
+	return l, true
+}
+
+// parseTime parses times as written by the encoders: seconds, milliseconds or
+// nanoseconds since the epoch, or formatted.
+func parseTime(v interface{}) (time.Time, bool) {
+	switch v := v.(type) {
+	case int64:
+		return parseTime(float64(v))
+	case float64:
+		switch {
+		case v < 1e11:
+			sec, frac := math.Modf(v)
+			return time.Unix(int64(sec), int64(frac*1e9)), true
+		case v < 1e14:
+			return time.Unix(0, int64(v)*int64(time.Millisecond)), true
+		default:
+			return time.Unix(0, int64(v)), true
+		}
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.000Z0700"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseJSON returns the members of the JSON object in line.
+func parseJSON(line []byte) ([]member, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil, errors.New("not an object")
+	}
+	var members []member
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := t.(string)
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		if n, ok := v.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				v = i
+			} else if f, err := n.Float64(); err == nil {
+				v = f
+			}
+		}
+		members = append(members, member{key, v})
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// parseLogfmt returns the members of line, made of key=value pairs with
+// values possibly quoted.
+func parseLogfmt(line string) ([]member, error) {
+	var members []member
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			return members, nil
+		}
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 || strings.ContainsAny(line[:eq], " \t\"") {
+			return nil, errors.New("not logfmt")
+		}
+		key := line[:eq]
+		line = line[eq+1:]
+		var value string
+		if strings.HasPrefix(line, `"`) {
+			end := 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, errors.New("unterminated quoted value")
+			}
+			var err error
+			if value, err = strconv.Unquote(line[:end+1]); err != nil {
+				return nil, err
+			}
+			line = line[end+1:]
+		} else {
+			end := strings.IndexAny(line, " \t")
+			if end < 0 {
+				end = len(line)
+			}
+			value, line = line[:end], line[end:]
+		}
+		members = append(members, member{key, value})
+	}
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+
+	"github.com/shanexu/logn/appender"
+	"github.com/shanexu/logn/common"
+	cfg "github.com/shanexu/logn/config"
+	lognzap "github.com/shanexu/logn/core/zap"
+	"github.com/shanexu/logn/filter"
+	"github.com/shanexu/logn/hook"
+	_ "github.com/shanexu/logn/includes"
+)
+
+var validateCommand = &command{
+	name:  "validate",
+	short: "check configuration files",
+	run:   runValidate,
+}
+
+// pathKeys are the settings of appenders naming files they write to.
+var pathKeys = []string{"file_name", "async.spill_file", "spool.file"}
+
+func runValidate(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.Usage = func() {
+		fs.Output().Write([]byte("usage: logn validate file ...\n"))
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	n := 0
+	for _, name := range fs.Args() {
+		problems, err := validate(name)
+		if err != nil {
+			return err
+		}
+		for _, p := range problems {
+			fmt.Fprintln(stdout, p.format(name))
+		}
+		if len(problems) == 0 {
+			fmt.Fprintf(stdout, "%s: ok\n", name)
+		}
+		n += len(problems)
+	}
+	if n > 0 {
+		return fmt.Errorf("%d problems found", n)
+	}
+	return nil
+}
+
+// problem is something wrong with the setting at path, at line of the
+// configuration file.
+type problem struct {
+	line int
+	path string
+	msg  string
+}
+
+func (p problem) format(file string) string {
+	s := file
+	if p.line > 0 {
+		s += ":" + strconv.Itoa(p.line)
+	}
+	if p.path != "" {
+		s += ": " + p.path
+	}
+	return s + ": " + p.msg
+}
+
+// validator collects the problems of a configuration.
+type validator struct {
+	root     *yaml.Node
+	raw      *common.Config
+	problems []problem
+}
+
+var accessing = regexp.MustCompile(` accessing '([^']*)'( \(source:'[^']*'\))?$`)
+
+// report records err as a problem of the setting at path, or of the setting
+// the error names, if any.
+func (v *validator) report(path string, err error) {
+	msg := err.Error()
+	if m := accessing.FindStringSubmatch(msg); m != nil {
+		path, msg = m[1], strings.TrimSuffix(msg, m[0])
+	}
+	v.add(path, msg)
+}
+
+// add records a problem of the setting at path.
+func (v *validator) add(path, msg string) {
+	v.problems = append(v.problems, problem{line: v.line(path), path: path, msg: msg})
+}
+
+// line returns the line of the setting at path, or of its closest parent set
+// in the file.
+func (v *validator) line(path string) int {
+	n := v.root
+	if n == nil {
+		return 0
+	}
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	line := 0
+	for _, key := range strings.Split(path, ".") {
+		if key == "" {
+			break
+		}
+		var next *yaml.Node
+		switch n.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == key {
+					line = n.Content[i].Line
+					next = n.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(n.Content) {
+				next = n.Content[i]
+				line = next.Line
+			}
+		}
+		if next == nil {
+			break
+		}
+		n = next
+	}
+	return line
+}
+
+// validate returns the problems of the configuration file called name: the
+// settings which do not unpack, the appenders which cannot be created or
+// write where they cannot, and the references to undefined appenders, hooks
+// or levels. Appenders are created writing to a temporary directory.
+func validate(name string) ([]problem, error) {
+	bs, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	v := &validator{}
+	var root yaml.Node
+	if err := yaml.Unmarshal(bs, &root); err != nil {
+		return []problem{{msg: err.Error()}}, nil
+	}
+	v.root = &root
+	v.raw, err = common.NewConfigWithYAML(bs, name)
+	if err != nil {
+		v.report("", err)
+		return v.problems, nil
+	}
+	dir, err := ioutil.TempDir("", "logn-validate")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	appenders := v.appenders(dir)
+	v.loggers(appenders)
+	var hooks struct {
+		Hooks []string `logn-config:"hooks"`
+	}
+	if err := v.raw.Unpack(&hooks); err != nil {
+		v.report("hooks", err)
+	} else if _, err := hook.Lookup(hooks.Hooks); err != nil {
+		v.report("hooks", err)
+	}
+	if len(v.problems) == 0 {
+		// what the checks above miss turns up creating the core
+		if _, err := lognzap.New(v.raw); err != nil {
+			v.report("", err)
+		}
+	}
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		return v.problems[i].line < v.problems[j].line
+	})
+	return v.problems, nil
+}
+
+// appenders checks the appenders and returns their names. Their files are
+// checked for writability, then replaced by files in dir.
+func (v *validator) appenders(dir string) map[string]bool {
+	names := map[string]bool{}
+	var section struct {
+		Appenders map[string][]*common.Config `logn-config:"appenders"`
+	}
+	if err := v.raw.Unpack(&section); err != nil {
+		v.report("appenders", err)
+		return names
+	}
+	types := section.Appenders
+	sorted := make([]string, 0, len(types))
+	for t := range types {
+		sorted = append(sorted, t)
+	}
+	sort.Strings(sorted)
+	for _, t := range sorted {
+		for i := range types[t] {
+			path := fmt.Sprintf("appenders.%s.%d", t, i)
+			for _, key := range pathKeys {
+				file, err := v.raw.String(path+"."+key, -1)
+				if err != nil {
+					continue
+				}
+				createDirs, _ := v.raw.Bool(path+".create_dirs", -1)
+				if err := checkWritable(file, createsDirs(t, key, file, createDirs)); err != nil {
+					v.report(path+"."+key, err)
+				}
+				tmp := filepath.Join(dir, strings.Replace(path+"."+key, ".", "_", -1))
+				if err := v.raw.SetString(path+"."+key, -1, tmp); err != nil {
+					v.report(path+"."+key, err)
+				}
+			}
+			c, err := v.raw.Child(path, -1)
+			if err != nil {
+				v.report(path, err)
+				continue
+			}
+			if a, err := appender.CreateAppender(t, c); err != nil {
+				v.report(path, err)
+			} else {
+				a.Close()
+			}
+			name, err := c.Name()
+			switch {
+			case err != nil || name == "":
+				v.add(path+".name", "missing appender name")
+			case names[name]:
+				v.add(path+".name", fmt.Sprintf("duplicated appender name %q", name))
+			}
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// loggers checks the levels, filters and appender references of the loggers.
+func (v *validator) loggers(appenders map[string]bool) {
+	var section struct {
+		Loggers struct {
+			Root   *common.Config   `logn-config:"root"`
+			Logger []*common.Config `logn-config:"logger"`
+		} `logn-config:"loggers"`
+	}
+	if err := v.raw.Unpack(&section); err != nil {
+		v.report("loggers", err)
+		return
+	}
+	var root cfg.RootLogger
+	if section.Loggers.Root != nil {
+		if err := section.Loggers.Root.Unpack(&root); err != nil {
+			v.report("loggers.root", err)
+		}
+	}
+	v.logger("loggers.root", cfg.Logger{
+		Level:           root.Level,
+		AppenderRefs:    root.AppenderRefs,
+		Filter:          root.Filter,
+		AllowMessages:   root.AllowMessages,
+		DenyMessages:    root.DenyMessages,
+		FieldFilters:    root.FieldFilters,
+		StacktraceLevel: root.StacktraceLevel,
+		AppenderLevels:  root.AppenderLevels,
+	}, root.AppenderRefs, appenders)
+	names := map[string]bool{}
+	for i, c := range section.Loggers.Logger {
+		path := fmt.Sprintf("loggers.logger.%d", i)
+		var l cfg.Logger
+		if err := c.Unpack(&l); err != nil {
+			v.report(path, err)
+			continue
+		}
+		if names[l.Name] {
+			v.add(path+".name", fmt.Sprintf("duplicated logger %q", l.Name))
+		}
+		names[l.Name] = true
+		refs := l.AppenderRefs
+		if len(refs) == 0 {
+			refs = root.AppenderRefs
+		}
+		if len(refs) == 0 {
+			v.add(path+".appender_refs", "empty appenders")
+		}
+		v.logger(path, l, refs, appenders)
+	}
+}
+
+// logger checks the settings of the logger l at path, refs being the
+// appenders it refers to, its own or those of root.
+func (v *validator) logger(path string, l cfg.Logger, refs []string, appenders map[string]bool) {
+	for _, level := range []struct{ key, value string }{
+		{"level", l.Level},
+		{"stacktrace_level", l.StacktraceLevel},
+	} {
+		if level.value == "" || level.key == "stacktrace_level" && level.value == "off" {
+			continue
+		}
+		var lv zapcore.Level
+		if err := lv.UnmarshalText([]byte(level.value)); err != nil {
+			v.report(path+"."+level.key, err)
+		}
+	}
+	for i, ref := range l.AppenderRefs {
+		if !appenders[ref] {
+			v.add(fmt.Sprintf("%s.appender_refs.%d", path, i), fmt.Sprintf("not found appender %q", ref))
+		}
+	}
+	names := make([]string, 0, len(l.AppenderLevels))
+	for name := range l.AppenderLevels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	referenced := common.MakeStringSet(refs...)
+	for _, name := range names {
+		var lv zapcore.Level
+		if err := lv.UnmarshalText([]byte(l.AppenderLevels[name])); err != nil {
+			v.report(path+".appender_levels."+name, err)
+		}
+		if !referenced.Has(name) {
+			v.add(path+".appender_levels."+name, fmt.Sprintf("level of appender %q, which is not referenced", name))
+		}
+	}
+	if l.Filter != "" {
+		if _, err := filter.Compile(l.Filter); err != nil {
+			v.report(path+".filter", err)
+		}
+	}
+	if _, err := filter.NewMessages(l.AllowMessages, l.DenyMessages); err != nil {
+		v.report(path, err)
+	}
+	if _, err := filter.NewFields(l.FieldFilters); err != nil {
+		v.report(path+".field_filters", err)
+	}
+}
+
+// createsDirs reports whether the appender of type t creates the missing
+// directories of file, its setting key: file appenders do when told to, or
+// when file is a template.
+func createsDirs(t, key, file string, createDirs bool) bool {
+	return t == "file" && key == "file_name" && (createDirs || strings.Contains(file, "%"))
+}
+
+// checkWritable returns why the file called name cannot be written, if it
+// cannot: it must be a writable file, or be missing from a writable
+// directory, or from a directory to be created in a writable one if
+// createDirs.
+func checkWritable(name string, createDirs bool) error {
+	fi, err := os.Stat(name)
+	switch {
+	case err == nil && fi.IsDir():
+		return fmt.Errorf("%s is a directory", name)
+	case err == nil:
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	case !os.IsNotExist(err):
+		return err
+	}
+	dir := filepath.Dir(name)
+	if createDirs {
+		dir = existingDir(name)
+	}
+	f, err := ioutil.TempFile(dir, ".logn-validate")
+	switch {
+	case os.IsNotExist(err):
+		return fmt.Errorf("directory %s does not exist", dir)
+	case os.IsPermission(err):
+		return fmt.Errorf("directory %s is not writable", dir)
+	case err != nil:
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/shanexu/logn/appender/writer/encrypt"
+	"github.com/shanexu/logn/sign"
+)
+
+var verifyCommand = &command{
+	name:  "verify",
+	short: "verify the signatures of signed appenders' output",
+	run:   runVerify,
+}
+
+func runVerify(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	keyRef := fs.String("key", "env:LOGN_SIGNING_KEY", "key reference, env:NAME or file:PATH")
+	field := fs.String("field", sign.DefaultField, "signature field")
+	fs.Usage = func() {
+		fs.Output().Write([]byte("usage: logn verify [-key ref] [-field name] file ...\n"))
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	key, err := encrypt.ResolveKey(*keyRef)
+	if err != nil {
+		return err
+	}
+	for _, name := range fs.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		res, err := sign.Verify(f, key, *field)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		fmt.Fprintf(stdout, "%s: %d entries verified", name, res.Entries)
+		if len(res.Restarts) > 0 {
+			fmt.Fprintf(stdout, ", chain restarted at lines %v", res.Restarts)
+		}
+		fmt.Fprintln(stdout)
+	}
+	return nil
+}
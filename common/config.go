@@ -4,8 +4,10 @@ import (
 	"crypto/md5"
 	"errors"
 	"io/ioutil"
+	"os"
 
 	"github.com/elastic/go-ucfg"
+	"github.com/elastic/go-ucfg/parse"
 	"github.com/elastic/go-ucfg/yaml"
 )
 
@@ -22,11 +24,25 @@ type ConfigNamespace struct {
 var configOpts = []ucfg.Option{
 	ucfg.PathSep("."),
 	ucfg.ResolveEnv,
+	ucfg.Resolve(resolveHostname),
 	ucfg.VarExp,
 	ucfg.StructTag("logn-config"),
 	ucfg.ValidatorTag("logn-validate"),
 }
 
+// resolveHostname resolves ${hostname}, unless the configuration or the
+// environment sets it, to the name of the host, e.g. for the names of files.
+func resolveHostname(name string) (string, parse.Config, error) {
+	if name != "hostname" {
+		return "", parse.EnvConfig, ucfg.ErrMissing
+	}
+	h, err := os.Hostname()
+	if err != nil {
+		return "", parse.EnvConfig, err
+	}
+	return h, parse.NoopConfig, nil
+}
+
 func NewConfig() *Config {
 	return fromConfig(ucfg.New())
 }
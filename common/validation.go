@@ -9,7 +9,8 @@ import (
 
 func init() {
 	if err := ucfg.RegisterValidator("logn.oneof", func(v interface{}, params string) error {
-		if v == nil {
+		// unset settings keep their default
+		if v == nil || v == "" {
 			return nil
 		}
 		val := reflect.ValueOf(v)
@@ -7,6 +7,32 @@ import (
 type Config struct {
 	Appenders map[string][]*common.Config `logn-config:"appenders"`
 	Loggers   Loggers                     `logn-config:"loggers"`
+	Fields    map[string]interface{}      `logn-config:"fields"`
+	Hooks     []string                    `logn-config:"hooks"`
+	// Status is the level of the status logger, which reports on logn
+	// itself.
+	Status string `logn-config:"status"`
+	// Metadata selects the fields about the process added to every entry.
+	Metadata Metadata `logn-config:"metadata"`
+	// CrashOutput, if set, is the name of the file appender the Go runtime
+	// writes the crashes of the process to, such as unrecovered panics and
+	// fatal errors, which bypass loggers.
+	CrashOutput string `logn-config:"crash_output"`
+}
+
+// Metadata selects the fields about the process added to every entry:
+// hostname and pid, unless turned off, the base name of the executable, and
+// the ID of the goroutine logging, which costs a stack capture per entry.
+type Metadata struct {
+	Hostname   *bool `logn-config:"hostname"`
+	Pid        *bool `logn-config:"pid"`
+	Executable bool  `logn-config:"executable"`
+	Goroutine  bool  `logn-config:"goroutine"`
+	// Build adds the module version, Go version and VCS revision the
+	// executable was built from: to every entry with entries, or to an entry
+	// of the root logger, logged once the configuration is first in use,
+	// with banner.
+	Build string `logn-config:"build" logn-validate:"logn.oneof=entries banner"`
 }
 
 type ScanConfig struct {
@@ -20,12 +46,108 @@ type Loggers struct {
 }
 
 type RootLogger struct {
-	Level        string   `logn-config:"level"`
-	AppenderRefs []string `logn-config:"appender_refs"`
+	Level        string     `logn-config:"level"`
+	AppenderRefs []string   `logn-config:"appender_refs"`
+	Sampling     *Sampling  `logn-config:"sampling"`
+	RateLimit    *RateLimit `logn-config:"rate_limit"`
+	Dedup        *Dedup     `logn-config:"dedup"`
+	// Filter is an expression entries must match to be logged, see package
+	// filter.
+	Filter string `logn-config:"filter"`
+	// AllowMessages, if not empty, restricts the logger to entries whose
+	// message matches one of these regular expressions.
+	AllowMessages []string `logn-config:"allow_messages"`
+	// DenyMessages drops the entries whose message matches one of these
+	// regular expressions.
+	DenyMessages []string `logn-config:"deny_messages"`
+	// FieldFilters select entries by their fields.
+	FieldFilters []FieldFilter `logn-config:"field_filters"`
+	// Caller tells whether entries carry the file and line of the logging
+	// call, which they do by default.
+	Caller *bool `logn-config:"caller"`
+	// StacktraceLevel is the level from which entries carry a stacktrace,
+	// error by default; "off" disables stacktraces.
+	StacktraceLevel string `logn-config:"stacktrace_level"`
+	// AppenderLevels raise the level of the logger for some of its appender
+	// refs, by appender name.
+	AppenderLevels map[string]string `logn-config:"appender_levels"`
+	// Sequence numbers the entries of the logger.
+	Sequence *Sequence `logn-config:"sequence"`
 }
 
 type Logger struct {
-	Name         string   `logn-config:"name" logn-validate:"required"`
-	Level        string   `logn-config:"level"`
-	AppenderRefs []string `logn-config:"appender_refs"`
+	Name            string        `logn-config:"name" logn-validate:"required"`
+	Level           string        `logn-config:"level"`
+	AppenderRefs    []string      `logn-config:"appender_refs"`
+	Sampling        *Sampling     `logn-config:"sampling"`
+	RateLimit       *RateLimit    `logn-config:"rate_limit"`
+	Dedup           *Dedup        `logn-config:"dedup"`
+	Audit           *Audit        `logn-config:"audit"`
+	Filter          string        `logn-config:"filter"`
+	AllowMessages   []string      `logn-config:"allow_messages"`
+	DenyMessages    []string      `logn-config:"deny_messages"`
+	FieldFilters    []FieldFilter `logn-config:"field_filters"`
+	Caller          *bool         `logn-config:"caller"`
+	StacktraceLevel string        `logn-config:"stacktrace_level"`
+	// AppenderLevels raise the level of the logger for some of its appender
+	// refs, by appender name. Loggers without appender refs take the levels
+	// of root along with its refs, unless they set some.
+	AppenderLevels map[string]string `logn-config:"appender_levels"`
+	// Sequence numbers the entries of the logger. Audit loggers number
+	// theirs anyway.
+	Sequence *Sequence `logn-config:"sequence"`
+}
+
+// Sequence numbers the entries of a logger as they are written, so that
+// consumers can detect the entries lost or reordered on their way. Scope
+// logger, the default, numbers the entries of each logger on its own, and
+// scope global those of all the loggers with this scope in a single sequence.
+type Sequence struct {
+	Key   string `logn-config:"key"`
+	Scope string `logn-config:"scope" logn-validate:"logn.oneof=logger global"`
+}
+
+// Sampling throttles a logger: per tick, the first Initial entries with the
+// same level and message are logged, then every Thereafter-th one.
+type Sampling struct {
+	Initial    int    `logn-config:"initial" logn-validate:"min=0"`
+	Thereafter int    `logn-config:"thereafter" logn-validate:"min=0"`
+	Tick       string `logn-config:"tick"`
+}
+
+// RateLimit caps the entries a logger emits to PerSecond per level. The number
+// of suppressed entries is reported every SummaryInterval.
+type RateLimit struct {
+	PerSecond       int    `logn-config:"per_second" logn-validate:"min=1"`
+	SummaryInterval string `logn-config:"summary_interval"`
+}
+
+// Dedup suppresses entries identical in level, message and fields to one
+// logged less than Window ago, tracking at most MaxKeys distinct entries.
+type Dedup struct {
+	Window  string `logn-config:"window"`
+	MaxKeys int    `logn-config:"max_keys" logn-validate:"min=0"`
+}
+
+// Audit makes a logger an audit logger: sampling, rate limiting and
+// deduplication do not apply, entries carry a sequence number and each one is
+// written and synced before the logging call returns.
+type Audit struct {
+	// OnFailure is what happens when an appender fails: "error" reports the
+	// failure and moves on, "block" retries until the appenders succeed.
+	OnFailure string `logn-config:"on_failure" logn-validate:"logn.oneof=error block"`
+	// RetryInterval is the delay between attempts under "block".
+	RetryInterval string `logn-config:"retry_interval"`
+}
+
+// FieldFilter matches entries on a field: it matches when the field is
+// present and, for those set, its value equals Equals, starts with Prefix and
+// matches the regular expression Matches. Entries matching a "drop" filter
+// (the default) are dropped; when there are "keep" filters, entries must match one of them.
+type FieldFilter struct {
+	Field   string `logn-config:"field" logn-validate:"required"`
+	Equals  string `logn-config:"equals"`
+	Prefix  string `logn-config:"prefix"`
+	Matches string `logn-config:"matches"`
+	Action  string `logn-config:"action"`
 }
@@ -0,0 +1,17 @@
+package core
+
+import "time"
+
+// Clock tells the time log entries are stamped with.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the wall clock, which a Core uses unless told otherwise.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
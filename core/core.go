@@ -1,11 +1,49 @@
 package core
 
-import "github.com/shanexu/logn/common"
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/shanexu/logn/common"
+)
 
 type Core interface {
 	GetLogger(name ...string) Logger
+	// GetFieldLogger returns the strongly-typed variant of GetLogger, whose
+	// logging calls do not allocate beyond what their fields need.
+	GetFieldLogger(name ...string) FieldLogger
 	Update(rawConfig *common.Config) error
-	RedirectStdLog()
+	// RedirectStdLog routes the standard library's package-global logger
+	// to the logger named "stdlog" at InfoLevel, and returns a function
+	// restoring the previous output, prefix and flags.
+	RedirectStdLog() (restore func(), err error)
+	// AppenderErrors returns, per appender name, the number of entries the
+	// appender failed to encode or write since it was created.
+	AppenderErrors() map[string]uint64
+	// Stats returns a snapshot of the activity of the core.
+	Stats() Stats
+	// AppenderHealth returns, per appender name, a snapshot of the health
+	// of the appender.
+	AppenderHealth() map[string]AppenderHealth
+	// SetClock makes the core stamp entries with the time told by clock,
+	// e.g. to freeze time in tests. A nil clock restores the wall clock.
+	SetClock(clock Clock)
+	// Close drains the queues of the appenders, syncs their files and
+	// closes them, releasing their files, connections and goroutines, e.g.
+	// before the process exits. The entries logged afterwards are lost.
+	Close() error
+	// Reopen closes and opens again the files the appenders write to, e.g.
+	// once logrotate moved them away.
+	Reopen() error
+	// BoostLevel makes the logger called name, "" being root, write the
+	// entries from level on for d, or until the returned function is called,
+	// whatever its configured level, which is restored afterwards.
+	BoostLevel(name string, level Level, d time.Duration) (cancel func())
+	// DebugOn makes the logger called name, "" being root, write debug
+	// entries for d whenever the process receives sig, restoring its level
+	// afterwards. It returns the function stopping the handling of sig.
+	DebugOn(sig os.Signal, name string, d time.Duration) (stop func())
 	Logger
 }
 
@@ -98,5 +136,39 @@ type Logger interface {
 	// Field such as logp.Stringer.
 	DPanicw(msg string, keysAndValues ...interface{})
 
+	// Enabled reports whether an entry at level would be written by at least
+	// one appender. Use it to guard expensive argument construction.
+	Enabled(level Level) bool
+
+	// IsDebug is shorthand for Enabled(DebugLevel).
+	IsDebug() bool
+
+	// With adds a variadic number of fields to the logging context and returns
+	// the child logger. It accepts the same key-value pairs as Infow; the
+	// parent logger is left unchanged.
+	With(args ...interface{}) Logger
+
+	// Named adds a sub-scope to the logger's name. Names are joined by
+	// periods, so logger "a" named "b" becomes "a.b".
+	Named(name string) Logger
+
+	// WithContext returns a child logger carrying the diagnostic context
+	// (see package mdc) bound to ctx. Without one the logger itself is
+	// returned.
+	WithContext(ctx context.Context) Logger
+
+	// WithCallerSkip returns a child logger skipping skip more stack frames
+	// when reporting the caller, so that wrappers around the logger report
+	// their own callers rather than themselves.
+	WithCallerSkip(skip int) Logger
+
+	// WithLevel returns a child logger writing the entries from level on,
+	// whatever the configured level, e.g. to debug a single request.
+	WithLevel(level Level) Logger
+
+	// Desugar returns the strongly-typed FieldLogger sharing this logger's
+	// appenders and level.
+	Desugar() FieldLogger
+
 	Sync() error
 }
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+var (
+	exitLocker sync.Mutex
+	exitHooks  []func()
+	exitFunc   = os.Exit
+)
+
+// RegisterExitHook registers f to run before the process exits because of a
+// Fatal entry. Hooks run in reverse registration order, like deferred calls.
+func RegisterExitHook(f func()) {
+	exitLocker.Lock()
+	defer exitLocker.Unlock()
+	exitHooks = append(exitHooks, f)
+}
+
+// SetExitFunc replaces os.Exit as the function terminating the process after
+// a Fatal entry, and returns a function restoring the previous one. Tests may
+// install a function that records the code instead of exiting; the Fatal
+// call then returns to its caller.
+func SetExitFunc(f func(code int)) (restore func()) {
+	exitLocker.Lock()
+	defer exitLocker.Unlock()
+	prev := exitFunc
+	exitFunc = f
+	return func() {
+		exitLocker.Lock()
+		defer exitLocker.Unlock()
+		exitFunc = prev
+	}
+}
+
+// Exit runs the registered exit hooks, then calls the exit function with
+// code. A panicking hook does not prevent the remaining ones from running.
+func Exit(code int) {
+	exitLocker.Lock()
+	hooks := make([]func(), len(exitHooks))
+	copy(hooks, exitHooks)
+	exit := exitFunc
+	exitLocker.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		runExitHook(hooks[i])
+	}
+	exit(code)
+}
+
+func runExitHook(f func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(os.Stderr, "logn: exit hook panicked: %v\n", r)
+		}
+	}()
+	f()
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"context"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Field is a strongly-typed key-value pair used by FieldLogger. Constructing
+// a Field does not allocate for primitive values.
+type Field = zapcore.Field
+
+// FieldLogger is the non-sugared logging surface. It only accepts Fields,
+// which avoids the interface{} boxing of the key-value methods on Logger.
+type FieldLogger interface {
+	// Debug logs a message at DebugLevel with the given fields.
+	Debug(msg string, fields ...Field)
+
+	// Info logs a message at InfoLevel with the given fields.
+	Info(msg string, fields ...Field)
+
+	// Warn logs a message at WarnLevel with the given fields.
+	Warn(msg string, fields ...Field)
+
+	// Error logs a message at ErrorLevel with the given fields.
+	Error(msg string, fields ...Field)
+
+	// DPanic logs a message at DPanicLevel with the given fields. In
+	// development, the logger then panics.
+	DPanic(msg string, fields ...Field)
+
+	// Panic logs a message at PanicLevel with the given fields, then panics.
+	Panic(msg string, fields ...Field)
+
+	// Fatal logs a message at FatalLevel with the given fields, then calls
+	// os.Exit(1).
+	Fatal(msg string, fields ...Field)
+
+	// Enabled reports whether an entry at level would be written by at least
+	// one appender.
+	Enabled(level Level) bool
+
+	// IsDebug is shorthand for Enabled(DebugLevel).
+	IsDebug() bool
+
+	// With returns a child logger carrying the given fields on every entry.
+	With(fields ...Field) FieldLogger
+
+	// Named adds a sub-scope to the logger's name.
+	Named(name string) FieldLogger
+
+	// WithContext returns a child logger carrying the diagnostic context
+	// (see package mdc) bound to ctx.
+	WithContext(ctx context.Context) FieldLogger
+
+	// WithCallerSkip returns a child logger skipping skip more stack frames
+	// when reporting the caller.
+	WithCallerSkip(skip int) FieldLogger
+
+	// WithLevel returns a child logger writing the entries from level on,
+	// whatever the configured level.
+	WithLevel(level Level) FieldLogger
+
+	// Sugar returns the key-value Logger sharing this logger's appenders.
+	Sugar() Logger
+
+	Sync() error
+}
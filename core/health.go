@@ -0,0 +1,33 @@
+package core
+
+import "time"
+
+// AppenderState tells whether an appender writes its entries.
+type AppenderState string
+
+const (
+	// AppenderConnected is the state of the appenders whose last write
+	// succeeded, or which wrote nothing yet.
+	AppenderConnected AppenderState = "connected"
+	// AppenderDegraded is the state of the appenders whose last write
+	// failed.
+	AppenderDegraded AppenderState = "degraded"
+	// AppenderOpenFailed is the state of the appenders whose circuit breaker
+	// opened after failed writes: their entries are dropped until a write
+	// probing the sink succeeds.
+	AppenderOpenFailed AppenderState = "open_failed"
+)
+
+// AppenderHealth is a snapshot of the health of an appender. Writes handed
+// to async or sharded appenders succeed once queued.
+type AppenderHealth struct {
+	State AppenderState `json:"state"`
+	// LastError is the last error of the appender, if any, and
+	// LastErrorTime when it happened.
+	LastError     string    `json:"last_error,omitempty"`
+	LastErrorTime time.Time `json:"last_error_time,omitempty"`
+	// LastSuccess is the time of the last entry written, zero if none was.
+	LastSuccess time.Time `json:"last_success,omitempty"`
+	// Errors counts the entries the appender failed to encode or write.
+	Errors uint64 `json:"errors"`
+}
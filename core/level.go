@@ -0,0 +1,16 @@
+package core
+
+import "go.uber.org/zap/zapcore"
+
+// Level is a logging priority. Higher levels are more important.
+type Level = zapcore.Level
+
+const (
+	DebugLevel  = zapcore.DebugLevel
+	InfoLevel   = zapcore.InfoLevel
+	WarnLevel   = zapcore.WarnLevel
+	ErrorLevel  = zapcore.ErrorLevel
+	DPanicLevel = zapcore.DPanicLevel
+	PanicLevel  = zapcore.PanicLevel
+	FatalLevel  = zapcore.FatalLevel
+)
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	namespacesMu sync.RWMutex
+	namespaces   = map[string]Core{}
+)
+
+// RegisterNamespace makes c available under namespace, which must not be
+// taken.
+func RegisterNamespace(namespace string, c Core) error {
+	namespacesMu.Lock()
+	defer namespacesMu.Unlock()
+	if _, exists := namespaces[namespace]; exists {
+		return fmt.Errorf("namespace %q is already registered", namespace)
+	}
+	namespaces[namespace] = c
+	return nil
+}
+
+// Namespace returns the Core registered under namespace, or nil.
+func Namespace(namespace string) Core {
+	namespacesMu.RLock()
+	defer namespacesMu.RUnlock()
+	return namespaces[namespace]
+}
+
+// UnregisterNamespace removes the Core registered under namespace and returns
+// it, or nil if there is none.
+func UnregisterNamespace(namespace string) Core {
+	namespacesMu.Lock()
+	defer namespacesMu.Unlock()
+	c := namespaces[namespace]
+	delete(namespaces, namespace)
+	return c
+}
+
+// Namespaces returns the registered namespaces in order.
+func Namespaces() []string {
+	namespacesMu.RLock()
+	defer namespacesMu.RUnlock()
+	names := make([]string, 0, len(namespaces))
+	for name := range namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,32 @@
+package core
+
+// Stats is a snapshot of the activity of a Core.
+type Stats struct {
+	// Loggers is the number of named loggers, whether configured or obtained
+	// through GetLogger.
+	Loggers int
+	// Entries counts, per level name, the entries written since the Core
+	// was created.
+	Entries map[string]uint64
+	// AppenderErrors counts, per appender name, the entries the appender
+	// failed to encode or write.
+	AppenderErrors map[string]uint64
+	// Appenders tells, per appender name, what the appender wrote.
+	Appenders map[string]AppenderStats
+}
+
+// AppenderStats is a snapshot of the work of an appender.
+type AppenderStats struct {
+	// Entries and Bytes count the entries the appender wrote, and their
+	// encoded size. Writes handed to async appenders count once queued.
+	Entries uint64
+	Bytes   uint64
+	// EncodeErrors and WriteErrors count the entries the appender failed to
+	// encode, and to write.
+	EncodeErrors uint64
+	WriteErrors  uint64
+	// QueueDepth and QueueCapacity are the number of writes queued by an
+	// async appender, and how many can be; both are 0 for other appenders.
+	QueueDepth    int
+	QueueCapacity int
+}
@@ -0,0 +1,46 @@
+package zap
+
+import (
+	"sync/atomic"
+
+	"github.com/shanexu/logn/appender"
+)
+
+// appenderRegistry holds the appenders of a Core by name. The map it holds
+// is never mutated: changes store a modified copy, so that readers need no
+// lock.
+type appenderRegistry struct {
+	v atomic.Value
+}
+
+func newAppenderRegistry() *appenderRegistry {
+	r := &appenderRegistry{}
+	r.v.Store(map[string]*appender.Appender{})
+	return r
+}
+
+// load returns the current appenders, which must not be modified.
+func (r *appenderRegistry) load() map[string]*appender.Appender {
+	return r.v.Load().(map[string]*appender.Appender)
+}
+
+// put adds a to the registry under name, unless an appender has this name
+// already. Concurrent calls must be serialized by the caller.
+func (r *appenderRegistry) put(name string, a *appender.Appender) bool {
+	old := r.load()
+	if _, exist := old[name]; exist {
+		return false
+	}
+	m := make(map[string]*appender.Appender, len(old)+1)
+	for k, v := range old {
+		m[k] = v
+	}
+	m[name] = a
+	r.v.Store(m)
+	return true
+}
+
+// swap replaces the appenders by those of other.
+func (r *appenderRegistry) swap(other *appenderRegistry) {
+	r.v.Store(other.load())
+}
@@ -0,0 +1,111 @@
+package zap
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	cfg "github.com/shanexu/logn/config"
+	"github.com/shanexu/logn/internal/checked"
+	"github.com/shanexu/logn/status"
+)
+
+const (
+	defaultAuditRetryInterval = time.Second
+
+	sequenceKey = "seq"
+)
+
+// audit makes a logger write each entry synchronously, numbered and synced
+// to its appenders. Write errors are returned, so that zap reports them on
+// stderr, or, with block set, retried until they succeed. A retry writes the
+// entry again to every appender, the sequence number telling duplicates
+// apart.
+type audit struct {
+	block         bool
+	retryInterval time.Duration
+}
+
+func newAudit(config *cfg.Audit) (*audit, error) {
+	if config == nil {
+		return nil, nil
+	}
+	a := &audit{
+		block:         config.OnFailure == "block",
+		retryInterval: defaultAuditRetryInterval,
+	}
+	if config.RetryInterval != "" {
+		var err error
+		a.retryInterval, err = time.ParseDuration(config.RetryInterval)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// wrap wraps zc, which must return write errors, numbering entries with seq.
+func (a *audit) wrap(zc zapcore.Core, seq *uint64) zapcore.Core {
+	if a == nil {
+		return zc
+	}
+	return &auditCore{Core: zc, state: &auditState{audit: a, seq: seq}}
+}
+
+// auditState is shared by a logger and the children derived from it.
+type auditState struct {
+	*audit
+	mu  sync.Mutex
+	seq *uint64
+}
+
+type auditCore struct {
+	zapcore.Core
+	state *auditState
+}
+
+func (c *auditCore) With(fields []zapcore.Field) zapcore.Core {
+	return &auditCore{Core: c.Core.With(fields), state: c.state}
+}
+
+func (c *auditCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *auditCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	s := c.state
+	// serialized so that entries are written in sequence order
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seq := atomic.AddUint64(s.seq, 1)
+	fs := make([]zapcore.Field, 0, len(fields)+1)
+	fs = append(fs, fields...)
+	fs = append(fs, zap.Uint64(sequenceKey, seq))
+	for attempt := 0; ; attempt++ {
+		err := checked.Write(c.Core, ent, fs)
+		if err == nil {
+			err = c.Core.Sync()
+		}
+		if err == nil {
+			if attempt > 0 {
+				status.Infof("audit logger %q wrote entry %d after %d retries", ent.LoggerName, seq, attempt)
+			}
+			return nil
+		}
+		if !s.block {
+			status.Errorf("audit logger %q failed to write entry %d: %v", ent.LoggerName, seq, err)
+			return err
+		}
+		if attempt == 0 {
+			status.Errorf("audit logger %q failed to write entry %d, blocking until it succeeds: %v", ent.LoggerName, seq, err)
+		}
+		time.Sleep(s.retryInterval)
+	}
+}
@@ -0,0 +1,155 @@
+package zap
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// noBoost is the level of boosts with no window open, enabling no entry.
+const noBoost = int32(zapcore.FatalLevel + 1)
+
+// boost lowers the level of the loggers of a name for windows of time. Like
+// levels, boosts are kept across configuration updates.
+type boost struct {
+	// level is the lowest level of the open windows, noBoost without any
+	level int32
+
+	mu      sync.Mutex
+	windows map[*boostWindow]bool
+}
+
+type boostWindow struct {
+	level zapcore.Level
+}
+
+func (b *boost) Enabled(lvl zapcore.Level) bool {
+	return int32(lvl) >= atomic.LoadInt32(&b.level)
+}
+
+// open opens a window at level, returning the function closing it.
+func (b *boost) open(level zapcore.Level) (close func()) {
+	w := &boostWindow{level: level}
+	b.mu.Lock()
+	b.windows[w] = true
+	b.update()
+	b.mu.Unlock()
+	return func() {
+		b.mu.Lock()
+		delete(b.windows, w)
+		b.update()
+		b.mu.Unlock()
+	}
+}
+
+func (b *boost) update() {
+	min := noBoost
+	for w := range b.windows {
+		if int32(w.level) < min {
+			min = int32(w.level)
+		}
+	}
+	atomic.StoreInt32(&b.level, min)
+}
+
+// boostOf returns the boost of the loggers with the given name, "" being
+// root, creating it if need be.
+func (ls *levels) boostOf(name string) *boost {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	b, ok := ls.boosts[name]
+	if !ok {
+		b = &boost{level: noBoost, windows: map[*boostWindow]bool{}}
+		ls.boosts[name] = b
+	}
+	return b
+}
+
+// boostedLevel enables the entries its level does, and those the boosts of
+// the name of the logger, and of the logger whose level it shares, do.
+type boostedLevel struct {
+	zapcore.LevelEnabler
+	boosts []*boost
+}
+
+func (l boostedLevel) Enabled(lvl zapcore.Level) bool {
+	for _, b := range l.boosts {
+		if b.Enabled(lvl) {
+			return true
+		}
+	}
+	return l.LevelEnabler.Enabled(lvl)
+}
+
+// boostedLevel returns the level of the logger of spec, boosted by the boosts
+// of its name and of the logger whose level it shares.
+func (c *Core) boostedLevel(spec loggerSpec) zapcore.LevelEnabler {
+	boosts := []*boost{c.levels.boostOf(spec.name)}
+	if spec.levelName != spec.name {
+		boosts = append(boosts, c.levels.boostOf(spec.levelName))
+	}
+	return boostedLevel{LevelEnabler: spec.level, boosts: boosts}
+}
+
+// BoostLevel makes the logger called name, "" being root, write the entries
+// from level on for d, or until the returned function is called, whatever its
+// configured level. Boosting root boosts the loggers sharing its level too.
+// Boosts overlap: the lowest level of those in effect applies, and the
+// configured level is back once all are over, updates of the configuration
+// included. The boosts are logged by the logger.
+func (c *Core) BoostLevel(name string, level zapcore.Level, d time.Duration) (cancel func()) {
+	name = normalizeName(name)
+	l := c.getLogger(name, true).load().base
+	// the boosts are logged at info, or at their level if higher, which the
+	// window enables
+	logLevel := zapcore.InfoLevel
+	if level > logLevel {
+		logLevel = level
+	}
+	closeWindow := c.levels.boostOf(name).open(level)
+	l.Log(logLevel, "level boosted", zap.Stringer("boost", level), zap.Duration("duration", d))
+	var once sync.Once
+	end := func() {
+		once.Do(func() {
+			l.Log(logLevel, "level boost ended", zap.Stringer("boost", level))
+			closeWindow()
+		})
+	}
+	timer := time.AfterFunc(d, end)
+	return func() {
+		timer.Stop()
+		end()
+	}
+}
+
+// DebugOn makes the logger called name, "" being root, write debug entries
+// for d whenever the process receives sig, e.g. SIGUSR1, its configured level
+// being restored afterwards. The boosts are logged by the logger. DebugOn
+// returns the function stopping the handling of sig.
+func (c *Core) DebugOn(sig os.Signal, name string, d time.Duration) (stop func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sig)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ch:
+				c.BoostLevel(name, zapcore.DebugLevel, d)
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package zap_test
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanexu/logn/core"
+)
+
+func TestDebugOn(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: warn
+    appender_refs:
+      - FILE
+  logger:
+    - name: http
+    - name: db
+      level: error
+`)
+	http := c.GetLogger("http").With("k", "v")
+	db := c.GetLogger("db")
+	stop := c.DebugOn(syscall.SIGUSR1, "", 100*time.Millisecond)
+	defer stop()
+
+	assert.Nil(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	assert.Eventually(t, http.IsDebug, time.Second, time.Millisecond)
+	http.Debug("boosted")
+	db.Debug("dropped")
+	assert.False(t, db.IsDebug())
+
+	// the level is restored once the boost ends
+	assert.Eventually(t, func() bool { return !http.IsDebug() }, time.Second, time.Millisecond)
+	http.Debug("dropped")
+	assert.True(t, http.Enabled(core.WarnLevel))
+
+	ls := lines()
+	if assert.Len(t, ls, 3) {
+		assert.Contains(t, ls[0], `"msg":"level boosted"`)
+		assert.Contains(t, ls[0], `"boost":"debug"`)
+		assert.Contains(t, ls[1], `"msg":"boosted"`)
+		assert.Contains(t, ls[2], `"msg":"level boost ended"`)
+	}
+}
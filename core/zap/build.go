@@ -0,0 +1,65 @@
+package zap
+
+import (
+	"runtime/debug"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const buildKey = "build"
+
+// buildInfo is what the executable tells of how it was built.
+type buildInfo struct {
+	version   string
+	goVersion string
+	revision  string
+	time      string
+	modified  bool
+}
+
+// readBuildInfo reads the build information embedded in the executable, if
+// any.
+func readBuildInfo() (buildInfo, bool) {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return buildInfo{}, false
+	}
+	b := buildInfo{version: bi.Main.Version, goVersion: bi.GoVersion}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			b.revision = s.Value
+		case "vcs.time":
+			b.time = s.Value
+		case "vcs.modified":
+			b.modified = s.Value == "true"
+		}
+	}
+	return b, true
+}
+
+func (b buildInfo) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	if b.version != "" {
+		enc.AddString("version", b.version)
+	}
+	enc.AddString("go", b.goVersion)
+	if b.revision != "" {
+		enc.AddString("revision", b.revision)
+		enc.AddBool("modified", b.modified)
+	}
+	if b.time != "" {
+		enc.AddString("time", b.time)
+	}
+	return nil
+}
+
+// buildField returns the field holding the build information, if the
+// executable embeds it.
+func buildField() (zap.Field, bool) {
+	b, ok := readBuildInfo()
+	if !ok {
+		return zap.Skip(), false
+	}
+	return zap.Object(buildKey, b), true
+}
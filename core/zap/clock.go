@@ -0,0 +1,43 @@
+package zap
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/shanexu/logn/core"
+)
+
+// clock is the zapcore.Clock of the loggers of a Core. Like stats it is kept
+// across configuration updates, so that SetClock applies to every logger
+// without rebuilding them.
+type clock struct {
+	v atomic.Value
+}
+
+// clockBox lets clocks of different types be stored in the same atomic.Value.
+type clockBox struct {
+	core.Clock
+}
+
+func newClock() *clock {
+	c := &clock{}
+	c.v.Store(clockBox{core.SystemClock})
+	return c
+}
+
+func (c *clock) Now() time.Time {
+	return c.v.Load().(clockBox).Now()
+}
+
+func (c *clock) NewTicker(d time.Duration) *time.Ticker {
+	return time.NewTicker(d)
+}
+
+// SetClock makes the core stamp entries with the time told by clk. A nil clk
+// restores the wall clock.
+func (c *Core) SetClock(clk core.Clock) {
+	if clk == nil {
+		clk = core.SystemClock
+	}
+	c.clock.v.Store(clockBox{clk})
+}
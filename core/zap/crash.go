@@ -0,0 +1,34 @@
+package zap
+
+import (
+	"fmt"
+
+	"github.com/shanexu/logn/appender"
+)
+
+// applyCrashOutput makes the Go runtime write the crashes of the process to
+// the file of the crash output appender, if the configuration names one. The
+// crash output is the process's: it is kept when a configuration without one
+// is put in use.
+func (c *Core) applyCrashOutput() error {
+	if c.crashOutput == nil {
+		return nil
+	}
+	f, ok := c.crashOutput.File()
+	if !ok {
+		return fmt.Errorf("crash_output: appender %q does not write to a file as it is", c.crashOutput.Name)
+	}
+	return setCrashOutput(f)
+}
+
+// crashAppender returns the appender named name for crash_output, if any.
+func (c *Core) crashAppender(name string) (*appender.Appender, error) {
+	if name == "" {
+		return nil, nil
+	}
+	a, err := c.getAppender(name)
+	if err != nil {
+		return nil, fmt.Errorf("crash_output: %v", err)
+	}
+	return a, nil
+}
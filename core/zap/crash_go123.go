@@ -0,0 +1,13 @@
+//go:build go1.23
+// +build go1.23
+
+package zap
+
+import (
+	"os"
+	"runtime/debug"
+)
+
+func setCrashOutput(f *os.File) error {
+	return debug.SetCrashOutput(f, debug.CrashOptions{})
+}
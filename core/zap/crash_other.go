@@ -0,0 +1,13 @@
+//go:build !go1.23
+// +build !go1.23
+
+package zap
+
+import (
+	"errors"
+	"os"
+)
+
+func setCrashOutput(*os.File) error {
+	return errors.New("crash_output needs Go 1.23 or later")
+}
@@ -0,0 +1,79 @@
+//go:build go1.23
+// +build go1.23
+
+package zap_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanexu/logn/common"
+	"github.com/shanexu/logn/core/zap"
+)
+
+func crashConfig(t *testing.T, file, crashOutput string) *common.Config {
+	rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  file:
+    - name: CRASH
+      file_name: %s
+      encoder:
+        json:
+  console:
+    - name: CONSOLE
+      encoder:
+        console:
+crash_output: %s
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - CONSOLE
+`, file, crashOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rawConfig
+}
+
+func TestCrashOutput(t *testing.T) {
+	if file := os.Getenv("LOGN_CRASH_FILE"); file != "" {
+		if _, err := zap.New(crashConfig(t, file, "CRASH")); err != nil {
+			t.Fatal(err)
+		}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			panic("crash test")
+		}()
+		<-done
+		return
+	}
+
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "crash.log")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestCrashOutput$")
+	cmd.Env = append(os.Environ(), "LOGN_CRASH_FILE="+file)
+	assert.Error(t, cmd.Run(), "the process crashes")
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, string(data), "panic: crash test")
+	assert.Contains(t, string(data), "goroutine")
+
+	_, err = zap.New(crashConfig(t, file, "CONSOLE"))
+	assert.EqualError(t, err, `crash_output: appender "CONSOLE" does not write to a file as it is`)
+	_, err = zap.New(crashConfig(t, file, "MISSING"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,215 @@
+package zap
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	cfg "github.com/shanexu/logn/config"
+	"github.com/shanexu/logn/internal/checked"
+	"github.com/shanexu/logn/metrics"
+)
+
+const (
+	defaultDedupWindow  = 10 * time.Second
+	defaultDedupMaxKeys = 1000
+
+	repeatedKey = "repeated"
+)
+
+// dedup suppresses entries identical to one logged less than window ago. The
+// first entry is written immediately; once the window has passed it is
+// written again with a repeated field counting the suppressed copies, by a
+// timer unless an identical entry comes first.
+type dedup struct {
+	window  time.Duration
+	maxKeys int
+}
+
+func newDedup(config *cfg.Dedup) (*dedup, error) {
+	if config == nil {
+		return nil, nil
+	}
+	d := &dedup{window: defaultDedupWindow, maxKeys: config.MaxKeys}
+	if config.Window != "" {
+		var err error
+		d.window, err = time.ParseDuration(config.Window)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if d.maxKeys == 0 {
+		d.maxKeys = defaultDedupMaxKeys
+	}
+	return d, nil
+}
+
+// wrap wraps zc, registering the state of the logger to fl for its pending
+// summaries to be written on Core.Sync and Core.Close.
+func (d *dedup) wrap(zc zapcore.Core, fl *flushers) zapcore.Core {
+	if d == nil {
+		return zc
+	}
+	s := &dedupState{dedup: d, seen: map[uint64]*dedupEntry{}}
+	fl.add(s)
+	return &dedupCore{Core: zc, state: s, ctxHash: fnvOffset64}
+}
+
+type dedupEntry struct {
+	core     zapcore.Core
+	ent      zapcore.Entry
+	fields   []zapcore.Field
+	emitted  time.Time
+	repeated int
+}
+
+// dedupState is shared by a logger and the children derived from it.
+type dedupState struct {
+	*dedup
+	mu        sync.Mutex
+	seen      map[uint64]*dedupEntry
+	lastSweep time.Time
+	// timer writes the summaries of the entries whose window ended, nil
+	// unless copies were suppressed since
+	timer *time.Timer
+}
+
+type dedupCore struct {
+	zapcore.Core
+	state   *dedupState
+	ctxHash uint64
+}
+
+func (c *dedupCore) With(fields []zapcore.Field) zapcore.Core {
+	h := fieldHash{sum: c.ctxHash}
+	h.writeFields(fields)
+	return &dedupCore{Core: c.Core.With(fields), state: c.state, ctxHash: h.sum}
+}
+
+func (c *dedupCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *dedupCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	key := c.hash(ent, fields)
+	s := c.state
+
+	s.mu.Lock()
+	var pending []*dedupEntry
+	if ent.Time.Sub(s.lastSweep) >= s.window {
+		// the entries are swept at most once per window
+		pending = s.sweep(ent.Time, false)
+	}
+	e, ok := s.seen[key]
+	suppress := ok && ent.Time.Sub(e.emitted) < s.window
+	if suppress {
+		e.repeated++
+		s.schedule()
+	} else {
+		if ok && e.repeated > 0 {
+			pending = append(pending, e)
+		}
+		if ok || len(s.seen) < s.maxKeys {
+			// the fields are copied, the caller being free to reuse them
+			fs := make([]zapcore.Field, len(fields))
+			copy(fs, fields)
+			s.seen[key] = &dedupEntry{core: c.Core, ent: ent, fields: fs, emitted: ent.Time}
+		}
+	}
+	s.mu.Unlock()
+
+	for _, p := range pending {
+		p.flush()
+	}
+	if suppress {
+		metrics.Dropped(ent.LoggerName, ent.Level, metrics.DropDedup)
+		return nil
+	}
+	return checked.Write(c.Core, ent, fields)
+}
+
+func (c *dedupCore) Sync() error {
+	c.state.flush()
+	return c.Core.Sync()
+}
+
+// schedule makes sure the summaries of the entries whose copies are
+// suppressed are written once their window ended, whether or not identical
+// entries keep coming. The caller must hold s.mu.
+func (s *dedupState) schedule() {
+	if s.timer == nil {
+		s.timer = time.AfterFunc(s.window, s.expire)
+	}
+}
+
+// expire writes the summaries of the entries whose window ended, scheduling
+// the next ones if copies of others are still suppressed.
+func (s *dedupState) expire() {
+	s.mu.Lock()
+	s.timer = nil
+	pending := s.sweep(time.Now(), false)
+	for _, e := range s.seen {
+		if e.repeated > 0 {
+			s.schedule()
+			break
+		}
+	}
+	s.mu.Unlock()
+	for _, p := range pending {
+		p.flush()
+	}
+}
+
+// flush writes the summaries of all the entries whose copies are suppressed
+// right away.
+func (s *dedupState) flush() {
+	s.mu.Lock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	pending := s.sweep(time.Now(), true)
+	s.mu.Unlock()
+	for _, p := range pending {
+		p.flush()
+	}
+}
+
+// sweep removes the entries whose window ended before now, or every entry
+// with all set, and returns those having suppressed copies to report. The
+// caller must hold s.mu.
+func (s *dedupState) sweep(now time.Time, all bool) []*dedupEntry {
+	s.lastSweep = now
+	var pending []*dedupEntry
+	for k, e := range s.seen {
+		if !all && now.Sub(e.emitted) < s.window {
+			continue
+		}
+		delete(s.seen, k)
+		if e.repeated > 0 {
+			pending = append(pending, e)
+		}
+	}
+	return pending
+}
+
+func (e *dedupEntry) flush() {
+	fields := make([]zapcore.Field, 0, len(e.fields)+1)
+	fields = append(fields, e.fields...)
+	fields = append(fields, zap.Int(repeatedKey, e.repeated))
+	checked.Write(e.core, e.ent, fields)
+}
+
+func (c *dedupCore) hash(ent zapcore.Entry, fields []zapcore.Field) uint64 {
+	h := fieldHash{sum: c.ctxHash}
+	h.writeUint64(uint64(ent.Level + zapcore.FatalLevel))
+	h.writeString(ent.LoggerName)
+	h.writeString(ent.Message)
+	h.writeFields(fields)
+	return h.sum
+}
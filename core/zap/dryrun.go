@@ -0,0 +1,152 @@
+package zap
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/appender"
+	"github.com/shanexu/logn/common"
+)
+
+// dryRunLevels are the levels of the synthetic entries fed to each logger by
+// DryRun. DPanic, Panic and Fatal entries would end the run.
+var dryRunLevels = []zapcore.Level{
+	zapcore.DebugLevel,
+	zapcore.InfoLevel,
+	zapcore.WarnLevel,
+	zapcore.ErrorLevel,
+}
+
+// DryRunReport is what a dry run of a configuration found.
+type DryRunReport struct {
+	// Routes maps the loggers, "" being root, to the levels of their
+	// synthetic entries each appender wrote.
+	Routes map[string]map[string][]zapcore.Level
+	// Appenders maps the appenders to what they wrote.
+	Appenders map[string]*DryRunAppender
+}
+
+// DryRunAppender is what an appender wrote during a dry run.
+type DryRunAppender struct {
+	Entries int
+	Bytes   int
+	// Errors are the problems found in the encoded entries.
+	Errors []string
+}
+
+// Loggers returns the names of the loggers of r, root first.
+func (r *DryRunReport) Loggers() []string {
+	names := make([]string, 0, len(r.Routes))
+	for name := range r.Routes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DryRun builds the Core rawConfig describes, its appenders encoding entries
+// as configured but writing them nowhere, and feeds a synthetic entry per
+// level of dryRunLevels, carrying fields of the common types, to the root
+// logger and each configured logger. It reports which entries reached which
+// appenders, and the encoded entries that were not well-formed, such as
+// invalid JSON.
+func DryRun(rawConfig *common.Config) (*DryRunReport, error) {
+	writers := map[string]*dryRunWriter{}
+	c, err := newCore(rawConfig, &stats{}, newClock(), newLevels(),
+		func(writerType string, config *common.Config) (*appender.Appender, error) {
+			w := &dryRunWriter{}
+			a, err := appender.CreateDryRunAppender(writerType, config, w)
+			if err == nil {
+				writers[a.Name] = w
+			}
+			return a, err
+		})
+	if err != nil {
+		return nil, err
+	}
+	c.levels.apply(c.levelValues)
+
+	r := &DryRunReport{
+		Routes:    map[string]map[string][]zapcore.Level{},
+		Appenders: map[string]*DryRunAppender{},
+	}
+	loggers := map[string]*ZapLogger{"": c.rootLogger}
+	c.loggers.each(func(name string, l *ZapLogger) {
+		loggers[name] = l
+	})
+	now := time.Now()
+	for name, l := range loggers {
+		routes := map[string][]zapcore.Level{}
+		for _, level := range dryRunLevels {
+			before := make(map[string]int, len(writers))
+			for an, w := range writers {
+				before[an] = w.entries()
+			}
+			l.load().base.Log(level, fmt.Sprintf("logn dry run: %s entry of logger %q", level, name),
+				zap.String("string", "value"),
+				zap.Int("int", 42),
+				zap.Float64("float", 4.2),
+				zap.Bool("bool", true),
+				zap.Duration("duration", time.Second),
+				zap.Time("time", now),
+				zap.Strings("strings", []string{"a", "b"}),
+				zap.Any("map", map[string]interface{}{"key": "value"}),
+				zap.Error(errors.New("dry run error")),
+			)
+			for an, w := range writers {
+				if w.entries() > before[an] {
+					routes[an] = append(routes[an], level)
+				}
+			}
+		}
+		r.Routes[name] = routes
+	}
+	c.Sync()
+	for name, w := range writers {
+		w.mu.Lock()
+		r.Appenders[name] = &DryRunAppender{Entries: w.n, Bytes: w.size, Errors: w.errors}
+		w.mu.Unlock()
+	}
+	return r, nil
+}
+
+// dryRunWriter counts the entries written to it, and checks them.
+type dryRunWriter struct {
+	mu     sync.Mutex
+	n      int
+	size   int
+	errors []string
+}
+
+func (w *dryRunWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.n++
+	w.size += len(p)
+	line := bytes.TrimSpace(p)
+	switch {
+	case len(line) == 0:
+		w.errors = append(w.errors, "empty entry")
+	case line[0] == '{' && !json.Valid(line):
+		w.errors = append(w.errors, fmt.Sprintf("invalid JSON: %s", line))
+	}
+	return len(p), nil
+}
+
+func (w *dryRunWriter) Sync() error {
+	return nil
+}
+
+func (w *dryRunWriter) entries() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.n
+}
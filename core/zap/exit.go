@@ -0,0 +1,29 @@
+package zap
+
+import (
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/core"
+)
+
+// fatalHook flushes the Core and runs the registered exit hooks after a
+// Fatal entry has been written.
+type fatalHook struct {
+	c *Core
+}
+
+func (h fatalHook) OnWrite(*zapcore.CheckedEntry, []zapcore.Field) {
+	h.c.Sync()
+	core.Exit(1)
+}
+
+// panicHook flushes the Core after a Panic entry has been written so the
+// entry is not lost if the panic is never recovered.
+type panicHook struct {
+	c *Core
+}
+
+func (h panicHook) OnWrite(ce *zapcore.CheckedEntry, _ []zapcore.Field) {
+	h.c.Sync()
+	panic(ce.Message)
+}
@@ -0,0 +1,157 @@
+package zap
+
+import (
+	"math"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// fieldHash is a 64-bit FNV-1a hash of fields, fed by encoding them to it:
+// marshalers hash what they encode, without reflection or fmt. The values of
+// reflected fields and Stringers are left out, so that hashing neither
+// allocates nor calls them; entries differing only by those hash the same.
+type fieldHash struct {
+	sum uint64
+}
+
+func newFieldHash() fieldHash {
+	return fieldHash{sum: fnvOffset64}
+}
+
+func (h *fieldHash) writeString(s string) {
+	for i := 0; i < len(s); i++ {
+		h.sum ^= uint64(s[i])
+		h.sum *= fnvPrime64
+	}
+	// strings are terminated, for "ab","c" and "a","bc" to differ
+	h.sum ^= 0xff
+	h.sum *= fnvPrime64
+}
+
+func (h *fieldHash) writeBytes(b []byte) {
+	for _, c := range b {
+		h.sum ^= uint64(c)
+		h.sum *= fnvPrime64
+	}
+	h.sum ^= 0xff
+	h.sum *= fnvPrime64
+}
+
+func (h *fieldHash) writeUint64(v uint64) {
+	for i := 0; i < 8; i++ {
+		h.sum ^= v >> (8 * i) & 0xff
+		h.sum *= fnvPrime64
+	}
+}
+
+// writeFields hashes fields by their key, type and value.
+func (h *fieldHash) writeFields(fields []zapcore.Field) {
+	for _, f := range fields {
+		h.writeUint64(uint64(f.Type))
+		switch f.Type {
+		case zapcore.StringerType:
+			h.writeString(f.Key)
+		case zapcore.ErrorType:
+			h.writeString(f.Key)
+			if err, ok := f.Interface.(error); ok && err != nil {
+				h.writeString(err.Error())
+			}
+		default:
+			f.AddTo(h)
+		}
+	}
+}
+
+func (h *fieldHash) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	h.writeString(key)
+	return arr.MarshalLogArray(h)
+}
+
+func (h *fieldHash) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	h.writeString(key)
+	return obj.MarshalLogObject(h)
+}
+
+func (h *fieldHash) AddBinary(key string, v []byte)     { h.writeString(key); h.writeBytes(v) }
+func (h *fieldHash) AddByteString(key string, v []byte) { h.writeString(key); h.writeBytes(v) }
+func (h *fieldHash) AddBool(key string, v bool)         { h.writeString(key); h.AppendBool(v) }
+func (h *fieldHash) AddComplex128(key string, v complex128) {
+	h.writeString(key)
+	h.AppendComplex128(v)
+}
+func (h *fieldHash) AddComplex64(key string, v complex64) { h.writeString(key); h.AppendComplex64(v) }
+func (h *fieldHash) AddDuration(key string, v time.Duration) {
+	h.writeString(key)
+	h.AppendDuration(v)
+}
+func (h *fieldHash) AddFloat64(key string, v float64) { h.writeString(key); h.AppendFloat64(v) }
+func (h *fieldHash) AddFloat32(key string, v float32) { h.writeString(key); h.AppendFloat32(v) }
+func (h *fieldHash) AddInt(key string, v int)         { h.writeString(key); h.AppendInt(v) }
+func (h *fieldHash) AddInt64(key string, v int64)     { h.writeString(key); h.AppendInt64(v) }
+func (h *fieldHash) AddInt32(key string, v int32)     { h.writeString(key); h.AppendInt32(v) }
+func (h *fieldHash) AddInt16(key string, v int16)     { h.writeString(key); h.AppendInt16(v) }
+func (h *fieldHash) AddInt8(key string, v int8)       { h.writeString(key); h.AppendInt8(v) }
+func (h *fieldHash) AddString(key, v string)          { h.writeString(key); h.writeString(v) }
+func (h *fieldHash) AddTime(key string, v time.Time)  { h.writeString(key); h.AppendTime(v) }
+func (h *fieldHash) AddUint(key string, v uint)       { h.writeString(key); h.AppendUint(v) }
+func (h *fieldHash) AddUint64(key string, v uint64)   { h.writeString(key); h.AppendUint64(v) }
+func (h *fieldHash) AddUint32(key string, v uint32)   { h.writeString(key); h.AppendUint32(v) }
+func (h *fieldHash) AddUint16(key string, v uint16)   { h.writeString(key); h.AppendUint16(v) }
+func (h *fieldHash) AddUint8(key string, v uint8)     { h.writeString(key); h.AppendUint8(v) }
+func (h *fieldHash) AddUintptr(key string, v uintptr) { h.writeString(key); h.AppendUintptr(v) }
+
+func (h *fieldHash) AddReflected(key string, _ interface{}) error {
+	h.writeString(key)
+	return nil
+}
+
+func (h *fieldHash) OpenNamespace(key string) { h.writeString(key) }
+
+func (h *fieldHash) AppendBool(v bool) {
+	if v {
+		h.writeUint64(1)
+	} else {
+		h.writeUint64(0)
+	}
+}
+
+func (h *fieldHash) AppendByteString(v []byte) { h.writeBytes(v) }
+func (h *fieldHash) AppendComplex128(v complex128) {
+	h.AppendFloat64(real(v))
+	h.AppendFloat64(imag(v))
+}
+func (h *fieldHash) AppendComplex64(v complex64)    { h.AppendComplex128(complex128(v)) }
+func (h *fieldHash) AppendDuration(v time.Duration) { h.writeUint64(uint64(v)) }
+func (h *fieldHash) AppendFloat64(v float64)        { h.writeUint64(math.Float64bits(v)) }
+func (h *fieldHash) AppendFloat32(v float32)        { h.writeUint64(uint64(math.Float32bits(v))) }
+func (h *fieldHash) AppendInt(v int)                { h.writeUint64(uint64(v)) }
+func (h *fieldHash) AppendInt64(v int64)            { h.writeUint64(uint64(v)) }
+func (h *fieldHash) AppendInt32(v int32)            { h.writeUint64(uint64(v)) }
+func (h *fieldHash) AppendInt16(v int16)            { h.writeUint64(uint64(v)) }
+func (h *fieldHash) AppendInt8(v int8)              { h.writeUint64(uint64(v)) }
+func (h *fieldHash) AppendString(v string)          { h.writeString(v) }
+func (h *fieldHash) AppendTime(v time.Time)         { h.writeUint64(uint64(v.UnixNano())) }
+func (h *fieldHash) AppendUint(v uint)              { h.writeUint64(uint64(v)) }
+func (h *fieldHash) AppendUint64(v uint64)          { h.writeUint64(v) }
+func (h *fieldHash) AppendUint32(v uint32)          { h.writeUint64(uint64(v)) }
+func (h *fieldHash) AppendUint16(v uint16)          { h.writeUint64(uint64(v)) }
+func (h *fieldHash) AppendUint8(v uint8)            { h.writeUint64(uint64(v)) }
+func (h *fieldHash) AppendUintptr(v uintptr)        { h.writeUint64(uint64(v)) }
+
+func (h *fieldHash) AppendArray(arr zapcore.ArrayMarshaler) error {
+	return arr.MarshalLogArray(h)
+}
+
+func (h *fieldHash) AppendObject(obj zapcore.ObjectMarshaler) error {
+	return obj.MarshalLogObject(h)
+}
+
+func (h *fieldHash) AppendReflected(interface{}) error {
+	return nil
+}
@@ -0,0 +1,99 @@
+package zap
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	cfg "github.com/shanexu/logn/config"
+	"github.com/shanexu/logn/internal/checked"
+)
+
+const (
+	hostnameKey   = "hostname"
+	pidKey        = "pid"
+	executableKey = "executable"
+	goroutineKey  = "goroutine"
+)
+
+// newStaticFields builds the fields attached to every entry of every logger:
+// the configured key-value pairs plus the metadata fields, unless the
+// configuration already provides those keys.
+func newStaticFields(configured map[string]interface{}, metadata cfg.Metadata) ([]zap.Field, error) {
+	fields := make([]zap.Field, 0, len(configured)+3)
+
+	if _, ok := configured[hostnameKey]; !ok && (metadata.Hostname == nil || *metadata.Hostname) {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, zap.String(hostnameKey, hostname))
+	}
+	if _, ok := configured[pidKey]; !ok && (metadata.Pid == nil || *metadata.Pid) {
+		fields = append(fields, zap.Int(pidKey, os.Getpid()))
+	}
+	if _, ok := configured[executableKey]; !ok && metadata.Executable {
+		executable, err := os.Executable()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, zap.String(executableKey, filepath.Base(executable)))
+	}
+
+	if _, ok := configured[buildKey]; !ok && metadata.Build == "entries" {
+		if f, ok := buildField(); ok {
+			fields = append(fields, f)
+		}
+	}
+
+	keys := make([]string, 0, len(configured))
+	for k := range configured {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fields = append(fields, zap.Any(k, configured[k]))
+	}
+	return fields, nil
+}
+
+// goroutineCore adds the ID of the goroutine logging to entries.
+type goroutineCore struct {
+	zapcore.Core
+}
+
+func (c goroutineCore) With(fields []zapcore.Field) zapcore.Core {
+	return goroutineCore{c.Core.With(fields)}
+}
+
+func (c goroutineCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c goroutineCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	fs := make([]zapcore.Field, 0, len(fields)+1)
+	fs = append(fs, zap.Int64(goroutineKey, goroutineID()))
+	fs = append(fs, fields...)
+	return checked.Write(c.Core, ent, fs)
+}
+
+// goroutineID returns the ID of the current goroutine, which the runtime only
+// tells in stack traces.
+func goroutineID() int64 {
+	var buf [64]byte
+	b := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseInt(string(b), 10, 64)
+	return id
+}
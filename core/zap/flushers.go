@@ -0,0 +1,32 @@
+package zap
+
+import "sync"
+
+// flusher is implemented by the states of the loggers which report what they
+// suppressed in summary entries, such as rate limits.
+type flusher interface {
+	// flush writes the pending summaries right away.
+	flush()
+}
+
+// flushers holds the flushers of the loggers of a Core, which Core.Sync and
+// Core.Close flush so that no summary is lost.
+type flushers struct {
+	mu sync.Mutex
+	fs []flusher
+}
+
+func (r *flushers) add(f flusher) {
+	r.mu.Lock()
+	r.fs = append(r.fs, f)
+	r.mu.Unlock()
+}
+
+func (r *flushers) flush() {
+	r.mu.Lock()
+	fs := r.fs
+	r.mu.Unlock()
+	for _, f := range fs {
+		f.flush()
+	}
+}
@@ -0,0 +1,68 @@
+package zap
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/core"
+)
+
+// Follower gives adapters the zap.Logger currently backing a logger, with the
+// options and fields of the adapter applied. Unlike the logger Unwrap
+// returns, it follows the in-place replacement of loggers on Core.Update, as
+// the loggers of NewStdLogAt do.
+type Follower struct {
+	logger core.Logger
+	opts   []zap.Option
+	fields []zapcore.Field
+
+	mu      sync.Mutex
+	base    *zap.Logger
+	derived *zap.Logger
+}
+
+// Follow returns a Follower of l, which must be backed by zap.
+func Follow(l core.Logger) (*Follower, error) {
+	if _, err := Unwrap(l); err != nil {
+		return nil, err
+	}
+	return &Follower{logger: l}, nil
+}
+
+// Logger returns the zap.Logger currently backing the logger, with the
+// options and fields of f applied. It is derived again only once the logger
+// was replaced.
+func (f *Follower) Logger() *zap.Logger {
+	base, _ := Unwrap(f.logger)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if base != f.base {
+		derived := base
+		if len(f.opts) > 0 {
+			derived = derived.WithOptions(f.opts...)
+		}
+		if len(f.fields) > 0 {
+			derived = derived.With(f.fields...)
+		}
+		f.base, f.derived = base, derived
+	}
+	return f.derived
+}
+
+// WithOptions returns a Follower of the same logger applying opts after the
+// options of f.
+func (f *Follower) WithOptions(opts ...zap.Option) *Follower {
+	nf := &Follower{logger: f.logger, fields: f.fields}
+	nf.opts = append(append(nf.opts, f.opts...), opts...)
+	return nf
+}
+
+// With returns a Follower of the same logger adding fields after the fields
+// of f.
+func (f *Follower) With(fields ...zapcore.Field) *Follower {
+	nf := &Follower{logger: f.logger, opts: f.opts}
+	nf.fields = append(append(nf.fields, f.fields...), fields...)
+	return nf
+}
@@ -0,0 +1,88 @@
+package zap
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// levels tracks the AtomicLevels of the loggers of a Core: one for root,
+// shared by the loggers without a level of their own, and one per configured
+// logger, shared by the loggers derived from it. Like stats it is kept across
+// configuration updates, which set the tracked levels in place, so that
+// adjusting a level reaches every logger using it.
+type levels struct {
+	mu    sync.Mutex
+	nodes map[string]zap.AtomicLevel
+	// boosts are the boosts of the loggers by name
+	boosts map[string]*boost
+}
+
+func newLevels() *levels {
+	return &levels{nodes: map[string]zap.AtomicLevel{}, boosts: map[string]*boost{}}
+}
+
+// node returns the AtomicLevel of the logger with the given name, "" being
+// root, creating it if need be.
+func (ls *levels) node(name string) zap.AtomicLevel {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	lvl, ok := ls.nodes[name]
+	if !ok {
+		lvl = zap.NewAtomicLevel()
+		ls.nodes[name] = lvl
+	}
+	return lvl
+}
+
+// apply sets the tracked levels to those of a configuration, and stops
+// tracking the loggers it does not configure.
+func (ls *levels) apply(values map[string]zapcore.Level) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	for name, lvl := range ls.nodes {
+		if v, ok := values[name]; ok {
+			lvl.SetLevel(v)
+		} else {
+			delete(ls.nodes, name)
+		}
+	}
+}
+
+// allLevels enables the appender cores of loggers at every level, the level
+// of a logger being enforced by the levelCore in front of them.
+var allLevels = zap.LevelEnablerFunc(func(zapcore.Level) bool { return true })
+
+// levelCore is the outermost core of a logger and enforces its level. Keeping
+// the level out of the cores it wraps is what lets WithLevel give a derived
+// logger a level of its own.
+type levelCore struct {
+	zapcore.Core
+	level zapcore.LevelEnabler
+}
+
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return c.level.Enabled(lvl)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), level: c.level}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.level.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// withLevel returns an option replacing the level of a logger by level.
+func withLevel(level zapcore.LevelEnabler) zap.Option {
+	return zap.WrapCore(func(zc zapcore.Core) zapcore.Core {
+		if lc, ok := zc.(*levelCore); ok {
+			return &levelCore{Core: lc.Core, level: level}
+		}
+		return zc
+	})
+}
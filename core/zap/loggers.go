@@ -0,0 +1,82 @@
+package zap
+
+import (
+	"strings"
+	"sync"
+)
+
+// loggerRegistry holds the named loggers of a Core in a sync.Map, so that
+// lookups, by far the most frequent operation, neither lock nor allocate,
+// while adding a logger does not copy the others.
+type loggerRegistry struct {
+	// mu serializes the changes
+	mu sync.Mutex
+	m  sync.Map
+}
+
+func newLoggerRegistry() *loggerRegistry {
+	return &loggerRegistry{}
+}
+
+// lookup returns the logger called name, if any.
+func (r *loggerRegistry) lookup(name string) (*ZapLogger, bool) {
+	l, ok := r.m.Load(name)
+	if !ok {
+		return nil, false
+	}
+	return l.(*ZapLogger), true
+}
+
+// each calls f with the loggers and their names.
+func (r *loggerRegistry) each(f func(name string, l *ZapLogger)) {
+	r.m.Range(func(k, v interface{}) bool {
+		f(k.(string), v.(*ZapLogger))
+		return true
+	})
+}
+
+// len returns the number of loggers.
+func (r *loggerRegistry) len() int {
+	n := 0
+	r.m.Range(func(interface{}, interface{}) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// get returns the logger called name, creating it with create if need be.
+// Goroutines asking for the same missing logger concurrently wait for one of
+// them to create it, rather than all creating one and throwing it away.
+func (r *loggerRegistry) get(name string, create func(name string) *ZapLogger) *ZapLogger {
+	if l, ok := r.lookup(name); ok {
+		return l
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if l, ok := r.lookup(name); ok {
+		return l
+	}
+	// copy the name, which may be a slice of a larger string the logger
+	// would keep alive
+	name = string([]byte(name))
+	l := create(name)
+	r.m.Store(name, l)
+	return l
+}
+
+// add adds l to the registry under name, unless a logger has this name
+// already.
+func (r *loggerRegistry) add(name string, l *ZapLogger) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, exist := r.m.LoadOrStore(name, l)
+	return !exist
+}
+
+// normalizeName returns the name under which the logger called name is
+// registered: surrounding white space and dots are ignored, so that e.g.
+// "app.db" and " app.db." are the same logger.
+func normalizeName(name string) string {
+	return strings.Trim(name, " \t\r\n.")
+}
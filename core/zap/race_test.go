@@ -0,0 +1,8 @@
+//go:build race
+// +build race
+
+package zap_test
+
+// raceEnabled tells allocation tests to skip, sync.Pool dropping items at
+// random under the race detector.
+const raceEnabled = true
@@ -0,0 +1,195 @@
+package zap
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	cfg "github.com/shanexu/logn/config"
+	"github.com/shanexu/logn/metrics"
+)
+
+const (
+	defaultSummaryInterval = time.Minute
+	rateLimitWindow        = time.Second
+
+	numLevels = int(zapcore.FatalLevel-zapcore.DebugLevel) + 1
+)
+
+// rateLimit caps the entries a logger emits per second and level. Unlike
+// sampling, the cap does not depend on the message, and suppressed entries
+// are reported by a summary entry at most once per summaryInterval, written
+// once it passed even if no entry follows, and by Core.Sync and Core.Close.
+type rateLimit struct {
+	perSecond       uint64
+	summaryInterval time.Duration
+}
+
+func newRateLimit(config *cfg.RateLimit) (*rateLimit, error) {
+	if config == nil {
+		return nil, nil
+	}
+	interval := defaultSummaryInterval
+	if config.SummaryInterval != "" {
+		var err error
+		interval, err = time.ParseDuration(config.SummaryInterval)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &rateLimit{
+		perSecond:       uint64(config.PerSecond),
+		summaryInterval: interval,
+	}, nil
+}
+
+func (r *rateLimit) wrap(zc zapcore.Core, clk zapcore.Clock, fl *flushers) zapcore.Core {
+	if r == nil {
+		return zc
+	}
+	s := &rateLimitState{rateLimit: r, core: zc, clock: clk, lastSummary: clk.Now()}
+	fl.add(s)
+	return &rateLimitCore{Core: zc, state: s}
+}
+
+const (
+	// windowCountBits is the number of low bits of a levelWindow holding
+	// the count of its entries
+	windowCountBits = 22
+	windowCountMask = 1<<windowCountBits - 1
+)
+
+// levelWindow counts the entries of a level in the current window: its high
+// bits are when the window started, in Unix milliseconds, and its low bits
+// the count of entries, so that a new window starts with a single CAS.
+type levelWindow struct {
+	v uint64
+}
+
+// inc counts an entry in the window containing now and reports whether the
+// entry is within the limit.
+func (w *levelWindow) inc(now int64, limit uint64) bool {
+	if limit > windowCountMask {
+		limit = windowCountMask
+	}
+	ms := uint64(now / int64(time.Millisecond))
+	for {
+		old := atomic.LoadUint64(&w.v)
+		next := ms<<windowCountBits | 1
+		if ms < old>>windowCountBits+uint64(rateLimitWindow/time.Millisecond) {
+			if old&windowCountMask >= limit {
+				return false
+			}
+			next = old + 1
+		}
+		if atomic.CompareAndSwapUint64(&w.v, old, next) {
+			return true
+		}
+	}
+}
+
+// rateLimitState is shared by a logger and the children derived from it.
+type rateLimitState struct {
+	// the fields accessed atomically come first, for them to be 64-bit
+	// aligned on 32-bit platforms
+	windows [numLevels]levelWindow
+	dropped [numLevels]uint64
+
+	*rateLimit
+	// core writes the summaries, stamped by clock
+	core  zapcore.Core
+	clock zapcore.Clock
+
+	mu sync.Mutex
+	// name is the name of the logger of the last entry dropped
+	name string
+	// timer writes the next summary, nil unless entries were dropped since
+	// the last one
+	timer       *time.Timer
+	lastSummary time.Time
+}
+
+type rateLimitCore struct {
+	zapcore.Core
+	state *rateLimitState
+}
+
+func (c *rateLimitCore) With(fields []zapcore.Field) zapcore.Core {
+	return &rateLimitCore{Core: c.Core.With(fields), state: c.state}
+}
+
+func (c *rateLimitCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	s := c.state
+	i := levelIndex(ent.Level)
+	if i < 0 || s.windows[i].inc(ent.Time.UnixNano(), s.perSecond) {
+		return c.Core.Check(ent, ce)
+	}
+	atomic.AddUint64(&s.dropped[i], 1)
+	metrics.Dropped(ent.LoggerName, ent.Level, metrics.DropRateLimit)
+	s.schedule(ent.LoggerName)
+	return ce
+}
+
+// schedule makes sure a summary is written once summaryInterval has passed
+// since the previous one, whether or not entries keep coming.
+func (s *rateLimitState) schedule(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.name = name
+	if s.timer != nil {
+		return
+	}
+	d := s.summaryInterval - s.clock.Now().Sub(s.lastSummary)
+	if d < 0 {
+		d = 0
+	}
+	s.timer = time.AfterFunc(d, s.flush)
+}
+
+// flush writes a warning with the number of entries dropped per level since
+// the previous summary, if any.
+func (s *rateLimitState) flush() {
+	s.mu.Lock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	now := s.clock.Now()
+	s.lastSummary = now
+	name := s.name
+	s.mu.Unlock()
+
+	var fields []zapcore.Field
+	for i := range s.dropped {
+		if n := atomic.SwapUint64(&s.dropped[i], 0); n > 0 {
+			level := zapcore.Level(i) + zapcore.DebugLevel
+			fields = append(fields, zap.Uint64(level.String(), n))
+		}
+	}
+	if len(fields) == 0 {
+		return
+	}
+	summary := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       now,
+		LoggerName: name,
+		Message:    "rate limit exceeded, entries suppressed",
+	}
+	if ce := s.core.Check(summary, nil); ce != nil {
+		ce.Write(append([]zapcore.Field{zap.Namespace("suppressed")}, fields...)...)
+	}
+}
+
+func levelIndex(l zapcore.Level) int {
+	i := int(l - zapcore.DebugLevel)
+	if i < 0 || i >= numLevels {
+		return -1
+	}
+	return i
+}
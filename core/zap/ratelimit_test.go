@@ -0,0 +1,34 @@
+package zap
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelWindow(t *testing.T) {
+	var w levelWindow
+	now := time.Now().Truncate(time.Millisecond).UnixNano()
+	var allowed int64
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if w.inc(now, 10) {
+					atomic.AddInt64(&allowed, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(10), allowed)
+
+	// a window starts once the previous one is over
+	assert.False(t, w.inc(now+int64(rateLimitWindow)-1, 10))
+	assert.True(t, w.inc(now+int64(rateLimitWindow), 10))
+}
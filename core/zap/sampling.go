@@ -0,0 +1,55 @@
+package zap
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	cfg "github.com/shanexu/logn/config"
+	"github.com/shanexu/logn/metrics"
+)
+
+const defaultSamplingTick = time.Second
+
+// sampling caps the entries a logger emits per tick: the first initial entries
+// with a given level and message are logged, then every thereafter-th one.
+type sampling struct {
+	tick       time.Duration
+	initial    int
+	thereafter int
+}
+
+func newSampling(config *cfg.Sampling) (*sampling, error) {
+	if config == nil {
+		return nil, nil
+	}
+	tick := defaultSamplingTick
+	if config.Tick != "" {
+		var err error
+		tick, err = time.ParseDuration(config.Tick)
+		if err != nil {
+			return nil, err
+		}
+		if tick <= 0 {
+			return nil, fmt.Errorf("sampling tick must be positive, got %q", config.Tick)
+		}
+	}
+	return &sampling{
+		tick:       tick,
+		initial:    config.Initial,
+		thereafter: config.Thereafter,
+	}, nil
+}
+
+func (s *sampling) wrap(zc zapcore.Core) zapcore.Core {
+	if s == nil {
+		return zc
+	}
+	return zapcore.NewSamplerWithOptions(zc, s.tick, s.initial, s.thereafter,
+		zapcore.SamplerHook(func(ent zapcore.Entry, dec zapcore.SamplingDecision) {
+			if dec&zapcore.LogDropped != 0 {
+				metrics.Dropped(ent.LoggerName, ent.Level, metrics.DropSampling)
+			}
+		}))
+}
@@ -0,0 +1,65 @@
+package zap
+
+import (
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	cfg "github.com/shanexu/logn/config"
+	"github.com/shanexu/logn/internal/checked"
+)
+
+// sequence numbers the entries a logger writes.
+type sequence struct {
+	key    string
+	global bool
+}
+
+func newSequence(config *cfg.Sequence) *sequence {
+	if config == nil {
+		return nil
+	}
+	s := &sequence{key: config.Key, global: config.Scope == "global"}
+	if s.key == "" {
+		s.key = sequenceKey
+	}
+	return s
+}
+
+// wrap wraps zc, numbering the entries of the logger called name with one of
+// the counters of st.
+func (s *sequence) wrap(zc zapcore.Core, st *stats, name string) zapcore.Core {
+	if s == nil {
+		return zc
+	}
+	seq := &st.globalSequence
+	if !s.global {
+		seq = st.sequence(name)
+	}
+	return &sequenceCore{Core: zc, key: s.key, seq: seq}
+}
+
+type sequenceCore struct {
+	zapcore.Core
+	key string
+	seq *uint64
+}
+
+func (c *sequenceCore) With(fields []zapcore.Field) zapcore.Core {
+	return &sequenceCore{Core: c.Core.With(fields), key: c.key, seq: c.seq}
+}
+
+func (c *sequenceCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *sequenceCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	fs := make([]zapcore.Field, 0, len(fields)+1)
+	fs = append(fs, fields...)
+	fs = append(fs, zap.Uint64(c.key, atomic.AddUint64(c.seq, 1)))
+	return checked.Write(c.Core, ent, fs)
+}
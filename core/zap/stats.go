@@ -0,0 +1,60 @@
+package zap
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/core"
+	"github.com/shanexu/logn/metrics"
+)
+
+// stats holds the counters of a Core. It is kept across configuration
+// updates, which is why loggers reference it rather than their Core.
+type stats struct {
+	// the counters come first, for them to be 64-bit aligned on 32-bit
+	// platforms
+	entries [numLevels]uint64
+	// globalSequence is the last sequence number of the loggers numbering
+	// their entries in a global sequence
+	globalSequence uint64
+	// sequences holds the last sequence number of audit loggers, and of
+	// loggers numbering their entries, by name
+	sequences sync.Map
+}
+
+// sequence returns the sequence counter of the logger with the given name.
+func (s *stats) sequence(name string) *uint64 {
+	v, _ := s.sequences.LoadOrStore(name, new(uint64))
+	return v.(*uint64)
+}
+
+// countEntry counts the entries written by loggers and reports them to the
+// metrics recorder.
+func (s *stats) countEntry(ent zapcore.Entry) error {
+	if i := levelIndex(ent.Level); i >= 0 {
+		atomic.AddUint64(&s.entries[i], 1)
+	}
+	metrics.Entry(ent.LoggerName, ent.Level)
+	return nil
+}
+
+// Stats returns a snapshot of the activity of the core.
+func (c *Core) Stats() core.Stats {
+	st := core.Stats{
+		Entries:        make(map[string]uint64, numLevels),
+		AppenderErrors: c.AppenderErrors(),
+	}
+	for i := range c.stats.entries {
+		lvl := zapcore.DebugLevel + zapcore.Level(i)
+		st.Entries[lvl.String()] = atomic.LoadUint64(&c.stats.entries[i])
+	}
+	appenders := c.appenders.load()
+	st.Appenders = make(map[string]core.AppenderStats, len(appenders))
+	for name, a := range appenders {
+		st.Appenders[name] = a.Stats()
+	}
+	st.Loggers = c.loggers.len()
+	return st
+}
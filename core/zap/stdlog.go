@@ -0,0 +1,69 @@
+package zap
+
+import (
+	"bytes"
+	"log"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/core"
+)
+
+// stdLogCallerSkip skips stdLogWriter.Write and the two frames of package
+// log (Printf and output) above it.
+const stdLogCallerSkip = 3
+
+// stdLogWriter writes each line printed by a standard library logger as one
+// entry. It follows the in-place replacement of loggers on Core.Update.
+type stdLogWriter struct {
+	logger *Follower
+	level  zapcore.Level
+}
+
+func newStdLogWriter(l core.Logger, level zapcore.Level) (*stdLogWriter, error) {
+	f, err := Follow(l)
+	if err != nil {
+		return nil, err
+	}
+	return &stdLogWriter{logger: f.WithOptions(zap.AddCallerSkip(stdLogCallerSkip)), level: level}, nil
+}
+
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	msg := string(bytes.TrimSuffix(p, []byte("\n")))
+	if ce := w.logger.Logger().Check(w.level, msg); ce != nil {
+		ce.Write()
+	}
+	return len(p), nil
+}
+
+// NewStdLogAt returns a *log.Logger writing each line as an entry of l at
+// level.
+func NewStdLogAt(l core.Logger, level core.Level) (*log.Logger, error) {
+	w, err := newStdLogWriter(l, level)
+	if err != nil {
+		return nil, err
+	}
+	return log.New(w, "", 0), nil
+}
+
+// RedirectStdLogAt routes the output of the standard library's package-global
+// logger to l at level, and returns a function restoring the previous output,
+// prefix and flags.
+func RedirectStdLogAt(l core.Logger, level core.Level) (func(), error) {
+	w, err := newStdLogWriter(l, level)
+	if err != nil {
+		return nil, err
+	}
+	flags := log.Flags()
+	prefix := log.Prefix()
+	out := log.Writer()
+	log.SetFlags(0)
+	log.SetPrefix("")
+	log.SetOutput(w)
+	return func() {
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+		log.SetOutput(out)
+	}, nil
+}
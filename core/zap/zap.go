@@ -1,12 +1,18 @@
 package zap
 
 import (
+	"context"
 	"fmt"
 	"github.com/pkg/errors"
 	"github.com/shanexu/logn/appender"
+	"github.com/shanexu/logn/appender/encoder"
 	"github.com/shanexu/logn/common"
 	cfg "github.com/shanexu/logn/config"
 	"github.com/shanexu/logn/core"
+	"github.com/shanexu/logn/filter"
+	"github.com/shanexu/logn/hook"
+	"github.com/shanexu/logn/internal/checked"
+	"github.com/shanexu/logn/status"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"sync"
@@ -14,26 +20,108 @@ import (
 
 type Core struct {
 	locker           sync.RWMutex
-	nameToLogger     sync.Map
-	nameToAppender   map[string]*appender.Appender
+	loggers          *loggerRegistry
+	appenders        *appenderRegistry
 	rootAppenders    map[string]*appender.Appender
 	rootLevel        zapcore.LevelEnabler
-	rootLevelName    string
 	rootAppenderRefs []string
-	rootLogger       *zap.SugaredLogger
+	rootSampling     *sampling
+	rootRateLimit    *rateLimit
+	rootDedup        *dedup
+	rootFilter       *filter.Filter
+	rootMessages     *filter.Messages
+	rootFields       *filter.Fields
+	rootCaller       bool
+	rootStacktrace   zapcore.LevelEnabler
+	rootLogger       *ZapLogger
 	globalLogger     *zap.SugaredLogger
+	fields           []zap.Field
+	hooks            []hook.Hook
+	stats            *stats
+	clock            *clock
+	levels           *levels
+	// levelValues holds the configured levels by logger name, set to levels
+	// once the configuration is in use
+	levelValues map[string]zapcore.Level
+	// rootAppenderLevels are the levels of root by appender, for those
+	// which have one
+	rootAppenderLevels map[string]zapcore.Level
+	rootSequence       *sequence
+	// goroutine tells whether entries carry the ID of the goroutine logging
+	goroutine bool
+	// buildBanner tells whether to log the build information once the
+	// configuration is first in use
+	buildBanner bool
+	// crashOutput is the appender the crashes of the process are written
+	// to once the configuration is in use, if any
+	crashOutput *appender.Appender
+	// flushers are those of the loggers built for the configuration
+	flushers *flushers
 }
 
 var StackTraceLevelEnabler = zap.NewAtomicLevelAt(zapcore.ErrorLevel)
 
+// noStacktrace is the stacktrace level of loggers with stacktrace_level off.
+var noStacktrace = zap.LevelEnablerFunc(func(zapcore.Level) bool { return false })
+
+// createStacktraceLevel parses the level from which entries carry a
+// stacktrace: "off" disables stacktraces and "" defers to
+// StackTraceLevelEnabler.
+func createStacktraceLevel(level string) (zapcore.LevelEnabler, error) {
+	switch level {
+	case "":
+		return StackTraceLevelEnabler, nil
+	case "off":
+		return noStacktrace, nil
+	}
+	return createLevel(level)
+}
+
 func createLevel(level string) (zapcore.LevelEnabler, error) {
-	var l zapcore.Level
-	if err := l.UnmarshalText([]byte(level)); err != nil {
+	l, err := parseLevel(level)
+	if err != nil {
 		return nil, err
 	}
 	return zap.NewAtomicLevelAt(l), nil
 }
 
+func parseLevel(level string) (zapcore.Level, error) {
+	var l zapcore.Level
+	err := l.UnmarshalText([]byte(level))
+	return l, err
+}
+
+// trackLevel returns the shared level of the logger with the given name,
+// "" being root, which is set to level once the configuration is in use.
+func (c *Core) trackLevel(name, level string) (zapcore.LevelEnabler, error) {
+	l, err := parseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	c.levelValues[name] = l
+	return c.levels.node(name), nil
+}
+
+// newAppenderLevels parses the levels of a logger by appender, which must be
+// among its appenders.
+func newAppenderLevels(levels map[string]string, appenders map[string]*appender.Appender) (map[string]zapcore.Level, error) {
+	if len(levels) == 0 {
+		return nil, nil
+	}
+	m := make(map[string]zapcore.Level, len(levels))
+	for name, level := range levels {
+		if _, ok := appenders[name]; !ok {
+			return nil, fmt.Errorf("level of appender %q, which is not referenced", name)
+		}
+		l, err := parseLevel(level)
+		if err != nil {
+			return nil, fmt.Errorf("level of appender %q: %v", name, err)
+		}
+		m[name] = l
+	}
+	return m, nil
+}
+
 func (c *Core) putAppender(name string, a *appender.Appender) error {
 	if name == "" {
 		return errors.New("name should not be empty")
@@ -41,15 +129,14 @@ func (c *Core) putAppender(name string, a *appender.Appender) error {
 	if a == nil {
 		return errors.New("appender should not be nil")
 	}
-	if _, exist := c.nameToAppender[name]; exist {
+	if !c.appenders.put(name, a) {
 		return fmt.Errorf("duplicated appender name %q", name)
 	}
-	c.nameToAppender[name] = a
 	return nil
 }
 
 func (c *Core) getAppender(name string) (*appender.Appender, error) {
-	a, exist := c.nameToAppender[name]
+	a, exist := c.appenders.load()[name]
 	if !exist {
 		return nil, fmt.Errorf("not found appender %q", name)
 	}
@@ -68,39 +155,127 @@ func (c *Core) getAppenders(names []string) (map[string]*appender.Appender, erro
 	return m, nil
 }
 
-func newZapCore(level zapcore.LevelEnabler, appenders map[string]*appender.Appender) zapcore.Core {
-	zcs := make([]zapcore.Core, 0)
-	for _, a := range appenders {
-		zcs = append(zcs, zapcore.NewCore(a.Encoder, a.Writer, level))
+func newZapCore(level zapcore.LevelEnabler, appenders map[string]*appender.Appender, appenderLevels map[string]zapcore.Level, strict bool) zapcore.Core {
+	zcs := make([]zapcore.Core, 0, len(appenders))
+	for name, a := range appenders {
+		enabler := level
+		if l, ok := appenderLevels[name]; ok {
+			enabler = l
+		}
+		if strict {
+			zcs = append(zcs, a.NewStrictCore(enabler))
+		} else {
+			zcs = append(zcs, a.NewCore(enabler))
+		}
+	}
+	if len(zcs) == 1 {
+		// most loggers have a single appender, spare them the tee
+		return zcs[0]
 	}
-	return zapcore.NewTee(zcs...)
+	return checked.NewTee(zcs...)
 }
 
-func newLogger(name string, level zapcore.LevelEnabler, appenders map[string]*appender.Appender) *zap.SugaredLogger {
-	zc := newZapCore(level, appenders)
-	logger := zap.New(zc, zap.AddCaller(), zap.AddStacktrace(StackTraceLevelEnabler))
-	if name != "" {
-		logger = logger.Named(name)
+// rendersCaller reports whether one of appenders renders the caller of
+// entries, capturing it being wasted otherwise.
+func rendersCaller(appenders map[string]*appender.Appender) bool {
+	for _, a := range appenders {
+		if encoder.RendersCaller(a.Encoder) {
+			return true
+		}
 	}
-	return logger.Sugar()
+	return false
 }
 
-func (c *Core) newLoggerFromCfg(loggerCfg cfg.Logger) (core.Logger, error) {
-	name := loggerCfg.Name
-	levelName := loggerCfg.Level
-	afs := loggerCfg.AppenderRefs
+// loggerSpec describes a logger to be built by Core.newLogger.
+type loggerSpec struct {
+	name  string
+	level zapcore.LevelEnabler
+	// levelName is the name of the logger whose level level is, "" being
+	// root
+	levelName string
+	appenders map[string]*appender.Appender
+	sampling  *sampling
+	rateLimit *rateLimit
+	dedup     *dedup
+	audit     *audit
+	filter    *filter.Filter
+	caller    bool
+	// stacktrace is the level from which entries carry a stacktrace
+	stacktrace zapcore.LevelEnabler
+	messages   *filter.Messages
+	fields     *filter.Fields
+	// appenderLevels are the levels of the logger by appender, for those
+	// which have one
+	appenderLevels map[string]zapcore.Level
+	sequence       *sequence
+}
 
-	if levelName == "" {
-		levelName = c.rootLevelName
+func (c *Core) rootSpec(name string) loggerSpec {
+	return loggerSpec{
+		name:           name,
+		level:          c.rootLevel,
+		levelName:      "",
+		appenders:      c.rootAppenders,
+		appenderLevels: c.rootAppenderLevels,
+		sampling:       c.rootSampling,
+		rateLimit:      c.rootRateLimit,
+		dedup:          c.rootDedup,
+		filter:         c.rootFilter,
+		messages:       c.rootMessages,
+		fields:         c.rootFields,
+		caller:         c.rootCaller,
+		stacktrace:     c.rootStacktrace,
+		sequence:       c.rootSequence,
 	}
+}
+
+func (c *Core) newLogger(spec loggerSpec) *ZapLogger {
+	zc := hook.NewCore(newZapCore(allLevels, spec.appenders, spec.appenderLevels, spec.audit != nil), c.hooks...)
+	zc = spec.audit.wrap(zc, c.stats.sequence(spec.name))
+	zc = spec.sequence.wrap(zc, c.stats, spec.name)
+	if c.goroutine {
+		zc = goroutineCore{zc}
+	}
+	zc = spec.dedup.wrap(zc, c.flushers)
+	zc = spec.sampling.wrap(zc)
+	zc = spec.rateLimit.wrap(zc, c.clock, c.flushers)
+	zc = filter.NewFieldsCore(zc, spec.fields)
+	zc = filter.NewCore(zc, spec.filter)
+	zc = filter.NewMessagesCore(zc, spec.messages)
+	zc = zapcore.RegisterHooks(zc, c.stats.countEntry)
+	zc = &levelCore{Core: zc, level: c.boostedLevel(spec)}
+	logger := zap.New(zc,
+		zap.WithCaller(spec.caller && rendersCaller(spec.appenders)),
+		zap.AddStacktrace(spec.stacktrace),
+		zap.Fields(c.fields...),
+		zap.WithFatalHook(fatalHook{c}),
+		zap.WithPanicHook(panicHook{c}),
+		zap.WithClock(c.clock),
+	)
+	if spec.name != "" {
+		logger = logger.Named(spec.name)
+	}
+	return newZapLogger(logger)
+}
+
+func (c *Core) newLoggerFromCfg(loggerCfg cfg.Logger) (*ZapLogger, error) {
+	name := normalizeName(loggerCfg.Name)
+	afs := loggerCfg.AppenderRefs
+	appenderLevels := c.rootAppenderLevels
 
 	if len(afs) == 0 {
 		afs = c.rootAppenderRefs
+	} else {
+		appenderLevels = nil
 	}
 
-	level, err := createLevel(levelName)
-	if err != nil {
-		return nil, err
+	level, levelName := c.rootLevel, ""
+	if loggerCfg.Level != "" {
+		var err error
+		if level, err = c.trackLevel(name, loggerCfg.Level); err != nil {
+			return nil, err
+		}
+		levelName = name
 	}
 
 	am, err := c.getAppenders(afs)
@@ -112,28 +287,111 @@ func (c *Core) newLoggerFromCfg(loggerCfg cfg.Logger) (core.Logger, error) {
 		return nil, errors.New("empty appenders")
 	}
 
-	return newLogger(name, level, am), nil
+	if len(loggerCfg.AppenderLevels) > 0 {
+		if appenderLevels, err = newAppenderLevels(loggerCfg.AppenderLevels, am); err != nil {
+			return nil, err
+		}
+	}
+
+	spec := loggerSpec{
+		name:           name,
+		level:          level,
+		levelName:      levelName,
+		appenders:      am,
+		appenderLevels: appenderLevels,
+		sampling:       c.rootSampling,
+		rateLimit:      c.rootRateLimit,
+		dedup:          c.rootDedup,
+		filter:         c.rootFilter,
+		messages:       c.rootMessages,
+		fields:         c.rootFields,
+		caller:         c.rootCaller,
+		stacktrace:     c.rootStacktrace,
+		sequence:       c.rootSequence,
+	}
+	if loggerCfg.Sequence != nil {
+		spec.sequence = newSequence(loggerCfg.Sequence)
+	}
+	if loggerCfg.Caller != nil {
+		spec.caller = *loggerCfg.Caller
+	}
+	if loggerCfg.StacktraceLevel != "" {
+		spec.stacktrace, err = createStacktraceLevel(loggerCfg.StacktraceLevel)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(loggerCfg.FieldFilters) > 0 {
+		spec.fields, err = filter.NewFields(loggerCfg.FieldFilters)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(loggerCfg.AllowMessages) > 0 || len(loggerCfg.DenyMessages) > 0 {
+		spec.messages, err = filter.NewMessages(loggerCfg.AllowMessages, loggerCfg.DenyMessages)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if loggerCfg.Filter != "" {
+		spec.filter, err = filter.Compile(loggerCfg.Filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if loggerCfg.Sampling != nil {
+		spec.sampling, err = newSampling(loggerCfg.Sampling)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if loggerCfg.RateLimit != nil {
+		spec.rateLimit, err = newRateLimit(loggerCfg.RateLimit)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if loggerCfg.Dedup != nil {
+		spec.dedup, err = newDedup(loggerCfg.Dedup)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if loggerCfg.Audit != nil {
+		spec.audit, err = newAudit(loggerCfg.Audit)
+		if err != nil {
+			return nil, err
+		}
+		// nothing may keep an audit entry from being written, and audit
+		// entries are numbered already
+		spec.sampling, spec.rateLimit, spec.dedup, spec.sequence = nil, nil, nil, nil
+	}
+
+	return c.newLogger(spec), nil
 }
 
-func (c *Core) newNamedLogger(name string) core.Logger {
-	return newLogger(name, c.rootLevel, c.rootAppenders)
+func (c *Core) newNamedLogger(name string) *ZapLogger {
+	return c.newLogger(c.rootSpec(name))
 }
 
-func (c *Core) getLogger(name string, lock bool) *zap.SugaredLogger {
-	if lock {
-		c.locker.RLock()
-		defer c.locker.RUnlock()
-	}
+// getLogger returns the logger called name, creating it if need be. Lookups
+// of existing loggers take no lock, and only creating one, which reads the
+// root settings, holds c.locker when lock is set.
+func (c *Core) getLogger(name string, lock bool) *ZapLogger {
 	if len(name) == 0 {
 		return c.rootLogger
 	}
-	logger, ok := c.nameToLogger.Load(name)
-	if ok {
-		return logger.(*zap.SugaredLogger)
+	if l, ok := c.loggers.lookup(name); ok {
+		return l
+	}
+	if name = normalizeName(name); name == "" {
+		return c.rootLogger
+	}
+	if lock {
+		c.locker.RLock()
+		defer c.locker.RUnlock()
 	}
-	zl := c.newNamedLogger(name)
-	v, _ := c.nameToLogger.LoadOrStore(name, zl)
-	return v.(*zap.SugaredLogger)
+	return c.loggers.get(name, c.newNamedLogger)
 }
 
 func (c *Core) GetLogger(name ...string) core.Logger {
@@ -143,74 +401,218 @@ func (c *Core) GetLogger(name ...string) core.Logger {
 	return c.getLogger(name[0], true)
 }
 
+// Enabled reports whether the root logger would write an entry at level.
+func (c *Core) Enabled(level core.Level) bool {
+	return c.getLogger("", true).Enabled(level)
+}
+
+// IsDebug is shorthand for Enabled(core.DebugLevel).
+func (c *Core) IsDebug() bool {
+	return c.Enabled(core.DebugLevel)
+}
+
+// With returns a child of the root logger carrying the given key-value pairs.
+func (c *Core) With(args ...interface{}) core.Logger {
+	return c.getLogger("", true).With(args...)
+}
+
+// Named returns a child of the root logger with the given name.
+func (c *Core) Named(name string) core.Logger {
+	return c.getLogger("", true).Named(name)
+}
+
+// WithContext returns a child of the root logger carrying the diagnostic
+// context bound to ctx.
+func (c *Core) WithContext(ctx context.Context) core.Logger {
+	return c.getLogger("", true).WithContext(ctx)
+}
+
+// WithCallerSkip returns a child of the root logger skipping skip more stack
+// frames when reporting the caller.
+func (c *Core) WithCallerSkip(skip int) core.Logger {
+	return c.getLogger("", true).WithCallerSkip(skip)
+}
+
+// WithLevel returns a child of the root logger writing the entries from
+// level on, whatever the configured level.
+func (c *Core) WithLevel(level core.Level) core.Logger {
+	return c.getLogger("", true).WithLevel(level)
+}
+
+// Desugar returns the strongly-typed variant of the root logger.
+func (c *Core) Desugar() core.FieldLogger {
+	return c.getLogger("", true).Desugar()
+}
+
+// GetFieldLogger returns the strongly-typed variant of the logger with the
+// given name, or of the root logger without one. Like loggers, it follows
+// configuration updates.
+func (c *Core) GetFieldLogger(name ...string) core.FieldLogger {
+	return c.GetLogger(name...).Desugar()
+}
+
 func (c *Core) Update(rawConfig *common.Config) error {
-	nc, err := newCore(rawConfig)
+	nc, err := newCore(rawConfig, c.stats, c.clock, c.levels, appender.CreateAppender)
 	if err != nil {
 		return err
 	}
+	if err := nc.applyCrashOutput(); err != nil {
+		nc.closeAppenders(nil)
+		return err
+	}
 	c.locker.Lock()
-	defer c.locker.Unlock()
-	c.Sync()
-	c.nameToAppender = nc.nameToAppender
+	c.syncAppenders()
+	old := c.appenders.load()
+	c.appenders.swap(nc.appenders)
 	c.rootAppenders = nc.rootAppenders
+	c.levels.apply(nc.levelValues)
 	c.rootLevel = nc.rootLevel
-	c.rootLevelName = nc.rootLevelName
 	c.rootAppenderRefs = nc.rootAppenderRefs
-	*c.rootLogger = *nc.rootLogger
+	c.rootAppenderLevels = nc.rootAppenderLevels
+	c.rootSequence = nc.rootSequence
+	c.rootSampling = nc.rootSampling
+	c.rootRateLimit = nc.rootRateLimit
+	c.rootDedup = nc.rootDedup
+	c.rootFilter = nc.rootFilter
+	c.rootMessages = nc.rootMessages
+	c.rootFields = nc.rootFields
+	c.rootCaller = nc.rootCaller
+	c.rootStacktrace = nc.rootStacktrace
+	c.rootLogger.update(nc.rootLogger)
 	c.rootAppenders = nc.rootAppenders
 	c.globalLogger = nc.globalLogger
-	c.nameToLogger.Range(func(key, value interface{}) bool {
-		name := key.(string)
-		*value.(*zap.SugaredLogger) = *nc.getLogger(name, false)
-		return true
+	c.fields = nc.fields
+	c.goroutine = nc.goroutine
+	c.hooks = nc.hooks
+	if nc.crashOutput != nil {
+		c.crashOutput = nc.crashOutput
+	}
+	c.loggers.each(func(name string, l *ZapLogger) {
+		l.update(nc.getLogger(name, false))
 	})
-	nc.nameToLogger.Range(func(key, value interface{}) bool {
-		if _, found := c.nameToLogger.Load(key); found {
-			return true
-		}
-		c.nameToLogger.Store(key, value)
-		return true
+	nc.loggers.each(func(name string, l *ZapLogger) {
+		c.loggers.add(name, l)
 	})
-	c.redirectStdLog()
+	crash := c.crashOutput
+	oldFlushers := c.flushers
+	c.flushers = nc.flushers
+	c.locker.Unlock()
+	// the summaries pending go to the appenders they were meant for
+	oldFlushers.flush()
+	replaceAppenders(old, nc.appenders.load(), crash)
 	return nil
 }
 
-func newCore(rawConfig *common.Config) (*Core, error) {
+// replaceAppenders closes the appenders of old, which were replaced by those
+// of current, but keep, the crash output the runtime writes to: the entries
+// still written to one of them, by loggers derived before, go to the
+// appender of current with the same name, if any.
+func replaceAppenders(old, current map[string]*appender.Appender, keep *appender.Appender) {
+	for name, a := range old {
+		if a == keep {
+			continue
+		}
+		if err := a.Replace(current[name]); err != nil {
+			status.Warnf("failed to close replaced appender %q: %v", name, err)
+		}
+	}
+}
+
+// closeAppenders closes the appenders of c but keep, which is synced only,
+// returning the first error met.
+func (c *Core) closeAppenders(keep *appender.Appender) error {
+	var first error
+	for name, a := range c.appenders.load() {
+		if a == keep {
+			a.Writer.Sync()
+			continue
+		}
+		if err := a.Close(); err != nil {
+			status.Warnf("failed to close appender %q: %v", name, err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+// newCore builds the Core rawConfig describes, its appenders created by
+// createAppender.
+func newCore(rawConfig *common.Config, st *stats, clk *clock, lv *levels,
+	createAppender func(writerType string, config *common.Config) (*appender.Appender, error)) (*Core, error) {
 	config := cfg.Config{}
 	err := rawConfig.Unpack(&config)
 	if err != nil {
 		return nil, err
 	}
 
+	if config.Status != "" {
+		if err := status.Configure(config.Status); err != nil {
+			return nil, err
+		}
+	}
+
 	co := Core{
-		nameToLogger:   sync.Map{},
-		nameToAppender: map[string]*appender.Appender{},
-		rootAppenders:  map[string]*appender.Appender{},
+		loggers:       newLoggerRegistry(),
+		appenders:     newAppenderRegistry(),
+		rootAppenders: map[string]*appender.Appender{},
+		stats:         st,
+		clock:         clk,
+		levels:        lv,
+		levelValues:   map[string]zapcore.Level{},
+		flushers:      &flushers{},
 	}
+	// the appenders created are closed unless the configuration is valid
+	built := false
+	defer func() {
+		if !built {
+			co.closeAppenders(nil)
+		}
+	}()
 
 	for appenderType, appenderConfigs := range config.Appenders {
 		for _, appenderConfig := range appenderConfigs {
-			a, err := appender.CreateAppender(appenderType, appenderConfig)
+			a, err := createAppender(appenderType, appenderConfig)
 			if err != nil {
 				return nil, err
 			}
 			name, err := appenderConfig.Name()
-			if err != nil {
-				return nil, err
+			if err == nil {
+				err = co.putAppender(name, a)
 			}
-			if err := co.putAppender(name, a); err != nil {
+			if err != nil {
+				a.Close()
 				return nil, err
 			}
 		}
 	}
 
+	// static fields
+	fields, err := newStaticFields(config.Fields, config.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	co.fields = fields
+	co.goroutine = config.Metadata.Goroutine
+	co.buildBanner = config.Metadata.Build == "banner"
+	co.crashOutput, err = co.crashAppender(config.CrashOutput)
+	if err != nil {
+		return nil, err
+	}
+
+	// global hooks
+	hooks, err := hook.Lookup(config.Hooks)
+	if err != nil {
+		return nil, err
+	}
+	co.hooks = hooks
+
 	// rootLevel
-	rootLevel, err := createLevel(config.Loggers.Root.Level)
+	co.rootLevel, err = co.trackLevel("", config.Loggers.Root.Level)
 	if err != nil {
 		return nil, err
 	}
-	co.rootLevel = rootLevel
-	co.rootLevelName = config.Loggers.Root.Level
 
 	// rootAppenders
 	rootAppenderRefSet := common.MakeStringSet(config.Loggers.Root.AppenderRefs...)
@@ -223,8 +625,64 @@ func newCore(rawConfig *common.Config) (*Core, error) {
 	}
 	co.rootAppenderRefs = rootAppenderRefSet.ToSlice()
 
+	// rootAppenderLevels
+	co.rootAppenderLevels, err = newAppenderLevels(config.Loggers.Root.AppenderLevels, co.rootAppenders)
+	if err != nil {
+		return nil, err
+	}
+
+	// rootSampling
+	co.rootSampling, err = newSampling(config.Loggers.Root.Sampling)
+	if err != nil {
+		return nil, err
+	}
+
+	// rootRateLimit
+	co.rootRateLimit, err = newRateLimit(config.Loggers.Root.RateLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	// rootDedup
+	co.rootDedup, err = newDedup(config.Loggers.Root.Dedup)
+	if err != nil {
+		return nil, err
+	}
+
+	// rootFilter
+	if config.Loggers.Root.Filter != "" {
+		co.rootFilter, err = filter.Compile(config.Loggers.Root.Filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// rootMessages
+	co.rootMessages, err = filter.NewMessages(config.Loggers.Root.AllowMessages, config.Loggers.Root.DenyMessages)
+	if err != nil {
+		return nil, err
+	}
+
+	// rootFields
+	co.rootFields, err = filter.NewFields(config.Loggers.Root.FieldFilters)
+	if err != nil {
+		return nil, err
+	}
+
+	// rootSequence
+	co.rootSequence = newSequence(config.Loggers.Root.Sequence)
+
+	// rootCaller
+	co.rootCaller = config.Loggers.Root.Caller == nil || *config.Loggers.Root.Caller
+
+	// rootStacktrace
+	co.rootStacktrace, err = createStacktraceLevel(config.Loggers.Root.StacktraceLevel)
+	if err != nil {
+		return nil, err
+	}
+
 	// rootLogger
-	co.rootLogger = newLogger("", co.rootLevel, co.rootAppenders)
+	co.rootLogger = co.newLogger(co.rootSpec(""))
 
 	// loggers
 	for _, lc := range config.Loggers.Logger {
@@ -232,37 +690,109 @@ func newCore(rawConfig *common.Config) (*Core, error) {
 		if err != nil {
 			return nil, err
 		}
-		if _, loaded := co.nameToLogger.LoadOrStore(lc.Name, l); loaded {
+		if !co.loggers.add(normalizeName(lc.Name), l) {
 			return nil, fmt.Errorf("duplicated logger %q", lc.Name)
 		}
 	}
 
-	co.globalLogger = co.rootLogger.Desugar().WithOptions(zap.AddCallerSkip(2)).Sugar()
+	co.globalLogger = co.rootLogger.load().base.WithOptions(zap.AddCallerSkip(2)).Sugar()
 
+	built = true
 	return &co, nil
 }
 
 func New(rawConfig *common.Config) (core.Core, error) {
-	return newCore(rawConfig)
+	c, err := newCore(rawConfig, &stats{}, newClock(), newLevels(), appender.CreateAppender)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.applyCrashOutput(); err != nil {
+		c.closeAppenders(nil)
+		return nil, err
+	}
+	c.levels.apply(c.levelValues)
+	if c.buildBanner {
+		if f, ok := buildField(); ok {
+			c.rootLogger.load().base.Info("build information", f)
+		}
+	}
+	return c, nil
 }
 
-func (c *Core) RedirectStdLog() {
-	zap.RedirectStdLog(c.getLogger("stdlog", true).Desugar())
+// RedirectStdLog routes the standard library's package-global logger to the
+// logger named "stdlog" at InfoLevel, and returns a function restoring the
+// previous output, prefix and flags. The redirection survives Update.
+func (c *Core) RedirectStdLog() (func(), error) {
+	return RedirectStdLogAt(c.getLogger("stdlog", true), zapcore.InfoLevel)
 }
 
-func (c *Core) redirectStdLog() {
-	zap.RedirectStdLog(c.getLogger("stdlog", false).Desugar())
+func (c *Core) AppenderErrors() map[string]uint64 {
+	appenders := c.appenders.load()
+	m := make(map[string]uint64, len(appenders))
+	for name, a := range appenders {
+		m[name] = a.Errors()
+	}
+	return m
 }
 
+// AppenderHealth returns, per appender name, a snapshot of the health of
+// the appender.
+func (c *Core) AppenderHealth() map[string]core.AppenderHealth {
+	appenders := c.appenders.load()
+	m := make(map[string]core.AppenderHealth, len(appenders))
+	for name, a := range appenders {
+		m[name] = a.Health()
+	}
+	return m
+}
+
+// Sync writes the pending summaries of the loggers, then syncs the
+// appenders.
 func (c *Core) Sync() error {
-	c.nameToLogger.Range(func(_, value interface{}) bool {
-		value.(core.Logger).Sync()
-		return false
-	})
-	for _, a := range c.rootAppenders {
+	c.locker.RLock()
+	fl := c.flushers
+	c.locker.RUnlock()
+	fl.flush()
+	c.syncAppenders()
+	return nil
+}
+
+func (c *Core) syncAppenders() {
+	for _, a := range c.appenders.load() {
 		a.Writer.Sync()
 	}
-	return nil
+}
+
+// Close writes the pending summaries of the loggers, then closes the
+// appenders, but the crash output, whose file the runtime writes to until
+// the process exits. It returns the first error met.
+func (c *Core) Close() error {
+	c.locker.Lock()
+	crash, fl := c.crashOutput, c.flushers
+	c.locker.Unlock()
+	fl.flush()
+	return c.closeAppenders(crash)
+}
+
+// Reopen reopens the files the appenders write to, returning the first
+// error met. The crash output follows its appender to the new file.
+func (c *Core) Reopen() error {
+	var first error
+	for name, a := range c.appenders.load() {
+		if err := a.Reopen(); err != nil {
+			status.Errorf("failed to reopen appender %q: %v", name, err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	if err := c.applyCrashOutput(); err != nil {
+		status.Errorf("failed to reopen the crash output: %v", err)
+		if first == nil {
+			first = err
+		}
+	}
+	return first
 }
 
 func init() {
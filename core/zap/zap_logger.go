@@ -0,0 +1,306 @@
+package zap
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	"github.com/shanexu/logn/core"
+	"github.com/shanexu/logn/mdc"
+)
+
+// ZapLogger is the core.Logger implementation handed out by Core. It shares
+// the zapcore.Core (appenders and level) of the logger it was derived from.
+type ZapLogger struct {
+	// current holds the *zapLogger l logs with, which Core.Update swaps
+	current   atomic.Value
+	desugared *ZapFieldLogger
+}
+
+// zapLogger is what a ZapLogger logs with.
+type zapLogger struct {
+	// base reports the callers of its own methods
+	base *zap.Logger
+	// sugar skips the frame of the methods of ZapLogger
+	sugar *zap.SugaredLogger
+}
+
+func newZapLogger(base *zap.Logger) *ZapLogger {
+	l := &ZapLogger{desugared: newZapFieldLogger(base)}
+	l.current.Store(&zapLogger{base: base, sugar: base.WithOptions(zap.AddCallerSkip(1)).Sugar()})
+	return l
+}
+
+func (l *ZapLogger) load() *zapLogger {
+	return l.current.Load().(*zapLogger)
+}
+
+// update makes l log like nl, keeping the FieldLogger handed out by Desugar
+// in step. The loggers are swapped rather than copied, for the goroutines
+// logging with l meanwhile to see either.
+func (l *ZapLogger) update(nl *ZapLogger) {
+	l.current.Store(nl.load())
+	l.desugared.current.Store(nl.desugared.load())
+}
+
+// Enabled reports whether an entry at level would be written.
+func (l *ZapLogger) Enabled(level core.Level) bool {
+	return l.load().base.Core().Enabled(level)
+}
+
+// IsDebug is shorthand for Enabled(core.DebugLevel).
+func (l *ZapLogger) IsDebug() bool {
+	return l.Enabled(core.DebugLevel)
+}
+
+// With returns a child logger carrying the given key-value pairs on every
+// entry. The child writes to the same appenders at the same level.
+func (l *ZapLogger) With(args ...interface{}) core.Logger {
+	return newZapLogger(l.load().base.Sugar().With(args...).Desugar())
+}
+
+// Named returns a child logger whose name is extended with the given segment,
+// joined by a period.
+func (l *ZapLogger) Named(name string) core.Logger {
+	return newZapLogger(l.load().base.Named(name))
+}
+
+// WithContext returns a child logger carrying the diagnostic context bound to
+// ctx.
+func (l *ZapLogger) WithContext(ctx context.Context) core.Logger {
+	kvs := mdc.KeysAndValues(ctx)
+	if len(kvs) == 0 {
+		return l
+	}
+	return l.With(kvs...)
+}
+
+// WithCallerSkip returns a child logger skipping skip more stack frames when
+// reporting the caller.
+func (l *ZapLogger) WithCallerSkip(skip int) core.Logger {
+	return newZapLogger(l.load().base.WithOptions(zap.AddCallerSkip(skip)))
+}
+
+// WithLevel returns a child logger writing the entries from level on,
+// whatever the configured level.
+func (l *ZapLogger) WithLevel(level core.Level) core.Logger {
+	return newZapLogger(l.load().base.WithOptions(withLevel(level)))
+}
+
+// Desugar returns the strongly-typed FieldLogger sharing this logger's
+// appenders and level. It is built along with the logger, so that Desugar
+// does not allocate, and follows the configuration updates of the Core like
+// the logger.
+func (l *ZapLogger) Desugar() core.FieldLogger {
+	return l.desugared
+}
+
+func (l *ZapLogger) Debug(args ...interface{}) {
+	l.load().sugar.Debug(args...)
+}
+
+func (l *ZapLogger) Info(args ...interface{}) {
+	l.load().sugar.Info(args...)
+}
+
+func (l *ZapLogger) Warn(args ...interface{}) {
+	l.load().sugar.Warn(args...)
+}
+
+func (l *ZapLogger) Error(args ...interface{}) {
+	l.load().sugar.Error(args...)
+}
+
+func (l *ZapLogger) DPanic(args ...interface{}) {
+	l.load().sugar.DPanic(args...)
+}
+
+func (l *ZapLogger) Panic(args ...interface{}) {
+	l.load().sugar.Panic(args...)
+}
+
+func (l *ZapLogger) Fatal(args ...interface{}) {
+	l.load().sugar.Fatal(args...)
+}
+
+func (l *ZapLogger) Debugf(format string, args ...interface{}) {
+	l.load().sugar.Debugf(format, args...)
+}
+
+func (l *ZapLogger) Infof(format string, args ...interface{}) {
+	l.load().sugar.Infof(format, args...)
+}
+
+func (l *ZapLogger) Warnf(format string, args ...interface{}) {
+	l.load().sugar.Warnf(format, args...)
+}
+
+func (l *ZapLogger) Errorf(format string, args ...interface{}) {
+	l.load().sugar.Errorf(format, args...)
+}
+
+func (l *ZapLogger) DPanicf(format string, args ...interface{}) {
+	l.load().sugar.DPanicf(format, args...)
+}
+
+func (l *ZapLogger) Panicf(format string, args ...interface{}) {
+	l.load().sugar.Panicf(format, args...)
+}
+
+func (l *ZapLogger) Fatalf(format string, args ...interface{}) {
+	l.load().sugar.Fatalf(format, args...)
+}
+
+func (l *ZapLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	l.load().sugar.Debugw(msg, keysAndValues...)
+}
+
+func (l *ZapLogger) Infow(msg string, keysAndValues ...interface{}) {
+	l.load().sugar.Infow(msg, keysAndValues...)
+}
+
+func (l *ZapLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	l.load().sugar.Warnw(msg, keysAndValues...)
+}
+
+func (l *ZapLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	l.load().sugar.Errorw(msg, keysAndValues...)
+}
+
+func (l *ZapLogger) DPanicw(msg string, keysAndValues ...interface{}) {
+	l.load().sugar.DPanicw(msg, keysAndValues...)
+}
+
+func (l *ZapLogger) Panicw(msg string, keysAndValues ...interface{}) {
+	l.load().sugar.Panicw(msg, keysAndValues...)
+}
+
+func (l *ZapLogger) Fatalw(msg string, keysAndValues ...interface{}) {
+	l.load().sugar.Fatalw(msg, keysAndValues...)
+}
+
+func (l *ZapLogger) Sync() error {
+	return l.load().sugar.Sync()
+}
+
+// ZapFieldLogger is the core.FieldLogger implementation backed by a zap.Logger.
+type ZapFieldLogger struct {
+	// current holds the *zapFieldLogger l logs with, which Core.Update swaps
+	current atomic.Value
+}
+
+// zapFieldLogger is what a ZapFieldLogger logs with.
+type zapFieldLogger struct {
+	// base reports the callers of its own methods
+	base *zap.Logger
+	// skipped skips the frame of the methods of ZapFieldLogger
+	skipped *zap.Logger
+}
+
+func newZapFieldLogger(base *zap.Logger) *ZapFieldLogger {
+	l := &ZapFieldLogger{}
+	l.current.Store(&zapFieldLogger{base: base, skipped: base.WithOptions(zap.AddCallerSkip(1))})
+	return l
+}
+
+func (l *ZapFieldLogger) load() *zapFieldLogger {
+	return l.current.Load().(*zapFieldLogger)
+}
+
+// Enabled reports whether an entry at level would be written.
+func (l *ZapFieldLogger) Enabled(level core.Level) bool {
+	return l.load().base.Core().Enabled(level)
+}
+
+// IsDebug is shorthand for Enabled(core.DebugLevel).
+func (l *ZapFieldLogger) IsDebug() bool {
+	return l.Enabled(core.DebugLevel)
+}
+
+// With returns a child logger carrying the given fields on every entry.
+func (l *ZapFieldLogger) With(fields ...core.Field) core.FieldLogger {
+	return newZapFieldLogger(l.load().base.With(fields...))
+}
+
+// Named adds a sub-scope to the logger's name.
+func (l *ZapFieldLogger) Named(name string) core.FieldLogger {
+	return newZapFieldLogger(l.load().base.Named(name))
+}
+
+// WithContext returns a child logger carrying the diagnostic context bound to
+// ctx.
+func (l *ZapFieldLogger) WithContext(ctx context.Context) core.FieldLogger {
+	kvs := mdc.KeysAndValues(ctx)
+	if len(kvs) == 0 {
+		return l
+	}
+	fields := make([]zap.Field, 0, len(kvs)/2)
+	for i := 0; i+1 < len(kvs); i += 2 {
+		fields = append(fields, zap.Any(kvs[i].(string), kvs[i+1]))
+	}
+	return l.With(fields...)
+}
+
+// WithCallerSkip returns a child logger skipping skip more stack frames when
+// reporting the caller.
+func (l *ZapFieldLogger) WithCallerSkip(skip int) core.FieldLogger {
+	return newZapFieldLogger(l.load().base.WithOptions(zap.AddCallerSkip(skip)))
+}
+
+// WithLevel returns a child logger writing the entries from level on,
+// whatever the configured level.
+func (l *ZapFieldLogger) WithLevel(level core.Level) core.FieldLogger {
+	return newZapFieldLogger(l.load().base.WithOptions(withLevel(level)))
+}
+
+// Sugar returns the key-value Logger sharing this logger's appenders.
+func (l *ZapFieldLogger) Sugar() core.Logger {
+	return newZapLogger(l.load().base)
+}
+
+func (l *ZapFieldLogger) Debug(msg string, fields ...core.Field) {
+	l.load().skipped.Debug(msg, fields...)
+}
+
+func (l *ZapFieldLogger) Info(msg string, fields ...core.Field) {
+	l.load().skipped.Info(msg, fields...)
+}
+
+func (l *ZapFieldLogger) Warn(msg string, fields ...core.Field) {
+	l.load().skipped.Warn(msg, fields...)
+}
+
+func (l *ZapFieldLogger) Error(msg string, fields ...core.Field) {
+	l.load().skipped.Error(msg, fields...)
+}
+
+func (l *ZapFieldLogger) DPanic(msg string, fields ...core.Field) {
+	l.load().skipped.DPanic(msg, fields...)
+}
+
+func (l *ZapFieldLogger) Panic(msg string, fields ...core.Field) {
+	l.load().skipped.Panic(msg, fields...)
+}
+
+func (l *ZapFieldLogger) Fatal(msg string, fields ...core.Field) {
+	l.load().skipped.Fatal(msg, fields...)
+}
+
+func (l *ZapFieldLogger) Sync() error {
+	return l.load().skipped.Sync()
+}
+
+// Unwrap returns the zap.Logger currently backing l, for adapters needing
+// zap's full API. The returned logger reports the caller of its methods.
+func Unwrap(l core.Logger) (*zap.Logger, error) {
+	switch v := l.(type) {
+	case *ZapLogger:
+		return v.load().base, nil
+	case *Core:
+		return v.getLogger("", true).load().base, nil
+	default:
+		return nil, fmt.Errorf("logger %T is not backed by zap", l)
+	}
+}
@@ -1,15 +1,75 @@
 package zap_test
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	uzap "go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
+	"github.com/shanexu/logn/appender"
+	"github.com/shanexu/logn/appender/writer"
 	"github.com/shanexu/logn/common"
+	"github.com/shanexu/logn/core"
 	"github.com/shanexu/logn/core/zap"
+	"github.com/shanexu/logn/hook"
 	_ "github.com/shanexu/logn/includes"
+	"github.com/shanexu/logn/metrics"
+	"github.com/shanexu/logn/sign"
 )
 
+// newFileCore builds a Core with a single json FILE appender writing to a
+// temporary file, followed by the given configuration. The returned function
+// syncs the core and returns the lines written so far.
+func newFileCore(t *testing.T, config string) (core.Core, func() []string) {
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	fileName := filepath.Join(dir, "app.log")
+
+	rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  file:
+    - name: FILE
+      file_name: %s
+      encoder:
+        json:
+`, fileName) + config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := zap.New(rawConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, func() []string {
+		c.Sync()
+		bs, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := strings.TrimSpace(string(bs))
+		if s == "" {
+			return nil
+		}
+		return strings.Split(s, "\n")
+	}
+}
+
 func TestNew(t *testing.T) {
 	rawConfig, err := common.NewConfigFrom(`
 appenders:
@@ -55,3 +115,1595 @@ loggers:
 	helloworld.Info("hello world")
 	c.Sync()
 }
+
+func TestZapLogger_WithNamed(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+`)
+
+	parent := c.GetLogger("parent")
+	child := parent.With("request_id", "abc").Named("child")
+	child.Info("from child")
+	child.Debug("filtered by parent level")
+	parent.Info("from parent")
+
+	ls := lines()
+	assert.Len(t, ls, 2)
+	assert.Contains(t, ls[0], `"logger":"parent.child"`)
+	assert.Contains(t, ls[0], `"request_id":"abc"`)
+	assert.Contains(t, ls[1], `"logger":"parent"`)
+	assert.NotContains(t, ls[1], "request_id")
+}
+
+func TestZapLogger_Desugar(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+`)
+
+	typed := c.GetLogger("typed").Desugar()
+	typed.With(uzap.String("k", "v")).Info("typed", uzap.Int("n", 1))
+	typed.Sugar().Infow("sugared", "n", 2)
+
+	ls := lines()
+	assert.Len(t, ls, 2)
+	assert.Contains(t, ls[0], `"k":"v","n":1`)
+	assert.Contains(t, ls[0], `"caller":"zap/zap_test.go`)
+	assert.Contains(t, ls[1], `"n":2`)
+}
+
+func TestGetLogger(t *testing.T) {
+	c, _ := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+  logger:
+    - name: " app.db"
+      level: error
+`)
+
+	// names are normalized
+	l := c.GetLogger("app.db")
+	assert.Equal(t, l, c.GetLogger("app.db."))
+	assert.False(t, l.Enabled(core.InfoLevel))
+
+	// concurrent lookups get the same logger
+	var wg sync.WaitGroup
+	ls := make([]core.Logger, 8)
+	for i := range ls {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ls[i] = c.GetLogger("app.cache")
+		}(i)
+	}
+	wg.Wait()
+	for _, other := range ls {
+		assert.True(t, ls[0] == other)
+	}
+
+	// lookups of existing loggers do not allocate
+	zc := c.(*zap.Core)
+	assert.Zero(t, testing.AllocsPerRun(100, func() { zc.GetLogger("app.cache") }))
+}
+
+func TestGetFieldLogger(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    caller: false
+    appender_refs:
+      - FILE
+`)
+
+	typed := c.GetFieldLogger("typed")
+	sugared := c.GetLogger("typed")
+	assert.Equal(t, typed, sugared.Desugar())
+	allocs := testing.AllocsPerRun(100, func() {
+		sugared.Desugar().Info("typed")
+	})
+	if !raceEnabled {
+		assert.Zero(t, allocs)
+	}
+	assert.Len(t, lines(), 101)
+
+	// field loggers follow configuration updates
+	rawConfig, err := common.NewConfigFrom(`
+appenders:
+  console:
+    - name: CONSOLE
+      target: stdout
+      encoder:
+        json:
+loggers:
+  root:
+    level: error
+    appender_refs:
+      - CONSOLE
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, c.Update(rawConfig))
+	assert.False(t, typed.Enabled(core.InfoLevel))
+	assert.False(t, c.Desugar().Enabled(core.InfoLevel))
+}
+
+func TestUpdateWhileLogging(t *testing.T) {
+	c, _ := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+`)
+	rawConfig, err := common.NewConfigFrom(`
+loggers:
+  root:
+    level: error
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := c.GetLogger("busy")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			l.Infow("busy", "i", i)
+			l.Desugar().Info("busy")
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, c.Update(rawConfig))
+	}
+	<-done
+	assert.False(t, l.Enabled(core.InfoLevel))
+}
+
+func TestStaticFields(t *testing.T) {
+	os.Setenv("LOGN_TEST_REGION", "eu-west-1")
+	defer os.Unsetenv("LOGN_TEST_REGION")
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+fields:
+  app: demo
+  region: ${LOGN_TEST_REGION}
+  env: ${LOGN_TEST_ENV:dev}
+`)
+	c.GetLogger("some").Info("hello")
+
+	hostname, _ := os.Hostname()
+	line := lines()[0]
+	assert.Contains(t, line, fmt.Sprintf(`"hostname":%q,"pid":%d`, hostname, os.Getpid()))
+	assert.Contains(t, line, `"app":"demo","env":"dev","region":"eu-west-1"`)
+}
+
+func TestMetadata(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+metadata:
+  hostname: false
+  executable: true
+  goroutine: true
+`)
+	c.GetLogger("some").Info("hello")
+	done := make(chan struct{})
+	go func() {
+		c.GetLogger("some").With("k", "v").Info("elsewhere")
+		close(done)
+	}()
+	<-done
+
+	executable, _ := os.Executable()
+	ls := lines()
+	if assert.Len(t, ls, 2) {
+		assert.NotContains(t, ls[0], `"hostname"`)
+		assert.Contains(t, ls[0], fmt.Sprintf(`"pid":%d,"executable":%q,"goroutine":`, os.Getpid(), filepath.Base(executable)))
+		assert.Contains(t, ls[1], `"k":"v","goroutine":`)
+		goroutine := func(line string) string {
+			return strings.SplitN(strings.SplitN(line, `"goroutine":`, 2)[1], ",", 2)[0]
+		}
+		assert.NotEqual(t, goroutine(ls[0]), goroutine(ls[1]))
+	}
+}
+
+func TestBuildInfo(t *testing.T) {
+	config := `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+metadata:
+  build: %s
+`
+	c, lines := newFileCore(t, fmt.Sprintf(config, "entries"))
+	c.GetLogger("some").Info("hello")
+	ls := lines()
+	if assert.Len(t, ls, 1) {
+		assert.Contains(t, ls[0], `"build":{`)
+		assert.Contains(t, ls[0], fmt.Sprintf(`"go":%q`, runtime.Version()))
+	}
+
+	c, lines = newFileCore(t, fmt.Sprintf(config, "banner"))
+	c.GetLogger("some").Info("hello")
+	ls = lines()
+	if assert.Len(t, ls, 2) {
+		assert.Contains(t, ls[0], `"msg":"build information","hostname"`)
+		assert.Contains(t, ls[0], `"build":{`)
+		assert.NotContains(t, ls[1], `"build"`)
+	}
+}
+
+func TestFatalExitHooks(t *testing.T) {
+	rawConfig, err := common.NewConfigFrom(`
+appenders:
+  console:
+    - name: CONSOLE
+      target: stdout
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - CONSOLE
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := zap.New(rawConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []string
+	core.RegisterExitHook(func() { calls = append(calls, "first") })
+	core.RegisterExitHook(func() { calls = append(calls, "second") })
+	restore := core.SetExitFunc(func(code int) { calls = append(calls, fmt.Sprint("exit ", code)) })
+	defer restore()
+
+	c.GetLogger("fatal").Fatal("going down")
+	assert.Equal(t, []string{"second", "first", "exit 1"}, calls)
+
+	assert.PanicsWithValue(t, "panicking", func() {
+		c.GetLogger("panic").Panic("panicking")
+	})
+}
+
+func TestSampling(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+  logger:
+    - name: chatty
+      sampling:
+        initial: 2
+        thereafter: 3
+        tick: 1m
+`)
+
+	chatty := c.GetLogger("chatty")
+	other := c.GetLogger("other")
+	for i := 0; i < 10; i++ {
+		chatty.Info("tick")
+		other.Info("tock")
+	}
+
+	counts := map[string]int{}
+	for _, l := range lines() {
+		if strings.Contains(l, `"msg":"tick"`) {
+			counts["chatty"]++
+		} else {
+			counts["other"]++
+		}
+	}
+	// 2 initial entries, then the 5th and 8th
+	assert.Equal(t, map[string]int{"chatty": 4, "other": 10}, counts)
+}
+
+func TestRateLimit(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+  logger:
+    - name: noisy
+      rate_limit:
+        per_second: 3
+        summary_interval: 50ms
+`)
+
+	noisy := c.GetLogger("noisy")
+	for i := 0; i < 10; i++ {
+		noisy.Infof("info %d", i)
+		noisy.Warnf("warn %d", i)
+	}
+	time.Sleep(60 * time.Millisecond)
+	// still within the one second window for info, but not for error
+	noisy.Info("dropped")
+	noisy.Error("after")
+
+	// syncing writes the summary pending
+	ls := lines()
+	assert.Len(t, ls, 9)
+	assert.Contains(t, ls[6], `"msg":"rate limit exceeded, entries suppressed"`)
+	assert.Contains(t, ls[6], `"suppressed":{"info":7,"warn":7}`)
+	assert.Contains(t, ls[7], `"msg":"after"`)
+	assert.Contains(t, ls[8], `"suppressed":{"info":1}`)
+}
+
+func TestRateLimitSummaryTimer(t *testing.T) {
+	rawConfig, err := common.NewConfigFrom(`
+appenders:
+  close_test:
+    - name: OUT
+      id: rate-limit-timer
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - OUT
+    rate_limit:
+      per_second: 1
+      summary_interval: 20ms
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := zap.New(rawConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, _ := closeTestWriters.Load("rate-limit-timer")
+	for i := 0; i < 5; i++ {
+		c.Info("burst")
+	}
+	// the summary is written once the interval passed, without waiting
+	// for another entry
+	assert.Eventually(t, func() bool {
+		out, _ := w.(*closeTestWriter).state()
+		return strings.Contains(out, `"suppressed":{"info":4}`)
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestDedup(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+  logger:
+    - name: bursty
+      dedup:
+        window: 50ms
+`)
+
+	bursty := c.GetLogger("bursty")
+	for i := 0; i < 5; i++ {
+		bursty.Infow("connection refused", "port", 5432)
+	}
+	bursty.Infow("connection refused", "port", 6379)
+	time.Sleep(60 * time.Millisecond)
+	bursty.Info("recovered")
+
+	ls := lines()
+	assert.Len(t, ls, 4)
+	assert.Contains(t, ls[0], `"port":5432`)
+	assert.NotContains(t, ls[0], `"repeated"`)
+	assert.Contains(t, ls[1], `"port":6379`)
+	assert.Contains(t, ls[2], `"port":5432,"repeated":4`)
+	assert.Contains(t, ls[3], `"msg":"recovered"`)
+}
+
+// countingStringer counts the calls to its String.
+type countingStringer struct {
+	calls int32
+}
+
+func (s *countingStringer) String() string {
+	atomic.AddInt32(&s.calls, 1)
+	return "counted"
+}
+
+func TestDedupSummaries(t *testing.T) {
+	rawConfig, err := common.NewConfigFrom(`
+appenders:
+  close_test:
+    - name: OUT
+      id: dedup-summaries
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - OUT
+  logger:
+    - name: timed
+      dedup:
+        window: 20ms
+    - name: closed
+      dedup:
+        window: 1h
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := zap.New(rawConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, _ := closeTestWriters.Load("dedup-summaries")
+	state := w.(*closeTestWriter).state
+
+	// the summary is written once the window passed, without waiting for
+	// another entry
+	timed := c.GetFieldLogger("timed")
+	for i := 0; i < 3; i++ {
+		timed.Info("timeout", uzap.Object("peer", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("host", "db")
+			return nil
+		})))
+	}
+	assert.Eventually(t, func() bool {
+		out, _ := state()
+		return strings.Contains(out, `"peer":{"host":"db"},"repeated":2`)
+	}, time.Second, 5*time.Millisecond)
+
+	// objects are told apart by what they encode, and Stringers are not
+	// called to tell entries apart
+	s := &countingStringer{}
+	closed := c.GetFieldLogger("closed")
+	fields := []zapcore.Field{uzap.Int("port", 5432), uzap.Stringer("s", s)}
+	for i := 0; i < 3; i++ {
+		closed.Info("refused", fields...)
+	}
+	fields[0] = uzap.Int("port", 6379)
+	closed.Info("refused", uzap.Object("peer", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		enc.AddString("host", "cache")
+		return nil
+	})))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&s.calls))
+
+	// the summaries pending are written on Close, with the fields of the
+	// first entry though the caller reused them
+	assert.NoError(t, c.Close())
+	out, _ := state()
+	assert.Contains(t, out, `"peer":{"host":"cache"}`)
+	assert.Contains(t, out, `"port":5432,"s":"counted","repeated":2`)
+	assert.NotContains(t, out, `6379`)
+}
+
+func TestZapLogger_Enabled(t *testing.T) {
+	c, _ := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+  logger:
+    - name: verbose
+      level: debug
+`)
+
+	quiet := c.GetLogger("quiet")
+	verbose := c.GetLogger("verbose")
+	assert.False(t, quiet.IsDebug())
+	assert.True(t, quiet.Enabled(core.WarnLevel))
+	assert.True(t, verbose.IsDebug())
+	assert.True(t, verbose.Desugar().IsDebug())
+	assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() { quiet.IsDebug() }))
+}
+
+func TestStdLog(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+`)
+
+	stdLogger, err := zap.NewStdLogAt(c.GetLogger("legacy"), core.WarnLevel)
+	assert.Nil(t, err)
+	stdLogger.Printf("from %s", "library")
+
+	restore, err := zap.RedirectStdLogAt(c.GetLogger("global"), core.ErrorLevel)
+	assert.Nil(t, err)
+	log.Print("from global")
+	restore()
+
+	ls := lines()
+	assert.Len(t, ls, 2)
+	assert.Contains(t, ls[0], `"level":"warn","ts":`)
+	assert.Contains(t, ls[0], `"logger":"legacy","caller":"zap/zap_test.go:`)
+	assert.Contains(t, ls[0], `"msg":"from library"`)
+	assert.Contains(t, ls[1], `"level":"error"`)
+	assert.Contains(t, ls[1], `"logger":"global","caller":"zap/zap_test.go:`)
+
+	out := log.Writer()
+	restore, err = c.RedirectStdLog()
+	assert.Nil(t, err)
+	log.Print("redirected")
+	restore()
+	assert.Equal(t, out, log.Writer())
+	ls = lines()
+	assert.Len(t, ls, 3)
+	assert.Contains(t, ls[2], `"logger":"stdlog"`)
+}
+
+type countingRecorder struct {
+	sync.Mutex
+	entries map[string]int
+	dropped map[string]int
+	writes  map[string]int
+}
+
+func (r *countingRecorder) Entry(logger string, level zapcore.Level) {
+	r.Lock()
+	r.entries[logger+"/"+level.String()]++
+	r.Unlock()
+}
+
+func (r *countingRecorder) Dropped(logger string, level zapcore.Level, reason string) {
+	r.Lock()
+	r.dropped[logger+"/"+reason]++
+	r.Unlock()
+}
+
+func (r *countingRecorder) AppenderWrite(appender string, elapsed time.Duration, err error) {
+	r.Lock()
+	r.writes[appender]++
+	r.Unlock()
+}
+
+func (r *countingRecorder) AppenderBytes(appender string, bytes int) {}
+
+func (r *countingRecorder) AppenderEncodeError(appender string) {}
+
+func (r *countingRecorder) QueueDepth(appender string, depth int) {}
+
+func (r *countingRecorder) QueueCapacity(appender string, capacity int) {}
+
+func (r *countingRecorder) QueueFull(appender string, outcome string) {}
+
+func TestMetrics(t *testing.T) {
+	r := &countingRecorder{entries: map[string]int{}, dropped: map[string]int{}, writes: map[string]int{}}
+	metrics.SetRecorder(r)
+	defer metrics.SetRecorder(nil)
+
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+  logger:
+    - name: noisy
+      rate_limit:
+        per_second: 2
+`)
+	c.GetLogger("quiet").Debug("disabled")
+	c.GetLogger("quiet").Warn("written")
+	for i := 0; i < 5; i++ {
+		c.GetLogger("noisy").Info("burst")
+	}
+
+	// the summary of the dropped entries is written on sync
+	assert.Len(t, lines(), 4)
+	assert.Equal(t, map[string]int{"quiet/warn": 1, "noisy/info": 2}, r.entries)
+	assert.Equal(t, map[string]int{"noisy/rate_limit": 3}, r.dropped)
+	assert.Equal(t, map[string]int{"FILE": 4}, r.writes)
+}
+
+func TestStats(t *testing.T) {
+	c, _ := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+  logger:
+    - name: configured
+`)
+	c.GetLogger("a").Info("one")
+	c.GetLogger("a").Debug("disabled")
+	c.GetLogger("b").Warn("two")
+
+	st := c.Stats()
+	assert.Equal(t, 3, st.Loggers)
+	assert.Equal(t, uint64(1), st.Entries["info"])
+	assert.Equal(t, uint64(1), st.Entries["warn"])
+	assert.Equal(t, uint64(0), st.Entries["debug"])
+	assert.Equal(t, map[string]uint64{"FILE": 0}, st.AppenderErrors)
+	file := st.Appenders["FILE"]
+	assert.Equal(t, uint64(2), file.Entries)
+	assert.True(t, file.Bytes > 0)
+	assert.Equal(t, uint64(0), file.EncodeErrors+file.WriteErrors)
+
+	// counters survive configuration updates
+	rawConfig, err := common.NewConfigFrom(`
+appenders:
+  console:
+    - name: CONSOLE
+      encoder:
+        console:
+loggers:
+  root:
+    level: error
+    appender_refs:
+      - CONSOLE
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, c.Update(rawConfig))
+	c.GetLogger("a").Info("disabled")
+	st = c.Stats()
+	assert.Equal(t, uint64(1), st.Entries["info"])
+}
+
+// flakyWriter fails the writes while failing is set.
+type flakyWriter struct {
+	failing int32
+	writes  int32
+}
+
+func (w *flakyWriter) Write(p []byte) (int, error) {
+	atomic.AddInt32(&w.writes, 1)
+	if atomic.LoadInt32(&w.failing) == 1 {
+		return 0, errors.New("device unavailable")
+	}
+	return len(p), nil
+}
+
+func (w *flakyWriter) Sync() error { return nil }
+
+var flaky = &flakyWriter{}
+
+func init() {
+	writer.RegisterType("flaky_test", func(*common.Config) (writer.Writer, error) {
+		return flaky, nil
+	})
+}
+
+func TestAudit(t *testing.T) {
+	c, lines := newFileCore(t, `
+  flaky_test:
+    - name: FLAKY
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+    rate_limit:
+      per_second: 1
+  logger:
+    - name: audit
+      audit:
+        on_failure: error
+    - name: blocking
+      appender_refs:
+        - FLAKY
+      audit:
+        on_failure: block
+        retry_interval: 10ms
+`)
+	a := c.GetLogger("audit")
+	for i := 0; i < 3; i++ {
+		a.Infow("granted", "user", "bob")
+	}
+	ls := lines()
+	if assert.Len(t, ls, 3) {
+		assert.Contains(t, ls[0], `"seq":1`)
+		assert.Contains(t, ls[2], `"seq":3`)
+	}
+
+	appender.SetErrorHandler(nil)
+	defer appender.SetErrorHandler(appender.NewRateLimitedErrorHandler(os.Stderr, time.Second))
+	atomic.StoreInt32(&flaky.failing, 1)
+	time.AfterFunc(50*time.Millisecond, func() { atomic.StoreInt32(&flaky.failing, 0) })
+	start := time.Now()
+	c.GetLogger("blocking").Info("revoked")
+	assert.True(t, time.Since(start) >= 50*time.Millisecond)
+	assert.True(t, atomic.LoadInt32(&flaky.writes) > 1)
+}
+
+func TestAppenderHealth(t *testing.T) {
+	w := &flakyWriter{}
+	writer.RegisterType("flaky_health_test", func(*common.Config) (writer.Writer, error) {
+		return w, nil
+	})
+	c, _ := newFileCore(t, `
+  flaky_health_test:
+    - name: FLAKY
+      circuit_breaker:
+        failures: 2
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+      - FLAKY
+`)
+	health := c.AppenderHealth()
+	assert.Equal(t, core.AppenderConnected, health["FILE"].State)
+	assert.True(t, health["FILE"].LastSuccess.IsZero())
+
+	c.Info("written")
+	health = c.AppenderHealth()
+	assert.Equal(t, core.AppenderConnected, health["FLAKY"].State)
+	assert.False(t, health["FLAKY"].LastSuccess.IsZero())
+
+	appender.SetErrorHandler(nil)
+	defer appender.SetErrorHandler(appender.NewRateLimitedErrorHandler(os.Stderr, time.Second))
+	atomic.StoreInt32(&w.failing, 1)
+	c.Info("failed")
+	health = c.AppenderHealth()
+	assert.Equal(t, core.AppenderDegraded, health["FLAKY"].State)
+	assert.Equal(t, "device unavailable", health["FLAKY"].LastError)
+	assert.Equal(t, uint64(1), health["FLAKY"].Errors)
+	assert.Equal(t, core.AppenderConnected, health["FILE"].State)
+
+	c.Info("failed again")
+	c.Info("refused")
+	health = c.AppenderHealth()
+	assert.Equal(t, core.AppenderOpenFailed, health["FLAKY"].State)
+	assert.Equal(t, "device unavailable", health["FLAKY"].LastError)
+	assert.Equal(t, uint64(3), health["FLAKY"].Errors)
+	st := c.Stats().Appenders["FLAKY"]
+	assert.Equal(t, uint64(1), st.Entries)
+	assert.Equal(t, uint64(3), st.WriteErrors)
+}
+
+func TestFilter(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: debug
+    appender_refs:
+      - FILE
+    filter: level >= 'warn' || fields.tenant == 'acme'
+  logger:
+    - name: billing
+      filter: message != 'noise'
+`)
+	c.GetLogger("web").Info("dropped")
+	c.GetLogger("web").With("tenant", "acme").Debug("tenant")
+	c.GetLogger("web").Error("failed")
+	c.GetLogger("billing").Info("charged")
+	c.GetLogger("billing").Info("noise")
+
+	ls := lines()
+	if assert.Len(t, ls, 3) {
+		assert.Contains(t, ls[0], `"msg":"tenant"`)
+		assert.Contains(t, ls[1], `"msg":"failed"`)
+		assert.Contains(t, ls[2], `"msg":"charged"`)
+	}
+
+	rawConfig, err := common.NewConfigFrom(`
+appenders:
+  console:
+    - name: CONSOLE
+      filter: level >>= 'warn'
+      encoder:
+        console:
+`)
+	assert.Nil(t, err)
+	_, err = zap.New(rawConfig)
+	assert.NotNil(t, err)
+}
+
+func TestMessageFilter(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+    deny_messages:
+      - ^health
+  logger:
+    - name: forensic
+      allow_messages:
+        - login
+`)
+	c.GetLogger("web").Info("health check")
+	c.GetLogger("web").Info("served")
+	c.GetLogger("forensic").Info("login failed")
+	c.GetLogger("forensic").Info("served")
+
+	ls := lines()
+	if assert.Len(t, ls, 2) {
+		assert.Contains(t, ls[0], `"msg":"served"`)
+		assert.Contains(t, ls[1], `"msg":"login failed"`)
+	}
+}
+
+func TestFieldFilters(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+    field_filters:
+      - field: user_agent
+        prefix: kube-probe/
+`)
+	l := c.GetLogger("web")
+	l.Infow("served", "user_agent", "kube-probe/1.27")
+	l.Infow("served", "user_agent", "curl/8.0")
+
+	ls := lines()
+	if assert.Len(t, ls, 1) {
+		assert.Contains(t, ls[0], `"user_agent":"curl/8.0"`)
+	}
+
+	rawConfig, err := common.NewConfigFrom(`
+loggers:
+  root:
+    field_filters:
+      - field: user_agent
+        action: ignore
+`)
+	assert.Nil(t, err)
+	_, err = zap.New(rawConfig)
+	assert.NotNil(t, err)
+}
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+func TestSetClock(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+`)
+	l := c.GetLogger("clock")
+	c.SetClock(fixedClock(time.Unix(1600000000, 0)))
+	l.Info("frozen")
+	c.SetClock(nil)
+	l.Info("running")
+
+	ls := lines()
+	if assert.Len(t, ls, 2) {
+		assert.Contains(t, ls[0], `"ts":1600000000,`)
+		assert.NotContains(t, ls[1], `"ts":1600000000,`)
+	}
+}
+
+func logThroughWrapper(l core.Logger, msg string) {
+	l.WithCallerSkip(1).Info(msg)
+}
+
+func TestCaller(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+  logger:
+    - name: nocaller
+      caller: false
+`)
+	logThroughWrapper(c.GetLogger("wrapped"), "wrapped")
+	_, _, line, _ := runtime.Caller(0)
+	c.GetLogger("nocaller").Info("no caller")
+
+	ls := lines()
+	if assert.Len(t, ls, 2) {
+		assert.Contains(t, ls[0], fmt.Sprintf(`"caller":"zap/zap_test.go:%d"`, line-1))
+		assert.NotContains(t, ls[1], `"caller"`)
+	}
+}
+
+func TestCallerNotRendered(t *testing.T) {
+	rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  file:
+    - name: DISCARD
+      file_name: %s
+      encoder:
+        json:
+          caller_key: ""
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - DISCARD
+`, os.DevNull))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := zap.New(rawConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// callers are not captured, as no appender renders them
+	l := c.GetFieldLogger("typed")
+	allocs := testing.AllocsPerRun(100, func() {
+		l.Info("typed")
+	})
+	if !raceEnabled {
+		assert.Zero(t, allocs)
+	}
+}
+
+func TestStacktraceLevel(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+    stacktrace_level: warn
+  logger:
+    - name: quiet
+      stacktrace_level: "off"
+`)
+	c.GetLogger("loud").Warn("warned")
+	c.GetLogger("loud").Info("informed")
+	c.GetLogger("quiet").Error("failed")
+
+	ls := lines()
+	if assert.Len(t, ls, 3) {
+		assert.Contains(t, ls[0], `"stacktrace"`)
+		assert.NotContains(t, ls[1], `"stacktrace"`)
+		assert.NotContains(t, ls[2], `"stacktrace"`)
+	}
+}
+
+func TestSigning(t *testing.T) {
+	os.Setenv("LOGN_TEST_SIGNING_KEY", base64.StdEncoding.EncodeToString([]byte("0123456789abcdef")))
+	defer os.Unsetenv("LOGN_TEST_SIGNING_KEY")
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "audit.log")
+
+	rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  file:
+    - name: AUDIT
+      file_name: %s
+      signing:
+        key: env:LOGN_TEST_SIGNING_KEY
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - AUDIT
+`, fileName))
+	assert.Nil(t, err)
+	c, err := zap.New(rawConfig)
+	assert.Nil(t, err)
+	c.GetLogger("audit").With("user", "shane").Info("login")
+	c.GetLogger("audit").Warn("logout")
+	c.Sync()
+
+	f, err := os.Open(fileName)
+	assert.Nil(t, err)
+	defer f.Close()
+	res, err := sign.Verify(f, []byte("0123456789abcdef"), sign.DefaultField)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, res.Entries)
+}
+
+func TestSharedLevels(t *testing.T) {
+	config := `
+appenders:
+  console:
+    - name: CONSOLE
+      target: stdout
+      encoder:
+        json:
+loggers:
+  root:
+    level: %s
+    appender_refs:
+      - CONSOLE
+  logger:
+    - name: http
+      level: %s
+    - name: db
+`
+	rawConfig, err := common.NewConfigFrom(fmt.Sprintf(config, "info", "debug"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := zap.New(rawConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	child := c.GetLogger("http").With("k", "v")
+	db := c.GetLogger("db").Named("pool")
+	assert.True(t, child.IsDebug())
+	assert.False(t, db.IsDebug())
+
+	// updates set the shared levels in place, reaching derived loggers
+	rawConfig, err = common.NewConfigFrom(fmt.Sprintf(config, "debug", "warn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, c.Update(rawConfig))
+	assert.False(t, child.Enabled(core.InfoLevel))
+	assert.True(t, db.IsDebug())
+
+	// failed updates leave them alone
+	rawConfig, err = common.NewConfigFrom(fmt.Sprintf(config, "error", "nonsense"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotNil(t, c.Update(rawConfig))
+	assert.True(t, db.IsDebug())
+}
+
+func TestWithLevel(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: warn
+    appender_refs:
+      - FILE
+`)
+	l := c.GetLogger("req").With("request_id", "abc")
+	debug := l.WithLevel(core.DebugLevel)
+	assert.True(t, debug.IsDebug())
+	assert.False(t, l.IsDebug())
+
+	l.Debug("dropped")
+	debug.Debug("forced")
+	debug.Named("db").With("table", "users").Debug("forced child")
+	debug.Desugar().Debug("forced typed")
+	l.Desugar().WithLevel(core.ErrorLevel).Warn("raised")
+
+	ls := lines()
+	if assert.Len(t, ls, 3) {
+		assert.Contains(t, ls[0], `"msg":"forced"`)
+		assert.Contains(t, ls[0], `"request_id":"abc"`)
+		assert.Contains(t, ls[1], `"logger":"req.db"`)
+		assert.Contains(t, ls[1], `"table":"users"`)
+		assert.Contains(t, ls[2], `"msg":"forced typed"`)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "missing", "app.log")
+	rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  rolling_file:
+    - name: FILE
+      file_name: %s
+      async:
+        spill_file: %s.spill
+      encoder:
+        json:
+  console:
+    - name: CONSOLE
+      filter: message != 'noise'
+      encoder:
+        console:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+      - CONSOLE
+  logger:
+    - name: audit
+      level: warn
+      appender_refs:
+        - FILE
+`, file, file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := zap.DryRun(rawConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = os.Stat(filepath.Dir(file))
+	assert.True(t, os.IsNotExist(err))
+
+	info := []zapcore.Level{zapcore.InfoLevel, zapcore.WarnLevel, zapcore.ErrorLevel}
+	assert.Equal(t, []string{"", "audit"}, r.Loggers())
+	assert.Equal(t, map[string][]zapcore.Level{"FILE": info, "CONSOLE": info}, r.Routes[""])
+	assert.Equal(t, map[string][]zapcore.Level{"FILE": info[1:]}, r.Routes["audit"])
+	assert.Equal(t, 5, r.Appenders["FILE"].Entries)
+	assert.Empty(t, r.Appenders["FILE"].Errors)
+	assert.Equal(t, 3, r.Appenders["CONSOLE"].Entries)
+
+	rawConfig, err = common.NewConfigFrom(`
+appenders:
+  nonsense:
+    - name: X
+      encoder:
+        json:
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = zap.DryRun(rawConfig)
+	assert.NotNil(t, err)
+}
+
+func TestAppenderLevels(t *testing.T) {
+	config := `
+appenders:
+  file:
+    - name: FILE
+      file_name: /nonexistent/app.log
+      encoder:
+        json:
+  console:
+    - name: CONSOLE
+      encoder:
+        console:
+loggers:
+  root:
+    level: debug
+    appender_refs:
+      - FILE
+      - CONSOLE
+    appender_levels:
+      CONSOLE: warn
+  logger:
+    - name: db
+      level: info
+    - name: web
+      appender_refs:
+        - FILE
+        - CONSOLE
+      appender_levels:
+        FILE: error
+    - name: jobs
+      appender_refs:
+        - FILE
+`
+	rawConfig, err := common.NewConfigFrom(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := zap.DryRun(rawConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := []zapcore.Level{zapcore.DebugLevel, zapcore.InfoLevel, zapcore.WarnLevel, zapcore.ErrorLevel}
+	assert.Equal(t, map[string][]zapcore.Level{"FILE": all, "CONSOLE": all[2:]}, r.Routes[""])
+	// the levels of root come with its refs
+	assert.Equal(t, map[string][]zapcore.Level{"FILE": all[1:], "CONSOLE": all[2:]}, r.Routes["db"])
+	assert.Equal(t, map[string][]zapcore.Level{"FILE": all[3:], "CONSOLE": all}, r.Routes["web"])
+	assert.Equal(t, map[string][]zapcore.Level{"FILE": all}, r.Routes["jobs"])
+
+	rawConfig, err = common.NewConfigFrom(strings.Replace(config, "FILE: error", "CONSOLE: loud", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = zap.DryRun(rawConfig)
+	assert.EqualError(t, err, `level of appender "CONSOLE": unrecognized level: "loud"`)
+	rawConfig, err = common.NewConfigFrom(strings.Replace(config, "FILE: error", "OTHER: error", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = zap.DryRun(rawConfig)
+	assert.EqualError(t, err, `level of appender "OTHER", which is not referenced`)
+}
+
+func TestAppenderLevelRange(t *testing.T) {
+	config := `
+appenders:
+  console:
+    - name: OUT
+      max_level: info
+      encoder:
+        console:
+    - name: ERR
+      target: stderr
+      min_level: warn
+      encoder:
+        console:
+loggers:
+  root:
+    level: debug
+    appender_refs:
+      - OUT
+      - ERR
+`
+	rawConfig, err := common.NewConfigFrom(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := zap.DryRun(rawConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := []zapcore.Level{zapcore.DebugLevel, zapcore.InfoLevel, zapcore.WarnLevel, zapcore.ErrorLevel}
+	assert.Equal(t, map[string][]zapcore.Level{"OUT": all[:2], "ERR": all[2:]}, r.Routes[""])
+
+	rawConfig, err = common.NewConfigFrom(strings.Replace(config, "max_level: info", "max_level: info\n      min_level: error", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = zap.DryRun(rawConfig)
+	assert.EqualError(t, err, "min_level error is above max_level info")
+}
+
+func TestSequence(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+    sequence:
+      key: entry
+  logger:
+    - name: a
+      sequence:
+        scope: global
+    - name: b
+      sequence:
+        scope: global
+`)
+	c.GetLogger("x").Info("x1")
+	c.GetLogger("a").Info("a1")
+	c.GetLogger("x").Debug("dropped")
+	c.GetLogger("b").Info("b1")
+	c.GetLogger("x").With("k", "v").Info("x2")
+	c.GetLogger("y").Info("y1")
+	c.GetLogger("a").Info("a2")
+
+	ls := lines()
+	if assert.Len(t, ls, 6) {
+		assert.Contains(t, ls[0], `"entry":1`)
+		assert.Contains(t, ls[1], `"seq":1`)
+		assert.Contains(t, ls[2], `"seq":2`)
+		assert.Contains(t, ls[3], `"entry":2`)
+		assert.Contains(t, ls[4], `"entry":1`)
+		assert.Contains(t, ls[5], `"seq":3`)
+	}
+}
+
+func TestBoostLevel(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: warn
+    appender_refs:
+      - FILE
+  logger:
+    - name: http
+    - name: db
+      level: error
+`)
+	http := c.GetLogger("http")
+	db := c.GetLogger("db").With("table", "users")
+
+	cancelInfo := c.BoostLevel("db", core.InfoLevel, time.Minute)
+	cancelDebug := c.BoostLevel("db", core.DebugLevel, time.Minute)
+	assert.True(t, db.IsDebug())
+	assert.False(t, http.Enabled(core.InfoLevel))
+	cancelDebug()
+	cancelDebug()
+	assert.False(t, db.IsDebug())
+	assert.True(t, db.Enabled(core.InfoLevel))
+
+	// boosts outlive updates of the configuration
+	rawConfig, err := common.NewConfigFrom(`
+loggers:
+  root:
+    level: warn
+    appender_refs:
+      - FILE
+  logger:
+    - name: db
+      level: error
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Update(rawConfig)
+	assert.True(t, c.GetLogger("db").Enabled(core.InfoLevel))
+	cancelInfo()
+	assert.False(t, db.Enabled(core.InfoLevel))
+	assert.False(t, c.GetLogger("db").Enabled(core.InfoLevel))
+
+	// boosts end by themselves
+	c.BoostLevel("", core.InfoLevel, 10*time.Millisecond)
+	assert.True(t, http.Enabled(core.InfoLevel))
+	assert.Eventually(t, func() bool { return !http.Enabled(core.InfoLevel) }, time.Second, time.Millisecond)
+
+	assert.Len(t, lines(), 6)
+}
+
+func init() {
+	hook.Register("tag_test", func(e *hook.Entry) bool {
+		e.Fields = append(e.Fields, uzap.Bool("tagged", true))
+		return true
+	})
+}
+
+// TestWrappedAppenderChecks checks that the settings acting on all the entries
+// of a logger leave its appenders to decide which entries they write.
+func TestWrappedAppenderChecks(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config string
+		key    string
+	}{
+		{"hooks", `
+hooks: [tag_test]
+loggers:
+  root:
+    level: info
+    appender_refs: [ALL, WARN, QUIET]
+`, `"tagged":true`},
+		{"dedup", `
+loggers:
+  root:
+    level: info
+    appender_refs: [ALL, WARN, QUIET]
+    dedup:
+      window: 1m
+`, `"logger":"x"`},
+		{"audit", `
+loggers:
+  root:
+    level: info
+    appender_refs: [ALL, WARN, QUIET]
+  logger:
+    - name: x
+      audit:
+        on_failure: error
+`, `"seq":`},
+		{"sequence", `
+loggers:
+  root:
+    level: info
+    appender_refs: [ALL, WARN, QUIET]
+    sequence:
+      key: entry
+`, `"entry":`},
+		{"goroutine", `
+metadata:
+  goroutine: true
+loggers:
+  root:
+    level: info
+    appender_refs: [ALL, WARN, QUIET]
+`, `"goroutine":`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "logn")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  file:
+    - name: ALL
+      file_name: %[1]s/all.log
+      encoder:
+        json:
+    - name: WARN
+      file_name: %[1]s/warn.log
+      min_level: warn
+      encoder:
+        json:
+    - name: QUIET
+      file_name: %[1]s/quiet.log
+      deny_messages: [noise]
+      encoder:
+        json:
+`, dir) + tc.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, err := zap.New(rawConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l := c.GetLogger("x")
+			l.Info("noise")
+			l.Info("kept")
+			l.Warn("noise")
+			c.Sync()
+
+			lines := func(name string) []string {
+				bs, _ := ioutil.ReadFile(filepath.Join(dir, name))
+				return strings.Split(strings.TrimSpace(string(bs)), "\n")
+			}
+			all := lines("all.log")
+			if assert.Len(t, all, 3) {
+				for _, line := range all {
+					assert.Contains(t, line, tc.key)
+				}
+			}
+			warn := lines("warn.log")
+			if assert.Len(t, warn, 1) {
+				assert.Contains(t, warn[0], `"level":"warn"`)
+			}
+			quiet := lines("quiet.log")
+			if assert.Len(t, quiet, 1) {
+				assert.Contains(t, quiet[0], `"msg":"kept"`)
+			}
+		})
+	}
+}
+
+// closeTestWriter records what is written to it until closed.
+type closeTestWriter struct {
+	mu     sync.Mutex
+	buf    strings.Builder
+	closed bool
+}
+
+func (w *closeTestWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, writer.ErrClosed
+	}
+	return w.buf.Write(p)
+}
+
+func (w *closeTestWriter) Sync() error {
+	return nil
+}
+
+func (w *closeTestWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	return nil
+}
+
+func (w *closeTestWriter) state() (string, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String(), w.closed
+}
+
+// closeTestWriters holds the writers of the close_test appenders by id.
+var closeTestWriters sync.Map
+
+func init() {
+	writer.RegisterType("close_test", func(config *common.Config) (writer.Writer, error) {
+		id, err := config.String("id", -1)
+		if err != nil {
+			return nil, err
+		}
+		w := &closeTestWriter{}
+		closeTestWriters.Store(id, w)
+		return w, nil
+	})
+}
+
+func TestUpdateClosesAppenders(t *testing.T) {
+	config := func(id string) *common.Config {
+		rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  close_test:
+    - name: OUT
+      id: %s
+      async:
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - OUT
+`, id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rawConfig
+	}
+	state := func(id string) (string, bool) {
+		w, _ := closeTestWriters.Load(id)
+		return w.(*closeTestWriter).state()
+	}
+
+	c, err := zap.New(config("update-first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := c.GetLogger("svc")
+	child := l.With("k", "v")
+	child.Info("before")
+
+	assert.NoError(t, c.Update(config("update-second")))
+	out, closed := state("update-first")
+	assert.True(t, closed)
+	assert.Contains(t, out, `"msg":"before"`)
+
+	// loggers derived before the update write to the appender replacing
+	// the closed one
+	child.Info("after")
+	l.Info("direct")
+	c.Sync()
+	out, closed = state("update-second")
+	assert.False(t, closed)
+	assert.Contains(t, out, `"msg":"after"`)
+	assert.Contains(t, out, `"k":"v"`)
+	assert.Contains(t, out, `"msg":"direct"`)
+	out, _ = state("update-first")
+	assert.NotContains(t, out, "after")
+}
+
+func TestInvalidConfigClosesAppenders(t *testing.T) {
+	rawConfig, err := common.NewConfigFrom(`
+appenders:
+  close_test:
+    - name: OUT
+      id: invalid-config
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - MISSING
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = zap.New(rawConfig)
+	assert.Error(t, err)
+	w, _ := closeTestWriters.Load("invalid-config")
+	_, closed := w.(*closeTestWriter).state()
+	assert.True(t, closed)
+}
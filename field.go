@@ -0,0 +1,178 @@
+package logn
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/core"
+	"github.com/shanexu/logn/marker"
+)
+
+// Field is a strongly-typed key-value pair accepted by core.FieldLogger.
+type Field = core.Field
+
+// Skip constructs a no-op field.
+func Skip() Field {
+	return zap.Skip()
+}
+
+// Binary constructs a field that carries an opaque binary blob.
+func Binary(key string, val []byte) Field {
+	return zap.Binary(key, val)
+}
+
+// Bool constructs a field that carries a bool.
+func Bool(key string, val bool) Field {
+	return zap.Bool(key, val)
+}
+
+// ByteString constructs a field that carries UTF-8 encoded text as a []byte.
+func ByteString(key string, val []byte) Field {
+	return zap.ByteString(key, val)
+}
+
+// Float64 constructs a field that carries a float64.
+func Float64(key string, val float64) Field {
+	return zap.Float64(key, val)
+}
+
+// Float32 constructs a field that carries a float32.
+func Float32(key string, val float32) Field {
+	return zap.Float32(key, val)
+}
+
+// Int constructs a field with the given key and value.
+func Int(key string, val int) Field {
+	return zap.Int(key, val)
+}
+
+// Int64 constructs a field with the given key and value.
+func Int64(key string, val int64) Field {
+	return zap.Int64(key, val)
+}
+
+// Int32 constructs a field with the given key and value.
+func Int32(key string, val int32) Field {
+	return zap.Int32(key, val)
+}
+
+// Uint constructs a field with the given key and value.
+func Uint(key string, val uint) Field {
+	return zap.Uint(key, val)
+}
+
+// Uint64 constructs a field with the given key and value.
+func Uint64(key string, val uint64) Field {
+	return zap.Uint64(key, val)
+}
+
+// Uint32 constructs a field with the given key and value.
+func Uint32(key string, val uint32) Field {
+	return zap.Uint32(key, val)
+}
+
+// String constructs a field with the given key and value.
+func String(key string, val string) Field {
+	return zap.String(key, val)
+}
+
+// Strings constructs a field that carries a slice of strings.
+func Strings(key string, val []string) Field {
+	return zap.Strings(key, val)
+}
+
+// Stringer constructs a field with the given key and the output of the
+// value's String method. The String method is called lazily.
+func Stringer(key string, val fmt.Stringer) Field {
+	return zap.Stringer(key, val)
+}
+
+// Time constructs a field with the given key and value.
+func Time(key string, val time.Time) Field {
+	return zap.Time(key, val)
+}
+
+// Duration constructs a field with the given key and value.
+func Duration(key string, val time.Duration) Field {
+	return zap.Duration(key, val)
+}
+
+// Err is shorthand for the common idiom NamedError("error", err).
+func Err(err error) Field {
+	return zap.Error(err)
+}
+
+// NamedError constructs a field that lazily stores err.Error() under the
+// provided key.
+func NamedError(key string, err error) Field {
+	return zap.NamedError(key, err)
+}
+
+// Object constructs a field with the given key and ObjectMarshaler.
+func Object(key string, val zapcore.ObjectMarshaler) Field {
+	return zap.Object(key, val)
+}
+
+// Array constructs a field with the given key and ArrayMarshaler.
+func Array(key string, val zapcore.ArrayMarshaler) Field {
+	return zap.Array(key, val)
+}
+
+// Namespace creates a named, isolated scope within the logger's context. All
+// subsequent fields will be added to the new namespace.
+func Namespace(key string) Field {
+	return zap.Namespace(key)
+}
+
+// Reflect constructs a field with the given key and an arbitrary object,
+// serialized with reflection by the appender's encoder.
+func Reflect(key string, val interface{}) Field {
+	return zap.Reflect(key, val)
+}
+
+// Any takes a key and an arbitrary value and chooses the best way to
+// represent them as a field, falling back to Reflect only when necessary.
+func Any(key string, val interface{}) Field {
+	return zap.Any(key, val)
+}
+
+// Marker constructs a field tagging the entry with named markers, which
+// appenders select entries by with their markers and deny_markers settings.
+func Marker(names ...string) Field {
+	return marker.Field(names...)
+}
+
+// Lazy constructs a field whose value is computed by fn only when an entry
+// carrying it is actually encoded, i.e. after level and sampling checks have
+// passed. fn is called at most once, even when several appenders encode the
+// entry. The result is serialized like Reflect.
+func Lazy(key string, fn func() interface{}) Field {
+	return zap.Reflect(key, &lazyValue{fn: fn})
+}
+
+type lazyValue struct {
+	once  sync.Once
+	fn    func() interface{}
+	value interface{}
+}
+
+func (l *lazyValue) get() interface{} {
+	l.once.Do(func() {
+		l.value = l.fn()
+		l.fn = nil
+	})
+	return l.value
+}
+
+func (l *lazyValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.get())
+}
+
+func (l *lazyValue) String() string {
+	return fmt.Sprint(l.get())
+}
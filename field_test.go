@@ -0,0 +1,30 @@
+package logn
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLazy(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg"})
+	zc := zapcore.NewCore(enc, zapcore.AddSync(buf), zapcore.InfoLevel)
+	logger := zap.New(zapcore.NewTee(zc, zc))
+
+	calls := 0
+	dump := func() interface{} {
+		calls++
+		return map[string]int{"size": 3}
+	}
+
+	logger.Debug("suppressed", Lazy("dump", dump))
+	assert.Equal(t, 0, calls)
+
+	logger.Info("emitted", Lazy("dump", dump))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "{\"msg\":\"emitted\",\"dump\":{\"size\":3}}\n{\"msg\":\"emitted\",\"dump\":{\"size\":3}}\n", buf.String())
+}
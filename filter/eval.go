@@ -0,0 +1,187 @@
+package filter
+
+import (
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// env is what an expression is evaluated against. Fields are only encoded
+// into a map when the expression looks at them.
+type env struct {
+	ent     zapcore.Entry
+	fields  [][]zapcore.Field
+	encoded map[string]interface{}
+}
+
+func (e *env) field(path []string) interface{} {
+	if e.encoded == nil {
+		enc := zapcore.NewMapObjectEncoder()
+		for _, fs := range e.fields {
+			for _, f := range fs {
+				f.AddTo(enc)
+			}
+		}
+		e.encoded = enc.Fields
+	}
+	var v interface{} = e.encoded
+	for _, k := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[k]
+	}
+	return v
+}
+
+type node interface {
+	eval(e *env) interface{}
+}
+
+type literal struct{ v interface{} }
+
+func (n literal) eval(*env) interface{} { return n.v }
+
+type levelNode struct{}
+
+func (levelNode) eval(e *env) interface{} { return e.ent.Level }
+
+type entryNode func(e *env) interface{}
+
+func (n entryNode) eval(e *env) interface{} { return n(e) }
+
+type fieldNode []string
+
+func (n fieldNode) eval(e *env) interface{} { return e.field(n) }
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(e *env) interface{} {
+	return truthy(n.left.eval(e)) || truthy(n.right.eval(e))
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(e *env) interface{} {
+	return truthy(n.left.eval(e)) && truthy(n.right.eval(e))
+}
+
+type notNode struct{ n node }
+
+func (n notNode) eval(e *env) interface{} { return !truthy(n.n.eval(e)) }
+
+type cmpNode struct {
+	op          string
+	left, right node
+}
+
+func (n cmpNode) eval(e *env) interface{} {
+	l, r := n.left.eval(e), n.right.eval(e)
+	c, ok := compare(l, r)
+	switch n.op {
+	case "==":
+		return ok && c == 0
+	case "!=":
+		return !ok || c != 0
+	case "<":
+		return ok && c < 0
+	case "<=":
+		return ok && c <= 0
+	case ">":
+		return ok && c > 0
+	case ">=":
+		return ok && c >= 0
+	}
+	return false
+}
+
+// compare orders a and b, reporting false when they are not comparable.
+func compare(a, b interface{}) (int, bool) {
+	if a == nil || b == nil {
+		return 0, a == nil && b == nil
+	}
+	if fa, ok := number(a); ok {
+		fb, ok := number(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case fa < fb:
+			return -1, true
+		case fa > fb:
+			return 1, true
+		}
+		return 0, true
+	}
+	switch av := a.(type) {
+	case string:
+		bv, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case av < bv:
+			return -1, true
+		case av > bv:
+			return 1, true
+		}
+		return 0, true
+	case bool:
+		bv, ok := b.(bool)
+		if !ok || av != bv {
+			return 0, false
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case zapcore.Level:
+		return float64(n), true
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case time.Duration:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func truthy(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case string:
+		return t != ""
+	}
+	if f, ok := number(v); ok {
+		return f != 0
+	}
+	return true
+}
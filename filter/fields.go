@@ -0,0 +1,154 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+
+	cfg "github.com/shanexu/logn/config"
+)
+
+type fieldRule struct {
+	field   string
+	equals  *string
+	prefix  string
+	matches *regexp.Regexp
+}
+
+func (r *fieldRule) match(fields [][]zapcore.Field) bool {
+	f, ok := lookup(fields, r.field)
+	if !ok {
+		return false
+	}
+	if r.equals == nil && r.prefix == "" && r.matches == nil {
+		return true
+	}
+	v := stringValue(f)
+	if r.equals != nil && v != *r.equals {
+		return false
+	}
+	if !strings.HasPrefix(v, r.prefix) {
+		return false
+	}
+	return r.matches == nil || r.matches.MatchString(v)
+}
+
+// lookup returns the last field named key, later fields overriding earlier
+// ones as they do in the encoded entry.
+func lookup(fields [][]zapcore.Field, key string) (zapcore.Field, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		fs := fields[i]
+		for j := len(fs) - 1; j >= 0; j-- {
+			if fs[j].Key == key && fs[j].Type != zapcore.SkipType {
+				return fs[j], true
+			}
+		}
+	}
+	return zapcore.Field{}, false
+}
+
+func stringValue(f zapcore.Field) string {
+	if f.Type == zapcore.StringType {
+		return f.String
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	return fmt.Sprint(enc.Fields[f.Key])
+}
+
+// Fields selects entries with declarative field filters.
+type Fields struct {
+	drop []*fieldRule
+	keep []*fieldRule
+}
+
+// NewFields compiles configs, returning nil when there are none.
+func NewFields(configs []cfg.FieldFilter) (*Fields, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+	fs := &Fields{}
+	for i := range configs {
+		c := &configs[i]
+		r := &fieldRule{field: c.Field, prefix: c.Prefix}
+		if c.Equals != "" {
+			r.equals = &c.Equals
+		}
+		if c.Matches != "" {
+			re, err := regexp.Compile(c.Matches)
+			if err != nil {
+				return nil, err
+			}
+			r.matches = re
+		}
+		switch c.Action {
+		case "", "drop":
+			fs.drop = append(fs.drop, r)
+		case "keep":
+			fs.keep = append(fs.keep, r)
+		default:
+			return nil, fmt.Errorf("field filter on %q: unknown action %q", c.Field, c.Action)
+		}
+	}
+	return fs, nil
+}
+
+// Match reports whether an entry with fields passes fs.
+func (fs *Fields) Match(fields []zapcore.Field) bool {
+	return fs.match([][]zapcore.Field{fields})
+}
+
+func (fs *Fields) match(fields [][]zapcore.Field) bool {
+	for _, r := range fs.drop {
+		if r.match(fields) {
+			return false
+		}
+	}
+	if len(fs.keep) == 0 {
+		return true
+	}
+	for _, r := range fs.keep {
+		if r.match(fields) {
+			return true
+		}
+	}
+	return false
+}
+
+type fieldsCore struct {
+	zapcore.Core
+	fields *Fields
+	ctx    []zapcore.Field
+}
+
+// NewFieldsCore wraps core so that it only writes the entries passing fs,
+// which are dropped before being encoded. A nil fs leaves core as is.
+func NewFieldsCore(core zapcore.Core, fs *Fields) zapcore.Core {
+	if fs == nil {
+		return core
+	}
+	return &fieldsCore{Core: core, fields: fs}
+}
+
+func (c *fieldsCore) With(fields []zapcore.Field) zapcore.Core {
+	ctx := make([]zapcore.Field, 0, len(c.ctx)+len(fields))
+	ctx = append(ctx, c.ctx...)
+	ctx = append(ctx, fields...)
+	return &fieldsCore{Core: c.Core.With(fields), fields: c.fields, ctx: ctx}
+}
+
+func (c *fieldsCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *fieldsCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.fields.match([][]zapcore.Field{c.ctx, fields}) {
+		return nil
+	}
+	return c.Core.Write(ent, fields)
+}
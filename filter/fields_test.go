@@ -0,0 +1,53 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	cfg "github.com/shanexu/logn/config"
+)
+
+func TestNewFieldsCore(t *testing.T) {
+	fs, err := NewFields([]cfg.FieldFilter{
+		{Field: "user_agent", Prefix: "kube-probe/"},
+		{Field: "path", Matches: `^/(healthz|readyz)$`},
+		{Field: "status", Equals: "200", Action: "drop"},
+	})
+	assert.Nil(t, err)
+
+	oc, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(NewFieldsCore(oc, fs))
+	logger.Info("probe", zap.String("user_agent", "kube-probe/1.27"))
+	logger.With(zap.String("path", "/healthz")).Info("health")
+	logger.Info("ok", zap.Int("status", 200))
+	logger.Info("not found", zap.Int("status", 404), zap.String("user_agent", "curl/8.0"))
+
+	entries := logs.AllUntimed()
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "not found", entries[0].Message)
+	}
+}
+
+func TestFieldsKeep(t *testing.T) {
+	fs, err := NewFields([]cfg.FieldFilter{
+		{Field: "tenant", Action: "keep"},
+		{Field: "tenant", Equals: "test"},
+	})
+	assert.Nil(t, err)
+
+	assert.True(t, fs.Match([]zapcore.Field{zap.String("tenant", "acme")}))
+	assert.False(t, fs.Match([]zapcore.Field{zap.String("tenant", "test")}))
+	assert.False(t, fs.Match([]zapcore.Field{zap.String("user", "shane")}))
+	assert.False(t, fs.Match([]zapcore.Field{zap.String("tenant", "acme"), zap.String("tenant", "test")}))
+
+	fs, err = NewFields(nil)
+	assert.Nil(t, fs)
+	assert.Nil(t, err)
+
+	_, err = NewFields([]cfg.FieldFilter{{Field: "path", Matches: "("}})
+	assert.NotNil(t, err)
+}
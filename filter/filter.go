@@ -0,0 +1,87 @@
+// Package filter compiles expressions selecting log entries, such as
+//
+//	level >= 'warn' || fields.tenant == "acme"
+//
+// An expression combines comparisons (==, !=, <, <=, >, >=) with &&, || and !
+// over string, number, true, false and null literals and the entry's level,
+// logger, message and fields (fields.name, fields.obj.name for nested
+// objects). Levels compare in severity order against level names.
+package filter
+
+import (
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Filter is a compiled expression.
+type Filter struct {
+	src  string
+	root node
+}
+
+// Compile parses src into a Filter.
+func Compile(src string) (*Filter, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, fmt.Errorf("filter %q: %v", src, err)
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.peek().kind != tokEOF {
+		err = fmt.Errorf("unexpected %q at %d", p.peek().text, p.peek().pos)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("filter %q: %v", src, err)
+	}
+	return &Filter{src: src, root: root}, nil
+}
+
+// Match reports whether the entry with fields satisfies the expression.
+func (f *Filter) Match(ent zapcore.Entry, fields []zapcore.Field) bool {
+	return f.match(&env{ent: ent, fields: [][]zapcore.Field{fields}})
+}
+
+func (f *Filter) match(e *env) bool {
+	return truthy(f.root.eval(e))
+}
+
+func (f *Filter) String() string {
+	return f.src
+}
+
+type filterCore struct {
+	zapcore.Core
+	filter *Filter
+	fields []zapcore.Field
+}
+
+// NewCore wraps core so that it only writes the entries matching f. A nil f
+// leaves core as is.
+func NewCore(core zapcore.Core, f *Filter) zapcore.Core {
+	if f == nil {
+		return core
+	}
+	return &filterCore{Core: core, filter: f}
+}
+
+func (c *filterCore) With(fields []zapcore.Field) zapcore.Core {
+	fs := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	fs = append(fs, c.fields...)
+	fs = append(fs, fields...)
+	return &filterCore{Core: c.Core.With(fields), filter: c.filter, fields: fs}
+}
+
+func (c *filterCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *filterCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.filter.match(&env{ent: ent, fields: [][]zapcore.Field{c.fields, fields}}) {
+		return nil
+	}
+	return c.Core.Write(ent, fields)
+}
@@ -0,0 +1,80 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestMatch(t *testing.T) {
+	ent := zapcore.Entry{Level: zapcore.InfoLevel, LoggerName: "http", Message: "served"}
+	fields := []zapcore.Field{
+		zap.String("tenant", "acme"),
+		zap.Int("status", 503),
+		zap.Bool("cached", false),
+		zap.Object("user", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("name", "shane")
+			return nil
+		})),
+	}
+	for _, tt := range []struct {
+		expr string
+		want bool
+	}{
+		{`level >= 'warn'`, false},
+		{`level >= 'info' && level < "error"`, true},
+		{`'debug' < level`, true},
+		{`level >= 'warn' || fields.tenant == "acme"`, true},
+		{`logger == 'http' && message != 'served'`, false},
+		{`fields.status >= 500`, true},
+		{`fields.status == '503'`, false},
+		{`!fields.cached`, true},
+		{`fields.missing == null`, true},
+		{`fields.missing`, false},
+		{`fields.user.name == 'shane'`, true},
+		{`!(fields.tenant == 'acme' && fields.status < 500)`, true},
+	} {
+		f, err := Compile(tt.expr)
+		if assert.Nil(t, err, tt.expr) {
+			assert.Equal(t, tt.want, f.Match(ent, fields), tt.expr)
+		}
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`level >= 'loud'`,
+		`level >=`,
+		`(level == 'info'`,
+		`fields.a == 'b' extra`,
+		`tenant == 'acme'`,
+		`message == 'unterminated`,
+		`a = b`,
+	} {
+		_, err := Compile(expr)
+		assert.NotNil(t, err, expr)
+	}
+}
+
+func TestNewCore(t *testing.T) {
+	oc, logs := observer.New(zapcore.DebugLevel)
+	f, err := Compile(`level >= 'warn' || fields.tenant == "acme"`)
+	assert.Nil(t, err)
+
+	logger := zap.New(NewCore(oc, f))
+	logger.Info("dropped")
+	logger.Warn("kept")
+	logger.With(zap.String("tenant", "acme")).Debug("kept by context")
+	logger.Info("kept by field", zap.String("tenant", "acme"))
+
+	entries := logs.AllUntimed()
+	if assert.Len(t, entries, 3) {
+		assert.Equal(t, "kept", entries[0].Message)
+		assert.Equal(t, "kept by context", entries[1].Message)
+		assert.Equal(t, "kept by field", entries[2].Message)
+	}
+}
@@ -0,0 +1,86 @@
+package filter
+
+import (
+	"regexp"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Messages selects entries by message with regular expressions.
+type Messages struct {
+	allow []*regexp.Regexp
+	deny  []*regexp.Regexp
+}
+
+// NewMessages compiles the allow and deny patterns. An entry passes if its
+// message matches one of allow, when allow is not empty, and none of deny.
+// NewMessages returns nil when both are empty.
+func NewMessages(allow, deny []string) (*Messages, error) {
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil, nil
+	}
+	m := &Messages{}
+	var err error
+	if m.allow, err = compileAll(allow); err != nil {
+		return nil, err
+	}
+	if m.deny, err = compileAll(deny); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func compileAll(patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// Match reports whether msg passes m.
+func (m *Messages) Match(msg string) bool {
+	if len(m.allow) > 0 && !matchAny(m.allow, msg) {
+		return false
+	}
+	return !matchAny(m.deny, msg)
+}
+
+func matchAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+type messagesCore struct {
+	zapcore.Core
+	messages *Messages
+}
+
+// NewMessagesCore wraps core so that it only writes the entries whose message
+// passes m. As only the message is looked at, entries are dropped before their
+// fields are encoded. A nil m leaves core as is.
+func NewMessagesCore(core zapcore.Core, m *Messages) zapcore.Core {
+	if m == nil {
+		return core
+	}
+	return &messagesCore{Core: core, messages: m}
+}
+
+func (c *messagesCore) With(fields []zapcore.Field) zapcore.Core {
+	return &messagesCore{Core: c.Core.With(fields), messages: c.messages}
+}
+
+func (c *messagesCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.messages.Match(ent.Message) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
@@ -0,0 +1,36 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestNewMessagesCore(t *testing.T) {
+	m, err := NewMessages([]string{`^payment`, `refund`}, []string{`heartbeat$`})
+	assert.Nil(t, err)
+
+	oc, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(NewMessagesCore(oc, m))
+	logger.Info("payment accepted")
+	logger.Info("issued refund")
+	logger.Info("payment heartbeat")
+	logger.Info("user logged in")
+	logger.Debug("payment below level")
+
+	entries := logs.AllUntimed()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "payment accepted", entries[0].Message)
+		assert.Equal(t, "issued refund", entries[1].Message)
+	}
+
+	m, err = NewMessages(nil, nil)
+	assert.Nil(t, m)
+	assert.Nil(t, err)
+
+	_, err = NewMessages(nil, []string{`(`})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,254 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go.uber.org/zap/zapcore"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+var operators = []string{"||", "&&", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")"}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '\'' || c == '"':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(src) && src[j] != src[i]; j++ {
+				if src[j] == '\\' && j+1 < len(src) {
+					j++
+				}
+				sb.WriteByte(src[j])
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, token{tokString, sb.String(), i})
+			i = j + 1
+		case unicode.IsDigit(c) || c == '-' && i+1 < len(src) && unicode.IsDigit(rune(src[i+1])):
+			j := i + 1
+			for j < len(src) && (unicode.IsDigit(rune(src[j])) || src[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{tokNumber, src[i:j], i})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i + 1
+			for j < len(src) && (unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j])) || src[j] == '_' || src[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{tokIdent, src[i:j], i})
+			i = j
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+			tokens = append(tokens, token{tokOp, op, i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(src)}), nil
+}
+
+// parser is a recursive descent parser for
+//
+//	or    = and { "||" and }
+//	and   = unary { "&&" unary }
+//	unary = "!" unary | cmp
+//	cmp   = value [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" ) value ]
+//	value = "(" or ")" | string | number | "true" | "false" | "null" | path
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.accept("!") {
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	}
+	return p.parseCmp()
+}
+
+func (p *parser) parseCmp() (node, error) {
+	left, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t.kind != tokOp {
+		return left, nil
+	}
+	switch t.text {
+	case "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return left, nil
+	}
+	p.next()
+	right, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	// level literals are resolved once here rather than per entry
+	if _, ok := left.(levelNode); ok {
+		if right, err = levelLiteral(right); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := right.(levelNode); ok {
+		if left, err = levelLiteral(left); err != nil {
+			return nil, err
+		}
+	}
+	return cmpNode{op: t.text, left: left, right: right}, nil
+}
+
+func levelLiteral(n node) (node, error) {
+	lit, ok := n.(literal)
+	if !ok {
+		return n, nil
+	}
+	s, ok := lit.v.(string)
+	if !ok {
+		return n, nil
+	}
+	var l zapcore.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return nil, err
+	}
+	return literal{l}, nil
+}
+
+func (p *parser) parseValue() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokOp:
+		if t.text == "(" {
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.accept(")") {
+				return nil, fmt.Errorf("expected ) at %d", p.peek().pos)
+			}
+			return n, nil
+		}
+	case tokString:
+		return literal{t.text}, nil
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at %d", t.text, t.pos)
+		}
+		return literal{f}, nil
+	case tokIdent:
+		return identifier(t)
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+func identifier(t token) (node, error) {
+	switch t.text {
+	case "true":
+		return literal{true}, nil
+	case "false":
+		return literal{false}, nil
+	case "null":
+		return literal{nil}, nil
+	case "level":
+		return levelNode{}, nil
+	case "logger":
+		return entryNode(func(e *env) interface{} { return e.ent.LoggerName }), nil
+	case "message":
+		return entryNode(func(e *env) interface{} { return e.ent.Message }), nil
+	}
+	if strings.HasPrefix(t.text, "fields.") && len(t.text) > len("fields.") {
+		return fieldNode(strings.Split(t.text[len("fields."):], ".")), nil
+	}
+	return nil, fmt.Errorf("unknown identifier %q at %d", t.text, t.pos)
+}
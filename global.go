@@ -1,5 +1,13 @@
 package logn
 
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/shanexu/logn/core"
+)
+
 func Debug(args ...interface{}) {
 	logncore.Debug(args...)
 }
@@ -128,3 +136,95 @@ func Panicw(msg string, keysAndValues ...interface{}) {
 func DPanicw(msg string, keysAndValues ...interface{}) {
 	logncore.DPanicw(msg, keysAndValues...)
 }
+
+// With returns a child of the root logger carrying the given key-value pairs.
+func With(args ...interface{}) core.Logger {
+	return logncore.With(args...)
+}
+
+// Named returns a child of the root logger with the given name.
+func Named(name string) core.Logger {
+	return logncore.Named(name)
+}
+
+// WithContext returns a child of the root logger carrying the diagnostic
+// context bound to ctx.
+func WithContext(ctx context.Context) core.Logger {
+	return logncore.WithContext(ctx)
+}
+
+// WithCallerSkip returns a child of the root logger skipping skip more stack
+// frames when reporting the caller.
+func WithCallerSkip(skip int) core.Logger {
+	return logncore.WithCallerSkip(skip)
+}
+
+// WithLevel returns a child of the root logger writing the entries from level
+// on, whatever the configured level.
+func WithLevel(level core.Level) core.Logger {
+	return logncore.WithLevel(level)
+}
+
+// Desugar returns the strongly-typed variant of the root logger.
+func Desugar() core.FieldLogger {
+	return logncore.Desugar()
+}
+
+// AppenderErrors returns, per appender name, the number of entries the
+// appender failed to encode or write.
+func AppenderErrors() map[string]uint64 {
+	return logncore.AppenderErrors()
+}
+
+// AppenderHealth returns, per appender name, a snapshot of the health of the
+// appender.
+func AppenderHealth() map[string]core.AppenderHealth {
+	return logncore.AppenderHealth()
+}
+
+// Stats returns a snapshot of the activity of the global core.
+func Stats() core.Stats {
+	return logncore.Stats()
+}
+
+// Reopen closes and opens again the files the appenders of the global core
+// write to, e.g. once logrotate moved them away.
+func Reopen() error {
+	return logncore.Reopen()
+}
+
+// BoostLevel makes the logger of the global core called name, "" being root,
+// write the entries from level on for d, or until the returned function is
+// called, whatever its configured level, which is restored afterwards. E.g.
+// debugging a component for five minutes:
+//
+//	cancel := logn.BoostLevel("db", logn.DebugLevel, 5*time.Minute)
+func BoostLevel(name string, level Level, d time.Duration) (cancel func()) {
+	return logncore.BoostLevel(name, level, d)
+}
+
+// DebugOn makes the logger of the global core called name, "" being root,
+// write debug entries for d whenever the process receives sig, e.g. SIGUSR1,
+// restoring its level afterwards. The changes of level are logged by the
+// logger. It returns the function stopping the handling of sig.
+func DebugOn(sig os.Signal, name string, d time.Duration) (stop func()) {
+	return logncore.DebugOn(sig, name, d)
+}
+
+// SetClock makes the global core stamp entries with the time told by clock. A
+// nil clock restores the wall clock.
+func SetClock(clock core.Clock) {
+	logncore.SetClock(clock)
+}
+
+// RegisterExitHook registers f to run before the process exits because of a
+// Fatal entry, e.g. to flush traces or close database connections.
+func RegisterExitHook(f func()) {
+	core.RegisterExitHook(f)
+}
+
+// SetExitFunc replaces os.Exit as the function called after a Fatal entry and
+// returns a function restoring the previous one.
+func SetExitFunc(f func(code int)) (restore func()) {
+	return core.SetExitFunc(f)
+}
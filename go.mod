@@ -3,10 +3,13 @@ module github.com/shanexu/logn
 go 1.14
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/elastic/go-ucfg v0.8.3
 	github.com/pkg/errors v0.9.1
-	github.com/stretchr/testify v1.4.0
-	go.uber.org/zap v1.15.0
+	github.com/stretchr/testify v1.8.1
+	go.uber.org/multierr v1.10.0
+	go.uber.org/zap v1.27.0
 	gopkg.in/natefinch/lumberjack.v2 v2.0.0
 	gopkg.in/yaml.v2 v2.3.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,95 @@
+// Package hook lets registered functions inspect, mutate or veto log entries
+// before they are encoded. Hooks are registered by name and referenced from
+// the configuration, either globally or per appender.
+package hook
+
+import (
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/internal/checked"
+)
+
+// Entry is the log entry handed to hooks. Fields holds both the fields passed
+// at the call site and those accumulated through With.
+type Entry struct {
+	zapcore.Entry
+	Fields []zapcore.Field
+}
+
+// Hook is called for every entry passing the level check. It may modify the
+// entry in place; returning false drops the entry.
+type Hook func(e *Entry) bool
+
+var hooks = map[string]Hook{}
+
+// Register makes a hook available to the configuration under name.
+func Register(name string, h Hook) {
+	if _, exists := hooks[name]; exists {
+		panic(fmt.Sprintf("hook %q already registered", name))
+	}
+	hooks[name] = h
+}
+
+// Lookup resolves hook names to registered hooks.
+func Lookup(names []string) ([]Hook, error) {
+	hs := make([]Hook, 0, len(names))
+	for _, name := range names {
+		h := hooks[name]
+		if h == nil {
+			return nil, fmt.Errorf("hook %q is not registered", name)
+		}
+		hs = append(hs, h)
+	}
+	return hs, nil
+}
+
+type hookCore struct {
+	zapcore.Core
+	hooks  []Hook
+	fields []zapcore.Field
+}
+
+// NewCore wraps core so that every written entry goes through hooks first.
+// Context fields are held back from the wrapped core until write time, which
+// is what allows hooks to see and rewrite them.
+func NewCore(core zapcore.Core, hooks ...Hook) zapcore.Core {
+	if len(hooks) == 0 {
+		return core
+	}
+	return &hookCore{Core: core, hooks: hooks}
+}
+
+func (c *hookCore) With(fields []zapcore.Field) zapcore.Core {
+	fs := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	fs = append(fs, c.fields...)
+	fs = append(fs, fields...)
+	return &hookCore{Core: c.Core, hooks: c.hooks, fields: fs}
+}
+
+func (c *hookCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *hookCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	e := &Entry{Entry: ent}
+	if len(c.fields) == 0 {
+		e.Fields = fields
+	} else {
+		e.Fields = make([]zapcore.Field, 0, len(c.fields)+len(fields))
+		e.Fields = append(e.Fields, c.fields...)
+		e.Fields = append(e.Fields, fields...)
+	}
+	for _, h := range c.hooks {
+		if !h(e) {
+			return nil
+		}
+	}
+	// the wrapped core decides whether to write the entry hooks may have
+	// changed, e.g. an appender of a tee with a higher level
+	return checked.Write(c.Core, e.Entry, e.Fields)
+}
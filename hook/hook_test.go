@@ -0,0 +1,53 @@
+package hook
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestNewCore(t *testing.T) {
+	oc, logs := observer.New(zapcore.InfoLevel)
+
+	redact := func(e *Entry) bool {
+		for i := range e.Fields {
+			if e.Fields[i].Key == "password" {
+				e.Fields[i] = zap.String("password", "***")
+			}
+		}
+		e.Fields = append(e.Fields, zap.Bool("hooked", true))
+		return true
+	}
+	veto := func(e *Entry) bool {
+		return !strings.HasPrefix(e.Message, "health")
+	}
+
+	logger := zap.New(NewCore(oc, redact, veto)).With(zap.String("password", "secret"))
+	logger.Info("login", zap.String("user", "shane"))
+	logger.Info("health check")
+	logger.Debug("below level")
+
+	entries := logs.AllUntimed()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "login", entries[0].Message)
+	assert.Equal(t, map[string]interface{}{
+		"password": "***",
+		"user":     "shane",
+		"hooked":   true,
+	}, entries[0].ContextMap())
+}
+
+func TestLookup(t *testing.T) {
+	Register("test_noop", func(e *Entry) bool { return true })
+
+	hs, err := Lookup([]string{"test_noop"})
+	assert.Nil(t, err)
+	assert.Len(t, hs, 1)
+
+	_, err = Lookup([]string{"missing"})
+	assert.NotNil(t, err)
+}
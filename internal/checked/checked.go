@@ -0,0 +1,93 @@
+// Package checked writes entries to cores through their Check, for the cores
+// wrapping others to act on the entries written, e.g. adding fields, while
+// leaving the wrapped cores to decide which entries they write.
+package checked
+
+import (
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+// Write writes ent with fields to the cores of core which accept it, as
+// their Check tells, returning their write errors as they are. Unlike
+// core.Write, it honors the levels and filters of the cores a Tee duplicates
+// entries to.
+func Write(core zapcore.Core, ent zapcore.Entry, fields []zapcore.Field) error {
+	if t, ok := core.(Tee); ok {
+		var err error
+		for _, c := range t {
+			err = multierr.Append(err, Write(c, ent, fields))
+		}
+		return err
+	}
+	if core.Check(ent, nil) == nil {
+		return nil
+	}
+	return core.Write(ent, fields)
+}
+
+// Tee duplicates entries to several cores, as zapcore.NewTee does, while
+// letting Write check them one by one.
+type Tee []zapcore.Core
+
+// NewTee returns a core duplicating entries to cores.
+func NewTee(cores ...zapcore.Core) zapcore.Core {
+	switch len(cores) {
+	case 0:
+		return zapcore.NewNopCore()
+	case 1:
+		return cores[0]
+	default:
+		return Tee(cores)
+	}
+}
+
+func (t Tee) Level() zapcore.Level {
+	minLvl := zapcore.InvalidLevel
+	for _, c := range t {
+		if lvl := zapcore.LevelOf(c); lvl < minLvl || minLvl == zapcore.InvalidLevel {
+			minLvl = lvl
+		}
+	}
+	return minLvl
+}
+
+func (t Tee) Enabled(lvl zapcore.Level) bool {
+	for _, c := range t {
+		if c.Enabled(lvl) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t Tee) With(fields []zapcore.Field) zapcore.Core {
+	clone := make(Tee, len(t))
+	for i, c := range t {
+		clone[i] = c.With(fields)
+	}
+	return clone
+}
+
+func (t Tee) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	for _, c := range t {
+		ce = c.Check(ent, ce)
+	}
+	return ce
+}
+
+func (t Tee) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var err error
+	for _, c := range t {
+		err = multierr.Append(err, c.Write(ent, fields))
+	}
+	return err
+}
+
+func (t Tee) Sync() error {
+	var err error
+	for _, c := range t {
+		err = multierr.Append(err, c.Sync())
+	}
+	return err
+}
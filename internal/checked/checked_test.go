@@ -0,0 +1,51 @@
+package checked
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+var errWrite = errors.New("write failed")
+
+type testCore struct {
+	zapcore.LevelEnabler
+	err     error
+	written int
+}
+
+func (c *testCore) With([]zapcore.Field) zapcore.Core { return c }
+
+func (c *testCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *testCore) Write(zapcore.Entry, []zapcore.Field) error {
+	c.written++
+	return c.err
+}
+
+func (c *testCore) Sync() error { return nil }
+
+func TestWrite(t *testing.T) {
+	info := &testCore{LevelEnabler: zapcore.InfoLevel, err: errWrite}
+	errLevel := &testCore{LevelEnabler: zapcore.ErrorLevel}
+	tee := NewTee(info, errLevel)
+
+	err := Write(tee, zapcore.Entry{Level: zapcore.InfoLevel}, nil)
+	assert.True(t, errors.Is(err, errWrite), "%v", err)
+	assert.Equal(t, 1, info.written)
+	assert.Equal(t, 0, errLevel.written)
+
+	assert.NoError(t, Write(tee, zapcore.Entry{Level: zapcore.DebugLevel}, nil))
+	assert.Equal(t, 1, info.written)
+
+	assert.True(t, errors.Is(Write(tee, zapcore.Entry{Level: zapcore.ErrorLevel}, nil), errWrite))
+	assert.Equal(t, 2, info.written)
+	assert.Equal(t, 1, errLevel.written)
+}
@@ -0,0 +1,26 @@
+package logn
+
+import "github.com/shanexu/logn/core"
+
+// Level is a logging priority. Higher levels are more important.
+type Level = core.Level
+
+const (
+	DebugLevel  = core.DebugLevel
+	InfoLevel   = core.InfoLevel
+	WarnLevel   = core.WarnLevel
+	ErrorLevel  = core.ErrorLevel
+	DPanicLevel = core.DPanicLevel
+	PanicLevel  = core.PanicLevel
+	FatalLevel  = core.FatalLevel
+)
+
+// Enabled reports whether the root logger would write an entry at level.
+func Enabled(level Level) bool {
+	return logncore.Enabled(level)
+}
+
+// IsDebug is shorthand for Enabled(DebugLevel).
+func IsDebug() bool {
+	return logncore.IsDebug()
+}
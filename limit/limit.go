@@ -0,0 +1,226 @@
+// Package limit caps the number of fields of log entries and how deep their
+// values nest, so that stores mapping each field, such as Elasticsearch,
+// are not flooded with mappings by a few careless entries. The fields beyond
+// the cap are collected under a single overflow field, and the values nested
+// too deep are flattened to their JSON text. Limits are enabled per appender.
+package limit
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultOverflowKey is the key of the field collecting the fields beyond
+// the cap unless configured otherwise.
+const DefaultOverflowKey = "_overflow"
+
+// Config is the field_limits section of an appender.
+type Config struct {
+	// MaxFields caps the number of fields of entries, those of the logger
+	// included, the overflow field being one of them. 0 leaves it uncapped.
+	MaxFields int `logn-config:"max_fields" logn-validate:"min=0"`
+	// MaxDepth caps how deep the objects and arrays of field values nest, a
+	// flat object being 1 deep. 0 leaves it uncapped.
+	MaxDepth int `logn-config:"max_depth" logn-validate:"min=0"`
+	// OverflowKey is the key of the field holding the JSON object of the
+	// fields beyond MaxFields, DefaultOverflowKey by default.
+	OverflowKey string `logn-config:"overflow_key"`
+}
+
+// Limiter applies the limits of a Config to fields.
+type Limiter struct {
+	maxFields   int
+	maxDepth    int
+	overflowKey string
+}
+
+// New returns the Limiter of config, or nil if it limits nothing.
+func New(config Config) *Limiter {
+	if config.MaxFields == 0 && config.MaxDepth == 0 {
+		return nil
+	}
+	l := &Limiter{maxFields: config.MaxFields, maxDepth: config.MaxDepth, overflowKey: config.OverflowKey}
+	if l.overflowKey == "" {
+		l.overflowKey = DefaultOverflowKey
+	}
+	return l
+}
+
+// Fields returns fields with the values nesting deeper than the maximum
+// depth flattened, and, given the number of fields already encoded, those
+// beyond the maximum number of fields collected under the overflow field.
+// Fields within the limits are returned as is; the slice passed in is not
+// modified.
+func (l *Limiter) Fields(encoded int, fields []zapcore.Field) []zapcore.Field {
+	fields = l.flatten(fields)
+	if l.maxFields == 0 || encoded+len(fields) <= l.maxFields {
+		return fields
+	}
+	kept := l.maxFields - 1 - encoded
+	if kept < 0 {
+		kept = 0
+	}
+	out := make([]zapcore.Field, kept, kept+1)
+	copy(out, fields[:kept])
+	return append(out, l.overflow(fields[kept:]))
+}
+
+// overflow returns the field holding the JSON object of fields, as a string
+// so that it is mapped as a single field.
+func (l *Limiter) overflow(fields []zapcore.Field) zapcore.Field {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	b, err := json.Marshal(enc.Fields)
+	if err != nil {
+		return zap.NamedError(l.overflowKey, err)
+	}
+	return zap.String(l.overflowKey, string(b))
+}
+
+func (l *Limiter) flatten(fields []zapcore.Field) []zapcore.Field {
+	if l.maxDepth == 0 {
+		return fields
+	}
+	var out []zapcore.Field
+	for i, f := range fields {
+		ff, changed := l.field(f)
+		if !changed {
+			if out != nil {
+				out = append(out, f)
+			}
+			continue
+		}
+		if out == nil {
+			out = make([]zapcore.Field, i, len(fields))
+			copy(out, fields[:i])
+		}
+		out = append(out, ff)
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+func (l *Limiter) field(f zapcore.Field) (zapcore.Field, bool) {
+	switch f.Type {
+	case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType, zapcore.ReflectType:
+	default:
+		return f, false
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	b, err := json.Marshal(enc.Fields[f.Key])
+	if err != nil {
+		return f, false
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return f, false
+	}
+	v, changed := l.value(v, 0)
+	if !changed {
+		return f, false
+	}
+	return zap.Any(f.Key, v), true
+}
+
+// value flattens the objects and arrays of v, a decoded JSON value depth
+// deep, nesting deeper than the maximum depth.
+func (l *Limiter) value(v interface{}, depth int) (interface{}, bool) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if depth == l.maxDepth {
+			return jsonText(t), true
+		}
+		changed := false
+		for k, e := range t {
+			var c bool
+			t[k], c = l.value(e, depth+1)
+			changed = changed || c
+		}
+		return t, changed
+	case []interface{}:
+		if depth == l.maxDepth {
+			return jsonText(t), true
+		}
+		changed := false
+		for i, e := range t {
+			var c bool
+			t[i], c = l.value(e, depth+1)
+			changed = changed || c
+		}
+		return t, changed
+	}
+	return v, false
+}
+
+func jsonText(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+type limitCore struct {
+	zapcore.Core
+	limiter *Limiter
+	// encoded is the number of fields given to Core.With, held those given
+	// to With which may have to overflow
+	encoded int
+	held    []zapcore.Field
+}
+
+// NewCore wraps core so that the fields of entries are limited before being
+// written. A nil l leaves core as is.
+func NewCore(core zapcore.Core, l *Limiter) zapcore.Core {
+	if l == nil {
+		return core
+	}
+	return &limitCore{Core: core, limiter: l}
+}
+
+func (c *limitCore) With(fields []zapcore.Field) zapcore.Core {
+	fields = c.limiter.flatten(fields)
+	clone := &limitCore{Core: c.Core, limiter: c.limiter, encoded: c.encoded}
+	// the fields are encoded with the logger as long as they leave room for
+	// the overflow field
+	n := len(fields)
+	if room := c.limiter.maxFields - 1 - c.encoded; c.limiter.maxFields > 0 && room < n {
+		n = room
+		if n < 0 {
+			n = 0
+		}
+	}
+	if n > 0 {
+		clone.Core = c.Core.With(fields[:n])
+		clone.encoded += n
+	}
+	if len(c.held) > 0 || n < len(fields) {
+		clone.held = make([]zapcore.Field, 0, len(c.held)+len(fields)-n)
+		clone.held = append(clone.held, c.held...)
+		clone.held = append(clone.held, fields[n:]...)
+	}
+	return clone
+}
+
+func (c *limitCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *limitCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if len(c.held) > 0 {
+		all := make([]zapcore.Field, 0, len(c.held)+len(fields))
+		all = append(all, c.held...)
+		fields = append(all, fields...)
+	}
+	return c.Core.Write(ent, c.limiter.Fields(c.encoded, fields))
+}
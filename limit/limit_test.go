@@ -0,0 +1,58 @@
+package limit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestNew(t *testing.T) {
+	assert.Nil(t, New(Config{}))
+	assert.Equal(t, DefaultOverflowKey, New(Config{MaxFields: 10}).overflowKey)
+}
+
+func TestMaxFields(t *testing.T) {
+	oc, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(NewCore(oc, New(Config{MaxFields: 4}))).With(zap.String("app", "demo"), zap.String("env", "prod"))
+	logger.Info("few", zap.Int("a", 1))
+	logger.With(zap.Int("a", 1), zap.Int("b", 2)).Info("many", zap.Int("c", 3))
+
+	entries := logs.AllUntimed()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, map[string]interface{}{
+			"app": "demo",
+			"env": "prod",
+			"a":   int64(1),
+		}, entries[0].ContextMap())
+		assert.Equal(t, map[string]interface{}{
+			"app":       "demo",
+			"env":       "prod",
+			"a":         int64(1),
+			"_overflow": `{"b":2,"c":3}`,
+		}, entries[1].ContextMap())
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	oc, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(NewCore(oc, New(Config{MaxDepth: 2, OverflowKey: "rest"})))
+	logger.Info("nested",
+		zap.Any("user", map[string]interface{}{
+			"id":      1,
+			"address": map[string]interface{}{"city": "Paris", "geo": map[string]float64{"lat": 48.8}},
+			"tags":    []interface{}{"a", []string{"b"}},
+		}),
+		zap.Strings("flat", []string{"x"}),
+	)
+
+	entries := logs.AllUntimed()
+	if assert.Len(t, entries, 1) {
+		user := entries[0].ContextMap()["user"].(map[string]interface{})
+		assert.Equal(t, map[string]interface{}{"city": "Paris", "geo": `{"lat":48.8}`}, user["address"])
+		assert.Equal(t, []interface{}{"a", `["b"]`}, user["tags"])
+		assert.Equal(t, []interface{}{"x"}, entries[0].ContextMap()["flat"])
+	}
+}
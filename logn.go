@@ -8,14 +8,15 @@ import (
 	"os/signal"
 	"path/filepath"
 	"sync"
-	"syscall"
 	"time"
 
 	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
 
 	"github.com/shanexu/logn/common"
 	"github.com/shanexu/logn/config"
 	"github.com/shanexu/logn/core"
+	"github.com/shanexu/logn/status"
 
 	_ "github.com/shanexu/logn/includes"
 )
@@ -42,7 +43,6 @@ var (
 	configFile     string
 	initLocker     sync.Mutex
 	explicitInited = false
-	debug          bool
 )
 
 func ConfigWithRawConfig(rawConfig *common.Config) (core.Core, error) {
@@ -94,13 +94,12 @@ func InitWithConfigFile(path string) error {
 		return err
 	}
 
-	if debug {
-		fmt.Println("logn using config file: ", path)
+	if status.Enabled(zapcore.DebugLevel) {
 		bs, err := ioutil.ReadFile(path)
 		if err != nil {
 			panic(err)
 		}
-		fmt.Println(string(bs))
+		status.Debugf("using config file %s:\n%s", path, bs)
 	}
 
 	rawConfig, configFileHash, err := common.LoadFile(path)
@@ -128,9 +127,7 @@ func InitWithConfigContent(content string) error {
 		return errors.New("logn is explicit inited")
 	}
 
-	if debug {
-		fmt.Println("logn InitWithConfigContent:\n" + content)
-	}
+	status.Debugf("using config content:\n%s", content)
 
 	rawConfig, err := common.NewConfigFrom(content)
 	if err != nil {
@@ -151,8 +148,6 @@ func init() {
 	initLocker.Lock()
 	defer initLocker.Unlock()
 
-	debug = os.Getenv("LOGN_DEBUG") == "true"
-
 	if configFile == "" {
 		cf, err := resolveConfigFileFromEnv()
 		if err == nil {
@@ -178,20 +173,17 @@ func init() {
 			panic(err)
 		}
 
-		if debug {
-			fmt.Println("logn using config file: ", configFile)
+		if status.Enabled(zapcore.DebugLevel) {
 			bs, err := ioutil.ReadFile(configFile)
 			if err != nil {
 				panic(err)
 			}
-			fmt.Println(string(bs))
+			status.Debugf("using config file %s:\n%s", configFile, bs)
 		}
 
 		rawConfig, configFileHash, err = common.LoadFile(configFile)
 	} else {
-		if debug {
-			fmt.Print("logn using default config:\n" + DefaultConfig)
-		}
+		status.Debugf("using default config:\n%s", DefaultConfig)
 		rawConfig, err = common.NewConfigFrom(DefaultConfig)
 	}
 
@@ -206,7 +198,9 @@ func init() {
 	}
 
 	logncore = co
-	logncore.RedirectStdLog()
+	if _, err := logncore.RedirectStdLog(); err != nil {
+		panic(err)
+	}
 
 	if configFile != "" {
 		explicitInited = true
@@ -215,12 +209,30 @@ func init() {
 	if explicitInited {
 		scanConfigFile(configFile, configFileHash, rawConfig)
 	}
+}
 
+// ReopenOn reopens the files of the global core whenever the process receives
+// one of sigs, e.g. the signal a logrotate postrotate script sends, SIGUSR2
+// if none is given:
+//
+//	postrotate
+//	    kill -USR2 $(cat /run/app.pid)
+//	endscript
+func ReopenOn(sigs ...os.Signal) {
+	if len(sigs) == 0 {
+		if sigs = defaultReopenSignals; len(sigs) == 0 {
+			status.Warnf("no signal to reopen the appender files on")
+			return
+		}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
 	go func() {
-		quit := make(chan os.Signal)
-		signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
-		<-quit
-		Sync()
+		for range ch {
+			if err := Reopen(); err == nil {
+				status.Infof("reopened appender files")
+			}
+		}
 	}()
 }
 
@@ -244,13 +256,18 @@ func scanConfigFile(configFile string, configFileHash [md5.Size]byte, rawConfig
 					<-t.C
 					rawConfig, hash, err := common.LoadFile(configFile)
 					if err != nil {
+						status.Errorf("reading config file %s: %v", configFile, err)
 						continue
 					}
 					if configFileHash == hash {
 						continue
 					}
 					configFileHash = hash
-					logncore.Update(rawConfig)
+					if err := logncore.Update(rawConfig); err != nil {
+						status.Errorf("reloading config file %s, keeping previous configuration: %v", configFile, err)
+						continue
+					}
+					status.Infof("reloaded config file %s", configFile)
 				}
 			}()
 		}()
@@ -264,3 +281,9 @@ func Sync() {
 func GetLogger(name ...string) core.Logger {
 	return logncore.GetLogger(name...)
 }
+
+// GetFieldLogger returns the strongly-typed variant of GetLogger, for hot
+// paths.
+func GetFieldLogger(name ...string) core.FieldLogger {
+	return logncore.GetFieldLogger(name...)
+}
@@ -75,6 +75,7 @@ loggers:
     level: info
     appender_refs:
       - CONSOLE`
+	defer func() { explicitInited = false }()
 	l := GetLogger("l")
 	Info("hello")
 	l.Info("hello")
@@ -87,6 +88,7 @@ loggers:
 }
 
 func TestInitWithConfigFile(t *testing.T) {
+	defer func() { explicitInited = false }()
 	l := GetLogger("l")
 	Info("hello")
 	l.Info("hello")
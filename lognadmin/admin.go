@@ -0,0 +1,110 @@
+// Package lognadmin serves administrative operations on logn cores over HTTP.
+// The handlers take no authentication: mount them on an internal listener,
+// e.g.
+//
+//	mux.Handle("/logn/", http.StripPrefix("/logn", lognadmin.Handler()))
+//
+// Operations:
+//
+//	POST /reopen   reopens the files of the appenders, e.g. after logrotate
+//	GET  /health   reports the health of the appenders, as JSON
+//	POST /boost    boosts the level of a logger for a while
+//
+// /boost takes the logger to boost, root if missing, the level, debug if
+// missing, and the duration of the boost as query parameters, e.g.
+//
+//	POST /boost?logger=db&level=debug&duration=5m
+//
+// The health report maps the appenders to their core.AppenderHealth. Its
+// status is 503 Service Unavailable if an appender is in state
+// core.AppenderOpenFailed, which is that of the appenders whose circuit
+// breaker is open, so that probes can take the log pipeline into account,
+// and 200 OK otherwise, degraded appenders included.
+package lognadmin
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/shanexu/logn"
+	"github.com/shanexu/logn/core"
+)
+
+// Handler returns the handler of the operations on the global core.
+func Handler() http.Handler {
+	return newHandler(logn.Reopen, logn.AppenderHealth, logn.BoostLevel)
+}
+
+// CoreHandler returns the handler of the operations on c.
+func CoreHandler(c core.Core) http.Handler {
+	return newHandler(c.Reopen, c.AppenderHealth, c.BoostLevel)
+}
+
+func newHandler(reopen func() error, health func() map[string]core.AppenderHealth,
+	boost func(string, core.Level, time.Duration) func()) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/reopen", post(func(w http.ResponseWriter, r *http.Request) {
+		if err := reopen(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	mux.HandleFunc("/health", get(func(w http.ResponseWriter, r *http.Request) {
+		appenders := health()
+		code := http.StatusOK
+		for _, h := range appenders {
+			if h.State == core.AppenderOpenFailed {
+				code = http.StatusServiceUnavailable
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(struct {
+			Appenders map[string]core.AppenderHealth `json:"appenders"`
+		}{appenders})
+	}))
+	mux.HandleFunc("/boost", post(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		level := core.DebugLevel
+		if l := q.Get("level"); l != "" {
+			if err := level.UnmarshalText([]byte(l)); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		d, err := time.ParseDuration(q.Get("duration"))
+		if err != nil || d <= 0 {
+			http.Error(w, "invalid duration "+q.Get("duration"), http.StatusBadRequest)
+			return
+		}
+		boost(q.Get("logger"), level, d)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	return mux
+}
+
+// get restricts h to GET and HEAD requests.
+func get(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", http.MethodGet+", "+http.MethodHead)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// post restricts h to POST requests, as it changes the state of the core.
+func post(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		h(w, r)
+	}
+}
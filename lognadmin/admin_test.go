@@ -0,0 +1,210 @@
+package lognadmin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanexu/logn/appender/writer"
+	"github.com/shanexu/logn/common"
+	"github.com/shanexu/logn/core"
+	"github.com/shanexu/logn/core/zap"
+	_ "github.com/shanexu/logn/includes"
+)
+
+func TestReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lognadmin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "app.log")
+	rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  file:
+    - name: FILE
+      file_name: %s
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+`, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := zap.New(rawConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := CoreHandler(c)
+
+	c.Info("rotated")
+	assert.Nil(t, os.Rename(name, name+".1"))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/reopen", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	c.Info("reopened")
+	c.Sync()
+
+	bs, _ := ioutil.ReadFile(name + ".1")
+	assert.Contains(t, string(bs), "rotated")
+	bs, _ = ioutil.ReadFile(name)
+	assert.NotContains(t, string(bs), "rotated")
+	assert.Contains(t, string(bs), "reopened")
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/reopen", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestHealth(t *testing.T) {
+	rawConfig, err := common.NewConfigFrom(`
+appenders:
+  console:
+    - name: CONSOLE
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - CONSOLE
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := zap.New(rawConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := CoreHandler(c)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var report struct {
+		Appenders map[string]core.AppenderHealth `json:"appenders"`
+	}
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, core.AppenderConnected, report.Appenders["CONSOLE"].State)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/health", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestBoost(t *testing.T) {
+	rawConfig, err := common.NewConfigFrom(`
+appenders:
+  console:
+    - name: CONSOLE
+      encoder:
+        json:
+loggers:
+  root:
+    level: warn
+    appender_refs:
+      - CONSOLE
+  logger:
+    - name: db
+      level: error
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := zap.New(rawConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := CoreHandler(c)
+	db := c.GetLogger("db")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/boost?logger=db&level=info&duration=1m", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.True(t, db.Enabled(core.InfoLevel))
+	assert.False(t, db.IsDebug())
+	assert.False(t, c.Enabled(core.InfoLevel))
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/boost?logger=db", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/boost?level=loud&duration=1m", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// failingWriter fails all writes.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("sink down")
+}
+
+func (failingWriter) Sync() error {
+	return nil
+}
+
+func init() {
+	writer.RegisterType("failing_test", func(*common.Config) (writer.Writer, error) {
+		return failingWriter{}, nil
+	})
+}
+
+func TestHealthBreakerOpen(t *testing.T) {
+	for _, tc := range []struct {
+		breaker string
+		code    int
+		state   core.AppenderState
+	}{
+		{"", http.StatusOK, core.AppenderDegraded},
+		{`
+      circuit_breaker:
+        failures: 1
+        cool_down: 1h`, http.StatusServiceUnavailable, core.AppenderOpenFailed},
+	} {
+		rawConfig, err := common.NewConfigFrom(`
+appenders:
+  failing_test:
+    - name: SINK` + tc.breaker + `
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - SINK
+`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := zap.New(rawConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h := CoreHandler(c)
+		c.Info("lost")
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+		assert.Equal(t, tc.code, rec.Code)
+		var report struct {
+			Appenders map[string]core.AppenderHealth `json:"appenders"`
+		}
+		assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.Equal(t, tc.state, report.Appenders["SINK"].State)
+		assert.Equal(t, "sink down", report.Appenders["SINK"].LastError)
+	}
+}
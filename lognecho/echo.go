@@ -0,0 +1,69 @@
+// Package lognecho provides echo middleware logging requests through logn.
+package lognecho
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/shanexu/logn"
+	"github.com/shanexu/logn/core"
+	"github.com/shanexu/logn/lognhttp"
+	"github.com/shanexu/logn/reqlog"
+)
+
+// loggerKey is the echo context key of the request-scoped logger.
+const loggerKey = "github.com/shanexu/logn/lognecho.logger"
+
+// Middleware returns an echo middleware logging requests to l like
+// lognhttp.Middleware. It sets a request-scoped logger, l carrying the
+// request id, retrieved with Logger, and recovers panics of later handlers,
+// logging them with their stack and handing echo a 500 error.
+func Middleware(l core.Logger, opts lognhttp.Options) (echo.MiddlewareFunc, error) {
+	rl, err := lognhttp.NewRequestLogger(l, opts)
+	if err != nil {
+		return nil, err
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			start := time.Now()
+			r := rl.Begin(c.Response(), c.Request())
+			if r == nil {
+				c.Set(loggerKey, l)
+				return next(c)
+			}
+			c.SetRequest(r)
+			c.Set(loggerKey, reqlog.Logger(r.Context()))
+
+			defer func() {
+				if v := recover(); v != nil {
+					if e, ok := v.(error); ok && errors.Is(e, http.ErrAbortHandler) {
+						panic(v)
+					}
+					rl.Recovered(c.Request(), v)
+					err = echo.NewHTTPError(http.StatusInternalServerError)
+				}
+				// let echo write the error response first, so that its
+				// status is the one logged
+				if err != nil {
+					c.Error(err)
+					err = nil
+				}
+				res := c.Response()
+				rl.End(c.Request(), res.Status, res.Size, time.Since(start))
+			}()
+			return next(c)
+		}
+	}, nil
+}
+
+// Logger returns the request-scoped logger set by Middleware, or the global
+// logn logger if there is none.
+func Logger(c echo.Context) core.Logger {
+	if l, ok := c.Get(loggerKey).(core.Logger); ok {
+		return l
+	}
+	return logn.GetLogger()
+}
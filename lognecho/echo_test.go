@@ -0,0 +1,92 @@
+package lognecho
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanexu/logn/common"
+	lognzap "github.com/shanexu/logn/core/zap"
+	_ "github.com/shanexu/logn/includes"
+	"github.com/shanexu/logn/lognhttp"
+)
+
+func TestMiddleware(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "app.log")
+
+	rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  file:
+    - name: FILE
+      file_name: %s
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+`, fileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := lognzap.New(rawConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mw, err := Middleware(c.GetLogger("echo"), lognhttp.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := echo.New()
+	r.Use(mw)
+	r.GET("/hello", func(c echo.Context) error {
+		Logger(c).Info("handling")
+		return c.String(http.StatusOK, "hello")
+	})
+	r.GET("/panic", func(c echo.Context) error {
+		panic("boom")
+	})
+
+	for _, p := range []string{"/hello", "/panic"} {
+		req := httptest.NewRequest("GET", p, nil)
+		req.Header.Set(lognhttp.DefaultRequestIDHeader, "abc")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if p == "/panic" {
+			assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		}
+	}
+	c.Sync()
+
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if assert.Len(t, lines, 4) {
+		assert.Contains(t, lines[0], `"msg":"handling"`)
+		assert.Contains(t, lines[0], `"request_id":"abc"`)
+		assert.Contains(t, lines[1], `"path":"/hello","status":200,"bytes":5`)
+		assert.Contains(t, lines[2], `"msg":"panic recovered"`)
+		assert.Contains(t, lines[2], `"panic":"boom"`)
+		assert.Equal(t, 1, strings.Count(lines[2], `"stacktrace"`))
+		assert.Contains(t, lines[2], `"stacktrace":"`)
+		assert.Contains(t, lines[3], `"level":"error"`)
+		assert.Contains(t, lines[3], `"status":500`)
+	}
+}
@@ -0,0 +1,27 @@
+// Package lognexpvar publishes logn statistics with expvar, making them
+// available at /debug/vars without a metrics system. It is a separate
+// package because importing expvar registers that handler on
+// http.DefaultServeMux.
+package lognexpvar
+
+import (
+	"expvar"
+
+	"github.com/shanexu/logn"
+	"github.com/shanexu/logn/core"
+)
+
+// Publish publishes the statistics of the global core under name. Like
+// expvar.Publish, it panics if name is already in use.
+func Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return logn.Stats()
+	}))
+}
+
+// PublishCore publishes the statistics of c under name.
+func PublishCore(name string, c core.Core) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return c.Stats()
+	}))
+}
@@ -0,0 +1,30 @@
+package lognexpvar
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanexu/logn/logntest"
+)
+
+func TestPublishCore(t *testing.T) {
+	c, _, err := logntest.New("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	PublishCore("logn_test", c)
+	c.GetLogger("app").Info("hello")
+
+	var st struct {
+		Loggers int
+		Entries map[string]uint64
+	}
+	if err := json.Unmarshal([]byte(expvar.Get("logn_test").String()), &st); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, st.Loggers)
+	assert.Equal(t, uint64(1), st.Entries["info"])
+}
@@ -0,0 +1,115 @@
+// Package logngcs uploads the files rotated by logn appenders to Google Cloud
+// Storage. Importing it registers the gcs rotation hook:
+//
+//	rolling_file:
+//	  - name: FILE
+//	    file_name: /var/log/app/app.log
+//	    compress: true
+//	    on_rotate:
+//	      - gcs:
+//	          bucket: logs
+//	          prefix: app/
+//
+// Credentials are found as the Google Cloud libraries do, from
+// GOOGLE_APPLICATION_CREDENTIALS or the metadata server, unless
+// credentials_file is set.
+package logngcs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+
+	"github.com/shanexu/logn/appender/writer/rotate"
+	"github.com/shanexu/logn/common"
+)
+
+const defaultTimeout = 10 * time.Minute
+
+// Config configures the gcs hook.
+type Config struct {
+	// Bucket is the bucket the files are uploaded to.
+	Bucket string `logn-config:"bucket" logn-validate:"required"`
+	// Prefix is prepended to the names of the files to make the names of
+	// their objects, e.g. app/ for app/app-2006-01-02T15-04-05.000.log.gz.
+	Prefix string `logn-config:"prefix"`
+	// CredentialsFile, if set, is the service account key file to use.
+	CredentialsFile string `logn-config:"credentials_file"`
+	// Endpoint, if set, overrides the URL of the service, e.g. for an
+	// emulator, which takes no credentials.
+	Endpoint string `logn-config:"endpoint"`
+	// Delete removes the files once uploaded.
+	Delete bool `logn-config:"delete"`
+	// Timeout is how long an upload may take, 10m by default.
+	Timeout string `logn-config:"timeout"`
+}
+
+// hook uploads the files to a bucket.
+type hook struct {
+	client  *storage.Client
+	cfg     Config
+	timeout time.Duration
+}
+
+// NewHook creates a gcs hook from its configuration.
+func NewHook(c *common.Config) (rotate.Hook, error) {
+	cfg := Config{}
+	if err := c.Unpack(&cfg); err != nil {
+		return nil, err
+	}
+	h := &hook{cfg: cfg, timeout: defaultTimeout}
+	if cfg.Timeout != "" {
+		d, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		h.timeout = d
+	}
+	var opts []option.ClientOption
+	switch {
+	case cfg.Endpoint != "":
+		opts = append(opts, option.WithEndpoint(cfg.Endpoint), option.WithoutAuthentication())
+	case cfg.CredentialsFile != "":
+		opts = append(opts, option.WithCredentialsFile(cfg.CredentialsFile))
+	}
+	client, err := storage.NewClient(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	h.client = client
+	return h, nil
+}
+
+func (h *hook) Rotated(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+	name := h.cfg.Prefix + filepath.Base(file)
+	w := h.client.Bucket(h.cfg.Bucket).Object(name).NewWriter(ctx)
+	if _, err := io.Copy(w, f); err != nil {
+		w.Close()
+		return "", fmt.Errorf("uploading to gs://%s/%s: %v", h.cfg.Bucket, name, err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("uploading to gs://%s/%s: %v", h.cfg.Bucket, name, err)
+	}
+	if h.cfg.Delete {
+		f.Close()
+		return file, os.Remove(file)
+	}
+	return file, nil
+}
+
+func init() {
+	rotate.RegisterType("gcs", NewHook)
+}
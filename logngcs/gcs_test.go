@@ -0,0 +1,73 @@
+package logngcs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanexu/logn/common"
+)
+
+func TestHook(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// single request multipart uploads: the metadata, then the content
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if r.Method != http.MethodPost || err != nil {
+			http.Error(w, "unexpected", http.StatusBadRequest)
+			return
+		}
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		var meta struct {
+			Bucket string `json:"bucket"`
+			Name   string `json:"name"`
+		}
+		p, err := mr.NextPart()
+		if err == nil {
+			err = json.NewDecoder(p).Decode(&meta)
+		}
+		if err == nil {
+			p, err = mr.NextPart()
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		bs, _ := ioutil.ReadAll(p)
+		mu.Lock()
+		objects[meta.Bucket+"/"+meta.Name] = string(bs)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(meta)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app-1.log")
+	assert.Nil(t, ioutil.WriteFile(name, []byte("rotated\n"), 0644))
+
+	c, err := common.NewConfigFrom(map[string]interface{}{
+		"bucket":   "logs",
+		"prefix":   "app/",
+		"endpoint": srv.URL + "/storage/v1/",
+		"delete":   true,
+	})
+	assert.Nil(t, err)
+	h, err := NewHook(c)
+	assert.Nil(t, err)
+	_, err = h.Rotated(name)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"logs/app/app-1.log": "rotated\n"}, objects)
+	_, err = os.Stat(name)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,67 @@
+// Package logngin provides gin middleware logging requests through logn.
+package logngin
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/shanexu/logn"
+	"github.com/shanexu/logn/core"
+	"github.com/shanexu/logn/lognhttp"
+	"github.com/shanexu/logn/reqlog"
+)
+
+// loggerKey is the gin context key of the request-scoped logger.
+const loggerKey = "github.com/shanexu/logn/logngin.logger"
+
+// Middleware returns a gin middleware logging requests to l like
+// lognhttp.Middleware. It sets a request-scoped logger, l carrying the
+// request id, retrieved with Logger, and recovers panics of later handlers,
+// logging them with their stack and answering 500.
+func Middleware(l core.Logger, opts lognhttp.Options) (gin.HandlerFunc, error) {
+	rl, err := lognhttp.NewRequestLogger(l, opts)
+	if err != nil {
+		return nil, err
+	}
+	return func(c *gin.Context) {
+		start := time.Now()
+		r := rl.Begin(c.Writer, c.Request)
+		if r == nil {
+			c.Set(loggerKey, l)
+			c.Next()
+			return
+		}
+		c.Request = r
+		c.Set(loggerKey, reqlog.Logger(r.Context()))
+
+		defer func() {
+			if v := recover(); v != nil {
+				if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(v)
+				}
+				rl.Recovered(c.Request, v)
+				c.AbortWithStatus(http.StatusInternalServerError)
+			}
+			size := c.Writer.Size()
+			if size < 0 {
+				size = 0
+			}
+			rl.End(c.Request, c.Writer.Status(), int64(size), time.Since(start))
+		}()
+		c.Next()
+	}, nil
+}
+
+// Logger returns the request-scoped logger set by Middleware, or the global
+// logn logger if there is none.
+func Logger(c *gin.Context) core.Logger {
+	if v, ok := c.Get(loggerKey); ok {
+		if l, ok := v.(core.Logger); ok {
+			return l
+		}
+	}
+	return logn.GetLogger()
+}
@@ -0,0 +1,99 @@
+package logngorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+
+	"github.com/shanexu/logn/core"
+)
+
+// Logger is a gorm logger.Interface. Statements are logged by an embedded
+// QueryLogger, with the call site in the application reported in a source
+// field. The gorm log mode defaults to Info, leaving filtering to the level
+// of the logn logger.
+type Logger struct {
+	*QueryLogger
+	// IgnoreRecordNotFoundError logs statements failing with
+	// gorm.ErrRecordNotFound as successful.
+	IgnoreRecordNotFoundError bool
+
+	level gormlogger.LogLevel
+}
+
+var (
+	_ gormlogger.Interface = (*Logger)(nil)
+	_ gorm.ParamsFilter    = (*Logger)(nil)
+)
+
+// New returns a gorm logger writing to l.
+func New(l core.Logger) (*Logger, error) {
+	q, err := NewQueryLogger(l)
+	if err != nil {
+		return nil, err
+	}
+	// call sites inside gorm are meaningless, the source field tells where
+	// the statement comes from
+	q.logger = q.logger.WithOptions(zap.WithCaller(false))
+	return &Logger{
+		QueryLogger: q,
+		level:       gormlogger.Info,
+	}, nil
+}
+
+func (l *Logger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	c := *l
+	c.level = level
+	return &c
+}
+
+func (l *Logger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Info {
+		l.logger.Logger().Sugar().Infow(fmt.Sprintf(msg, data...), "source", utils.FileWithLineNum())
+	}
+}
+
+func (l *Logger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		l.logger.Logger().Sugar().Warnw(fmt.Sprintf(msg, data...), "source", utils.FileWithLineNum())
+	}
+}
+
+func (l *Logger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Error {
+		l.logger.Logger().Sugar().Errorw(fmt.Sprintf(msg, data...), "source", utils.FileWithLineNum())
+	}
+}
+
+func (l *Logger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+	if err != nil && l.IgnoreRecordNotFoundError && errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	switch {
+	case err != nil:
+	case l.level >= gormlogger.Info:
+	case l.level >= gormlogger.Warn && l.SlowThreshold > 0 && time.Since(begin) >= l.SlowThreshold:
+	default:
+		return
+	}
+	sql, rows := fc()
+	l.log(ctx, begin, sql, nil, rows, err, zap.String("source", utils.FileWithLineNum()))
+}
+
+// ParamsFilter drops the statement parameters when RedactArgs is set, so
+// gorm logs the statement with placeholders instead of values.
+func (l *Logger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if l.RedactArgs {
+		return sql, nil
+	}
+	return sql, params
+}
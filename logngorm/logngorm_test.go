@@ -0,0 +1,168 @@
+package logngorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"github.com/shanexu/logn/common"
+	"github.com/shanexu/logn/core"
+	lognzap "github.com/shanexu/logn/core/zap"
+	_ "github.com/shanexu/logn/includes"
+)
+
+func newFileCore(t *testing.T, config string) (core.Core, func() []string) {
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	fileName := filepath.Join(dir, "app.log")
+
+	rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  file:
+    - name: FILE
+      file_name: %s
+      encoder:
+        json:
+`, fileName) + config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := lognzap.New(rawConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, func() []string {
+		c.Sync()
+		data, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+}
+
+func TestQueryLogger(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: debug
+    appender_refs:
+      - FILE
+`)
+	q, err := NewQueryLogger(c.GetLogger("sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	q.Log(ctx, time.Now(), "SELECT * FROM users WHERE id = ?", []interface{}{42}, 1, nil)
+	q.Log(ctx, time.Now().Add(-time.Second), "SELECT pg_sleep(1)", nil, -1, nil)
+	q.RedactArgs = true
+	q.Log(ctx, time.Now(), "UPDATE users SET password = ?", []interface{}{"secret"}, 0, errors.New("read only"))
+
+	ls := lines()
+	if assert.Len(t, ls, 3) {
+		assert.Contains(t, ls[0], `"level":"debug"`)
+		assert.Contains(t, ls[0], `"caller":"logngorm/logngorm_test.go:`)
+		assert.Contains(t, ls[0], `"args":[42],"rows":1`)
+		assert.Contains(t, ls[1], `"msg":"slow query"`)
+		assert.NotContains(t, ls[1], `"rows"`)
+		assert.Contains(t, ls[2], `"msg":"query failed"`)
+		assert.Contains(t, ls[2], `"args":1`)
+		assert.NotContains(t, ls[2], "secret")
+		assert.Contains(t, ls[2], `"error":"read only"`)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+`)
+	l, err := New(c.GetLogger("gorm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.IgnoreRecordNotFoundError = true
+	ctx := context.Background()
+	fc := func() (string, int64) { return "SELECT 1", 1 }
+
+	l.Trace(ctx, time.Now(), fc, nil)
+	l.Trace(ctx, time.Now(), fc, gorm.ErrRecordNotFound)
+	l.Trace(ctx, time.Now().Add(-time.Second), fc, nil)
+	l.LogMode(gormlogger.Error).Trace(ctx, time.Now().Add(-time.Second), fc, nil)
+	l.Warn(ctx, "deprecated %s", "option")
+
+	sql, params := l.ParamsFilter(ctx, "SELECT ?", 1)
+	assert.Equal(t, "SELECT ?", sql)
+	assert.Equal(t, []interface{}{1}, params)
+
+	ls := lines()
+	if assert.Len(t, ls, 2) {
+		assert.Contains(t, ls[0], `"msg":"slow query"`)
+		assert.Contains(t, ls[0], `"source":`)
+		assert.NotContains(t, ls[0], `"caller"`)
+		assert.Contains(t, ls[1], `"msg":"deprecated option"`)
+	}
+}
+
+func TestQueryLoggerUpdate(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: debug
+    appender_refs:
+      - FILE
+`)
+	q, err := NewQueryLogger(c.GetLogger("sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  file:
+    - name: OTHER
+      file_name: %s
+      encoder:
+        json:
+loggers:
+  root:
+    level: debug
+    appender_refs:
+      - OTHER
+`, filepath.Join(dir, "other.log")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	q.Log(ctx, time.Now(), "SELECT 1", nil, 1, nil)
+	assert.Nil(t, c.Update(rawConfig))
+	q.Log(ctx, time.Now(), "SELECT 2", nil, 1, nil)
+
+	ls := lines()
+	if assert.Len(t, ls, 1) {
+		assert.Contains(t, ls[0], `"sql":"SELECT 1"`)
+	}
+	bs, _ := ioutil.ReadFile(filepath.Join(dir, "other.log"))
+	assert.Contains(t, string(bs), `"sql":"SELECT 2"`)
+}
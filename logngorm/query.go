@@ -0,0 +1,89 @@
+// Package logngorm logs SQL statements through logn, either from gorm or,
+// using QueryLogger, from any database/sql based code.
+package logngorm
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/core"
+	lognzap "github.com/shanexu/logn/core/zap"
+	"github.com/shanexu/logn/mdc"
+)
+
+// DefaultSlowThreshold is the SlowThreshold of new query loggers.
+const DefaultSlowThreshold = 200 * time.Millisecond
+
+// QueryLogger logs executed statements with their arguments, affected rows
+// and duration. Failed statements are logged at error level, those slower
+// than SlowThreshold at warn level and the others at debug level.
+type QueryLogger struct {
+	// SlowThreshold is the duration from which a statement is reported as
+	// slow; zero disables slow statement reporting.
+	SlowThreshold time.Duration
+	// RedactArgs omits argument values, logging only their count.
+	RedactArgs bool
+
+	logger *lognzap.Follower
+}
+
+// NewQueryLogger returns a query logger writing to l.
+func NewQueryLogger(l core.Logger) (*QueryLogger, error) {
+	logger, err := lognzap.Follow(l)
+	if err != nil {
+		return nil, err
+	}
+	return &QueryLogger{
+		SlowThreshold: DefaultSlowThreshold,
+		// skip Log and log
+		logger: logger.WithOptions(zap.AddCallerSkip(2)),
+	}, nil
+}
+
+// Log logs a statement started at begin. A negative rows means the number of
+// affected rows is unknown. The caller of Log is reported as the entry's
+// caller.
+func (q *QueryLogger) Log(ctx context.Context, begin time.Time, query string, args []interface{}, rows int64, err error) {
+	q.log(ctx, begin, query, args, rows, err)
+}
+
+func (q *QueryLogger) log(ctx context.Context, begin time.Time, query string, args []interface{}, rows int64, err error, extra ...zapcore.Field) {
+	elapsed := time.Since(begin)
+	lvl, msg := zapcore.DebugLevel, "query"
+	switch {
+	case err != nil:
+		lvl, msg = zapcore.ErrorLevel, "query failed"
+	case q.SlowThreshold > 0 && elapsed >= q.SlowThreshold:
+		lvl, msg = zapcore.WarnLevel, "slow query"
+	}
+	ce := q.logger.Logger().Check(lvl, msg)
+	if ce == nil {
+		return
+	}
+
+	kvs := mdc.KeysAndValues(ctx)
+	fields := make([]zapcore.Field, 0, len(kvs)/2+len(extra)+5)
+	for i := 0; i+1 < len(kvs); i += 2 {
+		fields = append(fields, zap.Any(kvs[i].(string), kvs[i+1]))
+	}
+	fields = append(fields, zap.String("sql", query))
+	if len(args) > 0 {
+		if q.RedactArgs {
+			fields = append(fields, zap.Int("args", len(args)))
+		} else {
+			fields = append(fields, zap.Any("args", args))
+		}
+	}
+	if rows >= 0 {
+		fields = append(fields, zap.Int64("rows", rows))
+	}
+	fields = append(fields, zap.Duration("elapsed", elapsed))
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+	fields = append(fields, extra...)
+	ce.Write(fields...)
+}
@@ -0,0 +1,107 @@
+// Package logngrpc integrates logn with gRPC.
+package logngrpc
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc/grpclog"
+
+	"github.com/shanexu/logn/core"
+	lognzap "github.com/shanexu/logn/core/zap"
+)
+
+// LoggerV2 is a grpclog.LoggerV2 writing gRPC's internal logs to a logn
+// logger. Verbose logs, V(l) with l greater than zero, are only enabled while
+// the logger is at debug level.
+type LoggerV2 struct {
+	logger *lognzap.Follower
+	// sugar skips the method of LoggerV2 calling it too
+	sugar *lognzap.Follower
+}
+
+var (
+	_ grpclog.LoggerV2      = (*LoggerV2)(nil)
+	_ grpclog.DepthLoggerV2 = (*LoggerV2)(nil)
+)
+
+// NewLoggerV2 returns a LoggerV2 writing to l.
+func NewLoggerV2(l core.Logger) (*LoggerV2, error) {
+	logger, err := lognzap.Follow(l)
+	if err != nil {
+		return nil, err
+	}
+	// skip the grpclog package function calling us
+	logger = logger.WithOptions(zap.AddCallerSkip(1))
+	return &LoggerV2{
+		logger: logger,
+		sugar:  logger.WithOptions(zap.AddCallerSkip(1)),
+	}, nil
+}
+
+// ReplaceGrpcLoggerV2 makes gRPC log through l. Like grpclog.SetLoggerV2 it
+// is not safe to call concurrently with gRPC, so call it during start up.
+func ReplaceGrpcLoggerV2(l core.Logger) error {
+	g, err := NewLoggerV2(l)
+	if err != nil {
+		return err
+	}
+	grpclog.SetLoggerV2(g)
+	return nil
+}
+
+func (g *LoggerV2) Info(args ...interface{})                 { g.sugared().Info(args...) }
+func (g *LoggerV2) Infoln(args ...interface{})               { g.sugared().Infoln(args...) }
+func (g *LoggerV2) Infof(format string, args ...interface{}) { g.sugared().Infof(format, args...) }
+
+func (g *LoggerV2) Warning(args ...interface{})                 { g.sugared().Warn(args...) }
+func (g *LoggerV2) Warningln(args ...interface{})               { g.sugared().Warnln(args...) }
+func (g *LoggerV2) Warningf(format string, args ...interface{}) { g.sugared().Warnf(format, args...) }
+
+func (g *LoggerV2) Error(args ...interface{})                 { g.sugared().Error(args...) }
+func (g *LoggerV2) Errorln(args ...interface{})               { g.sugared().Errorln(args...) }
+func (g *LoggerV2) Errorf(format string, args ...interface{}) { g.sugared().Errorf(format, args...) }
+
+func (g *LoggerV2) Fatal(args ...interface{})                 { g.sugared().Fatal(args...) }
+func (g *LoggerV2) Fatalln(args ...interface{})               { g.sugared().Fatalln(args...) }
+func (g *LoggerV2) Fatalf(format string, args ...interface{}) { g.sugared().Fatalf(format, args...) }
+
+// sugared returns the logger of the methods taking arguments as fmt does.
+func (g *LoggerV2) sugared() *zap.SugaredLogger {
+	return g.sugar.Logger().Sugar()
+}
+
+func (g *LoggerV2) V(l int) bool {
+	return l <= 0 || g.logger.Logger().Core().Enabled(zapcore.DebugLevel)
+}
+
+func (g *LoggerV2) InfoDepth(depth int, args ...interface{}) {
+	g.logDepth(depth, zapcore.InfoLevel, args)
+}
+
+func (g *LoggerV2) WarningDepth(depth int, args ...interface{}) {
+	g.logDepth(depth, zapcore.WarnLevel, args)
+}
+
+func (g *LoggerV2) ErrorDepth(depth int, args ...interface{}) {
+	g.logDepth(depth, zapcore.ErrorLevel, args)
+}
+
+func (g *LoggerV2) FatalDepth(depth int, args ...interface{}) {
+	g.logDepth(depth, zapcore.FatalLevel, args)
+}
+
+// logDepth logs args formatted like fmt.Println, depth frames above the
+// caller of the *Depth method.
+func (g *LoggerV2) logDepth(depth int, lvl zapcore.Level, args []interface{}) {
+	logger := g.logger.Logger()
+	if !logger.Core().Enabled(lvl) {
+		return
+	}
+	msg := strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+	// g.logger already skips one frame, the *Depth method calling us is the
+	// other one
+	logger.WithOptions(zap.AddCallerSkip(depth)).Check(lvl, msg).Write()
+}
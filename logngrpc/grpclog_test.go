@@ -0,0 +1,125 @@
+package logngrpc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/grpclog"
+
+	"github.com/shanexu/logn/common"
+	"github.com/shanexu/logn/core"
+	lognzap "github.com/shanexu/logn/core/zap"
+	_ "github.com/shanexu/logn/includes"
+)
+
+func newFileCore(t *testing.T, config string) (core.Core, func() []string) {
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	fileName := filepath.Join(dir, "app.log")
+
+	rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  file:
+    - name: FILE
+      file_name: %s
+      encoder:
+        json:
+`, fileName) + config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := lognzap.New(rawConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, func() []string {
+		c.Sync()
+		data, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+}
+
+func TestLoggerV2(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+`)
+	if err := ReplaceGrpcLoggerV2(c.GetLogger("grpc")); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, grpclog.V(0))
+	assert.False(t, grpclog.V(2))
+	grpclog.Infof("dialing %s", "localhost")
+	grpclog.Warningln("transport", "closing")
+
+	ls := lines()
+	if assert.Len(t, ls, 2) {
+		assert.Contains(t, ls[0], `"logger":"grpc"`)
+		assert.Contains(t, ls[0], `"caller":"logngrpc/grpclog_test.go:`)
+		assert.Contains(t, ls[0], `"msg":"dialing localhost"`)
+		assert.Contains(t, ls[1], `"level":"warn"`)
+		assert.Contains(t, ls[1], `"msg":"transport closing"`)
+	}
+}
+
+func TestLoggerV2Update(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+`)
+	g, err := NewLoggerV2(c.GetLogger("grpc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  file:
+    - name: OTHER
+      file_name: %s
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - OTHER
+`, filepath.Join(dir, "other.log")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Info("before")
+	assert.Nil(t, c.Update(rawConfig))
+	g.Info("after")
+	g.InfoDepth(0, "after depth")
+
+	ls := lines()
+	if assert.Len(t, ls, 1) {
+		assert.Contains(t, ls[0], `"msg":"before"`)
+	}
+	bs, _ := ioutil.ReadFile(filepath.Join(dir, "other.log"))
+	assert.Contains(t, string(bs), `"msg":"after"`)
+	assert.Contains(t, string(bs), `"msg":"after depth"`)
+}
@@ -0,0 +1,308 @@
+package logngrpc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/shanexu/logn/core"
+	lognzap "github.com/shanexu/logn/core/zap"
+	"github.com/shanexu/logn/reqlog"
+)
+
+const (
+	// MethodKey is the mdc key of the full method name of the call being
+	// served.
+	MethodKey = "grpc.method"
+
+	// requestIDMetadata is the incoming metadata key read into the mdc as
+	// reqlog.RequestIDKey.
+	requestIDMetadata = "x-request-id"
+
+	// debugMetadata is the incoming metadata key carrying debug tokens.
+	debugMetadata = "x-debug-token"
+)
+
+// Options configure the interceptors.
+type Options struct {
+	// Levels maps full method names, e.g. /grpc.health.v1.Health/Check, to
+	// the level of their successful calls, info by default. Failed calls
+	// are logged at warn level for client errors such as NotFound and at
+	// error level otherwise.
+	Levels map[string]core.Level
+	// LogPayloads logs request and response messages, at debug level.
+	LogPayloads bool
+	// PayloadMethods restricts LogPayloads to the listed full method names.
+	PayloadMethods []string
+	// DebugToken, if set, makes the request-scoped logger of the calls whose
+	// x-debug-token metadata holds it write debug entries, whatever the
+	// configured levels. It must be kept secret.
+	DebugToken string
+}
+
+type interceptors struct {
+	l        core.Logger
+	logger   *lognzap.Follower
+	levels   map[string]zapcore.Level
+	payloads bool
+	methods  map[string]bool
+	debug    string
+}
+
+func newInterceptors(l core.Logger, opts Options) (*interceptors, error) {
+	logger, err := lognzap.Follow(l)
+	if err != nil {
+		return nil, err
+	}
+	i := &interceptors{
+		l: l,
+		// the call sites and stacks of call entries are those of the
+		// interceptors, not of the handlers
+		logger:   logger.WithOptions(zap.WithCaller(false), zap.AddStacktrace(zapcore.FatalLevel+1)),
+		levels:   opts.Levels,
+		payloads: opts.LogPayloads,
+		debug:    opts.DebugToken,
+	}
+	if len(opts.PayloadMethods) > 0 {
+		i.methods = map[string]bool{}
+		for _, m := range opts.PayloadMethods {
+			i.methods[m] = true
+		}
+	}
+	return i, nil
+}
+
+// Logger returns the request-scoped logger put in ctx by the server
+// interceptors, or the global logn logger with the mdc of ctx if there is
+// none.
+func Logger(ctx context.Context) core.Logger {
+	return reqlog.Logger(ctx)
+}
+
+// UnaryServerInterceptor returns an interceptor logging every unary call with
+// its method, code, duration and peer. The method and the request id, from
+// the x-request-id metadata or minted, are put in the mdc of the handler's
+// context, which also carries a request-scoped logger returned by Logger.
+func UnaryServerInterceptor(l core.Logger, opts Options) (grpc.UnaryServerInterceptor, error) {
+	i, err := newInterceptors(l, opts)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		ctx = i.serverContext(ctx, info.FullMethod)
+		i.payload(info.FullMethod, "request received", "grpc.request", req)
+		resp, err := handler(ctx, req)
+		if err == nil {
+			i.payload(info.FullMethod, "response sent", "grpc.response", resp)
+		}
+		i.finish("finished unary call", info.FullMethod, start, err, peerField(ctx))
+		return resp, err
+	}, nil
+}
+
+// StreamServerInterceptor is the streaming counterpart of
+// UnaryServerInterceptor.
+func StreamServerInterceptor(l core.Logger, opts Options) (grpc.StreamServerInterceptor, error) {
+	i, err := newInterceptors(l, opts)
+	if err != nil {
+		return nil, err
+	}
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx := i.serverContext(ss.Context(), info.FullMethod)
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx, i: i, method: info.FullMethod})
+		i.finish("finished streaming call", info.FullMethod, start, err, peerField(ctx))
+		return err
+	}, nil
+}
+
+// UnaryClientInterceptor returns an interceptor logging every unary call made
+// with its method, code, duration and target.
+func UnaryClientInterceptor(l core.Logger, opts Options) (grpc.UnaryClientInterceptor, error) {
+	i, err := newInterceptors(l, opts)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		start := time.Now()
+		i.payload(method, "request sent", "grpc.request", req)
+		err := invoker(ctx, method, req, reply, cc, callOpts...)
+		if err == nil {
+			i.payload(method, "response received", "grpc.response", reply)
+		}
+		i.finish("finished client unary call", method, start, err, zap.String("grpc.target", cc.Target()))
+		return err
+	}, nil
+}
+
+// StreamClientInterceptor is the streaming counterpart of
+// UnaryClientInterceptor. The call is logged once the stream ends, i.e. when
+// receiving fails or reaches the end of the stream.
+func StreamClientInterceptor(l core.Logger, opts Options) (grpc.StreamClientInterceptor, error) {
+	i, err := newInterceptors(l, opts)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		target := zap.String("grpc.target", cc.Target())
+		cs, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			i.finish("finished client streaming call", method, start, err, target)
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, i: i, method: method, start: start, target: target}, nil
+	}, nil
+}
+
+func (i *interceptors) serverContext(ctx context.Context, method string) context.Context {
+	var requestID string
+	var debug bool
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(requestIDMetadata); len(ids) > 0 {
+			requestID = ids[0]
+		}
+		if tokens := md.Get(debugMetadata); len(tokens) > 0 {
+			debug = reqlog.MatchToken(tokens[0], i.debug)
+		}
+	}
+	ctx = reqlog.Start(ctx, i.l, requestID, "", MethodKey, method)
+	if debug {
+		ctx = reqlog.Force(ctx, core.DebugLevel)
+	}
+	return ctx
+}
+
+// level returns the level of a call to method which ended with code.
+func (i *interceptors) level(method string, code codes.Code) zapcore.Level {
+	switch code {
+	case codes.OK:
+		if lvl, ok := i.levels[method]; ok {
+			return lvl
+		}
+		return zapcore.InfoLevel
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated, codes.ResourceExhausted,
+		codes.FailedPrecondition, codes.Aborted, codes.OutOfRange:
+		return zapcore.WarnLevel
+	default:
+		return zapcore.ErrorLevel
+	}
+}
+
+func (i *interceptors) finish(msg, method string, start time.Time, err error, extra zapcore.Field) {
+	code := status.Code(err)
+	ce := i.logger.Logger().Check(i.level(method, code), msg)
+	if ce == nil {
+		return
+	}
+	fields := []zapcore.Field{
+		zap.String(MethodKey, method),
+		zap.String("grpc.code", code.String()),
+		zap.Duration("grpc.elapsed", time.Since(start)),
+		extra,
+	}
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+	ce.Write(fields...)
+}
+
+// payload logs message m exchanged by a call to method, if payloads of
+// method are logged.
+func (i *interceptors) payload(method, msg, key string, m interface{}) {
+	if !i.payloads || (i.methods != nil && !i.methods[method]) {
+		return
+	}
+	if ce := i.logger.Logger().Check(zapcore.DebugLevel, msg); ce != nil {
+		ce.Write(zap.String(MethodKey, method), payloadField(key, m))
+	}
+}
+
+// payloadField encodes protobuf messages with protojson, which unlike
+// encoding/json follows the protobuf JSON mapping.
+func payloadField(key string, m interface{}) zapcore.Field {
+	if pm, ok := m.(proto.Message); ok {
+		if b, err := protojson.Marshal(pm); err == nil {
+			return zap.Reflect(key, json.RawMessage(b))
+		}
+	}
+	return zap.Any(key, m)
+}
+
+func peerField(ctx context.Context) zapcore.Field {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return zap.String("peer.address", p.Addr.String())
+	}
+	return zap.Skip()
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	i      *interceptors
+	method string
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.i.payload(s.method, "response sent", "grpc.response", m)
+	}
+	return err
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.i.payload(s.method, "request received", "grpc.request", m)
+	}
+	return err
+}
+
+type clientStream struct {
+	grpc.ClientStream
+	i      *interceptors
+	method string
+	start  time.Time
+	target zapcore.Field
+	once   sync.Once
+}
+
+func (s *clientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.i.payload(s.method, "request sent", "grpc.request", m)
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch err {
+	case nil:
+		s.i.payload(s.method, "response received", "grpc.response", m)
+	case io.EOF:
+		s.once.Do(func() { s.i.finish("finished client streaming call", s.method, s.start, nil, s.target) })
+	default:
+		s.once.Do(func() { s.i.finish("finished client streaming call", s.method, s.start, err, s.target) })
+	}
+	return err
+}
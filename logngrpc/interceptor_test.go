@@ -0,0 +1,148 @@
+package logngrpc
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/shanexu/logn/common"
+	"github.com/shanexu/logn/core"
+	"github.com/shanexu/logn/lognhttp"
+)
+
+const checkMethod = "/grpc.health.v1.Health/Check"
+
+type healthServer struct {
+	*health.Server
+}
+
+func (s *healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	Logger(ctx).Info("checking")
+	return s.Server.Check(ctx, req)
+}
+
+func TestInterceptors(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: debug
+    appender_refs:
+      - FILE
+`)
+	opts := Options{
+		Levels:         map[string]core.Level{checkMethod: core.DebugLevel},
+		LogPayloads:    true,
+		PayloadMethods: []string{checkMethod},
+	}
+	unaryServer, err := UnaryServerInterceptor(c.GetLogger("server"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unaryClient, err := UnaryClientInterceptor(c.GetLogger("client"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lis := bufconn.Listen(1 << 16)
+	s := grpc.NewServer(grpc.UnaryInterceptor(unaryServer))
+	healthpb.RegisterHealthServer(s, &healthServer{Server: health.NewServer()})
+	go s.Serve(lis)
+	defer s.Stop()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(unaryClient),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "abc")
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: "missing"})
+	assert.Error(t, err)
+
+	ls := lines()
+	if assert.Len(t, ls, 9) {
+		assert.Contains(t, ls[0], `"msg":"request received"`)
+		assert.Contains(t, ls[0], `"grpc.request":{}`)
+		assert.Contains(t, ls[1], `"msg":"checking"`)
+		assert.Contains(t, ls[1], `"grpc.method":"`+checkMethod+`"`)
+		assert.Contains(t, ls[1], `"`+lognhttp.RequestIDKey+`":"abc"`)
+		assert.Contains(t, ls[2], `"grpc.response":{"status":"SERVING"}`)
+		assert.Contains(t, ls[3], `"level":"debug"`)
+		assert.Contains(t, ls[3], `"msg":"finished unary call"`)
+		assert.Contains(t, ls[3], `"grpc.code":"OK"`)
+		assert.Contains(t, ls[3], `"peer.address":`)
+		assert.Contains(t, ls[4], `"logger":"client"`)
+		assert.Contains(t, ls[4], `"level":"info"`)
+		assert.Contains(t, ls[4], `"grpc.target":"bufnet"`)
+		assert.Contains(t, ls[5], `"grpc.request":{"service":"missing"}`)
+		assert.Contains(t, ls[7], `"level":"warn"`)
+		assert.Contains(t, ls[7], `"grpc.code":"NotFound"`)
+		assert.Contains(t, ls[8], `"level":"warn"`)
+		assert.Contains(t, ls[8], `"logger":"client"`)
+	}
+}
+
+func TestInterceptorsUpdate(t *testing.T) {
+	c, lines := newFileCore(t, `
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+`)
+	unary, err := UnaryServerInterceptor(c.GetLogger("server"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  file:
+    - name: OTHER
+      file_name: %s
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - OTHER
+`, filepath.Join(dir, "other.log")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return req, nil }
+	unary(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/svc/Before"}, handler)
+	assert.Nil(t, c.Update(rawConfig))
+	unary(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/svc/After"}, handler)
+
+	ls := lines()
+	if assert.Len(t, ls, 1) {
+		assert.Contains(t, ls[0], `/svc/Before`)
+	}
+	bs, _ := ioutil.ReadFile(filepath.Join(dir, "other.log"))
+	assert.Contains(t, string(bs), `/svc/After`)
+}
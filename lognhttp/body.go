@@ -0,0 +1,275 @@
+package lognhttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/core"
+	"github.com/shanexu/logn/redact"
+)
+
+const (
+	// DefaultMaxBodyBytes is how much of bodies is captured unless
+	// BodyOptions.MaxBytes is set.
+	DefaultMaxBodyBytes = 4096
+)
+
+var (
+	// DefaultBodyContentTypes are the media types of the bodies captured
+	// unless BodyOptions.ContentTypes is set.
+	DefaultBodyContentTypes = []string{"application/json", "application/x-www-form-urlencoded", "text/*"}
+	// DefaultRedactHeaders are the headers redacted unless
+	// BodyOptions.RedactHeaders is set.
+	DefaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+)
+
+// BodyOptions configure the capture of the headers and bodies of requests
+// and responses, logged with the request as request_headers, request_body,
+// response_headers and response_body. The bodies of responses are captured by
+// Middleware only, the middlewares of other frameworks capturing those of
+// requests.
+type BodyOptions struct {
+	// Level is the level from which the logger of the middleware must write
+	// entries for bodies to be captured, e.g. debug so that they are only
+	// logged while debugging. Requests forced to debug with the debug token
+	// are captured too.
+	Level core.Level
+	// MaxBytes caps how much of each body is captured, DefaultMaxBodyBytes
+	// if 0. The bodies of requests are captured as handlers read them, so
+	// that they are not held in memory.
+	MaxBytes int
+	// ContentTypes lists the media types of the bodies captured, a type
+	// ending in /* matching its subtypes. DefaultBodyContentTypes if empty.
+	ContentTypes []string
+	// RedactHeaders lists the headers whose values are replaced by
+	// redact.DefaultReplacement, DefaultRedactHeaders if empty.
+	RedactHeaders []string
+	// RedactFields lists the names of the fields of JSON bodies whose values
+	// are replaced by redact.DefaultReplacement, at any depth. JSON bodies
+	// cut by MaxBytes cannot be redacted, and are replaced as a whole if
+	// fields are to be.
+	RedactFields []string
+}
+
+// bodyLogger captures bodies as BodyOptions tell.
+type bodyLogger struct {
+	level         zapcore.Level
+	maxBytes      int
+	contentTypes  []string
+	redactHeaders map[string]bool
+	redactFields  map[string]bool
+}
+
+func newBodyLogger(opts *BodyOptions) *bodyLogger {
+	if opts == nil {
+		return nil
+	}
+	bl := &bodyLogger{
+		level:         opts.Level,
+		maxBytes:      opts.MaxBytes,
+		contentTypes:  opts.ContentTypes,
+		redactHeaders: map[string]bool{},
+		redactFields:  map[string]bool{},
+	}
+	if bl.maxBytes <= 0 {
+		bl.maxBytes = DefaultMaxBodyBytes
+	}
+	if len(bl.contentTypes) == 0 {
+		bl.contentTypes = DefaultBodyContentTypes
+	}
+	headers := opts.RedactHeaders
+	if len(headers) == 0 {
+		headers = DefaultRedactHeaders
+	}
+	for _, h := range headers {
+		bl.redactHeaders[http.CanonicalHeaderKey(h)] = true
+	}
+	for _, f := range opts.RedactFields {
+		bl.redactFields[f] = true
+	}
+	return bl
+}
+
+// captures reports whether the bodies of a content type are captured.
+func (bl *bodyLogger) captures(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range bl.contentTypes {
+		if t == mt || (strings.HasSuffix(t, "/*") && strings.HasPrefix(mt, t[:len(t)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// capture holds what was captured of a request.
+type capture struct {
+	bl              *bodyLogger
+	requestHeaders  http.Header
+	requestType     string
+	request         bodyBuffer
+	responseHeaders http.Header
+	response        bodyBuffer
+}
+
+type captureKey struct{}
+
+func captureFrom(ctx context.Context) *capture {
+	c, _ := ctx.Value(captureKey{}).(*capture)
+	return c
+}
+
+// begin starts capturing r, returning it with its body captured as read.
+func (bl *bodyLogger) begin(r *http.Request) *http.Request {
+	c := &capture{bl: bl, requestHeaders: r.Header, requestType: r.Header.Get("Content-Type")}
+	c.request.max = bl.maxBytes
+	c.response.max = bl.maxBytes
+	if r.Body != nil && r.Body != http.NoBody && bl.captures(c.requestType) {
+		r.Body = &teeBody{ReadCloser: r.Body, buf: &c.request}
+	}
+	return r.WithContext(context.WithValue(r.Context(), captureKey{}, c))
+}
+
+// captureResponse reports whether the body of the response, whose headers
+// are h, is to be captured.
+func (c *capture) captureResponse(h http.Header) bool {
+	c.responseHeaders = h
+	return c.bl.captures(h.Get("Content-Type"))
+}
+
+// fields returns the fields of what c captured.
+func (c *capture) fields() []zapcore.Field {
+	bl := c.bl
+	fields := []zapcore.Field{zap.Object("request_headers", headers{c.requestHeaders, bl.redactHeaders})}
+	fields = append(fields, bl.bodyFields("request_body", c.requestType, &c.request)...)
+	if c.responseHeaders != nil {
+		fields = append(fields, zap.Object("response_headers", headers{c.responseHeaders, bl.redactHeaders}))
+		fields = append(fields, bl.bodyFields("response_body", c.responseHeaders.Get("Content-Type"), &c.response)...)
+	}
+	return fields
+}
+
+func (bl *bodyLogger) bodyFields(key, contentType string, b *bodyBuffer) []zapcore.Field {
+	if b.buf.Len() == 0 {
+		return nil
+	}
+	body := b.buf.String()
+	if mt, _, _ := mime.ParseMediaType(contentType); len(bl.redactFields) > 0 && (mt == "application/json" || strings.HasSuffix(mt, "+json")) {
+		body = bl.redactJSON(body, b.truncated)
+	}
+	if b.truncated {
+		return []zapcore.Field{zap.String(key, body), zap.Bool(key+"_truncated", true)}
+	}
+	return []zapcore.Field{zap.String(key, body)}
+}
+
+// redactJSON returns body with the values of the fields to redact replaced,
+// or replaced as a whole if it cannot be parsed.
+func (bl *bodyLogger) redactJSON(body string, truncated bool) string {
+	if truncated {
+		return redact.DefaultReplacement
+	}
+	d := json.NewDecoder(strings.NewReader(body))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return redact.DefaultReplacement
+	}
+	v, changed := bl.redactValue(v)
+	if !changed {
+		return body
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return redact.DefaultReplacement
+	}
+	return string(b)
+}
+
+func (bl *bodyLogger) redactValue(v interface{}) (interface{}, bool) {
+	changed := false
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			if bl.redactFields[k] {
+				t[k] = redact.DefaultReplacement
+				changed = true
+				continue
+			}
+			var c bool
+			t[k], c = bl.redactValue(e)
+			changed = changed || c
+		}
+	case []interface{}:
+		for i, e := range t {
+			var c bool
+			t[i], c = bl.redactValue(e)
+			changed = changed || c
+		}
+	}
+	return v, changed
+}
+
+// headers encodes headers, those to redact replaced, and the values of
+// those repeated joined by commas.
+type headers struct {
+	h      http.Header
+	redact map[string]bool
+}
+
+func (h headers) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	keys := make([]string, 0, len(h.h))
+	for k := range h.h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		vs := h.h[k]
+		if h.redact[k] {
+			enc.AddString(k, redact.DefaultReplacement)
+			continue
+		}
+		enc.AddString(k, strings.Join(vs, ","))
+	}
+	return nil
+}
+
+// bodyBuffer keeps the first max bytes written to it.
+type bodyBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *bodyBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); len(p) > room {
+		b.truncated = true
+		b.buf.Write(p[:room])
+	} else {
+		b.buf.Write(p)
+	}
+	return len(p), nil
+}
+
+// teeBody captures what is read from a request body.
+type teeBody struct {
+	io.ReadCloser
+	buf *bodyBuffer
+}
+
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	t.buf.Write(p[:n])
+	return n, err
+}
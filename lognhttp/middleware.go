@@ -0,0 +1,311 @@
+// Package lognhttp logs net/http requests through logn.
+package lognhttp
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/core"
+	lognzap "github.com/shanexu/logn/core/zap"
+	"github.com/shanexu/logn/reqlog"
+)
+
+const (
+	// DefaultRequestIDHeader is the header carrying request ids unless
+	// Options.RequestIDHeader is set.
+	DefaultRequestIDHeader = "X-Request-ID"
+
+	// DefaultDebugHeader is the header carrying debug tokens unless
+	// Options.DebugHeader is set.
+	DefaultDebugHeader = "X-Debug-Token"
+
+	// RequestIDKey is the mdc key of the request id.
+	RequestIDKey = reqlog.RequestIDKey
+)
+
+// Options configure the request logging middleware.
+type Options struct {
+	// RequestIDHeader is the request and response header carrying the
+	// request id.
+	RequestIDHeader string
+	// TraceIDHeader, if set, is the request header carrying the trace id,
+	// put in the mdc as reqlog.TraceIDKey, for requests without a valid W3C
+	// traceparent header.
+	TraceIDHeader string
+	// DebugToken, if set, makes the request-scoped logger of the requests
+	// whose DebugHeader holds it write debug entries, whatever the
+	// configured levels. It must be kept secret.
+	DebugToken string
+	// DebugHeader is the request header carrying the debug token.
+	DebugHeader string
+	// Levels maps a status class, 1 to 5, to the level of the requests
+	// answered with it. Classes default to info, except 4 to warn and 5 to
+	// error.
+	Levels map[int]core.Level
+	// ExcludePaths lists the request paths not logged, e.g. health checks.
+	ExcludePaths []string
+	// Bodies, if set, makes the middleware capture the headers and bodies of
+	// requests and responses.
+	Bodies *BodyOptions
+	// Recover makes Middleware recover the panics of handlers, logging them
+	// with Recovered and answering 500 unless the response was started.
+	// Panics with http.ErrAbortHandler are let through. The middlewares of
+	// other frameworks always recover.
+	Recover bool
+}
+
+var defaultLevels = [6]zapcore.Level{
+	zapcore.InfoLevel,
+	zapcore.InfoLevel,
+	zapcore.InfoLevel,
+	zapcore.InfoLevel,
+	zapcore.WarnLevel,
+	zapcore.ErrorLevel,
+}
+
+// RequestLogger logs requests as Middleware does. It is the building block
+// of middlewares for frameworks not based on http.Handler.
+type RequestLogger struct {
+	l        core.Logger
+	logger   *lognzap.Follower
+	header   string
+	trace    string
+	debug    string
+	debugHdr string
+	levels   [6]zapcore.Level
+	excludes map[string]bool
+	bodies   *bodyLogger
+	recover  bool
+}
+
+// NewRequestLogger returns a request logger writing to l.
+func NewRequestLogger(l core.Logger, opts Options) (*RequestLogger, error) {
+	logger, err := lognzap.Follow(l)
+	if err != nil {
+		return nil, err
+	}
+	rl := &RequestLogger{
+		l: l,
+		// the call sites and stacks of request entries are those of the
+		// middleware, not of the handlers
+		logger:   logger.WithOptions(zap.WithCaller(false), zap.AddStacktrace(zapcore.FatalLevel+1)),
+		header:   opts.RequestIDHeader,
+		trace:    opts.TraceIDHeader,
+		debug:    opts.DebugToken,
+		debugHdr: opts.DebugHeader,
+		levels:   defaultLevels,
+		excludes: map[string]bool{},
+		bodies:   newBodyLogger(opts.Bodies),
+		recover:  opts.Recover,
+	}
+	if rl.header == "" {
+		rl.header = DefaultRequestIDHeader
+	}
+	if rl.debugHdr == "" {
+		rl.debugHdr = DefaultDebugHeader
+	}
+	for class, lvl := range opts.Levels {
+		if class > 0 && class < len(rl.levels) {
+			rl.levels[class] = lvl
+		}
+	}
+	for _, p := range opts.ExcludePaths {
+		rl.excludes[p] = true
+	}
+	return rl, nil
+}
+
+// Begin starts the request with reqlog: it returns r with the request id,
+// and the trace id if any, in the mdc of its context, which carries the
+// request-scoped logger returned by Logger. The request id is also set in the
+// response header of w. Begin returns nil if the path of r is excluded from
+// logging.
+//
+// The trace id is taken from the W3C traceparent header, along with the id of
+// the span of the caller, put in the mdc as reqlog.ParentSpanIDKey; the trace
+// context, tracestate included, is carried by the context of r for
+// reqlog.TraceContextFrom to return.
+func (rl *RequestLogger) Begin(w http.ResponseWriter, r *http.Request) *http.Request {
+	if rl.excludes[r.URL.Path] {
+		return nil
+	}
+	ctx := r.Context()
+	var traceID string
+	var kvs []interface{}
+	if tc, ok := reqlog.ParseTraceContext(r.Header.Get(reqlog.TraceparentHeader), r.Header.Get(reqlog.TracestateHeader)); ok {
+		ctx = reqlog.WithTraceContext(ctx, tc)
+		traceID = tc.TraceID
+		kvs = append(kvs, reqlog.ParentSpanIDKey, tc.ParentSpanID)
+	} else if rl.trace != "" {
+		traceID = r.Header.Get(rl.trace)
+	}
+	ctx = reqlog.Start(ctx, rl.l, r.Header.Get(rl.header), traceID, kvs...)
+	if reqlog.MatchToken(r.Header.Get(rl.debugHdr), rl.debug) {
+		ctx = reqlog.Force(ctx, core.DebugLevel)
+	}
+	w.Header().Set(rl.header, reqlog.RequestID(ctx))
+	r = r.WithContext(ctx)
+	if rl.bodies != nil && reqlog.Logger(ctx).Enabled(rl.bodies.level) {
+		r = rl.bodies.begin(r)
+	}
+	return r
+}
+
+// End logs r, as returned by Begin, answered with status and size bytes
+// after elapsed. A zero status is logged as 200.
+func (rl *RequestLogger) End(r *http.Request, status int, size int64, elapsed time.Duration) {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	lvl := zapcore.InfoLevel
+	if class := status / 100; class > 0 && class < len(rl.levels) {
+		lvl = rl.levels[class]
+	}
+	if ce := rl.logger.Logger().Check(lvl, "request"); ce != nil {
+		fields := []zap.Field{
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.Int("status", status),
+			zap.Int64("bytes", size),
+			zap.Duration("elapsed", elapsed),
+			zap.String("remote_addr", r.RemoteAddr),
+			zap.String(RequestIDKey, RequestID(r)),
+		}
+		if traceID := reqlog.TraceID(r.Context()); traceID != "" {
+			fields = append(fields, zap.String(reqlog.TraceIDKey, traceID))
+		}
+		if tc, ok := reqlog.TraceContextFrom(r.Context()); ok {
+			fields = append(fields, zap.String(reqlog.ParentSpanIDKey, tc.ParentSpanID))
+		}
+		if c := captureFrom(r.Context()); c != nil {
+			fields = append(fields, c.fields()...)
+		}
+		ce.Write(fields...)
+	}
+}
+
+// Recovered logs v, recovered from a panic while serving r, with the stack
+// of the panicking goroutine. It must be called from the deferred function
+// that recovered.
+func (rl *RequestLogger) Recovered(r *http.Request, v interface{}) {
+	fields := []zap.Field{
+		zap.Any("panic", v),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.String(RequestIDKey, RequestID(r)),
+	}
+	if traceID := reqlog.TraceID(r.Context()); traceID != "" {
+		fields = append(fields, zap.String(reqlog.TraceIDKey, traceID))
+	}
+	rl.logger.Logger().Error("panic recovered", append(fields, zap.StackSkip("stacktrace", 1))...)
+}
+
+type middleware struct {
+	*RequestLogger
+	next http.Handler
+}
+
+// Middleware returns a middleware logging every request with its method,
+// path, status, response size, duration, remote address and request id. The
+// request id is taken from the request header or generated, echoed in the
+// response header and put in the mdc of the request context, which carries a
+// request-scoped logger including it, returned by Logger.
+func Middleware(l core.Logger, opts Options) (func(http.Handler) http.Handler, error) {
+	rl, err := NewRequestLogger(l, opts)
+	if err != nil {
+		return nil, err
+	}
+	return func(next http.Handler) http.Handler {
+		return &middleware{RequestLogger: rl, next: next}
+	}, nil
+}
+
+func (m *middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	lr := m.Begin(w, r)
+	if lr == nil {
+		m.next.ServeHTTP(w, r)
+		return
+	}
+	sw := &statusWriter{ResponseWriter: w, capture: captureFrom(lr.Context())}
+	if m.recover {
+		defer func() {
+			if v := recover(); v != nil {
+				if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(v)
+				}
+				m.Recovered(lr, v)
+				if sw.status == 0 {
+					sw.WriteHeader(http.StatusInternalServerError)
+				}
+				m.End(lr, sw.status, sw.bytes, time.Since(start))
+			}
+		}()
+	}
+	m.next.ServeHTTP(sw, lr)
+	m.End(lr, sw.status, sw.bytes, time.Since(start))
+}
+
+// RequestID returns the request id the middleware put in the context of r.
+func RequestID(r *http.Request) string {
+	return reqlog.RequestID(r.Context())
+}
+
+// Logger returns the request-scoped logger the middleware put in the context
+// of r: the logger of the middleware bound to the request id. Without one it
+// returns the global logn logger with the mdc of the context of r.
+func Logger(r *http.Request) core.Logger {
+	return reqlog.Logger(r.Context())
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+	// capture, if set, captures the response, its body into body once the
+	// headers are written
+	capture *capture
+	body    *bodyBuffer
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+		w.captureHeaders()
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+		w.captureHeaders()
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	if w.body != nil {
+		w.body.Write(b[:n])
+	}
+	return n, err
+}
+
+func (w *statusWriter) captureHeaders() {
+	if w.capture != nil && w.capture.captureResponse(w.Header()) {
+		w.body = &w.capture.response
+	}
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap gives http.ResponseController access to the wrapped writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,272 @@
+package lognhttp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanexu/logn/common"
+	"github.com/shanexu/logn/core"
+	lognzap "github.com/shanexu/logn/core/zap"
+	_ "github.com/shanexu/logn/includes"
+	"github.com/shanexu/logn/logntest"
+	"github.com/shanexu/logn/reqlog"
+)
+
+func TestMiddleware(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "app.log")
+
+	rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  file:
+    - name: FILE
+      file_name: %s
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+`, fileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := lognzap.New(rawConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mw, err := Middleware(c.GetLogger("http"), Options{
+		Levels:        map[int]core.Level{4: core.InfoLevel},
+		ExcludePaths:  []string{"/healthz"},
+		TraceIDHeader: "X-Trace-ID",
+		DebugToken:    "s3cret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seen string
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r)
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		case "/fail":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			Logger(r).Info("handled")
+			Logger(r).Debug("details")
+			w.Write([]byte("hello"))
+		}
+	}))
+
+	for _, p := range []string{"/hello", "/healthz", "/missing", "/fail"} {
+		req := httptest.NewRequest("GET", p, nil)
+		switch p {
+		case "/hello":
+			req.Header.Set(DefaultRequestIDHeader, "abc")
+			req.Header.Set("X-Trace-ID", "t1")
+			req.Header.Set(DefaultDebugHeader, "s3cret")
+		case "/missing":
+			req.Header.Set("X-Trace-ID", "t2")
+			req.Header.Set(reqlog.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if p == "/hello" {
+			assert.Equal(t, "abc", seen)
+			assert.Equal(t, "abc", rec.Header().Get(DefaultRequestIDHeader))
+		}
+	}
+	c.Sync()
+
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if assert.Len(t, lines, 5) {
+		assert.Contains(t, lines[0], `"msg":"handled"`)
+		assert.Contains(t, lines[0], `"caller":"lognhttp/middleware_test.go`)
+		assert.Contains(t, lines[0], `"request_id":"abc","trace_id":"t1"`)
+		assert.Contains(t, lines[1], `"level":"debug"`)
+		assert.Contains(t, lines[1], `"msg":"details"`)
+		assert.Contains(t, lines[2], `"method":"GET","path":"/hello","status":200,"bytes":5`)
+		assert.Contains(t, lines[2], `"request_id":"abc","trace_id":"t1"`)
+		assert.Contains(t, lines[3], `"level":"info"`)
+		assert.Contains(t, lines[3], `"status":404`)
+		assert.Contains(t, lines[3], `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","parent_span_id":"00f067aa0ba902b7"`)
+		assert.Contains(t, lines[4], `"level":"error"`)
+		assert.Contains(t, lines[4], `"status":500`)
+		assert.NotContains(t, lines[4], `"request_id":""`)
+	}
+}
+
+func TestBodies(t *testing.T) {
+	c, rec, err := logntest.New("debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mw, err := Middleware(c.GetLogger("http"), Options{
+		Bodies: &BodyOptions{
+			Level:        core.DebugLevel,
+			MaxBytes:     64,
+			RedactFields: []string{"password"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte{0x89, 'P', 'N', 'G'})
+		case "/long":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(strings.Repeat("x", 100)))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Set-Cookie", "session=s3cret")
+			w.Write([]byte(`{"token":"t","user":{"name":"ann","password":"hunter2"}}`))
+		}
+	})
+	h := mw(handler)
+
+	req := httptest.NewRequest("POST", "/login", strings.NewReader(`{"user":"ann","password":"hunter2"}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer abc")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	req = httptest.NewRequest("POST", "/image", strings.NewReader("raw"))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/long", nil))
+
+	entries := rec.Entries()
+	if !assert.Len(t, entries, 3) {
+		return
+	}
+	login := entries[0].Fields
+	assert.Equal(t, `{"password":"[REDACTED]","user":"ann"}`, login["request_body"])
+	assert.Equal(t, "[REDACTED]", login["request_headers"].(map[string]interface{})["Authorization"])
+	assert.Equal(t, `{"token":"t","user":{"name":"ann","password":"[REDACTED]"}}`, login["response_body"])
+	assert.Equal(t, "[REDACTED]", login["response_headers"].(map[string]interface{})["Set-Cookie"])
+
+	image := entries[1].Fields
+	assert.NotContains(t, image, "request_body")
+	assert.NotContains(t, image, "response_body")
+	assert.Equal(t, "image/png", image["response_headers"].(map[string]interface{})["Content-Type"])
+
+	long := entries[2].Fields
+	assert.Equal(t, strings.Repeat("x", 64), long["response_body"])
+	assert.Equal(t, true, long["response_body_truncated"])
+
+	c, rec, err = logntest.New("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mw, err = Middleware(c.GetLogger("http"), Options{Bodies: &BodyOptions{Level: core.DebugLevel}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mw(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/long", nil))
+	if assert.Len(t, rec.Entries(), 1) {
+		assert.NotContains(t, rec.Entries()[0].Fields, "response_body", "bodies are captured at debug")
+	}
+}
+
+func TestRecover(t *testing.T) {
+	c, rec, err := logntest.New("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mw, err := Middleware(c.GetLogger("http"), Options{Recover: true, TraceIDHeader: "X-Trace-ID"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/abort" {
+			panic(http.ErrAbortHandler)
+		}
+		panic("boom")
+	}))
+
+	req := httptest.NewRequest("GET", "/boom", nil)
+	req.Header.Set("X-Trace-ID", "t1")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Panics(t, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/abort", nil))
+	})
+
+	entries := rec.Entries()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "panic recovered", entries[0].Message)
+		assert.Equal(t, "boom", entries[0].Fields["panic"])
+		assert.Equal(t, "t1", entries[0].Fields["trace_id"])
+		assert.Contains(t, entries[0].Stack, "lognhttp.TestRecover")
+		assert.Equal(t, float64(500), entries[1].Fields["status"])
+	}
+}
+
+func TestMiddlewareUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	config := func(name string) *common.Config {
+		rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  file:
+    - name: FILE
+      file_name: %s
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+`, filepath.Join(dir, name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rawConfig
+	}
+	c, err := lognzap.New(config("a.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mw, err := Middleware(c.GetLogger("http"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/before", nil))
+	assert.Nil(t, c.Update(config("b.log")))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/after", nil))
+	c.Sync()
+
+	bs, _ := ioutil.ReadFile(filepath.Join(dir, "a.log"))
+	assert.Contains(t, string(bs), `/before`)
+	assert.NotContains(t, string(bs), `/after`)
+	bs, _ = ioutil.ReadFile(filepath.Join(dir, "b.log"))
+	assert.Contains(t, string(bs), `/after`)
+}
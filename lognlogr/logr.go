@@ -0,0 +1,151 @@
+// Package lognlogr adapts logn to logr, the logging API used by
+// controller-runtime and other Kubernetes libraries.
+package lognlogr
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/core"
+	lognzap "github.com/shanexu/logn/core/zap"
+)
+
+// sinkCallerSkip skips the LogSink method between logr.Logger and zap.
+const sinkCallerSkip = 1
+
+// LogSink is a logr.LogSink writing through loggers of a logn Core. V-levels
+// greater than zero are written at DebugLevel. WithName resolves the extended
+// name against the Core, so "controller" followed by "reconciler" logs through
+// the logger configured as "controller.reconciler".
+type LogSink struct {
+	core      core.Core
+	name      string
+	values    []zapcore.Field
+	callDepth int
+	logger    *lognzap.Follower
+}
+
+var (
+	_ logr.LogSink          = (*LogSink)(nil)
+	_ logr.CallDepthLogSink = (*LogSink)(nil)
+)
+
+// New returns a logr.Logger writing to the logger of c with the given name;
+// an empty name selects the root logger.
+func New(c core.Core, name string) (logr.Logger, error) {
+	s, err := NewLogSink(c, name)
+	if err != nil {
+		return logr.Discard(), err
+	}
+	return logr.New(s), nil
+}
+
+// NewLogSink returns a LogSink writing to the logger of c with the given
+// name; an empty name selects the root logger.
+func NewLogSink(c core.Core, name string) (*LogSink, error) {
+	s := &LogSink{core: c, name: name}
+	if err := s.resolve(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *LogSink) resolve() error {
+	var l core.Logger
+	if s.name == "" {
+		l = s.core.GetLogger()
+	} else {
+		l = s.core.GetLogger(s.name)
+	}
+	logger, err := lognzap.Follow(l)
+	if err != nil {
+		return err
+	}
+	s.logger = logger.WithOptions(zap.AddCallerSkip(sinkCallerSkip + s.callDepth)).With(s.values...)
+	return nil
+}
+
+func (s *LogSink) clone() *LogSink {
+	c := *s
+	c.values = c.values[:len(c.values):len(c.values)]
+	return &c
+}
+
+func (s *LogSink) Init(info logr.RuntimeInfo) {
+	s.callDepth += info.CallDepth
+	s.logger = s.logger.WithOptions(zap.AddCallerSkip(info.CallDepth))
+}
+
+func level(v int) zapcore.Level {
+	if v > 0 {
+		return zapcore.DebugLevel
+	}
+	return zapcore.InfoLevel
+}
+
+func (s *LogSink) Enabled(v int) bool {
+	return s.logger.Logger().Core().Enabled(level(v))
+}
+
+func (s *LogSink) Info(v int, msg string, keysAndValues ...interface{}) {
+	if ce := s.logger.Logger().Check(level(v), msg); ce != nil {
+		ce.Write(fields(keysAndValues)...)
+	}
+}
+
+func (s *LogSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	if ce := s.logger.Logger().Check(zapcore.ErrorLevel, msg); ce != nil {
+		ce.Write(append(fields(keysAndValues), zap.Error(err))...)
+	}
+}
+
+func (s *LogSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	c := s.clone()
+	fs := fields(keysAndValues)
+	c.values = append(c.values, fs...)
+	c.logger = s.logger.With(fs...)
+	return c
+}
+
+func (s *LogSink) WithName(name string) logr.LogSink {
+	c := s.clone()
+	if c.name == "" {
+		c.name = name
+	} else {
+		c.name = c.name + "." + name
+	}
+	if err := c.resolve(); err != nil {
+		// unreachable: the Core was already known to be backed by zap
+		return s
+	}
+	return c
+}
+
+func (s *LogSink) WithCallDepth(depth int) logr.LogSink {
+	c := s.clone()
+	c.callDepth += depth
+	c.logger = s.logger.WithOptions(zap.AddCallerSkip(depth))
+	return c
+}
+
+// fields converts logr's alternating keys and values into fields. A
+// non-string key is formatted with fmt.Sprint; a trailing key without value
+// is kept with a nil value.
+func fields(keysAndValues []interface{}) []zapcore.Field {
+	fs := make([]zapcore.Field, 0, (len(keysAndValues)+1)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+		var val interface{}
+		if i+1 < len(keysAndValues) {
+			val = keysAndValues[i+1]
+		}
+		fs = append(fs, zap.Any(key, val))
+	}
+	return fs
+}
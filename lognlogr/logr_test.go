@@ -0,0 +1,127 @@
+package lognlogr
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanexu/logn/common"
+	lognzap "github.com/shanexu/logn/core/zap"
+	_ "github.com/shanexu/logn/includes"
+)
+
+func TestLogSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "app.log")
+
+	rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  file:
+    - name: FILE
+      file_name: %s
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+  logger:
+    - name: controller.reconciler
+      level: debug
+`, fileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := lognzap.New(rawConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := New(c, "controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("started", "workers", 2)
+	l.V(1).Info("hidden")
+	l.Error(errors.New("boom"), "failed")
+
+	r := l.WithValues("object", "ns/name").WithName("reconciler")
+	assert.True(t, r.V(1).Enabled())
+	r.V(1).Info("reconciling")
+	c.Sync()
+
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if assert.Len(t, lines, 3) {
+		assert.Contains(t, lines[0], `"logger":"controller"`)
+		assert.Contains(t, lines[0], `"msg":"started"`)
+		assert.Contains(t, lines[0], `"workers":2`)
+		assert.Contains(t, lines[0], `"caller":"lognlogr/logr_test.go:`)
+		assert.Contains(t, lines[1], `"level":"error"`)
+		assert.Contains(t, lines[1], `"error":"boom"`)
+		assert.Contains(t, lines[2], `"level":"debug"`)
+		assert.Contains(t, lines[2], `"logger":"controller.reconciler"`)
+		assert.Contains(t, lines[2], `"object":"ns/name"`)
+	}
+}
+
+func TestLogSinkUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	config := func(name string) *common.Config {
+		rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  file:
+    - name: FILE
+      file_name: %s
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+`, filepath.Join(dir, name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rawConfig
+	}
+	c, err := lognzap.New(config("a.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := New(c, "controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l = l.WithValues("object", "ns/name")
+	l.Info("before")
+	assert.Nil(t, c.Update(config("b.log")))
+	l.Info("after")
+	c.Sync()
+
+	bs, _ := ioutil.ReadFile(filepath.Join(dir, "a.log"))
+	assert.Contains(t, string(bs), `"msg":"before"`)
+	assert.NotContains(t, string(bs), `"msg":"after"`)
+	bs, _ = ioutil.ReadFile(filepath.Join(dir, "b.log"))
+	assert.Contains(t, string(bs), `"msg":"after"`)
+	assert.Contains(t, string(bs), `"object":"ns/name"`)
+}
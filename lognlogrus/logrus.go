@@ -0,0 +1,138 @@
+// Package lognlogrus eases migrating from logrus to logn: Hook forwards
+// logrus entries into logn loggers, and Formatter renders them with a logn
+// encoder, so both APIs can coexist while call sites are converted.
+package lognlogrus
+
+import (
+	"sort"
+
+	"github.com/sirupsen/logrus"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/appender/encoder"
+	"github.com/shanexu/logn/core"
+	lognzap "github.com/shanexu/logn/core/zap"
+)
+
+// Level maps a logrus level to the corresponding logn level. Trace maps to
+// debug, the lowest logn level.
+func Level(l logrus.Level) zapcore.Level {
+	switch l {
+	case logrus.PanicLevel:
+		return zapcore.PanicLevel
+	case logrus.FatalLevel:
+		return zapcore.FatalLevel
+	case logrus.ErrorLevel:
+		return zapcore.ErrorLevel
+	case logrus.WarnLevel:
+		return zapcore.WarnLevel
+	case logrus.InfoLevel:
+		return zapcore.InfoLevel
+	default:
+		return zapcore.DebugLevel
+	}
+}
+
+// Hook is a logrus.Hook writing every fired entry to a logn logger, keeping
+// the time and caller recorded by logrus. Panic and fatal entries are only
+// written: panicking and exiting is left to logrus.
+type Hook struct {
+	logger *lognzap.Follower
+	levels []logrus.Level
+}
+
+var _ logrus.Hook = (*Hook)(nil)
+
+// NewHook returns a hook writing to l. It fires for the given levels, or for
+// all of them if none are given.
+func NewHook(l core.Logger, levels ...logrus.Level) (*Hook, error) {
+	logger, err := lognzap.Follow(l)
+	if err != nil {
+		return nil, err
+	}
+	if len(levels) == 0 {
+		levels = logrus.AllLevels
+	}
+	return &Hook{logger: logger, levels: levels}, nil
+}
+
+func (h *Hook) Levels() []logrus.Level {
+	return h.levels
+}
+
+func (h *Hook) Fire(e *logrus.Entry) error {
+	// the core is checked directly, bypassing the logger's panic and fatal
+	// handling
+	logger := h.logger.Logger()
+	ce := logger.Core().Check(entry(logger.Name(), e), nil)
+	if ce == nil {
+		return nil
+	}
+	ce.Write(fields(e.Data)...)
+	return nil
+}
+
+// Formatter is a logrus.Formatter encoding entries with a logn encoder, so
+// output of loggers still on logrus matches the rest of the application.
+type Formatter struct {
+	encoder encoder.Encoder
+}
+
+var _ logrus.Formatter = (*Formatter)(nil)
+
+// NewFormatter returns a formatter using enc, typically created with
+// encoder.CreateEncoder.
+func NewFormatter(enc encoder.Encoder) *Formatter {
+	return &Formatter{encoder: enc}
+}
+
+func (f *Formatter) Format(e *logrus.Entry) ([]byte, error) {
+	buf, err := f.encoder.Clone().EncodeEntry(entry("", e), fields(e.Data))
+	if err != nil {
+		return nil, err
+	}
+	defer buf.Free()
+	b := make([]byte, buf.Len())
+	copy(b, buf.Bytes())
+	return b, nil
+}
+
+func entry(name string, e *logrus.Entry) zapcore.Entry {
+	ent := zapcore.Entry{
+		LoggerName: name,
+		Time:       e.Time,
+		Level:      Level(e.Level),
+		Message:    e.Message,
+	}
+	if e.Caller != nil {
+		ent.Caller = zapcore.EntryCaller{
+			Defined:  true,
+			PC:       e.Caller.PC,
+			File:     e.Caller.File,
+			Line:     e.Caller.Line,
+			Function: e.Caller.Function,
+		}
+	}
+	return ent
+}
+
+// fields converts logrus data to fields sorted by key, logrus.ErrorKey
+// becoming an error field.
+func fields(data logrus.Fields) []zapcore.Field {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fs := make([]zapcore.Field, 0, len(keys))
+	for _, k := range keys {
+		v := data[k]
+		if err, ok := v.(error); ok && k == logrus.ErrorKey {
+			fs = append(fs, zap.Error(err))
+			continue
+		}
+		fs = append(fs, zap.Any(k, v))
+	}
+	return fs
+}
@@ -0,0 +1,159 @@
+package lognlogrus
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/appender/encoder"
+	"github.com/shanexu/logn/common"
+	lognzap "github.com/shanexu/logn/core/zap"
+	_ "github.com/shanexu/logn/includes"
+)
+
+func TestLevel(t *testing.T) {
+	assert.Equal(t, zapcore.DebugLevel, Level(logrus.TraceLevel))
+	assert.Equal(t, zapcore.InfoLevel, Level(logrus.InfoLevel))
+	assert.Equal(t, zapcore.FatalLevel, Level(logrus.FatalLevel))
+}
+
+func TestHook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "app.log")
+
+	rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  file:
+    - name: FILE
+      file_name: %s
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+`, fileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := lognzap.New(rawConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewHook(c.GetLogger("legacy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := logrus.New()
+	l.SetOutput(ioutil.Discard)
+	l.SetLevel(logrus.TraceLevel)
+	l.AddHook(h)
+
+	l.Debug("hidden")
+	l.WithField("user", "bob").WithError(errors.New("boom")).Warn("login failed")
+	c.Sync()
+
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if assert.Len(t, lines, 1) {
+		assert.Contains(t, lines[0], `"level":"warn"`)
+		assert.Contains(t, lines[0], `"logger":"legacy"`)
+		assert.Contains(t, lines[0], `"msg":"login failed"`)
+		assert.Contains(t, lines[0], `"error":"boom"`)
+		assert.Contains(t, lines[0], `"user":"bob"`)
+	}
+}
+
+func TestHookUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	config := func(name string) *common.Config {
+		rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  file:
+    - name: FILE
+      file_name: %s
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+`, filepath.Join(dir, name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rawConfig
+	}
+	c, err := lognzap.New(config("a.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := NewHook(c.GetLogger("legacy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := logrus.New()
+	l.SetOutput(ioutil.Discard)
+	l.AddHook(h)
+
+	l.Info("before")
+	assert.Nil(t, c.Update(config("b.log")))
+	l.Info("after")
+	c.Sync()
+
+	bs, _ := ioutil.ReadFile(filepath.Join(dir, "a.log"))
+	assert.Contains(t, string(bs), `"msg":"before"`)
+	assert.NotContains(t, string(bs), `"msg":"after"`)
+	bs, _ = ioutil.ReadFile(filepath.Join(dir, "b.log"))
+	assert.Contains(t, string(bs), `"msg":"after"`)
+}
+
+func TestFormatter(t *testing.T) {
+	rawConfig, err := common.NewConfigFrom(`
+json:
+  time_encoder: ISO8601
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config encoder.Config
+	if err := rawConfig.Unpack(&config); err != nil {
+		t.Fatal(err)
+	}
+	enc, err := encoder.CreateEncoder(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	l := logrus.New()
+	l.SetOutput(&buf)
+	l.SetFormatter(NewFormatter(enc))
+	l.WithField("n", 1).Info("hello")
+
+	assert.Contains(t, buf.String(), `"level":"info"`)
+	assert.Contains(t, buf.String(), `"msg":"hello","n":1}`)
+}
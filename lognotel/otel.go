@@ -0,0 +1,268 @@
+// Package lognotel bridges logn to the OpenTelemetry logs API.
+//
+// Importing the package registers the otel appender type. Its appenders emit
+// entries as log records through a LoggerProvider of the application, by
+// default the global one, so that the resource, processors and exporters
+// configured in its OpenTelemetry SDK apply to logs too. They take no
+// encoder:
+//
+//	appenders:
+//	  otel:
+//	    - name: OTEL
+//	      provider: sdk
+//	      scope: github.com/acme/shop
+//
+// A provider other than the global one is named with RegisterLoggerProvider.
+package lognotel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/appender/writer"
+	"github.com/shanexu/logn/common"
+)
+
+// DefaultScope is the instrumentation scope of the loggers of appenders,
+// unless configured otherwise.
+const DefaultScope = "github.com/shanexu/logn"
+
+var (
+	providersMu sync.Mutex
+	providers   = map[string]log.LoggerProvider{}
+)
+
+// RegisterLoggerProvider names p so that otel appenders can emit through it.
+// Providers are to be registered before the configuration naming them is
+// loaded.
+func RegisterLoggerProvider(name string, p log.LoggerProvider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[name] = p
+}
+
+func lookupProvider(name string) (log.LoggerProvider, error) {
+	if name == "" {
+		return global.GetLoggerProvider(), nil
+	}
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	p, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("logger provider %q not registered", name)
+	}
+	return p, nil
+}
+
+// Config is the configuration of otel appenders.
+type Config struct {
+	// Provider is the name of the LoggerProvider given to
+	// RegisterLoggerProvider, the global one if empty.
+	Provider string `logn-config:"provider"`
+	// Scope is the name of the instrumentation scope of the records.
+	Scope string `logn-config:"scope"`
+}
+
+func defaultConfig() Config {
+	return Config{Scope: DefaultScope}
+}
+
+// Writer emits entries as log records through a Logger.
+type Writer struct {
+	provider log.LoggerProvider
+	logger   log.Logger
+}
+
+// NewWriter creates the writer of an otel appender.
+func NewWriter(rawConfig *common.Config) (writer.Writer, error) {
+	config := defaultConfig()
+	if err := rawConfig.Unpack(&config); err != nil {
+		return nil, err
+	}
+	p, err := lookupProvider(config.Provider)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{provider: p, logger: p.Logger(config.Scope)}, nil
+}
+
+func init() {
+	writer.RegisterType("otel", NewWriter)
+}
+
+// WriteEntry emits ent as a record, fields being its attributes.
+func (w *Writer) WriteEntry(ent zapcore.Entry, fields []zapcore.Field) error {
+	var rec log.Record
+	rec.SetTimestamp(ent.Time)
+	rec.SetObservedTimestamp(time.Now())
+	rec.SetSeverity(severity(ent.Level))
+	rec.SetSeverityText(ent.Level.CapitalString())
+	rec.SetBody(log.StringValue(ent.Message))
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	attrs := make([]log.KeyValue, 0, len(enc.Fields)+5)
+	if ent.LoggerName != "" {
+		attrs = append(attrs, log.String("logger", ent.LoggerName))
+	}
+	if ent.Caller.Defined {
+		attrs = append(attrs,
+			log.String("code.filepath", ent.Caller.File),
+			log.Int("code.lineno", ent.Caller.Line))
+		if ent.Caller.Function != "" {
+			attrs = append(attrs, log.String("code.function", ent.Caller.Function))
+		}
+	}
+	if ent.Stack != "" {
+		attrs = append(attrs, log.String("exception.stacktrace", ent.Stack))
+	}
+	attrs = append(attrs, keyValues(enc.Fields)...)
+	rec.AddAttributes(attrs...)
+
+	w.logger.Emit(context.Background(), rec)
+	return nil
+}
+
+// Write emits p as the body of a record, for what is written to the appender
+// other than entries.
+func (w *Writer) Write(p []byte) (int, error) {
+	var rec log.Record
+	now := time.Now()
+	rec.SetTimestamp(now)
+	rec.SetObservedTimestamp(now)
+	rec.SetBody(log.StringValue(string(p)))
+	w.logger.Emit(context.Background(), rec)
+	return len(p), nil
+}
+
+// Sync flushes the provider, if it can be.
+func (w *Writer) Sync() error {
+	if f, ok := w.provider.(interface {
+		ForceFlush(context.Context) error
+	}); ok {
+		return f.ForceFlush(context.Background())
+	}
+	return nil
+}
+
+// severity maps the levels of entries to severities, DPanic, Panic and Fatal
+// being increasingly severe fatal ones.
+func severity(l zapcore.Level) log.Severity {
+	switch l {
+	case zapcore.DebugLevel:
+		return log.SeverityDebug
+	case zapcore.InfoLevel:
+		return log.SeverityInfo
+	case zapcore.WarnLevel:
+		return log.SeverityWarn
+	case zapcore.ErrorLevel:
+		return log.SeverityError
+	case zapcore.DPanicLevel:
+		return log.SeverityFatal1
+	case zapcore.PanicLevel:
+		return log.SeverityFatal2
+	case zapcore.FatalLevel:
+		return log.SeverityFatal3
+	}
+	if l < zapcore.DebugLevel {
+		return log.SeverityTrace
+	}
+	return log.SeverityFatal4
+}
+
+// keyValues converts the fields a MapObjectEncoder encoded, sorted by key.
+func keyValues(fields map[string]interface{}) []log.KeyValue {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	kvs := make([]log.KeyValue, len(keys))
+	for i, k := range keys {
+		kvs[i] = log.KeyValue{Key: k, Value: value(fields[k])}
+	}
+	return kvs
+}
+
+// value converts a value a MapObjectEncoder encoded.
+func value(v interface{}) log.Value {
+	switch v := v.(type) {
+	case nil:
+		return log.Value{}
+	case string:
+		return log.StringValue(v)
+	case bool:
+		return log.BoolValue(v)
+	case int:
+		return log.IntValue(v)
+	case int8:
+		return log.Int64Value(int64(v))
+	case int16:
+		return log.Int64Value(int64(v))
+	case int32:
+		return log.Int64Value(int64(v))
+	case int64:
+		return log.Int64Value(v)
+	case uint:
+		return uintValue(uint64(v))
+	case uint8:
+		return log.Int64Value(int64(v))
+	case uint16:
+		return log.Int64Value(int64(v))
+	case uint32:
+		return log.Int64Value(int64(v))
+	case uint64:
+		return uintValue(v)
+	case uintptr:
+		return uintValue(uint64(v))
+	case float32:
+		return log.Float64Value(float64(v))
+	case float64:
+		return log.Float64Value(v)
+	case complex64, complex128:
+		return log.StringValue(fmt.Sprint(v))
+	case []byte:
+		return log.BytesValue(v)
+	case time.Time:
+		return log.StringValue(v.Format(time.RFC3339Nano))
+	case map[string]interface{}:
+		return log.MapValue(keyValues(v)...)
+	case []interface{}:
+		vs := make([]log.Value, len(v))
+		for i, e := range v {
+			vs[i] = value(e)
+		}
+		return log.SliceValue(vs...)
+	case fmt.Stringer:
+		return log.StringValue(v.String())
+	}
+	// reflected values, as encoding/json sees them
+	b, err := json.Marshal(v)
+	if err != nil {
+		return log.StringValue(fmt.Sprint(v))
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		return log.StringValue(string(b))
+	}
+	return value(decoded)
+}
+
+// uintValue converts v, as a string if it overflows an int64.
+func uintValue(v uint64) log.Value {
+	if v > math.MaxInt64 {
+		return log.StringValue(fmt.Sprint(v))
+	}
+	return log.Int64Value(int64(v))
+}
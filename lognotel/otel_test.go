@@ -0,0 +1,86 @@
+package lognotel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/logtest"
+	"go.uber.org/zap"
+
+	"github.com/shanexu/logn/common"
+	lognzap "github.com/shanexu/logn/core/zap"
+)
+
+func TestAppender(t *testing.T) {
+	rec := logtest.NewRecorder()
+	RegisterLoggerProvider("test", rec)
+	rawConfig, err := common.NewConfigFrom(`
+appenders:
+  otel:
+    - name: OTEL
+      provider: test
+      scope: shop
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - OTEL
+  logger:
+    - name: orders
+      level: debug
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := lognzap.New(rawConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := c.GetLogger("orders").With("order", 42)
+	l.Debugw("placed", "items", []string{"book", "pen"}, "customer", map[string]interface{}{"id": "c1"})
+	l.Errorw("failed", zap.Error(assert.AnError))
+	c.GetLogger("").Debug("dropped")
+	assert.NoError(t, c.Sync())
+
+	result := rec.Result()
+	var scope *logtest.ScopeRecords
+	for _, s := range result {
+		if s.Name == "shop" {
+			scope = s
+		}
+	}
+	if !assert.NotNil(t, scope) || !assert.Len(t, scope.Records, 2) {
+		return
+	}
+
+	placed := scope.Records[0]
+	assert.Equal(t, "placed", placed.Body().AsString())
+	assert.Equal(t, log.SeverityDebug, placed.Severity())
+	assert.Equal(t, "DEBUG", placed.SeverityText())
+	assert.False(t, placed.Timestamp().IsZero())
+	attrs := map[string]log.Value{}
+	placed.WalkAttributes(func(kv log.KeyValue) bool {
+		attrs[kv.Key] = kv.Value
+		return true
+	})
+	assert.Equal(t, "orders", attrs["logger"].AsString())
+	assert.Equal(t, int64(42), attrs["order"].AsInt64())
+	assert.True(t, log.SliceValue(log.StringValue("book"), log.StringValue("pen")).Equal(attrs["items"]))
+	assert.True(t, log.MapValue(log.String("id", "c1")).Equal(attrs["customer"]))
+	assert.Contains(t, attrs["code.filepath"].AsString(), "otel_test.go")
+
+	failed := scope.Records[1]
+	assert.Equal(t, log.SeverityError, failed.Severity())
+	failed.WalkAttributes(func(kv log.KeyValue) bool {
+		if kv.Key == "error" {
+			assert.Equal(t, assert.AnError.Error(), kv.Value.AsString())
+		}
+		return true
+	})
+}
+
+func TestUnregisteredProvider(t *testing.T) {
+	_, err := NewWriter(common.MustNewConfigFrom(map[string]interface{}{"provider": "missing"}))
+	assert.EqualError(t, err, `logger provider "missing" not registered`)
+}
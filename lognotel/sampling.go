@@ -0,0 +1,42 @@
+package lognotel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/shanexu/logn/core"
+	"github.com/shanexu/logn/reqlog"
+)
+
+// Sampled reports whether the span of ctx is sampled, that is whether the
+// request being served is traced.
+func Sampled(ctx context.Context) bool {
+	return trace.SpanContextFromContext(ctx).IsSampled()
+}
+
+// SampledDebugLogger returns l bound to the mdc of ctx, writing debug entries
+// exactly if the span of ctx is sampled, whatever the configured levels:
+// detailed logs are written for the requests that have traces, and for them
+// only.
+func SampledDebugLogger(ctx context.Context, l core.Logger) core.Logger {
+	return sampledDebug(ctx, l.WithContext(ctx))
+}
+
+// SampledDebug returns a copy of ctx whose request-scoped logger, as
+// reqlog.Logger returns it, writes debug entries exactly if the span of ctx
+// is sampled. It is meant to be called once both the tracing and the logging
+// middlewares started the request, e.g. in a middleware of its own.
+func SampledDebug(ctx context.Context) context.Context {
+	return reqlog.NewContext(ctx, sampledDebug(ctx, reqlog.Logger(ctx)))
+}
+
+func sampledDebug(ctx context.Context, l core.Logger) core.Logger {
+	switch {
+	case Sampled(ctx):
+		return l.WithLevel(core.DebugLevel)
+	case l.IsDebug():
+		return l.WithLevel(core.InfoLevel)
+	}
+	return l
+}
@@ -0,0 +1,63 @@
+package lognotel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/shanexu/logn/core"
+	"github.com/shanexu/logn/logntest"
+	"github.com/shanexu/logn/reqlog"
+)
+
+func spanContext(flags trace.TraceFlags) context.Context {
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: flags,
+	})
+	return trace.ContextWithSpanContext(context.Background(), sc)
+}
+
+func TestSampledDebugLogger(t *testing.T) {
+	c, rec, err := logntest.New("debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sampled := spanContext(trace.FlagsSampled)
+	unsampled := spanContext(0)
+	assert.True(t, Sampled(sampled))
+	assert.False(t, Sampled(unsampled))
+
+	SampledDebugLogger(sampled, c).Debug("sampled")
+	SampledDebugLogger(unsampled, c).Debug("unsampled")
+	SampledDebugLogger(unsampled, c).Info("info")
+	SampledDebugLogger(context.Background(), c).Debug("untraced")
+	assert.Equal(t, []string{"sampled", "info"}, messages(rec))
+}
+
+func TestSampledDebug(t *testing.T) {
+	c, rec, err := logntest.New("warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := reqlog.Start(spanContext(trace.FlagsSampled), c, "r1", "")
+	reqlog.Logger(ctx).Debug("configured")
+	reqlog.Logger(SampledDebug(ctx)).Debug("sampled")
+
+	ctx = reqlog.Start(spanContext(0), c, "r2", "")
+	reqlog.Logger(SampledDebug(ctx)).Info("unsampled")
+	reqlog.Logger(SampledDebug(ctx)).Warn("warned")
+	assert.Equal(t, []string{"sampled", "warned"}, messages(rec))
+	assert.Equal(t, "r1", rec.Entries().FilterLevel(core.DebugLevel)[0].Fields["request_id"])
+}
+
+func messages(rec *logntest.Recorder) []string {
+	var msgs []string
+	for _, e := range rec.Entries() {
+		msgs = append(msgs, e.Message)
+	}
+	return msgs
+}
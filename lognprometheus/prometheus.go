@@ -0,0 +1,175 @@
+// Package lognprometheus exports logn activity as Prometheus metrics.
+package lognprometheus
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/metrics"
+)
+
+const namespace = "logn"
+
+// Collector is a metrics.Recorder exposing what it records as Prometheus
+// metrics:
+//
+//	logn_entries_total{logger,level}
+//	logn_dropped_entries_total{logger,level,reason}
+//	logn_appender_writes_total{appender}
+//	logn_appender_write_errors_total{appender}
+//	logn_appender_encode_errors_total{appender}
+//	logn_appender_bytes_total{appender}
+//	logn_appender_write_duration_seconds{appender}
+//	logn_appender_queue_depth{appender}
+//	logn_appender_queue_capacity{appender}
+//	logn_appender_queue_full_total{appender,outcome}
+type Collector struct {
+	entries       *prometheus.CounterVec
+	dropped       *prometheus.CounterVec
+	writes        *prometheus.CounterVec
+	writeErrors   *prometheus.CounterVec
+	encodeErrors  *prometheus.CounterVec
+	bytes         *prometheus.CounterVec
+	writeDuration *prometheus.HistogramVec
+	queueDepth    *prometheus.GaugeVec
+	queueCapacity *prometheus.GaugeVec
+	queueFull     *prometheus.CounterVec
+}
+
+var (
+	_ metrics.Recorder     = (*Collector)(nil)
+	_ prometheus.Collector = (*Collector)(nil)
+)
+
+// NewCollector returns a collector. It records nothing until it is set with
+// metrics.SetRecorder; Register does both.
+func NewCollector() *Collector {
+	return &Collector{
+		entries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "entries_total",
+			Help:      "Entries written by loggers.",
+		}, []string{"logger", "level"}),
+		dropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "dropped_entries_total",
+			Help:      "Entries discarded by loggers, by sampling, rate limiting, deduplication or load shedding.",
+		}, []string{"logger", "level", "reason"}),
+		writes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "appender_writes_total",
+			Help:      "Entries written by appenders.",
+		}, []string{"appender"}),
+		writeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "appender_write_errors_total",
+			Help:      "Entries appenders failed to write.",
+		}, []string{"appender"}),
+		encodeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "appender_encode_errors_total",
+			Help:      "Entries appenders failed to encode.",
+		}, []string{"appender"}),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "appender_bytes_total",
+			Help:      "Bytes of the entries written by appenders.",
+		}, []string{"appender"}),
+		writeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "appender_write_duration_seconds",
+			Help:      "Time appenders take to encode and write an entry.",
+			Buckets:   prometheus.ExponentialBuckets(1e-6, 4, 10),
+		}, []string{"appender"}),
+		queueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "appender_queue_depth",
+			Help:      "Entries queued by asynchronous appenders.",
+		}, []string{"appender"}),
+		queueCapacity: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "appender_queue_capacity",
+			Help:      "Entries asynchronous appenders can queue.",
+		}, []string{"appender"}),
+		queueFull: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "appender_queue_full_total",
+			Help:      "Entries arriving while the queue of asynchronous appenders is full, by outcome.",
+		}, []string{"appender", "outcome"}),
+	}
+}
+
+// Register registers a new collector with reg and makes it the metrics
+// recorder.
+func Register(reg prometheus.Registerer) (*Collector, error) {
+	c := NewCollector()
+	if err := reg.Register(c); err != nil {
+		return nil, err
+	}
+	metrics.SetRecorder(c)
+	return c, nil
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.entries.Describe(ch)
+	c.dropped.Describe(ch)
+	c.writes.Describe(ch)
+	c.writeErrors.Describe(ch)
+	c.encodeErrors.Describe(ch)
+	c.bytes.Describe(ch)
+	c.writeDuration.Describe(ch)
+	c.queueDepth.Describe(ch)
+	c.queueCapacity.Describe(ch)
+	c.queueFull.Describe(ch)
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.entries.Collect(ch)
+	c.dropped.Collect(ch)
+	c.writes.Collect(ch)
+	c.writeErrors.Collect(ch)
+	c.encodeErrors.Collect(ch)
+	c.bytes.Collect(ch)
+	c.writeDuration.Collect(ch)
+	c.queueDepth.Collect(ch)
+	c.queueCapacity.Collect(ch)
+	c.queueFull.Collect(ch)
+}
+
+func (c *Collector) Entry(logger string, level zapcore.Level) {
+	c.entries.WithLabelValues(logger, level.String()).Inc()
+}
+
+func (c *Collector) Dropped(logger string, level zapcore.Level, reason string) {
+	c.dropped.WithLabelValues(logger, level.String(), reason).Inc()
+}
+
+func (c *Collector) AppenderWrite(appender string, elapsed time.Duration, err error) {
+	c.writes.WithLabelValues(appender).Inc()
+	if err != nil {
+		c.writeErrors.WithLabelValues(appender).Inc()
+	}
+	c.writeDuration.WithLabelValues(appender).Observe(elapsed.Seconds())
+}
+
+func (c *Collector) AppenderBytes(appender string, bytes int) {
+	c.bytes.WithLabelValues(appender).Add(float64(bytes))
+}
+
+func (c *Collector) AppenderEncodeError(appender string) {
+	c.encodeErrors.WithLabelValues(appender).Inc()
+}
+
+func (c *Collector) QueueDepth(appender string, depth int) {
+	c.queueDepth.WithLabelValues(appender).Set(float64(depth))
+}
+
+func (c *Collector) QueueCapacity(appender string, capacity int) {
+	c.queueCapacity.WithLabelValues(appender).Set(float64(capacity))
+}
+
+func (c *Collector) QueueFull(appender string, outcome string) {
+	c.queueFull.WithLabelValues(appender, outcome).Inc()
+}
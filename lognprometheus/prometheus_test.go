@@ -0,0 +1,48 @@
+package lognprometheus
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/metrics"
+)
+
+func TestRegister(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	c, err := Register(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer metrics.SetRecorder(nil)
+
+	metrics.Entry("app", zapcore.InfoLevel)
+	metrics.Entry("app", zapcore.InfoLevel)
+	metrics.Dropped("app", zapcore.DebugLevel, metrics.DropSampling)
+	metrics.AppenderWrite("FILE", time.Millisecond, nil)
+	metrics.AppenderWrite("FILE", time.Millisecond, errors.New("disk full"))
+	metrics.AppenderBytes("FILE", 120)
+	metrics.AppenderEncodeError("FILE")
+	metrics.QueueDepth("ASYNC", 7)
+	metrics.QueueCapacity("ASYNC", 1024)
+	metrics.QueueFull("ASYNC", metrics.QueueDroppedNewest)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(c.entries.WithLabelValues("app", "info")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.dropped.WithLabelValues("app", "debug", "sampling")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(c.writes.WithLabelValues("FILE")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.writeErrors.WithLabelValues("FILE")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.encodeErrors.WithLabelValues("FILE")))
+	assert.Equal(t, 120.0, testutil.ToFloat64(c.bytes.WithLabelValues("FILE")))
+	assert.Equal(t, 7.0, testutil.ToFloat64(c.queueDepth.WithLabelValues("ASYNC")))
+	assert.Equal(t, 1024.0, testutil.ToFloat64(c.queueCapacity.WithLabelValues("ASYNC")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.queueFull.WithLabelValues("ASYNC", "drop_newest")))
+
+	n, err := testutil.GatherAndCount(reg, "logn_appender_write_duration_seconds")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
@@ -0,0 +1,128 @@
+// Package logns3 uploads the files rotated by logn appenders to Amazon S3, or
+// to a service compatible with it. Importing it registers the s3 rotation
+// hook:
+//
+//	rolling_file:
+//	  - name: FILE
+//	    file_name: /var/log/app/app.log
+//	    compress: true
+//	    on_rotate:
+//	      - s3:
+//	          bucket: logs
+//	          prefix: app/
+//
+// Credentials and the region are found as the AWS SDK does, from the
+// environment, the shared configuration files or the instance role.
+package logns3
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/shanexu/logn/appender/writer/rotate"
+	"github.com/shanexu/logn/common"
+)
+
+const defaultTimeout = 10 * time.Minute
+
+// Config configures the s3 hook.
+type Config struct {
+	// Bucket is the bucket the files are uploaded to.
+	Bucket string `logn-config:"bucket" logn-validate:"required"`
+	// Prefix is prepended to the names of the files to make their keys,
+	// e.g. app/ for app/app-2006-01-02T15-04-05.000.log.gz.
+	Prefix string `logn-config:"prefix"`
+	// Region overrides the region found in the environment.
+	Region string `logn-config:"region"`
+	// Endpoint, if set, is the URL of a service compatible with S3, such as
+	// MinIO.
+	Endpoint string `logn-config:"endpoint"`
+	// PathStyle addresses the bucket in the path of the URLs rather than in
+	// their host, as some compatible services require.
+	PathStyle bool `logn-config:"path_style"`
+	// Delete removes the files once uploaded.
+	Delete bool `logn-config:"delete"`
+	// Timeout is how long an upload may take, 10m by default.
+	Timeout string `logn-config:"timeout"`
+}
+
+// hook uploads the files to a bucket.
+type hook struct {
+	client  *s3.Client
+	cfg     Config
+	timeout time.Duration
+}
+
+// NewHook creates an s3 hook from its configuration.
+func NewHook(c *common.Config) (rotate.Hook, error) {
+	cfg := Config{}
+	if err := c.Unpack(&cfg); err != nil {
+		return nil, err
+	}
+	h := &hook{cfg: cfg, timeout: defaultTimeout}
+	if cfg.Timeout != "" {
+		d, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		h.timeout = d
+	}
+	var opts []func(*config.LoadOptions) error
+	if cfg.Region != "" {
+		opts = append(opts, config.WithRegion(cfg.Region))
+	}
+	awsCfg, err := config.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	h.client = s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+			// compatible services may not take the checksums trailing
+			// the bodies
+			o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+		}
+		o.UsePathStyle = cfg.PathStyle
+	})
+	return h, nil
+}
+
+func (h *hook) Rotated(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+	key := h.cfg.Prefix + filepath.Base(file)
+	_, err = h.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(h.cfg.Bucket),
+		Key:           aws.String(key),
+		Body:          f,
+		ContentLength: aws.Int64(fi.Size()),
+	})
+	if err != nil {
+		return "", fmt.Errorf("uploading to s3://%s/%s: %v", h.cfg.Bucket, key, err)
+	}
+	if h.cfg.Delete {
+		f.Close()
+		return file, os.Remove(file)
+	}
+	return file, nil
+}
+
+func init() {
+	rotate.RegisterType("s3", NewHook)
+}
@@ -0,0 +1,59 @@
+package logns3
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanexu/logn/common"
+)
+
+func TestHook(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "unexpected", http.StatusBadRequest)
+			return
+		}
+		bs, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		objects[r.URL.Path] = string(bs)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_CONFIG_FILE", os.DevNull)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", os.DevNull)
+
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app-1.log")
+	assert.Nil(t, ioutil.WriteFile(name, []byte("rotated\n"), 0644))
+
+	c, err := common.NewConfigFrom(map[string]interface{}{
+		"bucket":     "logs",
+		"prefix":     "app/",
+		"region":     "us-east-1",
+		"endpoint":   srv.URL,
+		"path_style": true,
+		"delete":     true,
+	})
+	assert.Nil(t, err)
+	h, err := NewHook(c)
+	assert.Nil(t, err)
+	_, err = h.Rotated(name)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"/logs/app/app-1.log": "rotated\n"}, objects)
+	_, err = os.Stat(name)
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = h.Rotated(name)
+	assert.NotNil(t, err)
+}
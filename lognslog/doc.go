@@ -0,0 +1,4 @@
+// Package lognslog provides a log/slog Handler backed by a logn logger, so
+// applications can use slog as their logging API while logn handles
+// configuration, appenders and levels. It requires Go 1.21 or later.
+package lognslog
@@ -0,0 +1,157 @@
+//go:build go1.21
+
+package lognslog
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/core"
+	lognzap "github.com/shanexu/logn/core/zap"
+)
+
+// Handler is a slog.Handler writing records through a logn logger. Attributes
+// become fields and groups become nested objects.
+type Handler struct {
+	logger *lognzap.Follower
+	// groups opened by WithGroup but not yet materialized, since slog drops
+	// groups without attributes
+	groups []string
+}
+
+var _ slog.Handler = (*Handler)(nil)
+
+// NewHandler returns a Handler writing to l, as configured by the last
+// Core.Update.
+func NewHandler(l core.Logger) (*Handler, error) {
+	logger, err := lognzap.Follow(l)
+	if err != nil {
+		return nil, err
+	}
+	// the caller is taken from the record instead
+	return &Handler{logger: logger.WithOptions(zap.WithCaller(false))}, nil
+}
+
+// Level maps a slog level onto the closest logn level at or below it.
+func Level(l slog.Level) zapcore.Level {
+	switch {
+	case l < slog.LevelInfo:
+		return zapcore.DebugLevel
+	case l < slog.LevelWarn:
+		return zapcore.InfoLevel
+	case l < slog.LevelError:
+		return zapcore.WarnLevel
+	default:
+		return zapcore.ErrorLevel
+	}
+}
+
+func (h *Handler) Enabled(_ context.Context, l slog.Level) bool {
+	return h.logger.Logger().Core().Enabled(Level(l))
+}
+
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	ce := h.logger.Logger().Check(Level(r.Level), r.Message)
+	if ce == nil {
+		return nil
+	}
+	if !r.Time.IsZero() {
+		ce.Time = r.Time
+	}
+	if r.PC != 0 {
+		frames := runtime.CallersFrames([]uintptr{r.PC})
+		f, _ := frames.Next()
+		ce.Caller = zapcore.NewEntryCaller(f.PC, f.File, f.Line, true)
+	}
+
+	fields := make([]zapcore.Field, 0, len(h.groups)+r.NumAttrs())
+	if r.NumAttrs() > 0 {
+		fields = appendGroups(fields, h.groups)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, a)
+		return true
+	})
+	ce.Write(fields...)
+	return nil
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	fields := make([]zapcore.Field, 0, len(h.groups)+len(attrs))
+	fields = appendGroups(fields, h.groups)
+	for _, a := range attrs {
+		fields = appendAttr(fields, a)
+	}
+	return &Handler{logger: h.logger.With(fields...)}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	groups := make([]string, len(h.groups), len(h.groups)+1)
+	copy(groups, h.groups)
+	return &Handler{logger: h.logger, groups: append(groups, name)}
+}
+
+func appendGroups(fields []zapcore.Field, groups []string) []zapcore.Field {
+	for _, g := range groups {
+		fields = append(fields, zap.Namespace(g))
+	}
+	return fields
+}
+
+func appendAttr(fields []zapcore.Field, a slog.Attr) []zapcore.Field {
+	v := a.Value.Resolve()
+	if a.Key == "" && v.Kind() != slog.KindGroup {
+		return fields
+	}
+	switch v.Kind() {
+	case slog.KindString:
+		return append(fields, zap.String(a.Key, v.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(a.Key, v.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(a.Key, v.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(a.Key, v.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(a.Key, v.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(a.Key, v.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(a.Key, v.Time()))
+	case slog.KindGroup:
+		attrs := v.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		if a.Key == "" {
+			return append(fields, zap.Inline(groupMarshaler(attrs)))
+		}
+		return append(fields, zap.Object(a.Key, groupMarshaler(attrs)))
+	default:
+		if err, ok := v.Any().(error); ok {
+			return append(fields, zap.NamedError(a.Key, err))
+		}
+		return append(fields, zap.Any(a.Key, v.Any()))
+	}
+}
+
+type groupMarshaler []slog.Attr
+
+func (g groupMarshaler) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, a := range g {
+		for _, f := range appendAttr(nil, a) {
+			f.AddTo(enc)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+//go:build go1.21
+
+package lognslog
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/common"
+	lognzap "github.com/shanexu/logn/core/zap"
+	_ "github.com/shanexu/logn/includes"
+)
+
+func TestLevel(t *testing.T) {
+	assert.Equal(t, zapcore.DebugLevel, Level(slog.LevelDebug))
+	assert.Equal(t, zapcore.DebugLevel, Level(slog.LevelInfo-1))
+	assert.Equal(t, zapcore.InfoLevel, Level(slog.LevelInfo))
+	assert.Equal(t, zapcore.WarnLevel, Level(slog.LevelWarn+2))
+	assert.Equal(t, zapcore.ErrorLevel, Level(slog.LevelError+4))
+}
+
+func TestHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "app.log")
+
+	rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  file:
+    - name: FILE
+      file_name: %s
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+`, fileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := lognzap.New(rawConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewHandler(c.GetLogger("slog"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(h)
+	logger.Debug("dropped")
+	logger.With("a", 1).WithGroup("req").WithGroup("empty").Info("hello",
+		"path", "/", slog.Group("user", "id", 7), "err", errors.New("boom"))
+	logger.WithGroup("unused").Warn("no attrs")
+	c.Sync()
+
+	bs, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(bs)), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"level":"info"`)
+	assert.Contains(t, lines[0], `"logger":"slog","caller":"lognslog/handler_test.go:`)
+	assert.Contains(t, lines[0], `"msg":"hello"`)
+	assert.Contains(t, lines[0], `"a":1,"req":{"empty":{"path":"/","user":{"id":7},"err":"boom"}}}`)
+	assert.Contains(t, lines[1], `"msg":"no attrs"`)
+	assert.NotContains(t, lines[1], "unused")
+}
+
+func TestHandlerUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	config := func(name string) *common.Config {
+		rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  file:
+    - name: FILE
+      file_name: %s
+      encoder:
+        json:
+loggers:
+  root:
+    level: info
+    appender_refs:
+      - FILE
+`, filepath.Join(dir, name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rawConfig
+	}
+	c, err := lognzap.New(config("a.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := NewHandler(c.GetLogger("slog"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(h).With("k", "v")
+	logger.Info("before")
+	assert.Nil(t, c.Update(config("b.log")))
+	logger.Info("after")
+	c.Sync()
+
+	bs, _ := ioutil.ReadFile(filepath.Join(dir, "a.log"))
+	assert.Contains(t, string(bs), `"msg":"before"`)
+	assert.NotContains(t, string(bs), `"msg":"after"`)
+	bs, _ = ioutil.ReadFile(filepath.Join(dir, "b.log"))
+	assert.Contains(t, string(bs), `"msg":"after"`)
+	assert.Contains(t, string(bs), `"k":"v"`)
+}
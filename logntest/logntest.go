@@ -0,0 +1,264 @@
+// Package logntest records log entries in memory so that tests can assert on
+// logging behavior.
+//
+// Importing the package registers the memory appender type. Its appenders
+// keep every entry they are given and must use the json encoder with its
+// default keys:
+//
+//	appenders:
+//	  memory:
+//	    - name: MEM
+//	      encoder:
+//	        json:
+//
+// The recorder of an appender is obtained with Lookup. New builds a whole
+// Core logging to a fresh recorder.
+package logntest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/appender/writer"
+	"github.com/shanexu/logn/common"
+	"github.com/shanexu/logn/core"
+	lognzap "github.com/shanexu/logn/core/zap"
+
+	_ "github.com/shanexu/logn/appender/encoder/json"
+)
+
+var (
+	recordersMu sync.Mutex
+	recorders   = map[string]*Recorder{}
+
+	coreID uint64
+)
+
+func init() {
+	writer.RegisterType("memory", func(config *common.Config) (writer.Writer, error) {
+		name, err := config.Name()
+		if err != nil {
+			return nil, err
+		}
+		r := &Recorder{}
+		recordersMu.Lock()
+		recorders[name] = r
+		recordersMu.Unlock()
+		return r, nil
+	})
+}
+
+// Lookup returns the recorder of the memory appender with the given name
+// most recently created, or nil.
+func Lookup(name string) *Recorder {
+	recordersMu.Lock()
+	defer recordersMu.Unlock()
+	return recorders[name]
+}
+
+// New returns a Core whose loggers all write to the returned recorder, with
+// the root logger at the given level.
+func New(level string) (core.Core, *Recorder, error) {
+	name := fmt.Sprintf("logntest-%d", atomic.AddUint64(&coreID, 1))
+	rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  memory:
+    - name: %s
+      encoder:
+        json:
+loggers:
+  root:
+    level: %s
+    appender_refs:
+      - %s
+`, name, level, name))
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := lognzap.New(rawConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	recordersMu.Lock()
+	r := recorders[name]
+	delete(recorders, name)
+	recordersMu.Unlock()
+	return c, r, nil
+}
+
+// Entry is a recorded log entry.
+type Entry struct {
+	Level   core.Level
+	Logger  string
+	Caller  string
+	Message string
+	Stack   string
+	// Fields holds the fields of the entry as decoded from JSON, so numbers
+	// are float64 and objects map[string]interface{}.
+	Fields map[string]interface{}
+}
+
+// Entries is a list of recorded entries.
+type Entries []Entry
+
+// FilterLevel returns the entries at exactly level.
+func (es Entries) FilterLevel(level core.Level) Entries {
+	var filtered Entries
+	for _, e := range es {
+		if e.Level == level {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// FilterMessage returns the entries whose message contains substr.
+func (es Entries) FilterMessage(substr string) Entries {
+	var filtered Entries
+	for _, e := range es {
+		if strings.Contains(e.Message, substr) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// FilterField returns the entries carrying field with the same value.
+func (es Entries) FilterField(field core.Field) Entries {
+	key, value := decodeField(field)
+	var filtered Entries
+	for _, e := range es {
+		if v, ok := e.Fields[key]; ok && equal(v, value) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// Recorder is the writer of memory appenders.
+type Recorder struct {
+	mu    sync.Mutex
+	lines [][]byte
+}
+
+func (r *Recorder) Write(p []byte) (int, error) {
+	line := make([]byte, len(p))
+	copy(line, p)
+	r.mu.Lock()
+	r.lines = append(r.lines, line)
+	r.mu.Unlock()
+	return len(p), nil
+}
+
+func (r *Recorder) Sync() error {
+	return nil
+}
+
+// Len returns the number of recorded entries.
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.lines)
+}
+
+// Reset discards the recorded entries.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.lines = nil
+	r.mu.Unlock()
+}
+
+// Entries returns the recorded entries, oldest first.
+func (r *Recorder) Entries() Entries {
+	r.mu.Lock()
+	lines := r.lines
+	r.mu.Unlock()
+
+	es := make(Entries, 0, len(lines))
+	for _, line := range lines {
+		es = append(es, decodeEntry(line))
+	}
+	return es
+}
+
+// AssertLogged reports a test error unless an entry at level, whose message
+// contains msgSubstr and carrying fields, was recorded.
+func (r *Recorder) AssertLogged(t testing.TB, level core.Level, msgSubstr string, fields ...core.Field) bool {
+	t.Helper()
+	all := r.Entries()
+	es := all.FilterLevel(level).FilterMessage(msgSubstr)
+	for _, f := range fields {
+		es = es.FilterField(f)
+	}
+	if len(es) > 0 {
+		return true
+	}
+	var b strings.Builder
+	for _, e := range all {
+		fmt.Fprintf(&b, "\n\t%s %s %v", e.Level, e.Message, e.Fields)
+	}
+	t.Errorf("no %s entry with message containing %q and fields %v, recorded:%s", level, msgSubstr, fieldsMap(fields), b.String())
+	return false
+}
+
+func decodeEntry(line []byte) Entry {
+	var m map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(line))
+	if err := d.Decode(&m); err != nil {
+		return Entry{Message: string(bytes.TrimSpace(line)), Fields: map[string]interface{}{}}
+	}
+	e := Entry{Fields: m}
+	if s, ok := m["level"].(string); ok {
+		e.Level.UnmarshalText([]byte(s))
+	}
+	e.Logger, _ = m["logger"].(string)
+	e.Caller, _ = m["caller"].(string)
+	e.Message, _ = m["msg"].(string)
+	e.Stack, _ = m["stacktrace"].(string)
+	for _, k := range []string{"level", "ts", "logger", "caller", "msg", "stacktrace"} {
+		delete(m, k)
+	}
+	return e
+}
+
+// decodeField returns the key of field and its value as recorded entries
+// hold it.
+func decodeField(field core.Field) (string, interface{}) {
+	m := fieldsMap([]core.Field{field})
+	return field.Key, m[field.Key]
+}
+
+// fieldsMap encodes fields as the json encoder of memory appenders does.
+func fieldsMap(fields []core.Field) map[string]interface{} {
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+		EncodeTime:     zapcore.EpochTimeEncoder,
+		EncodeDuration: zapcore.SecondsDurationEncoder,
+	})
+	buf, err := enc.EncodeEntry(zapcore.Entry{}, fields)
+	if err != nil {
+		return nil
+	}
+	defer buf.Free()
+	var m map[string]interface{}
+	json.Unmarshal(buf.Bytes(), &m)
+	return m
+}
+
+func equal(a, b interface{}) bool {
+	ab, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	bb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(ab, bb)
+}
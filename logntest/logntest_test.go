@@ -0,0 +1,52 @@
+package logntest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/shanexu/logn/core"
+)
+
+func TestRecorder(t *testing.T) {
+	c, r, err := New("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := c.GetLogger("test")
+	l.Debug("hidden")
+	l.Infow("user logged in", "user", "bob", "attempts", 2)
+	l.Desugar().Warn("slow", zap.Duration("elapsed", 1500*time.Millisecond))
+	l.Errorw("failed", "error", errors.New("boom"))
+
+	assert.Equal(t, 3, r.Len())
+	es := r.Entries()
+	assert.Equal(t, "test", es[0].Logger)
+	assert.Equal(t, "user logged in", es[0].Message)
+	assert.Equal(t, "bob", es[0].Fields["user"])
+	assert.Len(t, es.FilterLevel(core.ErrorLevel), 1)
+	assert.Len(t, es.FilterMessage("log"), 1)
+
+	r.AssertLogged(t, core.InfoLevel, "logged in", zap.String("user", "bob"), zap.Int("attempts", 2))
+	r.AssertLogged(t, core.WarnLevel, "slow", zap.Duration("elapsed", 1500*time.Millisecond))
+	r.AssertLogged(t, core.ErrorLevel, "", zap.Error(errors.New("boom")))
+
+	ft := &testing.T{}
+	assert.False(t, r.AssertLogged(ft, core.InfoLevel, "logged in", zap.String("user", "alice")))
+	assert.True(t, ft.Failed())
+
+	r.Reset()
+	assert.Equal(t, 0, r.Len())
+}
+
+func TestLookup(t *testing.T) {
+	_, r, err := New("debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotNil(t, r)
+	assert.Nil(t, Lookup("missing"))
+}
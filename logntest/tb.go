@@ -0,0 +1,166 @@
+package logntest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanexu/logn/appender/writer"
+	"github.com/shanexu/logn/common"
+	"github.com/shanexu/logn/core"
+	lognzap "github.com/shanexu/logn/core/zap"
+
+	_ "github.com/shanexu/logn/appender/encoder/console"
+)
+
+// TBConfig is the configuration of testing appenders.
+type TBConfig struct {
+	// FailLevel is the level from which entries are written with t.Error,
+	// failing the test. Empty disables failing.
+	FailLevel string `logn-config:"fail_level"`
+}
+
+var (
+	tbWritersMu sync.Mutex
+	tbWriters   = map[string]*tbWriter{}
+)
+
+func init() {
+	writer.RegisterType("testing", func(config *common.Config) (writer.Writer, error) {
+		name, err := config.Name()
+		if err != nil {
+			return nil, err
+		}
+		tc := TBConfig{}
+		if err := config.Unpack(&tc); err != nil {
+			return nil, err
+		}
+		w := &tbWriter{}
+		if tc.FailLevel != "" {
+			if err := w.failLevel.UnmarshalText([]byte(tc.FailLevel)); err != nil {
+				return nil, err
+			}
+			w.fail = true
+		}
+		tbWritersMu.Lock()
+		tbWriters[name] = w
+		tbWritersMu.Unlock()
+		return w, nil
+	})
+}
+
+// Bind makes the testing appender with the given name most recently created
+// write through t, until t completes. Entries written while no test is bound
+// are dropped. Under go test, t.Log output is only shown for failing tests or
+// with -v.
+func Bind(name string, t testing.TB) error {
+	tbWritersMu.Lock()
+	w := tbWriters[name]
+	tbWritersMu.Unlock()
+	if w == nil {
+		return fmt.Errorf("no testing appender %q", name)
+	}
+	w.bind(t)
+	return nil
+}
+
+// NewTB returns a Core whose loggers all write through t, with the root
+// logger at the given level. Entries from failLevel on fail the test; an
+// empty failLevel never does.
+func NewTB(t testing.TB, level, failLevel string) (core.Core, error) {
+	name := fmt.Sprintf("logntest-%d", atomic.AddUint64(&coreID, 1))
+	rawConfig, err := common.NewConfigFrom(fmt.Sprintf(`
+appenders:
+  testing:
+    - name: %s
+      fail_level: %q
+      encoder:
+        console:
+          time_encoder: ISO8601
+loggers:
+  root:
+    level: %s
+    appender_refs:
+      - %s
+`, name, failLevel, level, name))
+	if err != nil {
+		return nil, err
+	}
+	c, err := lognzap.New(rawConfig)
+	if err != nil {
+		return nil, err
+	}
+	tbWritersMu.Lock()
+	w := tbWriters[name]
+	delete(tbWriters, name)
+	tbWritersMu.Unlock()
+	w.bind(t)
+	return c, nil
+}
+
+type tbWriter struct {
+	mu        sync.Mutex
+	t         testing.TB
+	fail      bool
+	failLevel zapcore.Level
+}
+
+func (w *tbWriter) bind(t testing.TB) {
+	w.mu.Lock()
+	w.t = t
+	w.mu.Unlock()
+	// logging through t once it completed panics
+	t.Cleanup(func() {
+		w.mu.Lock()
+		if w.t == t {
+			w.t = nil
+		}
+		w.mu.Unlock()
+	})
+}
+
+func (w *tbWriter) Write(p []byte) (int, error) {
+	line := string(bytes.TrimRight(p, "\n"))
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.t == nil {
+		return len(p), nil
+	}
+	if lvl, ok := lineLevel(p); ok && w.fail && lvl >= w.failLevel {
+		w.t.Error(line)
+	} else {
+		w.t.Log(line)
+	}
+	return len(p), nil
+}
+
+func (w *tbWriter) Sync() error {
+	return nil
+}
+
+// lineLevel finds the level of an entry encoded by the json or the console
+// encoder.
+func lineLevel(line []byte) (zapcore.Level, bool) {
+	var lvl zapcore.Level
+	if bytes.HasPrefix(line, []byte("{")) {
+		var e struct {
+			Level string `json:"level"`
+		}
+		if err := json.Unmarshal(line, &e); err != nil {
+			return lvl, false
+		}
+		return lvl, lvl.UnmarshalText([]byte(e.Level)) == nil
+	}
+	// the level is among the leading tab-separated columns of console lines
+	for i, col := range bytes.SplitN(line, []byte("\t"), 4) {
+		if i < 3 && lvl.UnmarshalText(col) == nil {
+			return lvl, true
+		}
+	}
+	return lvl, false
+}
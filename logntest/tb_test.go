@@ -0,0 +1,59 @@
+package logntest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTB records what is logged through it.
+type fakeTB struct {
+	testing.TB
+	logs     []string
+	errors   []string
+	cleanups []func()
+}
+
+func (t *fakeTB) Log(args ...interface{})   { t.logs = append(t.logs, fmt.Sprint(args...)) }
+func (t *fakeTB) Error(args ...interface{}) { t.errors = append(t.errors, fmt.Sprint(args...)) }
+func (t *fakeTB) Cleanup(f func())          { t.cleanups = append(t.cleanups, f) }
+
+func TestNewTB(t *testing.T) {
+	tb := &fakeTB{}
+	c, err := NewTB(tb, "info", "error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := c.GetLogger("tb")
+	l.Debug("hidden")
+	l.Infow("hello", "n", 1)
+	l.Error("broken")
+
+	if assert.Len(t, tb.logs, 1) {
+		assert.True(t, strings.Contains(tb.logs[0], "info\ttb\t"))
+		assert.Contains(t, tb.logs[0], "\thello\t")
+		assert.True(t, strings.HasSuffix(tb.logs[0], `"n": 1}`))
+	}
+	if assert.Len(t, tb.errors, 1) {
+		assert.Contains(t, tb.errors[0], "broken")
+	}
+
+	for _, f := range tb.cleanups {
+		f()
+	}
+	l.Info("after the test")
+	assert.Len(t, tb.logs, 1)
+}
+
+func TestLineLevel(t *testing.T) {
+	lvl, ok := lineLevel([]byte(`{"level":"warn","msg":"m"}`))
+	assert.True(t, ok)
+	assert.Equal(t, "warn", lvl.String())
+	lvl, ok = lineLevel([]byte("2020-01-01T00:00:00.000Z\terror\tm\n"))
+	assert.True(t, ok)
+	assert.Equal(t, "error", lvl.String())
+	_, ok = lineLevel([]byte("plain text"))
+	assert.False(t, ok)
+}
@@ -0,0 +1,102 @@
+// Package marker tags log entries with named markers, e.g. SECURITY or
+// BILLING, which appenders and hooks can match on independently of the
+// level and logger name. Markers are attached with a field, at the call site
+// or through With, and are encoded as a "markers" array.
+package marker
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Key is the key of marker fields.
+const Key = "markers"
+
+type set []string
+
+func (s set) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, m := range s {
+		enc.AppendString(m)
+	}
+	return nil
+}
+
+// Field returns a field marking entries with names.
+func Field(names ...string) zapcore.Field {
+	return zap.Array(Key, set(names))
+}
+
+// Of returns the markers carried by fields.
+func Of(fields []zapcore.Field) []string {
+	var names []string
+	for _, f := range fields {
+		if s, ok := f.Interface.(set); ok && f.Type == zapcore.ArrayMarshalerType {
+			names = append(names, s...)
+		}
+	}
+	return names
+}
+
+// Has reports whether fields carry one of names.
+func Has(fields []zapcore.Field, names ...string) bool {
+	return matches(Of(fields), names)
+}
+
+func matches(markers, names []string) bool {
+	for _, m := range markers {
+		for _, n := range names {
+			if m == n {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type filterCore struct {
+	zapcore.Core
+	allow   []string
+	deny    []string
+	markers []string
+}
+
+// NewCore wraps core so that it only writes entries carrying one of allow,
+// if allow is not empty, and none of deny.
+func NewCore(core zapcore.Core, allow, deny []string) zapcore.Core {
+	if len(allow) == 0 && len(deny) == 0 {
+		return core
+	}
+	return &filterCore{Core: core, allow: allow, deny: deny}
+}
+
+func (c *filterCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.Core = c.Core.With(fields)
+	if ms := Of(fields); len(ms) > 0 {
+		clone.markers = make([]string, 0, len(c.markers)+len(ms))
+		clone.markers = append(clone.markers, c.markers...)
+		clone.markers = append(clone.markers, ms...)
+	}
+	return &clone
+}
+
+func (c *filterCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *filterCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	markers := Of(fields)
+	if len(c.markers) > 0 {
+		markers = append(markers, c.markers...)
+	}
+	if len(c.allow) > 0 && !matches(markers, c.allow) {
+		return nil
+	}
+	if matches(markers, c.deny) {
+		return nil
+	}
+	return c.Core.Write(ent, fields)
+}
@@ -0,0 +1,33 @@
+package marker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestNewCore(t *testing.T) {
+	oc, logs := observer.New(zapcore.InfoLevel)
+
+	logger := zap.New(NewCore(oc, []string{"SECURITY", "BILLING"}, []string{"NOISY"}))
+	logger.Info("unmarked")
+	logger.Info("login failed", Field("SECURITY"))
+	logger.Info("charged", Field("BILLING", "NOISY"))
+	logger.With(Field("BILLING")).Info("refunded", zap.Int("amount", 3))
+
+	entries := logs.AllUntimed()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "login failed", entries[0].Message)
+	assert.Equal(t, []interface{}{"SECURITY"}, entries[0].ContextMap()[Key])
+	assert.Equal(t, "refunded", entries[1].Message)
+}
+
+func TestOf(t *testing.T) {
+	fields := []zapcore.Field{Field("A"), zap.Strings(Key, []string{"B"}), Field("C", "D")}
+	assert.Equal(t, []string{"A", "C", "D"}, Of(fields))
+	assert.True(t, Has(fields, "D"))
+	assert.False(t, Has(fields, "B"))
+}
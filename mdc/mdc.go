@@ -0,0 +1,140 @@
+// Package mdc provides a mapped diagnostic context: key-value pairs bound to a
+// context.Context which loggers attach to every entry made through
+// Logger.WithContext.
+package mdc
+
+import (
+	"context"
+	"sync"
+)
+
+type contextKey struct{}
+
+// Scope is a set of key-value pairs flowing into log entries. Scopes nest: a
+// child sees the pairs of its parent and may override them. Scopes are safe
+// for concurrent use, so pairs put deep in a call stack (e.g. a user id known
+// only after authentication) are visible to every logger sharing the scope.
+type Scope struct {
+	parent *Scope
+
+	mu     sync.RWMutex
+	keys   []string
+	values map[string]interface{}
+}
+
+// NewScope creates a root scope holding the given key-value pairs.
+func NewScope(keysAndValues ...interface{}) *Scope {
+	return newScope(nil, keysAndValues)
+}
+
+func newScope(parent *Scope, keysAndValues []interface{}) *Scope {
+	s := &Scope{
+		parent: parent,
+		values: map[string]interface{}{},
+	}
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			continue
+		}
+		s.Put(key, keysAndValues[i+1])
+	}
+	return s
+}
+
+// Put sets key to value in this scope.
+func (s *Scope) Put(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.values[key]; !exists {
+		s.keys = append(s.keys, key)
+	}
+	s.values[key] = value
+}
+
+// Remove deletes key from this scope. Pairs inherited from a parent scope are
+// left untouched.
+func (s *Scope) Remove(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.values[key]; !exists {
+		return
+	}
+	delete(s.values, key)
+	for i, k := range s.keys {
+		if k == key {
+			s.keys = append(s.keys[:i], s.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Get returns the value of key, looking up parent scopes if this scope does
+// not hold it.
+func (s *Scope) Get(key string) (interface{}, bool) {
+	for sc := s; sc != nil; sc = sc.parent {
+		sc.mu.RLock()
+		v, ok := sc.values[key]
+		sc.mu.RUnlock()
+		if ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// KeysAndValues flattens the scope and its parents into alternating keys and
+// values, outermost scope first. Overridden keys keep their first position.
+func (s *Scope) KeysAndValues() []interface{} {
+	var chain []*Scope
+	for sc := s; sc != nil; sc = sc.parent {
+		chain = append(chain, sc)
+	}
+
+	var kvs []interface{}
+	index := map[string]int{}
+	for i := len(chain) - 1; i >= 0; i-- {
+		sc := chain[i]
+		sc.mu.RLock()
+		for _, k := range sc.keys {
+			v := sc.values[k]
+			if j, ok := index[k]; ok {
+				kvs[j+1] = v
+				continue
+			}
+			index[k] = len(kvs)
+			kvs = append(kvs, k, v)
+		}
+		sc.mu.RUnlock()
+	}
+	return kvs
+}
+
+// NewContext returns a copy of ctx carrying the scope.
+func NewContext(ctx context.Context, s *Scope) context.Context {
+	return context.WithValue(ctx, contextKey{}, s)
+}
+
+// FromContext returns the scope carried by ctx, or nil.
+func FromContext(ctx context.Context) *Scope {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(contextKey{}).(*Scope)
+	return s
+}
+
+// With returns a copy of ctx carrying a child of its current scope holding
+// the given key-value pairs.
+func With(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	return NewContext(ctx, newScope(FromContext(ctx), keysAndValues))
+}
+
+// KeysAndValues returns the flattened pairs of the scope carried by ctx.
+func KeysAndValues(ctx context.Context) []interface{} {
+	s := FromContext(ctx)
+	if s == nil {
+		return nil
+	}
+	return s.KeysAndValues()
+}
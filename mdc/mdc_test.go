@@ -0,0 +1,33 @@
+package mdc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWith(t *testing.T) {
+	ctx := With(context.Background(), "request_id", "r1", "tenant", "acme")
+	ctx = With(ctx, "tenant", "globex", "user_id", 42)
+
+	assert.Equal(t, []interface{}{"request_id", "r1", "tenant", "globex", "user_id", 42}, KeysAndValues(ctx))
+	assert.Nil(t, KeysAndValues(context.Background()))
+}
+
+func TestScope(t *testing.T) {
+	s := NewScope("request_id", "r1")
+	ctx := NewContext(context.Background(), s)
+	child := With(ctx, "span", "s1")
+
+	// pairs put on the parent after the child was derived are still visible
+	s.Put("user_id", 7)
+	assert.Equal(t, []interface{}{"request_id", "r1", "user_id", 7, "span", "s1"}, KeysAndValues(child))
+
+	s.Remove("request_id")
+	v, ok := FromContext(child).Get("user_id")
+	assert.True(t, ok)
+	assert.Equal(t, 7, v)
+	_, ok = FromContext(child).Get("request_id")
+	assert.False(t, ok)
+}
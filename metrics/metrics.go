@@ -0,0 +1,146 @@
+// Package metrics reports logging activity, such as entries written, entries
+// dropped and appender writes, to a process-wide Recorder. Module
+// lognprometheus provides a Recorder exporting Prometheus metrics.
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Reasons passed to Recorder.Dropped.
+const (
+	DropSampling  = "sampling"
+	DropRateLimit = "rate_limit"
+	DropDedup     = "dedup"
+	// DropLoadShedding is the reason of the entries async appenders shed.
+	DropLoadShedding = "load_shedding"
+)
+
+// Outcomes passed to Recorder.QueueFull, one per backpressure policy of
+// asynchronous appenders.
+const (
+	QueueBlocked       = "block"
+	QueueDroppedNewest = "drop_newest"
+	QueueDroppedOldest = "drop_oldest"
+	QueueSpilled       = "spill"
+)
+
+// Recorder receives logging activity. Its methods are called on the logging
+// path and must be cheap and safe for concurrent use.
+type Recorder interface {
+	// Entry is called for every entry a logger writes to its appenders.
+	Entry(logger string, level zapcore.Level)
+	// Dropped is called for every entry a logger discards, with the reason.
+	Dropped(logger string, level zapcore.Level, reason string)
+	// AppenderWrite is called after every write of an entry by an appender,
+	// with the duration of its encoding and writing, and its error, if any.
+	AppenderWrite(appender string, elapsed time.Duration, err error)
+	// AppenderBytes is called after every successful write of an entry by an
+	// appender, with the size of the encoded entry.
+	AppenderBytes(appender string, bytes int)
+	// AppenderEncodeError is called for every entry an appender failed to
+	// encode, which it does not write.
+	AppenderEncodeError(appender string)
+	// QueueDepth reports the number of entries queued by an asynchronous
+	// appender.
+	QueueDepth(appender string, depth int)
+	// QueueCapacity reports the number of entries an asynchronous appender
+	// can queue, so that its occupancy is QueueDepth over QueueCapacity.
+	QueueCapacity(appender string, capacity int)
+	// QueueFull is called for every entry arriving while the queue of an
+	// asynchronous appender is full, with what happened to it.
+	QueueFull(appender string, outcome string)
+}
+
+type holder struct {
+	r Recorder
+}
+
+var (
+	recorder atomic.Value
+	enabled  int32
+)
+
+func init() {
+	recorder.Store(holder{})
+}
+
+// SetRecorder replaces the process-wide recorder. A nil recorder disables
+// reporting.
+func SetRecorder(r Recorder) {
+	recorder.Store(holder{r})
+	if r != nil {
+		atomic.StoreInt32(&enabled, 1)
+	} else {
+		atomic.StoreInt32(&enabled, 0)
+	}
+}
+
+// Enabled reports whether a recorder is set, letting callers skip the cost
+// of measuring what would not be reported.
+func Enabled() bool {
+	return atomic.LoadInt32(&enabled) == 1
+}
+
+func current() Recorder {
+	return recorder.Load().(holder).r
+}
+
+// Entry reports an entry written by logger.
+func Entry(logger string, level zapcore.Level) {
+	if r := current(); r != nil {
+		r.Entry(logger, level)
+	}
+}
+
+// Dropped reports an entry discarded by logger.
+func Dropped(logger string, level zapcore.Level, reason string) {
+	if r := current(); r != nil {
+		r.Dropped(logger, level, reason)
+	}
+}
+
+// AppenderWrite reports a write of appender.
+func AppenderWrite(appender string, elapsed time.Duration, err error) {
+	if r := current(); r != nil {
+		r.AppenderWrite(appender, elapsed, err)
+	}
+}
+
+// AppenderBytes reports the size of an entry written by appender.
+func AppenderBytes(appender string, bytes int) {
+	if r := current(); r != nil {
+		r.AppenderBytes(appender, bytes)
+	}
+}
+
+// AppenderEncodeError reports an entry appender failed to encode.
+func AppenderEncodeError(appender string) {
+	if r := current(); r != nil {
+		r.AppenderEncodeError(appender)
+	}
+}
+
+// QueueDepth reports the queue depth of appender.
+func QueueDepth(appender string, depth int) {
+	if r := current(); r != nil {
+		r.QueueDepth(appender, depth)
+	}
+}
+
+// QueueCapacity reports the queue capacity of appender.
+func QueueCapacity(appender string, capacity int) {
+	if r := current(); r != nil {
+		r.QueueCapacity(appender, capacity)
+	}
+}
+
+// QueueFull reports an entry arriving while the queue of appender is full.
+func QueueFull(appender string, outcome string) {
+	if r := current(); r != nil {
+		r.QueueFull(appender, outcome)
+	}
+}
@@ -0,0 +1,37 @@
+package logn
+
+import (
+	"fmt"
+
+	"github.com/shanexu/logn/common"
+	"github.com/shanexu/logn/core"
+)
+
+// NewNamespace creates a Core from rawConfig, independent from the global
+// one and from other namespaces, and registers it under namespace, e.g. to
+// give each tenant or embedded component its own configuration.
+func NewNamespace(namespace string, rawConfig *common.Config) (core.Core, error) {
+	c, err := ConfigWithRawConfig(rawConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := core.RegisterNamespace(namespace, c); err != nil {
+		c.Sync()
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetNamespace returns the Core registered under namespace, or nil.
+func GetNamespace(namespace string) core.Core {
+	return core.Namespace(namespace)
+}
+
+// CloseNamespace unregisters the Core of namespace and flushes it.
+func CloseNamespace(namespace string) error {
+	c := core.UnregisterNamespace(namespace)
+	if c == nil {
+		return fmt.Errorf("namespace %q is not registered", namespace)
+	}
+	return c.Sync()
+}
@@ -0,0 +1,48 @@
+package logn
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanexu/logn/common"
+)
+
+func TestNamespace(t *testing.T) {
+	newConfig := func(level string) *common.Config {
+		rawConfig, err := common.NewConfigFrom(`
+appenders:
+  console:
+    - name: CONSOLE
+      target: stdout
+      encoder:
+        console:
+loggers:
+  root:
+    level: ` + level + `
+    appender_refs:
+      - CONSOLE
+`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rawConfig
+	}
+
+	a, err := NewNamespace("tenant-a", newConfig("debug"))
+	assert.Nil(t, err)
+	b, err := NewNamespace("tenant-b", newConfig("error"))
+	assert.Nil(t, err)
+	_, err = NewNamespace("tenant-a", newConfig("info"))
+	assert.NotNil(t, err)
+
+	assert.Equal(t, a, GetNamespace("tenant-a"))
+	assert.True(t, GetNamespace("tenant-a").GetLogger("http").IsDebug())
+	assert.False(t, GetNamespace("tenant-b").GetLogger("http").Enabled(InfoLevel))
+	assert.NotEqual(t, a.GetLogger("http"), b.GetLogger("http"))
+
+	assert.Nil(t, CloseNamespace("tenant-a"))
+	assert.Nil(t, GetNamespace("tenant-a"))
+	assert.NotNil(t, CloseNamespace("tenant-a"))
+	assert.Nil(t, CloseNamespace("tenant-b"))
+}
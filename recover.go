@@ -0,0 +1,113 @@
+package logn
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/shanexu/logn/core"
+)
+
+// RecoverOption configures RecoverAndLog.
+type RecoverOption func(*recoverOptions)
+
+type recoverOptions struct {
+	level   Level
+	repanic bool
+	ctx     context.Context
+}
+
+// RecoverLevel sets the level of the entries of recovered panics, ErrorLevel
+// by default. At FatalLevel the process exits once the entry is written.
+func RecoverLevel(level Level) RecoverOption {
+	return func(o *recoverOptions) {
+		o.level = level
+	}
+}
+
+// Repanic makes RecoverAndLog panic again with the recovered value once it is
+// logged, e.g. to let the process crash after all.
+func Repanic() RecoverOption {
+	return func(o *recoverOptions) {
+		o.repanic = true
+	}
+}
+
+// RecoverContext adds the diagnostic context bound to ctx, e.g. the id of the
+// request being served, to the entries of recovered panics.
+func RecoverContext(ctx context.Context) RecoverOption {
+	return func(o *recoverOptions) {
+		o.ctx = ctx
+	}
+}
+
+// RecoverAndLog recovers a panic and logs it to logger, with the panic value
+// as the panic field and the stack of the panicking goroutine as the stack
+// field, the caller of the entry being where the panic happened. It must be
+// deferred itself, recover having no effect otherwise:
+//
+//	defer logn.RecoverAndLog(logger, logn.RecoverContext(ctx))
+func RecoverAndLog(logger core.Logger, opts ...RecoverOption) {
+	if v := recover(); v != nil {
+		logRecovered(logger, v, opts)
+	}
+}
+
+func logRecovered(logger core.Logger, v interface{}, opts []RecoverOption) {
+	o := recoverOptions{level: ErrorLevel}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	l := logger.Desugar()
+	if o.ctx != nil {
+		l = l.WithContext(o.ctx)
+	}
+	skip := panicSkip()
+	l = l.WithCallerSkip(skip)
+	msg := fmt.Sprintf("panic recovered: %v", v)
+	fields := []Field{zap.Any("panic", v), zap.StackSkip("stack", skip)}
+	switch o.level {
+	case DebugLevel:
+		l.Debug(msg, fields...)
+	case InfoLevel:
+		l.Info(msg, fields...)
+	case WarnLevel:
+		l.Warn(msg, fields...)
+	case DPanicLevel:
+		l.DPanic(msg, fields...)
+	case PanicLevel:
+		l.Panic(msg, fields...)
+	case FatalLevel:
+		l.Fatal(msg, fields...)
+	default:
+		l.Error(msg, fields...)
+	}
+	if o.repanic {
+		panic(v)
+	}
+}
+
+// panicSkip returns how many frames above logRecovered is the function which
+// panicked, the first one out of the runtime below runtime.gopanic.
+func panicSkip() int {
+	pcs := make([]uintptr, 64)
+	// from the caller of logRecovered on
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	panicking := false
+	for i := 0; ; i++ {
+		f, more := frames.Next()
+		switch {
+		case f.Function == "runtime.gopanic":
+			panicking = true
+		case panicking && !strings.HasPrefix(f.Function, "runtime."):
+			return i + 1
+		}
+		if !more {
+			return 0
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package logn_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanexu/logn"
+	"github.com/shanexu/logn/logntest"
+	"github.com/shanexu/logn/mdc"
+)
+
+func TestRecoverAndLog(t *testing.T) {
+	c, rec, err := logntest.New("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := mdc.With(context.Background(), "request_id", "r1")
+	func() {
+		defer logn.RecoverAndLog(c, logn.RecoverContext(ctx))
+		panic("boom")
+	}()
+	func() {
+		defer logn.RecoverAndLog(c, logn.RecoverLevel(logn.WarnLevel))
+		var m map[string]int
+		m["nil"]++
+	}()
+	assert.Panics(t, func() {
+		defer logn.RecoverAndLog(c, logn.Repanic())
+		panic("again")
+	})
+	func() {
+		defer logn.RecoverAndLog(c)
+	}()
+
+	entries := rec.Entries()
+	if !assert.Len(t, entries, 3) {
+		return
+	}
+	boom := entries[0]
+	assert.Equal(t, logn.ErrorLevel, boom.Level)
+	assert.Equal(t, "panic recovered: boom", boom.Message)
+	assert.Equal(t, "boom", boom.Fields["panic"])
+	assert.Equal(t, "r1", boom.Fields["request_id"])
+	assert.Contains(t, boom.Caller, "recover_test.go:22")
+	assert.Contains(t, boom.Fields["stack"], "logn_test.TestRecoverAndLog")
+	assert.NotContains(t, boom.Fields["stack"], "logn.RecoverAndLog")
+
+	assert.Equal(t, logn.WarnLevel, entries[1].Level)
+	assert.Contains(t, entries[1].Caller, "recover_test.go:27")
+	assert.Contains(t, entries[1].Message, "assignment to entry in nil map")
+}
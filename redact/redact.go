@@ -0,0 +1,253 @@
+// Package redact scrubs personal data out of log entries before they are
+// encoded. Built-in detectors recognize e-mail addresses, credit card numbers,
+// IPv4 addresses and bearer tokens; custom rules add regular expressions.
+// Redaction is enabled per appender, so that e.g. only the sinks shipped to a
+// third party are scrubbed.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultReplacement replaces the detected data unless configured otherwise.
+const DefaultReplacement = "[REDACTED]"
+
+// Config is the redact section of an appender.
+type Config struct {
+	// Detectors lists the built-in detectors to apply: email, credit_card,
+	// ipv4 and bearer_token.
+	Detectors []string `logn-config:"detectors"`
+	// Rules are custom detectors.
+	Rules []Rule `logn-config:"rules"`
+	// Replacement replaces the detected data, DefaultReplacement by default.
+	Replacement string `logn-config:"replacement"`
+}
+
+// Rule replaces the matches of Pattern by Replacement, which may refer to
+// submatches as $1, or by the replacement of the Config if empty.
+type Rule struct {
+	Pattern     string `logn-config:"pattern" logn-validate:"required"`
+	Replacement string `logn-config:"replacement"`
+}
+
+type detector struct {
+	re *regexp.Regexp
+	// replace returns the replacement of a match given the configured one
+	replace func(match, replacement string) string
+}
+
+var bearerToken = regexp.MustCompile(`(?i)\b(bearer\s+)[A-Za-z0-9\-._~+/]+=*`)
+
+var detectors = map[string]detector{
+	"email": {
+		re: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	},
+	"credit_card": {
+		re: regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`),
+		replace: func(match, replacement string) string {
+			if !luhn(match) {
+				return match
+			}
+			return replacement
+		},
+	},
+	"ipv4": {
+		re: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`),
+	},
+	"bearer_token": {
+		re: bearerToken,
+		replace: func(match, replacement string) string {
+			// keep the scheme, replacing the token only
+			return bearerToken.FindStringSubmatch(match)[1] + replacement
+		},
+	},
+}
+
+// luhn reports whether the digits of s pass the Luhn checksum of card numbers.
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return sum%10 == 0
+}
+
+// Redactor scrubs strings and fields.
+type Redactor struct {
+	detectors   []detector
+	replacement string
+}
+
+// New builds the Redactor described by config.
+func New(config Config) (*Redactor, error) {
+	r := &Redactor{replacement: config.Replacement}
+	if r.replacement == "" {
+		r.replacement = DefaultReplacement
+	}
+	for _, name := range config.Detectors {
+		d, ok := detectors[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown redact detector %q", name)
+		}
+		r.detectors = append(r.detectors, d)
+	}
+	for _, rule := range config.Rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		d := detector{re: re}
+		if rule.Replacement != "" {
+			tmpl := rule.Replacement
+			d.replace = func(match, _ string) string {
+				return re.ReplaceAllString(match, tmpl)
+			}
+		}
+		r.detectors = append(r.detectors, d)
+	}
+	return r, nil
+}
+
+// String returns s with the detected data replaced.
+func (r *Redactor) String(s string) string {
+	for _, d := range r.detectors {
+		if !d.re.MatchString(s) {
+			continue
+		}
+		if d.replace == nil {
+			s = d.re.ReplaceAllLiteralString(s, r.replacement)
+			continue
+		}
+		s = d.re.ReplaceAllStringFunc(s, func(match string) string {
+			return d.replace(match, r.replacement)
+		})
+	}
+	return s
+}
+
+// Fields returns fields with the detected data replaced in string values,
+// including those of errors, Stringers, objects and arrays. Fields holding
+// nothing to redact are returned as is; the slice passed in is not modified.
+func (r *Redactor) Fields(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		rf, changed := r.field(f)
+		if !changed {
+			if out != nil {
+				out = append(out, f)
+			}
+			continue
+		}
+		if out == nil {
+			out = make([]zapcore.Field, i, len(fields))
+			copy(out, fields[:i])
+		}
+		out = append(out, rf)
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+func (r *Redactor) field(f zapcore.Field) (zapcore.Field, bool) {
+	switch f.Type {
+	case zapcore.StringType:
+		s := r.String(f.String)
+		return zap.String(f.Key, s), s != f.String
+	case zapcore.ByteStringType:
+		b := string(f.Interface.([]byte))
+		s := r.String(b)
+		return zap.String(f.Key, s), s != b
+	case zapcore.ErrorType, zapcore.StringerType, zapcore.ReflectType,
+		zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType:
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		v, ok := enc.Fields[f.Key]
+		if !ok {
+			return f, false
+		}
+		rv, changed := r.value(v)
+		if !changed {
+			return f, false
+		}
+		if s, ok := rv.(string); ok {
+			return zap.String(f.Key, s), true
+		}
+		return zap.Any(f.Key, rv), true
+	}
+	return f, false
+}
+
+// value redacts the strings in v, as encoded by a MapObjectEncoder.
+func (r *Redactor) value(v interface{}) (interface{}, bool) {
+	switch t := v.(type) {
+	case string:
+		s := r.String(t)
+		return s, s != t
+	case map[string]interface{}:
+		changed := false
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			var c bool
+			m[k], c = r.value(e)
+			changed = changed || c
+		}
+		return m, changed
+	case []interface{}:
+		changed := false
+		a := make([]interface{}, len(t))
+		for i, e := range t {
+			var c bool
+			a[i], c = r.value(e)
+			changed = changed || c
+		}
+		return a, changed
+	}
+	return v, false
+}
+
+type redactCore struct {
+	zapcore.Core
+	redactor *Redactor
+}
+
+// NewCore wraps core so that messages and fields are redacted before being
+// written. A nil r leaves core as is.
+func NewCore(core zapcore.Core, r *Redactor) zapcore.Core {
+	if r == nil {
+		return core
+	}
+	return &redactCore{Core: core, redactor: r}
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(c.redactor.Fields(fields)), redactor: c.redactor}
+}
+
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = c.redactor.String(ent.Message)
+	return c.Core.Write(ent, c.redactor.Fields(fields))
+}
@@ -0,0 +1,58 @@
+package redact
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestString(t *testing.T) {
+	r, err := New(Config{
+		Detectors: []string{"email", "credit_card", "ipv4", "bearer_token"},
+		Rules:     []Rule{{Pattern: `ssn=(\d{3})-\d{2}-\d{4}`, Replacement: "ssn=$1-**-****"}},
+	})
+	assert.Nil(t, err)
+
+	for in, want := range map[string]string{
+		"mail shane@example.com now":       "mail [REDACTED] now",
+		"card 4111 1111 1111 1111 charged": "card [REDACTED] charged",
+		"order 1234567890123 shipped":      "order 1234567890123 shipped",
+		"from 192.168.1.20:8080":           "from [REDACTED]:8080",
+		"Authorization: Bearer abc.def-1=": "Authorization: Bearer [REDACTED]",
+		"ssn=123-45-6789":                  "ssn=123-**-****",
+		"nothing to see":                   "nothing to see",
+	} {
+		assert.Equal(t, want, r.String(in), in)
+	}
+
+	_, err = New(Config{Detectors: []string{"phone"}})
+	assert.NotNil(t, err)
+}
+
+func TestNewCore(t *testing.T) {
+	r, err := New(Config{Detectors: []string{"email"}, Replacement: "***"})
+	assert.Nil(t, err)
+
+	oc, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(NewCore(oc, r)).With(zap.String("user", "shane@example.com"))
+	logger.Info("mail sent to bob@example.com",
+		zap.Error(errors.New("bounced: bob@example.com")),
+		zap.Strings("cc", []string{"eve@example.com", "ops"}),
+		zap.Int("attempts", 2),
+	)
+
+	entries := logs.AllUntimed()
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "mail sent to ***", entries[0].Message)
+		assert.Equal(t, map[string]interface{}{
+			"user":     "***",
+			"error":    "bounced: ***",
+			"cc":       []interface{}{"***", "ops"},
+			"attempts": int64(2),
+		}, entries[0].ContextMap())
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package logn
+
+import "os"
+
+// defaultReopenSignals is empty where there is no SIGUSR2, ReopenOn needing
+// signals to be given there.
+var defaultReopenSignals []os.Signal
@@ -0,0 +1,13 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package logn
+
+import (
+	"os"
+	"syscall"
+)
+
+// defaultReopenSignals are the signals ReopenOn handles without any given,
+// the one logrotate configurations send with postrotate kill -USR2.
+var defaultReopenSignals = []os.Signal{syscall.SIGUSR2}
@@ -0,0 +1,104 @@
+// Package reqlog hands out request-scoped loggers: child loggers bound to the
+// request and trace ids of the request being served, created once when the
+// request starts and carried by its context for handlers to retrieve. The
+// lognhttp, logngin, lognecho and logngrpc middlewares start requests with
+// it.
+package reqlog
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+
+	"github.com/shanexu/logn"
+	"github.com/shanexu/logn/core"
+	"github.com/shanexu/logn/mdc"
+)
+
+const (
+	// RequestIDKey is the mdc key of the request id.
+	RequestIDKey = "request_id"
+	// TraceIDKey is the mdc key of the trace id.
+	TraceIDKey = "trace_id"
+)
+
+type loggerKey struct{}
+
+// Start returns a copy of ctx for serving a request. Its mdc holds the
+// request id, minted with NewID if empty, the trace id unless empty and the
+// given key-value pairs, and it carries l bound to that mdc as the
+// request-scoped logger returned by Logger.
+//
+// The request-scoped logger is built once: pairs put into the mdc later, e.g.
+// a user id known after authentication, are only seen by loggers obtained
+// through WithContext.
+func Start(ctx context.Context, l core.Logger, requestID, traceID string, keysAndValues ...interface{}) context.Context {
+	if requestID == "" {
+		requestID = NewID()
+	}
+	kvs := make([]interface{}, 0, 4+len(keysAndValues))
+	kvs = append(kvs, RequestIDKey, requestID)
+	if traceID != "" {
+		kvs = append(kvs, TraceIDKey, traceID)
+	}
+	kvs = append(kvs, keysAndValues...)
+	ctx = mdc.With(ctx, kvs...)
+	return NewContext(ctx, l.WithContext(ctx))
+}
+
+// Force returns a copy of ctx whose request-scoped logger writes the entries
+// from level on, whatever the configured levels, e.g. to debug a single
+// request in production.
+func Force(ctx context.Context, level core.Level) context.Context {
+	return NewContext(ctx, Logger(ctx).WithLevel(level))
+}
+
+// NewContext returns a copy of ctx carrying l as the request-scoped logger.
+func NewContext(ctx context.Context, l core.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// Logger returns the request-scoped logger carried by ctx, or the global logn
+// logger with the mdc of ctx if there is none.
+func Logger(ctx context.Context) core.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(core.Logger); ok {
+		return l
+	}
+	return logn.GetLogger().WithContext(ctx)
+}
+
+// RequestID returns the request id in the mdc of ctx.
+func RequestID(ctx context.Context) string {
+	return mdcString(ctx, RequestIDKey)
+}
+
+// TraceID returns the trace id in the mdc of ctx.
+func TraceID(ctx context.Context) string {
+	return mdcString(ctx, TraceIDKey)
+}
+
+func mdcString(ctx context.Context, key string) string {
+	s := mdc.FromContext(ctx)
+	if s == nil {
+		return ""
+	}
+	v, _ := s.Get(key)
+	str, _ := v.(string)
+	return str
+}
+
+// MatchToken reports whether token, e.g. taken from a request header, equals
+// secret, in constant time. An empty secret matches nothing.
+func MatchToken(token, secret string) bool {
+	return secret != "" && subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+}
+
+// NewID returns a random 128-bit request id in hex.
+func NewID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,73 @@
+package reqlog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanexu/logn"
+	"github.com/shanexu/logn/logntest"
+)
+
+func TestStart(t *testing.T) {
+	c, rec, err := logntest.New("debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := Start(context.Background(), c.GetLogger("http"), "", "4bf92f3577b34da6a3ce929d0e0e4736", "tenant", "acme")
+	id := RequestID(ctx)
+	assert.Len(t, id, 32)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", TraceID(ctx))
+
+	l := Logger(ctx)
+	assert.True(t, l == Logger(ctx), "the request logger is cached")
+	l.Info("handled")
+	rec.AssertLogged(t, logn.InfoLevel, "handled",
+		logn.String(RequestIDKey, id),
+		logn.String(TraceIDKey, "4bf92f3577b34da6a3ce929d0e0e4736"),
+		logn.String("tenant", "acme"),
+	)
+
+	ctx = Start(context.Background(), c.GetLogger("http"), "given", "")
+	assert.Equal(t, "given", RequestID(ctx))
+	assert.Equal(t, "", TraceID(ctx))
+	assert.Equal(t, "", RequestID(context.Background()))
+}
+
+func TestParseTraceContext(t *testing.T) {
+	tc, ok := ParseTraceContext("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "vendor=opaque")
+	if assert.True(t, ok) {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tc.TraceID)
+		assert.Equal(t, "00f067aa0ba902b7", tc.ParentSpanID)
+		assert.True(t, tc.Sampled())
+		assert.Equal(t, "vendor=opaque", tc.State)
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", tc.Traceparent())
+	}
+
+	tc, ok = ParseTraceContext("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future", "")
+	assert.True(t, ok, "later versions may append fields")
+	assert.False(t, tc.Sampled())
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7_01",
+	} {
+		_, ok := ParseTraceContext(invalid, "")
+		assert.False(t, ok, invalid)
+	}
+
+	ctx := WithTraceContext(context.Background(), tc)
+	got, ok := TraceContextFrom(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, tc, got)
+	_, ok = TraceContextFrom(context.Background())
+	assert.False(t, ok)
+}
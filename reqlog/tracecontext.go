@@ -0,0 +1,105 @@
+package reqlog
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// TraceparentHeader is the W3C Trace Context header carrying the trace id
+	// and the span of the caller.
+	TraceparentHeader = "traceparent"
+	// TracestateHeader is the W3C Trace Context header carrying the
+	// vendor-specific trace data.
+	TracestateHeader = "tracestate"
+
+	// ParentSpanIDKey is the mdc key of the id of the span of the caller,
+	// taken from the traceparent header.
+	ParentSpanIDKey = "parent_span_id"
+)
+
+// TraceContext is the W3C Trace Context of a request, as its caller sent it.
+type TraceContext struct {
+	// TraceID is the trace id, 32 lowercase hex digits.
+	TraceID string
+	// ParentSpanID is the id of the span of the caller, 16 lowercase hex
+	// digits.
+	ParentSpanID string
+	// Flags are the trace flags, the lowest bit telling whether the caller
+	// sampled the trace.
+	Flags byte
+	// State is the tracestate header, kept as is.
+	State string
+}
+
+// Sampled reports whether the caller sampled the trace.
+func (tc TraceContext) Sampled() bool {
+	return tc.Flags&1 == 1
+}
+
+// Traceparent returns the traceparent header of tc, for propagating it to the
+// requests made while serving the request.
+func (tc TraceContext) Traceparent() string {
+	return "00-" + tc.TraceID + "-" + tc.ParentSpanID + "-" + hex.EncodeToString([]byte{tc.Flags})
+}
+
+// ParseTraceContext parses the traceparent and tracestate headers of a
+// request, reporting false if traceparent is missing or invalid, in which
+// case tracestate is to be ignored too. Versions above 00 are parsed as 00,
+// as the specification requires.
+func ParseTraceContext(traceparent, tracestate string) (TraceContext, bool) {
+	traceparent = strings.TrimSpace(traceparent)
+	// version-traceid-parentid-flags, future versions may append fields
+	if len(traceparent) < 55 || (len(traceparent) > 55 && traceparent[55] != '-') {
+		return TraceContext{}, false
+	}
+	if traceparent[2] != '-' || traceparent[35] != '-' || traceparent[52] != '-' {
+		return TraceContext{}, false
+	}
+	version, ok := hexField(traceparent[0:2])
+	if !ok || version == "ff" || (version == "00" && len(traceparent) != 55) {
+		return TraceContext{}, false
+	}
+	traceID, ok := hexField(traceparent[3:35])
+	if !ok || isZero(traceID) {
+		return TraceContext{}, false
+	}
+	spanID, ok := hexField(traceparent[36:52])
+	if !ok || isZero(spanID) {
+		return TraceContext{}, false
+	}
+	flags, ok := hexField(traceparent[53:55])
+	if !ok {
+		return TraceContext{}, false
+	}
+	b, _ := hex.DecodeString(flags)
+	return TraceContext{TraceID: traceID, ParentSpanID: spanID, Flags: b[0], State: strings.TrimSpace(tracestate)}, true
+}
+
+// hexField returns s if it holds lowercase hex digits only.
+func hexField(s string) (string, bool) {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return "", false
+		}
+	}
+	return s, true
+}
+
+func isZero(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
+
+type traceContextKey struct{}
+
+// WithTraceContext returns a copy of ctx carrying tc.
+func WithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// TraceContextFrom returns the trace context carried by ctx, if any.
+func TraceContextFrom(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceContextKey{}).(TraceContext)
+	return tc, ok
+}
@@ -0,0 +1,134 @@
+// Package sign makes log output tamper-evident. Every entry gets a field
+// holding the HMAC-SHA256 of the entry chained with the signature of the
+// previous one, so that modifying, removing or reordering entries breaks the
+// chain, which Verify detects.
+//
+// Signatures are appended as the last member of the encoded JSON object, so
+// only encoders writing one JSON object per line (json and gelf) can be
+// signed. A chain starts with every new signer, i.e. when the process starts
+// or the configuration is reloaded.
+package sign
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// DefaultField is the key of the signature field unless configured otherwise.
+const DefaultField = "sig"
+
+// Config is the signing section of an appender.
+type Config struct {
+	// Key references the HMAC key, in the same form as encryption keys, e.g.
+	// env:NAME or file:PATH.
+	Key string `logn-config:"key" logn-validate:"required"`
+	// Field is the key of the signature field, DefaultField by default.
+	Field string `logn-config:"field"`
+}
+
+// Signer signs the entries written through it.
+type Signer struct {
+	key    []byte
+	suffix []byte // ,"<field>":"
+
+	mu   sync.Mutex
+	prev [sha256.Size]byte
+	buf  []byte
+}
+
+// NewSigner returns a Signer using key, adding signatures under field.
+func NewSigner(key []byte, field string) *Signer {
+	return &Signer{key: key, suffix: suffix(field)}
+}
+
+func suffix(field string) []byte {
+	if field == "" {
+		field = DefaultField
+	}
+	return []byte(`,"` + field + `":"`)
+}
+
+func mac(key []byte, prev []byte, entry []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(prev)
+	h.Write(entry)
+	return h.Sum(nil)
+}
+
+// Write signs the encoded entry and writes it to w. Entries are signed and
+// written in the same order, even when written concurrently.
+func (s *Signer) Write(w io.Writer, entry []byte) (int, error) {
+	line := bytes.TrimSuffix(entry, []byte("\n"))
+	if len(line) == 0 || line[len(line)-1] != '}' {
+		return 0, errors.New("sign: entry is not a JSON object")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sum := mac(s.key, s.prev[:], line)
+	copy(s.prev[:], sum)
+
+	s.buf = append(s.buf[:0], line[:len(line)-1]...)
+	s.buf = append(s.buf, s.suffix...)
+	n := len(s.buf)
+	s.buf = append(s.buf, make([]byte, hex.EncodedLen(len(sum)))...)
+	hex.Encode(s.buf[n:], sum)
+	s.buf = append(s.buf, "\"}\n"...)
+	return w.Write(s.buf)
+}
+
+// Result is the outcome of a successful Verify.
+type Result struct {
+	// Entries is the number of entries verified.
+	Entries int
+	// Restarts lists the line numbers of the entries starting a new chain
+	// after the first one, which are expected where the process was
+	// restarted or reconfigured and suspicious elsewhere.
+	Restarts []int
+}
+
+// Verify checks the chain of signatures of the entries read from r, returning
+// an error naming the first line not matching its signature.
+func Verify(r io.Reader, key []byte, field string) (Result, error) {
+	var res Result
+	suf := suffix(field)
+	prev := make([]byte, sha256.Size)
+	zero := make([]byte, sha256.Size)
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 16<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		i := bytes.LastIndex(line, suf)
+		if i < 0 || !bytes.HasSuffix(line, []byte("\"}")) {
+			return res, fmt.Errorf("line %d: no signature", n)
+		}
+		sig, err := hex.DecodeString(string(line[i+len(suf) : len(line)-2]))
+		if err != nil {
+			return res, fmt.Errorf("line %d: malformed signature", n)
+		}
+		entry := append(line[:i:i], '}')
+		switch {
+		case hmac.Equal(sig, mac(key, prev, entry)):
+		case hmac.Equal(sig, mac(key, zero, entry)):
+			if res.Entries > 0 {
+				res.Restarts = append(res.Restarts, n)
+			}
+		default:
+			return res, fmt.Errorf("line %d: signature mismatch", n)
+		}
+		prev = sig
+		res.Entries++
+	}
+	return res, sc.Err()
+}
@@ -0,0 +1,51 @@
+package sign
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testKey = []byte("0123456789abcdef")
+
+func TestVerify(t *testing.T) {
+	var out bytes.Buffer
+	s := NewSigner(testKey, "")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Write(&out, []byte(`{"msg":"entry"}`+"\n"))
+		}()
+	}
+	wg.Wait()
+	s2 := NewSigner(testKey, "")
+	s2.Write(&out, []byte(`{"msg":"restarted"}`+"\n"))
+
+	_, err := s.Write(&out, []byte("plain text\n"))
+	assert.NotNil(t, err)
+
+	signed := out.String()
+	assert.Contains(t, signed, `{"msg":"entry","sig":"`)
+
+	res, err := Verify(strings.NewReader(signed), testKey, "")
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Entries: 11, Restarts: []int{11}}, res)
+
+	lines := strings.SplitAfter(signed, "\n")
+	for name, tampered := range map[string]string{
+		"modified":  strings.Replace(signed, "restarted", "restart3d", 1),
+		"removed":   strings.Join(append(lines[:3:3], lines[4:]...), ""),
+		"reordered": strings.Join(append([]string{lines[1], lines[0]}, lines[2:]...), ""),
+	} {
+		_, err := Verify(strings.NewReader(tampered), testKey, "")
+		assert.NotNil(t, err, name)
+	}
+
+	_, err = Verify(strings.NewReader(signed), []byte("another key 0123"), "")
+	assert.EqualError(t, err, "line 1: signature mismatch")
+}
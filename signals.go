@@ -0,0 +1,104 @@
+package logn
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/shanexu/logn/core"
+	"github.com/shanexu/logn/status"
+)
+
+// DefaultShutdownTimeout is how long HandleSignals waits for the appenders
+// to close unless ShutdownTimeout is given.
+const DefaultShutdownTimeout = 5 * time.Second
+
+// SignalOption configures HandleSignals.
+type SignalOption func(*signalOptions)
+
+type signalOptions struct {
+	timeout time.Duration
+	sigs    []os.Signal
+}
+
+// ShutdownTimeout sets how long HandleSignals waits for the appenders to
+// close before exiting anyway, DefaultShutdownTimeout by default.
+func ShutdownTimeout(d time.Duration) SignalOption {
+	return func(o *signalOptions) {
+		o.timeout = d
+	}
+}
+
+// Signals sets the signals HandleSignals handles, SIGTERM and SIGINT by
+// default.
+func Signals(sigs ...os.Signal) SignalOption {
+	return func(o *signalOptions) {
+		o.sigs = sigs
+	}
+}
+
+// HandleSignals makes the process, on SIGTERM or SIGINT, log the signal to c,
+// close c, which drains the queues of asynchronous appenders, syncs the files
+// and closes the appenders, and exit, so that the last entries are not lost
+// or cut. Closing waits at most the shutdown timeout, and a second signal
+// exits right away.
+// The process exits through the exit function with status 128 plus the
+// number of the signal, once the exit hooks have run. A nil c stands for the
+// global core. HandleSignals returns the function uninstalling the handler,
+// e.g. to shut down otherwise:
+//
+//	stop := logn.HandleSignals(nil, logn.ShutdownTimeout(2*time.Second))
+func HandleSignals(c core.Core, opts ...SignalOption) (stop func()) {
+	if c == nil {
+		c = logncore
+	}
+	o := signalOptions{timeout: DefaultShutdownTimeout, sigs: []os.Signal{syscall.SIGTERM, syscall.SIGINT}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	ch := make(chan os.Signal, 2)
+	signal.Notify(ch, o.sigs...)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-ch:
+			shutdown(c, sig, ch, o.timeout)
+		case <-done:
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// shutdown closes c, waiting for at most timeout, or until another signal
+// arrives on ch, then exits for sig.
+func shutdown(c core.Core, sig os.Signal, ch chan os.Signal, timeout time.Duration) {
+	c.Infow("shutting down on signal", "signal", sig.String())
+	closed := make(chan struct{})
+	go func() {
+		if err := c.Close(); err != nil {
+			status.Warnf("closing on signal %v: %v", sig, err)
+		}
+		close(closed)
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-closed:
+	case <-timer.C:
+		status.Warnf("closing on signal %v timed out after %v", sig, timeout)
+	case <-ch:
+	}
+	code := 1
+	if s, ok := sig.(syscall.Signal); ok {
+		code = 128 + int(s)
+	}
+	core.Exit(code)
+}
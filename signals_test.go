@@ -0,0 +1,46 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package logn_test
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanexu/logn"
+	"github.com/shanexu/logn/logntest"
+)
+
+func TestHandleSignals(t *testing.T) {
+	c, rec, err := logntest.New("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes := make(chan int, 1)
+	defer logn.SetExitFunc(func(code int) { codes <- code })()
+	stop := logn.HandleSignals(c, logn.Signals(syscall.SIGUSR2), logn.ShutdownTimeout(time.Second))
+	defer stop()
+
+	c.Info("last words")
+	assert.Nil(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR2))
+	select {
+	case code := <-codes:
+		assert.Equal(t, 128+int(syscall.SIGUSR2), code)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the process did not exit")
+	}
+
+	entries := rec.Entries()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "last words", entries[0].Message)
+		assert.Equal(t, "shutting down on signal", entries[1].Message)
+		assert.Equal(t, syscall.SIGUSR2.String(), entries[1].Fields["signal"])
+	}
+
+	// the appenders are closed before exiting
+	c.Info("too late")
+	assert.Len(t, rec.Entries(), 2)
+}
@@ -0,0 +1,140 @@
+// Package status is the logger logn reports on itself with: configuration
+// problems, reload results and appender lifecycle events. It writes to its
+// own sink, stderr by default, independently of the configured loggers, so
+// it keeps working when their configuration does not.
+//
+// Only entries from warn level on are written unless the level is lowered,
+// with SetLevel, the status configuration key or LOGN_DEBUG=true.
+package status
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// recentSize is the number of entries kept for Recent.
+const recentSize = 100
+
+// Entry is a status entry.
+type Entry struct {
+	Time    time.Time
+	Level   zapcore.Level
+	Message string
+}
+
+func (e Entry) String() string {
+	return fmt.Sprintf("%s logn %s: %s", e.Time.Format(time.RFC3339), e.Level.CapitalString(), e.Message)
+}
+
+var (
+	mu     sync.Mutex
+	out    io.Writer = os.Stderr
+	level            = zapcore.WarnLevel
+	forced bool
+	recent []Entry
+	next   int
+)
+
+func init() {
+	if os.Getenv("LOGN_DEBUG") == "true" {
+		level = zapcore.DebugLevel
+		forced = true
+	}
+}
+
+// SetOutput redirects status entries to w.
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	out = w
+	mu.Unlock()
+}
+
+// SetLevel sets the level from which status entries are written.
+func SetLevel(l zapcore.Level) {
+	mu.Lock()
+	level = l
+	mu.Unlock()
+}
+
+// Configure sets the level from its name, as given by the status
+// configuration key. It has no effect under LOGN_DEBUG=true, which forces
+// debug level.
+func Configure(levelName string) error {
+	var l zapcore.Level
+	if err := l.UnmarshalText([]byte(levelName)); err != nil {
+		return fmt.Errorf("invalid status level %q", levelName)
+	}
+	mu.Lock()
+	if !forced {
+		level = l
+	}
+	mu.Unlock()
+	return nil
+}
+
+// Level returns the level from which status entries are written.
+func Level() zapcore.Level {
+	mu.Lock()
+	defer mu.Unlock()
+	return level
+}
+
+// Enabled reports whether entries at l are written.
+func Enabled(l zapcore.Level) bool {
+	return l >= Level()
+}
+
+// Recent returns the last written status entries, oldest first.
+func Recent() []Entry {
+	mu.Lock()
+	defer mu.Unlock()
+	es := make([]Entry, 0, len(recent))
+	if len(recent) == recentSize {
+		es = append(es, recent[next:]...)
+		es = append(es, recent[:next]...)
+	} else {
+		es = append(es, recent...)
+	}
+	return es
+}
+
+// Debugf writes a debug status entry.
+func Debugf(format string, args ...interface{}) {
+	logf(zapcore.DebugLevel, format, args)
+}
+
+// Infof writes an info status entry.
+func Infof(format string, args ...interface{}) {
+	logf(zapcore.InfoLevel, format, args)
+}
+
+// Warnf writes a warn status entry.
+func Warnf(format string, args ...interface{}) {
+	logf(zapcore.WarnLevel, format, args)
+}
+
+// Errorf writes an error status entry.
+func Errorf(format string, args ...interface{}) {
+	logf(zapcore.ErrorLevel, format, args)
+}
+
+func logf(l zapcore.Level, format string, args []interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+	if l < level {
+		return
+	}
+	e := Entry{Time: time.Now(), Level: l, Message: fmt.Sprintf(format, args...)}
+	if len(recent) < recentSize {
+		recent = append(recent, e)
+	} else {
+		recent[next] = e
+		next = (next + 1) % recentSize
+	}
+	fmt.Fprintln(out, e)
+}
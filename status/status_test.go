@@ -0,0 +1,34 @@
+package status
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestStatus(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(os.Stderr)
+	defer SetLevel(Level())
+
+	assert.NoError(t, Configure("info"))
+	assert.Error(t, Configure("verbose"))
+	assert.False(t, Enabled(zapcore.DebugLevel))
+	Debugf("hidden")
+	Infof("reloaded %s", "logn.yml")
+	assert.Contains(t, buf.String(), " logn INFO: reloaded logn.yml\n")
+	assert.NotContains(t, buf.String(), "hidden")
+
+	for i := 0; i < recentSize+5; i++ {
+		Warnf("warning %d", i)
+	}
+	es := Recent()
+	assert.Len(t, es, recentSize)
+	assert.Equal(t, "warning 5", es[0].Message)
+	assert.Equal(t, fmt.Sprintf("warning %d", recentSize+4), es[recentSize-1].Message)
+}
@@ -0,0 +1,28 @@
+package logn
+
+import (
+	"log"
+
+	"github.com/shanexu/logn/core"
+	"github.com/shanexu/logn/core/zap"
+)
+
+// RedirectStdLog routes the output of the standard library's package-global
+// logger to logger at InfoLevel. It returns a function restoring the previous
+// output.
+func RedirectStdLog(logger core.Logger) (func(), error) {
+	return RedirectStdLogAt(logger, InfoLevel)
+}
+
+// RedirectStdLogAt routes the output of the standard library's package-global
+// logger to logger at level. It returns a function restoring the previous
+// output.
+func RedirectStdLogAt(logger core.Logger, level Level) (func(), error) {
+	return zap.RedirectStdLogAt(logger, level)
+}
+
+// NewStdLogAt returns a *log.Logger for libraries that only accept the
+// standard library logger, writing each line as an entry of logger at level.
+func NewStdLogAt(logger core.Logger, level Level) (*log.Logger, error) {
+	return zap.NewStdLogAt(logger, level)
+}